@@ -0,0 +1,26 @@
+package inmemory
+
+import (
+	coreCache "github.com/enesanbar/go-service/core/cache"
+	"github.com/enesanbar/go-service/core/config"
+	"github.com/enesanbar/go-service/core/log"
+	"go.uber.org/zap"
+)
+
+// Caches returns a map of the caches configured under 'cache.inmemory.<name>'.
+func Caches(conf config.Config, logger log.Factory, instrumentor *coreCache.Instrumentor) (map[string]*Cache, error) {
+	caches := make(map[string]*Cache)
+	for _, name := range coreCache.InstanceNames(conf, "inmemory") {
+		cfg, err := NewNamedConfig(conf, name)
+		if err != nil {
+			logger.Bg().
+				With(zap.String("cache", name)).
+				With(zap.Error(err)).
+				Error("failed to create cache config")
+			return nil, err
+		}
+		caches[name] = NewInMemoryCache(cfg, logger, instrumentor)
+	}
+
+	return caches, nil
+}
@@ -2,6 +2,7 @@ package inmemory
 
 import (
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/enesanbar/go-service/core/config"
@@ -9,37 +10,114 @@ import (
 )
 
 const (
+	PolicyUnbounded = "unbounded"
+	PolicyLRU       = "lru"
+	PolicyTinyLFU   = "tinylfu"
+
+	PolicyKey     = "policy"
+	PolicyDefault = PolicyUnbounded
+
 	ExpirationKey     = "default-expiration"
 	ExpirationDefault = 5
 
-	CleanupIntervalKey     = "default-expiration"
+	CleanupIntervalKey     = "cleanup-interval"
 	CleanupIntervalDefault = 10
+
+	MaxEntriesKey = "max-entries"
+	MaxBytesKey   = "max-bytes"
 )
 
 type Config struct {
+	// Name is the name of the instance configured under 'cache.inmemory.<name>', empty for the default cache.
+	Name            string
+	Policy          string
 	Expiration      time.Duration
 	CleanupInterval time.Duration
+	// MaxEntries and MaxBytes bound the lru and tinylfu policies, zero means no limit.
+	MaxEntries int
+	MaxBytes   int64
+	// Cost returns the size of an entry accounted against MaxBytes.
+	Cost CostFunc
 }
 
 func NewConfig(cfg config.Config, logger log.Factory) (*Config, error) {
-	prefix := "cache.inmemory"
+	return newConfig(cfg, "", "cache.inmemory")
+}
+
+// NewNamedConfig returns the config of the instance configured under 'cache.inmemory.<name>'.
+func NewNamedConfig(cfg config.Config, name string) (*Config, error) {
+	return newConfig(cfg, name, fmt.Sprintf("cache.inmemory.%s", name))
+}
+
+func newConfig(cfg config.Config, name string, prefix string) (*Config, error) {
 	keyTemplate := "%s.%s"
-	property := fmt.Sprintf(keyTemplate, prefix, ExpirationKey)
 
-	expiration := cfg.GetInt(property)
-	if expiration == 0 {
-		expiration = ExpirationDefault
+	policy := cfg.GetString(fmt.Sprintf(keyTemplate, prefix, PolicyKey))
+	if policy == "" {
+		policy = PolicyDefault
+	}
+
+	property := fmt.Sprintf(keyTemplate, prefix, ExpirationKey)
+	expiration, err := duration(cfg, property, ExpirationDefault*time.Minute)
+	if err != nil {
+		return nil, err
 	}
 
 	property = fmt.Sprintf(keyTemplate, prefix, CleanupIntervalKey)
+	cleanupInterval, err := duration(cfg, property, CleanupIntervalDefault*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	maxEntries := cfg.GetInt(fmt.Sprintf(keyTemplate, prefix, MaxEntriesKey))
+	maxBytes := int64(cfg.GetInt(fmt.Sprintf(keyTemplate, prefix, MaxBytesKey)))
 
-	cleanupInterval := cfg.GetInt(property)
-	if cleanupInterval == 0 {
-		cleanupInterval = CleanupIntervalDefault
+	switch policy {
+	case PolicyUnbounded:
+	case PolicyLRU:
+		if maxEntries <= 0 && maxBytes <= 0 {
+			return nil, fmt.Errorf("lru policy of '%s' requires 'max-entries' or 'max-bytes' to be set", prefix)
+		}
+	case PolicyTinyLFU:
+		if maxEntries <= 0 && maxBytes <= 0 {
+			return nil, fmt.Errorf("tinylfu policy of '%s' requires 'max-entries' or 'max-bytes' to be set", prefix)
+		}
+		if maxEntries > 0 && maxBytes > 0 {
+			return nil, fmt.Errorf("tinylfu policy of '%s' supports either 'max-entries' or 'max-bytes', not both", prefix)
+		}
+	default:
+		return nil, fmt.Errorf("invalid policy of '%s': '%s', must be one of unbounded, lru or tinylfu", prefix, policy)
 	}
 
 	return &Config{
-		Expiration:      time.Duration(expiration) * time.Minute,
-		CleanupInterval: time.Duration(cleanupInterval) * time.Minute,
+		Name:            name,
+		Policy:          policy,
+		Expiration:      expiration,
+		CleanupInterval: cleanupInterval,
+		MaxEntries:      maxEntries,
+		MaxBytes:        maxBytes,
+		Cost:            EstimateCost,
 	}, nil
 }
+
+// duration reads a duration property such as '500ms' or '1m30s'.
+// Plain numbers are read as minutes for backward compatibility.
+func duration(cfg config.Config, property string, defaultValue time.Duration) (time.Duration, error) {
+	value := cfg.GetString(property)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	if minutes, err := strconv.Atoi(value); err == nil {
+		if minutes == 0 {
+			return defaultValue, nil
+		}
+		return time.Duration(minutes) * time.Minute, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration of '%s': %w", property, err)
+	}
+	return d, nil
+}
@@ -0,0 +1,66 @@
+package inmemory
+
+import (
+	"reflect"
+)
+
+// CostFunc returns the size of an entry in bytes, accounted against the 'max-bytes' limit.
+type CostFunc func(key string, value interface{}) int64
+
+// EstimateCost is the default CostFunc.
+// It returns the exact length of []byte and string values,
+// and an estimate of the memory held by other values.
+func EstimateCost(key string, value interface{}) int64 {
+	switch v := value.(type) {
+	case []byte:
+		return int64(len(key) + len(v))
+	case string:
+		return int64(len(key) + len(v))
+	}
+
+	return int64(len(key)) + estimateSize(reflect.ValueOf(value), 0)
+}
+
+// maxDepth stops the estimation of deeply nested or cyclic values.
+const maxDepth = 8
+
+func estimateSize(v reflect.Value, depth int) int64 {
+	if !v.IsValid() {
+		return 0
+	}
+
+	size := int64(v.Type().Size())
+	if depth >= maxDepth {
+		return size
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		size += int64(v.Len())
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			size += estimateSize(v.Index(i), depth+1)
+		}
+	case reflect.Array:
+		size = 0
+		for i := 0; i < v.Len(); i++ {
+			size += estimateSize(v.Index(i), depth+1)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			size += estimateSize(iter.Key(), depth+1) + estimateSize(iter.Value(), depth+1)
+		}
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			size += estimateSize(v.Elem(), depth+1)
+		}
+	case reflect.Struct:
+		size = 0
+		for i := 0; i < v.NumField(); i++ {
+			size += estimateSize(v.Field(i), depth+1)
+		}
+	}
+
+	return size
+}
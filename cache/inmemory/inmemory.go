@@ -2,20 +2,25 @@ package inmemory
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	coreCache "github.com/enesanbar/go-service/core/cache"
 	"github.com/enesanbar/go-service/core/log"
-	"github.com/patrickmn/go-cache"
 	"go.uber.org/zap"
 )
 
-var ErrKeyNotFound = errors.New("'key' not found in cache")
+// ErrKeyNotFound is kept for backward compatibility, use cache.ErrKeyNotFound of the core module instead.
+var ErrKeyNotFound = coreCache.ErrKeyNotFound
 
-// Cache is an in memory cache implementation of Cache interface
+// Cache is an in memory cache implementation of Cache interface.
+// The eviction policy of the cache is selected with 'cache.inmemory.policy'.
 type Cache struct {
 	cfg          *Config
-	cache        *cache.Cache
+	cacheType    string
+	store        store
+	tags         *tagIndex
 	log          log.Factory
 	instrumentor *coreCache.Instrumentor
 }
@@ -23,34 +28,92 @@ type Cache struct {
 // NewInMemoryCache returns a pointer to the instance of Cache
 func NewInMemoryCache(cfg *Config, log log.Factory, instrumentor *coreCache.Instrumentor) *Cache {
 	log.Bg().With(zap.Any("config", cfg)).Info("creating inmemory cache")
-	return &Cache{
+
+	cacheType := "inmemory"
+	if cfg.Name != "" {
+		cacheType = fmt.Sprintf("inmemory.%s", cfg.Name)
+	}
+
+	c := &Cache{
 		cfg:          cfg,
-		cache:        cache.New(cfg.Expiration, cfg.CleanupInterval),
+		cacheType:    cacheType,
+		tags:         newTagIndex(),
 		log:          log,
 		instrumentor: instrumentor,
 	}
+	c.store = newStore(cfg, func(key string, reason string) {
+		c.tags.remove(key)
+		if reason != "" {
+			instrumentor.Evict(reason, cacheType)
+		}
+	})
+
+	instrumentor.RegisterSize(cacheType, func() int64 {
+		return int64(c.Len())
+	})
+	return c
 }
 
 // Set sets a value in cache
-func (c *Cache) Set(_ context.Context, key string, value interface{}) error {
-	c.cache.Set(key, value, c.cfg.Expiration)
+func (c *Cache) Set(_ context.Context, key string, value interface{}, options ...coreCache.SetOption) error {
+	defer c.instrumentor.Observe("set", c.cacheType, time.Now())
+
+	o := coreCache.NewSetOptions(options...)
+
+	expiration := c.cfg.Expiration
+	if o.TTL > 0 {
+		expiration = o.TTL
+	}
+
+	// the tags are indexed first, so that an immediate eviction removes them
+	c.tags.set(key, o.Tags)
+	c.store.set(key, value, expiration)
 	return nil
 }
 
 // Get gets a value from the cache
 func (c *Cache) Get(_ context.Context, key string) (interface{}, error) {
-	cached, found := c.cache.Get(key)
+	defer c.instrumentor.Observe("get", c.cacheType, time.Now())
+
+	cached, found := c.store.get(key)
 	if found {
-		c.instrumentor.Hit(key, "inmemory")
+		c.instrumentor.Hit(key, c.cacheType)
 		return cached, nil
 	}
 
-	c.instrumentor.Miss(key, "inmemory")
+	c.instrumentor.Miss(key, c.cacheType)
 	return nil, ErrKeyNotFound
 }
 
 // Invalidate invalidates a value in the cache
 func (c *Cache) Invalidate(_ context.Context, key string) error {
-	c.cache.Delete(key)
+	c.tags.remove(key)
+	c.store.delete(key)
 	return nil
 }
+
+// InvalidateTags invalidates the entries set with any of the tags
+func (c *Cache) InvalidateTags(_ context.Context, tags ...string) error {
+	for _, key := range c.tags.keysOf(tags...) {
+		c.tags.remove(key)
+		c.store.delete(key)
+	}
+	return nil
+}
+
+// InvalidatePrefix invalidates the entries whose keys start with the prefix.
+// An empty prefix invalidates all the entries.
+func (c *Cache) InvalidatePrefix(_ context.Context, prefix string) error {
+	for _, key := range c.store.keys() {
+		if strings.HasPrefix(key, prefix) {
+			c.tags.remove(key)
+			c.store.delete(key)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of entries in the cache, which may include the expired ones not yet removed.
+func (c *Cache) Len() int {
+	return c.store.len()
+}
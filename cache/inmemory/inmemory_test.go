@@ -0,0 +1,306 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	coreCache "github.com/enesanbar/go-service/core/cache"
+	"github.com/enesanbar/go-service/core/config"
+	"github.com/enesanbar/go-service/core/config/configtest"
+	"github.com/enesanbar/go-service/core/log"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+func newTestCache(t *testing.T, properties map[string]any) *Cache {
+	t.Helper()
+
+	cfg, err := newTestConfig(t, properties)
+	if err != nil {
+		t.Fatalf("unable to create inmemory config: %v", err)
+	}
+
+	instrumentor, err := coreCache.NewInstrumentor(coreCache.InstrumentorParams{})
+	if err != nil {
+		t.Fatalf("unable to create instrumentor: %v", err)
+	}
+
+	return NewInMemoryCache(cfg, log.NewFactory(zap.NewNop()), instrumentor)
+}
+
+func newTestConfig(t *testing.T, properties map[string]any) (*Config, error) {
+	t.Helper()
+
+	return NewConfig(configtest.New(t, properties), log.NewFactory(zap.NewNop()))
+}
+
+func TestNewConfig_Durations(t *testing.T) {
+	cfg, err := newTestConfig(t, map[string]any{
+		"cache.inmemory.default-expiration": 3,
+		"cache.inmemory.cleanup-interval":   "500ms",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Expiration != 3*time.Minute {
+		t.Errorf("Expected expiration to be '%s', got '%s'", 3*time.Minute, cfg.Expiration)
+	}
+	if cfg.CleanupInterval != 500*time.Millisecond {
+		t.Errorf("Expected cleanup interval to be '%s', got '%s'", 500*time.Millisecond, cfg.CleanupInterval)
+	}
+}
+
+func TestNewConfig_BoundedPolicyRequiresLimit(t *testing.T) {
+	for _, policy := range []string{PolicyLRU, PolicyTinyLFU} {
+		_, err := newTestConfig(t, map[string]any{"cache.inmemory.policy": policy})
+		if err == nil {
+			t.Errorf("Expected an error for policy '%s' without limits", policy)
+		}
+	}
+
+	_, err := newTestConfig(t, map[string]any{"cache.inmemory.policy": "fifo"})
+	if err == nil {
+		t.Error("Expected an error for an unknown policy")
+	}
+}
+
+func TestCache_LRUMaxEntries(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCache(t, map[string]any{
+		"cache.inmemory.policy":      PolicyLRU,
+		"cache.inmemory.max-entries": 2,
+	})
+
+	_ = c.Set(ctx, "a", 1)
+	_ = c.Set(ctx, "b", 2)
+	// touching 'a' makes 'b' the least recently used entry
+	_, _ = c.Get(ctx, "a")
+	_ = c.Set(ctx, "c", 3)
+
+	if _, err := c.Get(ctx, "b"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected 'b' to be evicted, got '%v'", err)
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, err := c.Get(ctx, key); err != nil {
+			t.Errorf("Expected '%s' to be cached, got '%v'", key, err)
+		}
+	}
+	if c.Len() != 2 {
+		t.Errorf("Expected length to be '%d', got '%d'", 2, c.Len())
+	}
+}
+
+func TestCache_LRUMaxBytes(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCache(t, map[string]any{
+		"cache.inmemory.policy":    PolicyLRU,
+		"cache.inmemory.max-bytes": 30,
+	})
+
+	// each entry costs 2 bytes of key and 8 bytes of value
+	for i := 0; i < 5; i++ {
+		_ = c.Set(ctx, fmt.Sprintf("k%d", i), []byte("12345678"))
+	}
+
+	if c.Len() != 3 {
+		t.Errorf("Expected length to be '%d', got '%d'", 3, c.Len())
+	}
+	if _, err := c.Get(ctx, "k0"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected 'k0' to be evicted, got '%v'", err)
+	}
+
+	// an entry larger than the cache is not stored
+	_ = c.Set(ctx, "large", make([]byte, 64))
+	if _, err := c.Get(ctx, "large"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected 'large' not to be cached, got '%v'", err)
+	}
+}
+
+func TestCache_LRUSubSecondTTL(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCache(t, map[string]any{
+		"cache.inmemory.policy":      PolicyLRU,
+		"cache.inmemory.max-entries": 10,
+	})
+
+	_ = c.Set(ctx, "key", "value", coreCache.WithTTL(20*time.Millisecond))
+	if _, err := c.Get(ctx, "key"); err != nil {
+		t.Fatalf("Expected 'key' to be cached, got '%v'", err)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	if _, err := c.Get(ctx, "key"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected 'key' to be expired, got '%v'", err)
+	}
+}
+
+func TestCache_TinyLFUMaxEntries(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCache(t, map[string]any{
+		"cache.inmemory.policy":      PolicyTinyLFU,
+		"cache.inmemory.max-entries": 100,
+	})
+
+	for i := 0; i < 1000; i++ {
+		_ = c.Set(ctx, fmt.Sprintf("key-%d", i), i)
+	}
+
+	tinyLFU := c.store.(*tinyLFUStore)
+	tinyLFU.cache.CleanUp()
+	if c.Len() > 100 {
+		t.Errorf("Expected length to be at most '%d', got '%d'", 100, c.Len())
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	if cost := EstimateCost("key", []byte("value")); cost != 8 {
+		t.Errorf("Expected cost to be '%d', got '%d'", 8, cost)
+	}
+	if cost := EstimateCost("key", "value"); cost != 8 {
+		t.Errorf("Expected cost to be '%d', got '%d'", 8, cost)
+	}
+
+	small := EstimateCost("key", map[string]string{"a": "b"})
+	large := EstimateCost("key", map[string]string{"a": string(make([]byte, 1024))})
+	if large-small < 1000 {
+		t.Errorf("Expected the cost to grow with the value, got '%d' and '%d'", small, large)
+	}
+}
+
+func TestModule_NamedCaches(t *testing.T) {
+	cfg := configtest.New(t, map[string]any{
+		"cache.inmemory.default-expiration":   1,
+		"cache.inmemory.sessions.policy":      PolicyLRU,
+		"cache.inmemory.sessions.max-entries": 1,
+		"cache.inmemory.catalog.policy":       PolicyTinyLFU,
+		"cache.inmemory.catalog.max-entries":  100,
+	})
+
+	var p struct {
+		fx.In
+
+		Default  coreCache.Cache
+		Caches   map[string]coreCache.Cache
+		Sessions coreCache.Cache `name:"cache.sessions"`
+	}
+
+	app := fx.New(
+		fx.NopLogger,
+		fx.Supply(fx.Annotate(cfg, fx.As(new(config.Config)))),
+		fx.Supply(log.NewFactory(zap.NewNop())),
+		coreCache.Module,
+		Module,
+		coreCache.Named("sessions"),
+		fx.Populate(&p),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(p.Caches) != 2 {
+		t.Fatalf("Expected '%d' named caches, got '%d'", 2, len(p.Caches))
+	}
+	if p.Caches["sessions"] != p.Sessions {
+		t.Error("Expected the named value to be the 'sessions' cache")
+	}
+	if p.Default == p.Sessions {
+		t.Error("Expected the default cache to be a separate instance")
+	}
+
+	sessions := p.Sessions.(*Cache)
+	if sessions.cfg.MaxEntries != 1 || sessions.cfg.Policy != PolicyLRU {
+		t.Errorf("Expected the 'sessions' cache to be configured from its block, got '%+v'", sessions.cfg)
+	}
+}
+
+func TestCache_InvalidateTags(t *testing.T) {
+	for _, policy := range []string{PolicyUnbounded, PolicyLRU, PolicyTinyLFU} {
+		t.Run(policy, func(t *testing.T) {
+			ctx := context.Background()
+			c := newTestCache(t, map[string]any{
+				"cache.inmemory.policy":      policy,
+				"cache.inmemory.max-entries": 100,
+			})
+
+			_ = c.Set(ctx, "product:1", "product", coreCache.WithTags("product:1"))
+			_ = c.Set(ctx, "products:page:1", "list", coreCache.WithTags("product:1", "product:2"))
+			_ = c.Set(ctx, "products:page:2", "list", coreCache.WithTags("product:3"))
+			// setting the key again without tags drops its tags
+			_ = c.Set(ctx, "category:1", "category", coreCache.WithTags("product:1"))
+			_ = c.Set(ctx, "category:1", "category")
+
+			if err := c.InvalidateTags(ctx, "product:1"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, key := range []string{"product:1", "products:page:1"} {
+				if _, err := c.Get(ctx, key); !errors.Is(err, ErrKeyNotFound) {
+					t.Errorf("Expected '%s' to be invalidated, got '%v'", key, err)
+				}
+			}
+			for _, key := range []string{"products:page:2", "category:1"} {
+				if _, err := c.Get(ctx, key); err != nil {
+					t.Errorf("Expected '%s' to be cached, got '%v'", key, err)
+				}
+			}
+		})
+	}
+}
+
+func TestCache_InvalidatePrefix(t *testing.T) {
+	for _, policy := range []string{PolicyUnbounded, PolicyLRU, PolicyTinyLFU} {
+		t.Run(policy, func(t *testing.T) {
+			ctx := context.Background()
+			c := newTestCache(t, map[string]any{
+				"cache.inmemory.policy":      policy,
+				"cache.inmemory.max-entries": 100,
+			})
+
+			_ = c.Set(ctx, "user:1", 1, coreCache.WithTags("users"))
+			_ = c.Set(ctx, "user:2", 2)
+			_ = c.Set(ctx, "users", []int{1, 2})
+
+			if err := c.InvalidatePrefix(ctx, "user:"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, key := range []string{"user:1", "user:2"} {
+				if _, err := c.Get(ctx, key); !errors.Is(err, ErrKeyNotFound) {
+					t.Errorf("Expected '%s' to be invalidated, got '%v'", key, err)
+				}
+			}
+			if _, err := c.Get(ctx, "users"); err != nil {
+				t.Errorf("Expected '%s' to be cached, got '%v'", "users", err)
+			}
+			if keys := c.tags.keysOf("users"); len(keys) != 0 {
+				t.Errorf("Expected the tags of the invalidated entries to be removed, got '%v'", keys)
+			}
+
+			if err := c.InvalidatePrefix(ctx, ""); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if c.Len() != 0 {
+				t.Errorf("Expected an empty prefix to invalidate all the entries, got '%d' entries", c.Len())
+			}
+		})
+	}
+}
+
+func TestCache_EvictionRemovesTags(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCache(t, map[string]any{
+		"cache.inmemory.policy":      PolicyLRU,
+		"cache.inmemory.max-entries": 1,
+	})
+
+	_ = c.Set(ctx, "a", 1, coreCache.WithTags("tag"))
+	_ = c.Set(ctx, "b", 2)
+
+	if keys := c.tags.keysOf("tag"); len(keys) != 0 {
+		t.Errorf("Expected the tags of the evicted entry to be removed, got '%v'", keys)
+	}
+}
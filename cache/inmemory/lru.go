@@ -0,0 +1,164 @@
+package inmemory
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type lruEntry struct {
+	key       string
+	value     interface{}
+	cost      int64
+	expiresAt time.Time
+}
+
+func (e *lruEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// lruStore evicts the least recently used entries once MaxEntries or MaxBytes is exceeded.
+// Expired entries are removed when they are read, and preferred over live ones when evicting.
+type lruStore struct {
+	mu         sync.Mutex
+	items      map[string]*list.Element
+	order      *list.List
+	bytes      int64
+	maxEntries int
+	maxBytes   int64
+	cost       CostFunc
+	onEvict    evictFunc
+}
+
+func newLRUStore(cfg *Config, onEvict evictFunc) *lruStore {
+	return &lruStore{
+		items:      make(map[string]*list.Element),
+		order:      list.New(),
+		maxEntries: cfg.MaxEntries,
+		maxBytes:   cfg.MaxBytes,
+		cost:       cfg.Cost,
+		onEvict:    onEvict,
+	}
+}
+
+func (s *lruStore) get(key string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*lruEntry)
+	if entry.expired(time.Now()) {
+		s.remove(element, EvictionReasonExpiration)
+		return nil, false
+	}
+
+	s.order.MoveToFront(element)
+	return entry.value, true
+}
+
+func (s *lruStore) set(key string, value interface{}, ttl time.Duration) {
+	entry := &lruEntry{key: key, value: value}
+	if s.cost != nil {
+		entry.cost = s.cost(key, value)
+	}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// an entry larger than the whole cache would evict everything else and then itself
+	if s.maxBytes > 0 && entry.cost > s.maxBytes {
+		if element, ok := s.items[key]; ok {
+			s.remove(element, "")
+		}
+		s.notify(key, EvictionReasonCapacity)
+		return
+	}
+
+	if element, ok := s.items[key]; ok {
+		s.bytes -= element.Value.(*lruEntry).cost
+		element.Value = entry
+		s.order.MoveToFront(element)
+	} else {
+		s.items[key] = s.order.PushFront(entry)
+	}
+	s.bytes += entry.cost
+
+	s.evict()
+}
+
+func (s *lruStore) delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.items[key]; ok {
+		s.remove(element, "")
+	}
+}
+
+func (s *lruStore) keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.items))
+	for key := range s.items {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (s *lruStore) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.items)
+}
+
+// evict removes entries until the limits are satisfied, expired entries first.
+func (s *lruStore) evict() {
+	if !s.overflows() {
+		return
+	}
+
+	now := time.Now()
+	for element := s.order.Back(); element != nil && s.overflows(); {
+		previous := element.Prev()
+		if element.Value.(*lruEntry).expired(now) {
+			s.remove(element, EvictionReasonExpiration)
+		}
+		element = previous
+	}
+
+	for s.overflows() {
+		s.remove(s.order.Back(), EvictionReasonCapacity)
+	}
+}
+
+func (s *lruStore) overflows() bool {
+	return (s.maxEntries > 0 && len(s.items) > s.maxEntries) ||
+		(s.maxBytes > 0 && s.bytes > s.maxBytes)
+}
+
+// remove removes the element, reporting an eviction unless the reason is empty.
+func (s *lruStore) remove(element *list.Element, reason string) {
+	entry := element.Value.(*lruEntry)
+	s.order.Remove(element)
+	delete(s.items, entry.key)
+	s.bytes -= entry.cost
+
+	if reason != "" {
+		s.notify(entry.key, reason)
+	}
+}
+
+func (s *lruStore) notify(key string, reason string) {
+	if s.onEvict != nil {
+		s.onEvict(key, reason)
+	}
+}
@@ -6,14 +6,28 @@ import (
 	"go.uber.org/fx"
 )
 
+// BackendModule provides the in-memory cache as *Cache without providing it as cache.Cache,
+// so that it can be composed with other caches, e.g. as the near tier of cache/tiered.
+var BackendModule = fx.Provide(
+	NewConfig,
+	NewInMemoryCache,
+)
+
+// Module provides the default in-memory cache as cache.Cache,
+// and the caches configured under 'cache.inmemory.<name>' as named caches.
 var Module = fx.Module(
 	"cache.inmemory",
+	BackendModule,
 	fx.Provide(
-		NewConfig,
-		fx.Annotate(
-			NewInMemoryCache,
-			fx.As(new(cache.Cache)),
-		),
+		func(c *Cache) cache.Cache { return c },
+		Caches,
+		cache.AsNamedCaches(func(caches map[string]*Cache) []cache.NamedCache {
+			result := make([]cache.NamedCache, 0, len(caches))
+			for name, c := range caches {
+				result = append(result, cache.NamedCache{Name: name, Cache: c})
+			}
+			return result
+		}),
 	),
 )
 
@@ -0,0 +1,78 @@
+package inmemory
+
+import (
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+// Eviction reasons reported to the instrumentor.
+const (
+	EvictionReasonCapacity   = "capacity"
+	EvictionReasonExpiration = "expiration"
+)
+
+// store is the storage of a Cache, implemented for each eviction policy.
+type store interface {
+	get(key string) (interface{}, bool)
+	set(key string, value interface{}, ttl time.Duration)
+	delete(key string)
+	keys() []string
+	len() int
+}
+
+// evictFunc is called for the entries removed by the store itself, with the reason of the eviction.
+// The reason is empty when the store does not tell evictions apart from other removals.
+type evictFunc func(key string, reason string)
+
+func newStore(cfg *Config, onEvict evictFunc) store {
+	switch cfg.Policy {
+	case PolicyLRU:
+		return newLRUStore(cfg, onEvict)
+	case PolicyTinyLFU:
+		return newTinyLFUStore(cfg, onEvict)
+	default:
+		return newGoCacheStore(cfg, onEvict)
+	}
+}
+
+// goCacheStore is the unbounded store of the default policy.
+type goCacheStore struct {
+	cache *cache.Cache
+}
+
+func newGoCacheStore(cfg *Config, onEvict evictFunc) *goCacheStore {
+	c := cache.New(cfg.Expiration, cfg.CleanupInterval)
+	if onEvict != nil {
+		// go-cache calls it for the deleted entries as well as the expired ones
+		c.OnEvicted(func(key string, _ interface{}) {
+			onEvict(key, "")
+		})
+	}
+	return &goCacheStore{cache: c}
+}
+
+func (s *goCacheStore) get(key string) (interface{}, bool) {
+	return s.cache.Get(key)
+}
+
+func (s *goCacheStore) set(key string, value interface{}, ttl time.Duration) {
+	s.cache.Set(key, value, ttl)
+}
+
+func (s *goCacheStore) delete(key string) {
+	s.cache.Delete(key)
+}
+
+func (s *goCacheStore) keys() []string {
+	items := s.cache.Items()
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (s *goCacheStore) len() int {
+	return s.cache.ItemCount()
+}
@@ -0,0 +1,74 @@
+package inmemory
+
+import "sync"
+
+// tagIndex maps the tags to the keys of the entries set with them.
+type tagIndex struct {
+	mu   sync.Mutex
+	keys map[string]map[string]struct{}
+	tags map[string][]string
+}
+
+func newTagIndex() *tagIndex {
+	return &tagIndex{
+		keys: make(map[string]map[string]struct{}),
+		tags: make(map[string][]string),
+	}
+}
+
+// set replaces the tags of the key.
+func (t *tagIndex) set(key string, tags []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.removeLocked(key)
+	if len(tags) == 0 {
+		return
+	}
+
+	for _, tag := range tags {
+		keys, ok := t.keys[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			t.keys[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+	t.tags[key] = tags
+}
+
+func (t *tagIndex) remove(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.removeLocked(key)
+}
+
+// keysOf returns the keys tagged with any of the tags.
+func (t *tagIndex) keysOf(tags ...string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, tag := range tags {
+		for key := range t.keys[tag] {
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+func (t *tagIndex) removeLocked(key string) {
+	for _, tag := range t.tags[key] {
+		delete(t.keys[tag], key)
+		if len(t.keys[tag]) == 0 {
+			delete(t.keys, tag)
+		}
+	}
+	delete(t.tags, key)
+}
@@ -0,0 +1,94 @@
+package inmemory
+
+import (
+	"math"
+	"time"
+
+	"github.com/maypok86/otter/v2"
+)
+
+type tinyLFUEntry struct {
+	value interface{}
+	cost  int64
+	// ttl of zero never expires the entry
+	ttl time.Duration
+}
+
+// tinyLFUStore is backed by otter, which implements the W-TinyLFU policy.
+// It admits new entries based on their estimated access frequency,
+// so that a burst of one-off keys does not flush the frequently used ones.
+type tinyLFUStore struct {
+	cache *otter.Cache[string, tinyLFUEntry]
+	cost  CostFunc
+}
+
+func newTinyLFUStore(cfg *Config, onEvict evictFunc) *tinyLFUStore {
+	s := &tinyLFUStore{cost: cfg.Cost}
+
+	options := &otter.Options[string, tinyLFUEntry]{
+		ExpiryCalculator: otter.ExpiryWritingFunc(func(entry otter.Entry[string, tinyLFUEntry]) time.Duration {
+			return entry.Value.ttl
+		}),
+		OnDeletion: func(event otter.DeletionEvent[string, tinyLFUEntry]) {
+			if !event.WasEvicted() || onEvict == nil {
+				return
+			}
+			// the listener runs asynchronously, the key may have been set again in the meantime
+			if _, ok := s.cache.GetEntryQuietly(event.Key); ok {
+				return
+			}
+			reason := EvictionReasonCapacity
+			if event.Cause == otter.CauseExpiration {
+				reason = EvictionReasonExpiration
+			}
+			onEvict(event.Key, reason)
+		},
+	}
+
+	if cfg.MaxBytes > 0 {
+		options.MaximumWeight = uint64(cfg.MaxBytes)
+		options.Weigher = func(_ string, entry tinyLFUEntry) uint32 {
+			if entry.cost > math.MaxUint32 {
+				return math.MaxUint32
+			}
+			return uint32(entry.cost)
+		}
+	} else {
+		options.MaximumSize = cfg.MaxEntries
+	}
+
+	s.cache = otter.Must(options)
+	return s
+}
+
+func (s *tinyLFUStore) get(key string) (interface{}, bool) {
+	entry, ok := s.cache.GetIfPresent(key)
+	if !ok {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (s *tinyLFUStore) set(key string, value interface{}, ttl time.Duration) {
+	entry := tinyLFUEntry{value: value, ttl: ttl}
+	if s.cost != nil {
+		entry.cost = s.cost(key, value)
+	}
+	s.cache.Set(key, entry)
+}
+
+func (s *tinyLFUStore) delete(key string) {
+	s.cache.Invalidate(key)
+}
+
+func (s *tinyLFUStore) keys() []string {
+	keys := make([]string, 0, s.cache.EstimatedSize())
+	for key := range s.cache.Keys() {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (s *tinyLFUStore) len() int {
+	return s.cache.EstimatedSize()
+}
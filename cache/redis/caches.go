@@ -0,0 +1,49 @@
+package redis
+
+import (
+	coreCache "github.com/enesanbar/go-service/core/cache"
+	"github.com/enesanbar/go-service/core/config"
+	"github.com/enesanbar/go-service/core/log"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type CachesParams struct {
+	fx.In
+
+	Config         config.Config
+	TracerProvider *trace.TracerProvider
+	Logger         log.Factory
+	Instrumentor   *coreCache.Instrumentor
+}
+
+// Caches returns a map of the caches configured under 'cache.redis.<name>',
+// each connected with its own client.
+func Caches(p CachesParams) (map[string]*Cache, error) {
+	caches := make(map[string]*Cache)
+	for _, name := range coreCache.InstanceNames(p.Config, "redis") {
+		cfg, err := NewNamedConfig(p.Config, name)
+		if err != nil {
+			p.Logger.Bg().
+				With(zap.String("cache", name)).
+				With(zap.Error(err)).
+				Error("failed to create cache config")
+			return nil, err
+		}
+
+		client, err := NewClient(ClientParams{Config: cfg, TracerProvider: p.TracerProvider})
+		if err != nil {
+			return nil, err
+		}
+
+		caches[name] = NewRedisCache(CacheParams{
+			Config:       cfg,
+			Client:       client,
+			Logger:       p.Logger,
+			Instrumentor: p.Instrumentor,
+		})
+	}
+
+	return caches, nil
+}
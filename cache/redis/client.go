@@ -0,0 +1,49 @@
+package redis
+
+import (
+	"fmt"
+
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	goredis "github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/fx"
+)
+
+type ClientParams struct {
+	fx.In
+
+	Config         *Config
+	TracerProvider *trace.TracerProvider
+}
+
+// NewClient returns a redis client for the configured mode,
+// instrumented with OpenTelemetry tracing.
+func NewClient(p ClientParams) (goredis.UniversalClient, error) {
+	options := &goredis.UniversalOptions{
+		Addrs:        p.Config.Addresses,
+		MasterName:   p.Config.MasterName,
+		Username:     p.Config.Username,
+		Password:     p.Config.Password,
+		DB:           p.Config.DB,
+		PoolSize:     p.Config.PoolSize,
+		DialTimeout:  p.Config.DialTimeout,
+		ReadTimeout:  p.Config.ReadTimeout,
+		WriteTimeout: p.Config.WriteTimeout,
+	}
+
+	var client goredis.UniversalClient
+	switch p.Config.Mode {
+	case ModeSentinel:
+		client = goredis.NewFailoverClient(options.Failover())
+	case ModeCluster:
+		client = goredis.NewClusterClient(options.Cluster())
+	default:
+		client = goredis.NewClient(options.Simple())
+	}
+
+	if err := redisotel.InstrumentTracing(client, redisotel.WithTracerProvider(p.TracerProvider)); err != nil {
+		return nil, fmt.Errorf("unable to instrument redis client: %w", err)
+	}
+
+	return client, nil
+}
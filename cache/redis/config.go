@@ -0,0 +1,155 @@
+package redis
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	coreCache "github.com/enesanbar/go-service/core/cache"
+	"github.com/enesanbar/go-service/core/config"
+)
+
+const (
+	ModeStandalone = "standalone"
+	ModeSentinel   = "sentinel"
+	ModeCluster    = "cluster"
+
+	ModeKey     = "mode"
+	ModeDefault = ModeStandalone
+
+	AddressesKey     = "addresses"
+	AddressesDefault = "localhost:6379"
+
+	MasterNameKey = "master-name"
+	UsernameKey   = "username"
+	PasswordKey   = "password"
+	DBKey         = "db"
+	KeyPrefixKey  = "key-prefix"
+
+	CodecKey     = "codec"
+	CodecDefault = "json"
+
+	ExpirationKey     = "default-expiration"
+	ExpirationDefault = 5
+
+	PoolSizeKey = "pool-size"
+
+	InvalidationChannelKey     = "invalidation-channel"
+	InvalidationChannelDefault = "cache-invalidations"
+
+	DialTimeoutKey     = "dial-timeout"
+	DialTimeoutDefault = 5
+
+	ReadTimeoutKey     = "read-timeout"
+	ReadTimeoutDefault = 3
+
+	WriteTimeoutKey     = "write-timeout"
+	WriteTimeoutDefault = 3
+)
+
+type Config struct {
+	// Name is the name of the instance configured under 'cache.redis.<name>', empty for the default cache.
+	Name       string
+	Mode       string
+	Addresses  []string
+	MasterName string
+	Username   string
+	Password   string
+	DB         int
+	KeyPrefix  string
+	Codec      coreCache.Codec
+	Expiration time.Duration
+	PoolSize   int
+	// InvalidationChannel is the pub/sub channel of the invalidations broadcast between the instances.
+	InvalidationChannel string
+	DialTimeout         time.Duration
+	ReadTimeout         time.Duration
+	WriteTimeout        time.Duration
+}
+
+func NewConfig(cfg config.Config) (*Config, error) {
+	return newConfig(cfg, "", "cache.redis")
+}
+
+// NewNamedConfig returns the config of the instance configured under 'cache.redis.<name>'.
+func NewNamedConfig(cfg config.Config, name string) (*Config, error) {
+	return newConfig(cfg, name, fmt.Sprintf("cache.redis.%s", name))
+}
+
+func newConfig(cfg config.Config, name string, prefix string) (*Config, error) {
+	keyTemplate := "%s.%s"
+
+	mode := cfg.GetString(fmt.Sprintf(keyTemplate, prefix, ModeKey))
+	if mode == "" {
+		mode = ModeDefault
+	}
+	if !slices.Contains([]string{ModeStandalone, ModeSentinel, ModeCluster}, mode) {
+		return nil, fmt.Errorf("invalid mode of '%s': '%s', must be one of standalone, sentinel or cluster", prefix, mode)
+	}
+
+	addresses := cfg.GetStringSlice(fmt.Sprintf(keyTemplate, prefix, AddressesKey))
+	if len(addresses) == 0 {
+		addresses = []string{AddressesDefault}
+	}
+
+	property := fmt.Sprintf(keyTemplate, prefix, MasterNameKey)
+	masterName := cfg.GetString(property)
+	if mode == ModeSentinel && masterName == "" {
+		return nil, config.NewMissingPropertyError(property)
+	}
+
+	property = fmt.Sprintf(keyTemplate, prefix, CodecKey)
+	codecName := cfg.GetString(property)
+	if codecName == "" {
+		codecName = CodecDefault
+	}
+	codec, ok := coreCache.CodecByName(codecName)
+	if !ok {
+		return nil, fmt.Errorf("invalid value of '%s': unknown codec '%s'", property, codecName)
+	}
+
+	expiration := cfg.GetInt(fmt.Sprintf(keyTemplate, prefix, ExpirationKey))
+	if expiration == 0 {
+		expiration = ExpirationDefault
+	}
+
+	dialTimeout := cfg.GetInt(fmt.Sprintf(keyTemplate, prefix, DialTimeoutKey))
+	if dialTimeout == 0 {
+		dialTimeout = DialTimeoutDefault
+	}
+
+	readTimeout := cfg.GetInt(fmt.Sprintf(keyTemplate, prefix, ReadTimeoutKey))
+	if readTimeout == 0 {
+		readTimeout = ReadTimeoutDefault
+	}
+
+	keyPrefix := cfg.GetString(fmt.Sprintf(keyTemplate, prefix, KeyPrefixKey))
+
+	invalidationChannel := cfg.GetString(fmt.Sprintf(keyTemplate, prefix, InvalidationChannelKey))
+	if invalidationChannel == "" {
+		invalidationChannel = keyPrefix + InvalidationChannelDefault
+	}
+
+	writeTimeout := cfg.GetInt(fmt.Sprintf(keyTemplate, prefix, WriteTimeoutKey))
+	if writeTimeout == 0 {
+		writeTimeout = WriteTimeoutDefault
+	}
+
+	return &Config{
+		Name:                name,
+		Mode:                mode,
+		Addresses:           addresses,
+		MasterName:          masterName,
+		Username:            cfg.GetString(fmt.Sprintf(keyTemplate, prefix, UsernameKey)),
+		Password:            cfg.GetString(fmt.Sprintf(keyTemplate, prefix, PasswordKey)),
+		DB:                  cfg.GetInt(fmt.Sprintf(keyTemplate, prefix, DBKey)),
+		KeyPrefix:           keyPrefix,
+		Codec:               codec,
+		Expiration:          time.Duration(expiration) * time.Minute,
+		PoolSize:            cfg.GetInt(fmt.Sprintf(keyTemplate, prefix, PoolSizeKey)),
+		InvalidationChannel: invalidationChannel,
+		DialTimeout:         time.Duration(dialTimeout) * time.Second,
+		ReadTimeout:         time.Duration(readTimeout) * time.Second,
+		WriteTimeout:        time.Duration(writeTimeout) * time.Second,
+	}, nil
+}
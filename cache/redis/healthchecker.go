@@ -0,0 +1,31 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/enesanbar/go-service/core/healthchecker"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// HealthChecker checks whether redis answers to PING.
+type HealthChecker struct {
+	client goredis.UniversalClient
+	name   string
+}
+
+func NewHealthChecker(client goredis.UniversalClient) *HealthChecker {
+	return &HealthChecker{client: client, name: "cache.redis"}
+}
+
+// Name returns the name of the health checker.
+func (h *HealthChecker) Name() string {
+	return h.name
+}
+
+// Check pings redis and returns the result.
+func (h *HealthChecker) Check(ctx context.Context) *healthchecker.ProbeResult {
+	if err := h.client.Ping(ctx).Err(); err != nil {
+		return healthchecker.NewProbeResult(false, "redis ping failed: "+err.Error())
+	}
+	return healthchecker.NewProbeResult(true, "redis ping success")
+}
@@ -0,0 +1,99 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// tagKeyPrefix separates the sets holding the keys of the tags from the entries.
+const tagKeyPrefix = "__tags__:"
+
+// scanCount is the number of keys requested from each SCAN call of InvalidatePrefix.
+const scanCount = 500
+
+// InvalidateTags invalidates the entries set with any of the tags.
+// The keys of a tag are kept in a set, which is removed along with the entries.
+func (c *Cache) InvalidateTags(ctx context.Context, tags ...string) error {
+	for _, tag := range tags {
+		keys, err := c.client.SMembers(ctx, c.tagKey(tag)).Result()
+		if err != nil {
+			return fmt.Errorf("unable to get the keys of tag '%s' from redis: %w", tag, err)
+		}
+
+		// keys are removed one by one, as they may belong to different slots in cluster mode
+		_, err = c.client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+			for _, key := range keys {
+				pipe.Unlink(ctx, c.key(key))
+			}
+			pipe.Unlink(ctx, c.tagKey(tag))
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("unable to invalidate tag '%s' in redis: %w", tag, err)
+		}
+	}
+	return nil
+}
+
+// InvalidatePrefix invalidates the entries whose keys start with the prefix, after the configured key prefix.
+// An empty prefix invalidates all the entries under the key prefix, so that
+// the whole database is flushed when no key prefix is configured.
+func (c *Cache) InvalidatePrefix(ctx context.Context, prefix string) error {
+	pattern := escapePattern(c.key(prefix)) + "*"
+
+	cluster, ok := c.client.(*goredis.ClusterClient)
+	if !ok {
+		return c.unlinkMatching(ctx, c.client, pattern)
+	}
+
+	return cluster.ForEachMaster(ctx, func(ctx context.Context, client *goredis.Client) error {
+		return c.unlinkMatching(ctx, client, pattern)
+	})
+}
+
+func (c *Cache) unlinkMatching(ctx context.Context, client goredis.Cmdable, pattern string) error {
+	var cursor uint64
+	for {
+		keys, next, err := client.Scan(ctx, cursor, pattern, scanCount).Result()
+		if err != nil {
+			return fmt.Errorf("unable to scan '%s' in redis: %w", pattern, err)
+		}
+
+		if len(keys) > 0 {
+			_, err = client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+				for _, key := range keys {
+					pipe.Unlink(ctx, key)
+				}
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("unable to invalidate the keys matching '%s' in redis: %w", pattern, err)
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+func (c *Cache) tagKey(tag string) string {
+	return c.key(tagKeyPrefix + tag)
+}
+
+// escapePattern escapes the special characters of the glob-style patterns of SCAN.
+func escapePattern(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,71 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/enesanbar/go-service/core/cache"
+	"github.com/enesanbar/go-service/core/log"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// InvalidationBus broadcasts cache invalidations between the instances of a service over redis pub/sub.
+type InvalidationBus struct {
+	client  goredis.UniversalClient
+	channel string
+	logger  log.Factory
+}
+
+// NewInvalidationBus returns a pointer to the new instance of InvalidationBus
+func NewInvalidationBus(client goredis.UniversalClient, cfg *Config, logger log.Factory) *InvalidationBus {
+	return &InvalidationBus{
+		client:  client,
+		channel: cfg.InvalidationChannel,
+		logger:  logger,
+	}
+}
+
+// Publish publishes the message to the invalidation channel
+func (b *InvalidationBus) Publish(ctx context.Context, message cache.InvalidationMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("unable to encode invalidation message: %w", err)
+	}
+
+	return b.client.Publish(ctx, b.channel, data).Err()
+}
+
+// Subscribe calls the handler for every message received on the invalidation channel until ctx is done
+func (b *InvalidationBus) Subscribe(ctx context.Context, handler func(ctx context.Context, message cache.InvalidationMessage)) error {
+	subscription := b.client.Subscribe(ctx, b.channel)
+	defer func() {
+		if err := subscription.Close(); err != nil {
+			b.logger.Bg().With(zap.Error(err)).Error("unable to close invalidation subscription")
+		}
+	}()
+
+	messages := subscription.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+
+			message := cache.InvalidationMessage{}
+			if err := json.Unmarshal([]byte(msg.Payload), &message); err != nil {
+				b.logger.Bg().
+					With(zap.String("channel", b.channel)).
+					With(zap.Error(err)).
+					Error("unable to decode invalidation message")
+				continue
+			}
+
+			handler(ctx, message)
+		}
+	}
+}
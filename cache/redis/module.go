@@ -0,0 +1,87 @@
+package redis
+
+import (
+	"github.com/enesanbar/go-service/core/cache"
+	"github.com/enesanbar/go-service/core/healthchecker"
+	"github.com/enesanbar/go-service/core/service"
+	"github.com/enesanbar/go-service/core/wiring"
+	"go.uber.org/fx"
+)
+
+// BackendModule provides the redis cache as *Cache without providing it as cache.Cache,
+// so that it can be composed with other caches, e.g. as the remote tier of cache/tiered.
+var BackendModule = fx.Options(
+	fx.Provide(
+		NewConfig,
+		NewClient,
+		NewRedisCache,
+		fx.Annotate(
+			func(c *Cache) wiring.Connection { return c },
+			fx.ResultTags(`group:"connections"`),
+		),
+		healthchecker.AsHealthCheckerProbe(NewHealthChecker),
+	),
+)
+
+// NamedModule provides the caches configured under 'cache.redis.<name>' as named caches,
+// each with its own connection and health probe. It can be used without Module
+// when no default cache is configured under 'cache.redis'.
+var NamedModule = fx.Module(
+	"cache.redis.named",
+	fx.Provide(
+		Caches,
+		cache.AsNamedCaches(func(caches map[string]*Cache) []cache.NamedCache {
+			result := make([]cache.NamedCache, 0, len(caches))
+			for name, c := range caches {
+				result = append(result, cache.NamedCache{Name: name, Cache: c})
+			}
+			return result
+		}),
+		fx.Annotate(
+			func(caches map[string]*Cache) []wiring.Connection {
+				result := make([]wiring.Connection, 0, len(caches))
+				for _, c := range caches {
+					result = append(result, c)
+				}
+				return result
+			},
+			fx.ResultTags(`group:"connection-group"`),
+		),
+		fx.Annotate(
+			func(caches map[string]*Cache) []healthchecker.Probe {
+				result := make([]healthchecker.Probe, 0, len(caches))
+				for _, c := range caches {
+					result = append(result, &HealthChecker{client: c.client, name: c.Name()})
+				}
+				return result
+			},
+			fx.ResultTags(`group:"health-checker-probes,flatten"`),
+		),
+	),
+)
+
+// Module provides the default redis cache as cache.Cache,
+// and the caches configured under 'cache.redis.<name>' as named caches.
+var Module = fx.Module(
+	"cache.redis",
+	BackendModule,
+	fx.Provide(
+		func(c *Cache) cache.Cache { return c },
+	),
+	NamedModule,
+)
+
+// InvalidationBusModule provides redis pub/sub as the cache.InvalidationBus.
+var InvalidationBusModule = fx.Provide(
+	fx.Annotate(
+		NewInvalidationBus,
+		fx.As(new(cache.InvalidationBus)),
+	),
+)
+
+func Option(options ...fx.Option) service.Option {
+	return func(cfg *service.AppConfig) {
+		cfg.Options = append(cfg.Options, Module)
+		cfg.Options = append(cfg.Options, options...)
+	}
+}
@@ -0,0 +1,165 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	coreCache "github.com/enesanbar/go-service/core/cache"
+	"github.com/enesanbar/go-service/core/log"
+	goredis "github.com/redis/go-redis/v9"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// Cache is a redis implementation of Cache interface.
+//
+// Values other than []byte and string are serialized with the configured codec,
+// and Get always returns the stored bytes. Use cache.NewTyped with
+// cache.WithCodec(c.Codec()) to get typed values back.
+type Cache struct {
+	cfg          *Config
+	client       goredis.UniversalClient
+	log          log.Factory
+	instrumentor *coreCache.Instrumentor
+}
+
+type CacheParams struct {
+	fx.In
+
+	Config       *Config
+	Client       goredis.UniversalClient
+	Logger       log.Factory
+	Instrumentor *coreCache.Instrumentor
+}
+
+// NewRedisCache returns a pointer to the instance of Cache
+func NewRedisCache(p CacheParams) *Cache {
+	p.Logger.Bg().
+		With(zap.String("mode", p.Config.Mode)).
+		With(zap.Strings("addresses", p.Config.Addresses)).
+		With(zap.String("codec", p.Config.Codec.Name())).
+		Info("creating redis cache")
+
+	return &Cache{
+		cfg:          p.Config,
+		client:       p.Client,
+		log:          p.Logger,
+		instrumentor: p.Instrumentor,
+	}
+}
+
+// Set sets a value in cache
+func (c *Cache) Set(ctx context.Context, key string, value interface{}, options ...coreCache.SetOption) error {
+	defer c.instrumentor.Observe("set", c.cacheType(), time.Now())
+
+	o := coreCache.NewSetOptions(options...)
+
+	expiration := c.cfg.Expiration
+	if o.TTL > 0 {
+		expiration = o.TTL
+	}
+
+	data, err := c.encode(value)
+	if err != nil {
+		return fmt.Errorf("unable to encode the value of '%s': %w", key, err)
+	}
+
+	if len(o.Tags) == 0 {
+		return c.client.Set(ctx, c.key(key), data, expiration).Err()
+	}
+
+	// the tag sets live at least as long as their entries
+	_, err = c.client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.Set(ctx, c.key(key), data, expiration)
+		for _, tag := range o.Tags {
+			pipe.SAdd(ctx, c.tagKey(tag), key)
+			pipe.ExpireNX(ctx, c.tagKey(tag), expiration)
+			pipe.ExpireGT(ctx, c.tagKey(tag), expiration)
+		}
+		return nil
+	})
+	return err
+}
+
+// Get gets a value from the cache
+func (c *Cache) Get(ctx context.Context, key string) (interface{}, error) {
+	defer c.instrumentor.Observe("get", c.cacheType(), time.Now())
+
+	data, err := c.client.Get(ctx, c.key(key)).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		c.instrumentor.Miss(key, c.cacheType())
+		return nil, coreCache.ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to get '%s' from redis: %w", key, err)
+	}
+
+	c.instrumentor.Hit(key, c.cacheType())
+	return data, nil
+}
+
+// Invalidate invalidates a value in the cache
+func (c *Cache) Invalidate(ctx context.Context, key string) error {
+	return c.client.Del(ctx, c.key(key)).Err()
+}
+
+// Codec returns the codec used to serialize the values.
+func (c *Cache) Codec() coreCache.Codec {
+	return c.cfg.Codec
+}
+
+// Client returns the underlying redis client.
+func (c *Cache) Client() goredis.UniversalClient {
+	return c.client
+}
+
+func (c *Cache) Start(ctx context.Context) error {
+	c.log.Bg().
+		With(zap.String("mode", c.cfg.Mode)).
+		With(zap.Strings("addresses", c.cfg.Addresses)).
+		Info("connecting to redis")
+
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.DialTimeout)
+	defer cancel()
+
+	if err := c.client.Ping(ctx).Err(); err != nil {
+		c.log.Bg().With(zap.Error(err)).Error("could not ping redis")
+		return nil
+	}
+	return nil
+}
+
+func (c *Cache) Close(ctx context.Context) error {
+	c.log.For(ctx).Info("closing redis connection")
+	return c.client.Close()
+}
+
+func (c *Cache) Name() string {
+	if c.cfg.Name != "" {
+		return fmt.Sprintf("cache.redis.%s", c.cfg.Name)
+	}
+	return "cache.redis"
+}
+
+// cacheType returns the type label of the metrics.
+func (c *Cache) cacheType() string {
+	if c.cfg.Name != "" {
+		return fmt.Sprintf("redis.%s", c.cfg.Name)
+	}
+	return "redis"
+}
+
+func (c *Cache) key(key string) string {
+	return c.cfg.KeyPrefix + key
+}
+
+func (c *Cache) encode(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case []byte, string:
+		return v, nil
+	default:
+		return c.cfg.Codec.Marshal(value)
+	}
+}
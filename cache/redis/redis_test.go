@@ -0,0 +1,298 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	coreCache "github.com/enesanbar/go-service/core/cache"
+	"github.com/enesanbar/go-service/core/config"
+	"github.com/enesanbar/go-service/core/config/configtest"
+	"github.com/enesanbar/go-service/core/healthchecker"
+	"github.com/enesanbar/go-service/core/log"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func newTestCache(t *testing.T, properties map[string]any) (*Cache, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	properties["cache.redis.addresses"] = []string{server.Addr()}
+
+	cfg, err := NewConfig(configtest.New(t, properties))
+	if err != nil {
+		t.Fatalf("unable to create redis config: %v", err)
+	}
+
+	client, err := NewClient(ClientParams{Config: cfg, TracerProvider: trace.NewTracerProvider()})
+	if err != nil {
+		t.Fatalf("unable to create redis client: %v", err)
+	}
+
+	instrumentor, err := coreCache.NewInstrumentor(coreCache.InstrumentorParams{})
+	if err != nil {
+		t.Fatalf("unable to create instrumentor: %v", err)
+	}
+
+	c := NewRedisCache(CacheParams{
+		Config:       cfg,
+		Client:       client,
+		Logger:       log.NewFactory(zap.NewNop()),
+		Instrumentor: instrumentor,
+	})
+	t.Cleanup(func() { _ = c.Close(context.Background()) })
+
+	return c, server
+}
+
+func TestNewConfig(t *testing.T) {
+	cfg, err := NewConfig(configtest.New(t, map[string]any{}))
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if cfg.Mode != ModeStandalone {
+		t.Errorf("Expected Mode to be '%s', got '%s'", ModeStandalone, cfg.Mode)
+	}
+	if len(cfg.Addresses) != 1 || cfg.Addresses[0] != AddressesDefault {
+		t.Errorf("Expected Addresses to be '[%s]', got '%v'", AddressesDefault, cfg.Addresses)
+	}
+	if cfg.Expiration != ExpirationDefault*time.Minute {
+		t.Errorf("Expected Expiration to be '%v', got '%v'", ExpirationDefault*time.Minute, cfg.Expiration)
+	}
+	if cfg.Codec.Name() != CodecDefault {
+		t.Errorf("Expected Codec to be '%s', got '%s'", CodecDefault, cfg.Codec.Name())
+	}
+
+	_, err = NewConfig(configtest.New(t, map[string]any{"cache.redis.mode": ModeSentinel}))
+	if err == nil {
+		t.Error("Expected an error for sentinel mode without master name, got nil")
+	}
+
+	_, err = NewConfig(configtest.New(t, map[string]any{"cache.redis.mode": "unknown"}))
+	if err == nil {
+		t.Error("Expected an error for an unknown mode, got nil")
+	}
+
+	_, err = NewConfig(configtest.New(t, map[string]any{"cache.redis.codec": "xml"}))
+	if err == nil {
+		t.Error("Expected an error for an unknown codec, got nil")
+	}
+}
+
+func TestCache_SetGetInvalidate(t *testing.T) {
+	c, server := newTestCache(t, map[string]any{"cache.redis.key-prefix": "my-service:"})
+	ctx := context.Background()
+
+	if err := c.Set(ctx, "greeting", "hello"); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	if !server.Exists("my-service:greeting") {
+		t.Error("Expected the key to be stored with the prefix")
+	}
+
+	value, err := c.Get(ctx, "greeting")
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if string(value.([]byte)) != "hello" {
+		t.Errorf("Expected value to be 'hello', got '%s'", value)
+	}
+
+	if err := c.Invalidate(ctx, "greeting"); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	_, err = c.Get(ctx, "greeting")
+	if !errors.Is(err, coreCache.ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got '%v'", err)
+	}
+}
+
+func TestCache_Expiration(t *testing.T) {
+	c, server := newTestCache(t, map[string]any{})
+	ctx := context.Background()
+
+	if err := c.Set(ctx, "default", "value"); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if ttl := server.TTL("default"); ttl != ExpirationDefault*time.Minute {
+		t.Errorf("Expected TTL to be '%v', got '%v'", ExpirationDefault*time.Minute, ttl)
+	}
+
+	if err := c.Set(ctx, "short", "value", coreCache.WithTTL(10*time.Second)); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if ttl := server.TTL("short"); ttl != 10*time.Second {
+		t.Errorf("Expected TTL to be '10s', got '%v'", ttl)
+	}
+
+	server.FastForward(11 * time.Second)
+
+	_, err := c.Get(ctx, "short")
+	if !errors.Is(err, coreCache.ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound after expiration, got '%v'", err)
+	}
+}
+
+func TestCache_Typed(t *testing.T) {
+	for _, codec := range []string{"json", "gob", "msgpack"} {
+		t.Run(codec, func(t *testing.T) {
+			c, _ := newTestCache(t, map[string]any{"cache.redis.codec": codec})
+			ctx := context.Background()
+			users := coreCache.NewTyped[user](c, coreCache.WithCodec(c.Codec()))
+
+			if err := users.Set(ctx, "user:1", user{ID: 1, Name: "john"}); err != nil {
+				t.Fatalf("Expected no error, got '%v'", err)
+			}
+
+			u, err := users.Get(ctx, "user:1")
+			if err != nil {
+				t.Fatalf("Expected no error, got '%v'", err)
+			}
+			if u.ID != 1 || u.Name != "john" {
+				t.Errorf("Expected user to be '{1 john}', got '%v'", u)
+			}
+
+			loads := 0
+			loader := func(ctx context.Context, key string) (user, error) {
+				loads++
+				return user{ID: 2, Name: "jane"}, nil
+			}
+			for range 3 {
+				u, err = users.GetOrLoad(ctx, "user:2", loader, time.Minute)
+				if err != nil {
+					t.Fatalf("Expected no error, got '%v'", err)
+				}
+			}
+			if loads != 1 {
+				t.Errorf("Expected loader to be called once, got '%d'", loads)
+			}
+			if u.Name != "jane" {
+				t.Errorf("Expected name to be 'jane', got '%s'", u.Name)
+			}
+		})
+	}
+}
+
+func TestHealthChecker_Check(t *testing.T) {
+	c, server := newTestCache(t, map[string]any{})
+	checker := NewHealthChecker(c.Client())
+
+	if result := checker.Check(context.Background()); !result.Success {
+		t.Errorf("Expected probe to succeed, got '%s'", result.Message)
+	}
+
+	server.Close()
+
+	if result := checker.Check(context.Background()); result.Success {
+		t.Error("Expected probe to fail when redis is down")
+	}
+}
+
+func TestCache_InvalidateTags(t *testing.T) {
+	c, server := newTestCache(t, map[string]any{"cache.redis.key-prefix": "my-service:"})
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "product:1", "product", coreCache.WithTags("product:1"), coreCache.WithTTL(time.Hour))
+	_ = c.Set(ctx, "products:page:1", "list", coreCache.WithTags("product:1", "product:2"))
+	_ = c.Set(ctx, "products:page:2", "list", coreCache.WithTags("product:3"))
+
+	if ttl := server.TTL("my-service:__tags__:product:1"); ttl != time.Hour {
+		t.Errorf("Expected the tag to live as long as its longest entry, got '%v'", ttl)
+	}
+
+	if err := c.InvalidateTags(ctx, "product:1"); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	for _, key := range []string{"product:1", "products:page:1"} {
+		if _, err := c.Get(ctx, key); !errors.Is(err, coreCache.ErrKeyNotFound) {
+			t.Errorf("Expected '%s' to be invalidated, got '%v'", key, err)
+		}
+	}
+	if _, err := c.Get(ctx, "products:page:2"); err != nil {
+		t.Errorf("Expected '%s' to be cached, got '%v'", "products:page:2", err)
+	}
+	if server.Exists("my-service:__tags__:product:1") {
+		t.Error("Expected the tag to be removed")
+	}
+}
+
+func TestCache_InvalidatePrefix(t *testing.T) {
+	c, server := newTestCache(t, map[string]any{"cache.redis.key-prefix": "my-service:"})
+	ctx := context.Background()
+
+	_ = server.Set("other-service:user:1", "value")
+	for i := range 1200 {
+		_ = c.Set(ctx, fmt.Sprintf("user:%d", i), "value")
+	}
+	_ = c.Set(ctx, "user*", "value")
+	_ = c.Set(ctx, "users", "value")
+
+	if err := c.InvalidatePrefix(ctx, "user:"); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	keys := server.Keys()
+	if len(keys) != 3 {
+		t.Errorf("Expected '%d' keys to remain, got '%v'", 3, keys)
+	}
+
+	if err := c.InvalidatePrefix(ctx, "user*"); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if !server.Exists("my-service:users") {
+		t.Error("Expected the special characters of the prefix to be escaped")
+	}
+}
+
+func TestNamedModule_WithoutDefaultCache(t *testing.T) {
+	server := miniredis.RunT(t)
+	cfg := configtest.New(t, map[string]any{
+		"cache.redis.sessions.addresses": []string{server.Addr()},
+	})
+
+	var p struct {
+		fx.In
+
+		Sessions coreCache.Cache       `name:"cache.sessions"`
+		Probes   []healthchecker.Probe `group:"health-checker-probes"`
+	}
+
+	app := fx.New(
+		fx.NopLogger,
+		fx.Supply(fx.Annotate(cfg, fx.As(new(config.Config)))),
+		fx.Supply(log.NewFactory(zap.NewNop())),
+		fx.Supply(trace.NewTracerProvider()),
+		coreCache.Module,
+		NamedModule,
+		coreCache.Named("sessions"),
+		fx.Populate(&p),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := p.Sessions.Set(context.Background(), "session:1", "ada"); err != nil {
+		t.Errorf("Expected no error, got '%v'", err)
+	}
+
+	// the default cache, connecting to localhost:6379, is not created
+	if len(p.Probes) != 1 {
+		t.Fatalf("Expected '%d' health probe, got '%d'", 1, len(p.Probes))
+	}
+	if result := p.Probes[0].Check(context.Background()); !result.Success {
+		t.Errorf("Expected the probe of the 'sessions' cache to be healthy, got '%+v'", result)
+	}
+}
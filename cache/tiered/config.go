@@ -0,0 +1,31 @@
+package tiered
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/enesanbar/go-service/core/config"
+)
+
+const (
+	NearTTLKey     = "near-ttl"
+	NearTTLDefault = 30
+)
+
+type Config struct {
+	NearTTL time.Duration
+}
+
+func NewConfig(cfg config.Config) (*Config, error) {
+	prefix := "cache.tiered"
+	keyTemplate := "%s.%s"
+
+	nearTTL := cfg.GetInt(fmt.Sprintf(keyTemplate, prefix, NearTTLKey))
+	if nearTTL == 0 {
+		nearTTL = NearTTLDefault
+	}
+
+	return &Config{
+		NearTTL: time.Duration(nearTTL) * time.Second,
+	}, nil
+}
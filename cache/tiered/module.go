@@ -0,0 +1,35 @@
+package tiered
+
+import (
+	"github.com/enesanbar/go-service/cache/inmemory"
+	"github.com/enesanbar/go-service/cache/redis"
+	"github.com/enesanbar/go-service/core/cache"
+	"github.com/enesanbar/go-service/core/service"
+	"github.com/enesanbar/go-service/core/wiring"
+	"go.uber.org/fx"
+)
+
+// Module provides an in-memory cache in front of the redis cache as cache.Cache.
+// Invalidations are broadcast to the other instances when a cache.InvalidationBus is provided,
+// e.g. with redis.InvalidationBusModule.
+var Module = fx.Module(
+	"cache.tiered",
+	inmemory.BackendModule,
+	redis.BackendModule,
+	fx.Provide(
+		NewConfig,
+		NewTieredCache,
+		func(c *cache.Tiered) cache.Cache { return c },
+		fx.Annotate(
+			func(c *cache.Tiered) wiring.Runnable { return c },
+			fx.ResultTags(`group:"runnables"`),
+		),
+	),
+)
+
+func Option(options ...fx.Option) service.Option {
+	return func(cfg *service.AppConfig) {
+		cfg.Options = append(cfg.Options, Module)
+		cfg.Options = append(cfg.Options, options...)
+	}
+}
@@ -0,0 +1,38 @@
+package tiered
+
+import (
+	"github.com/enesanbar/go-service/cache/inmemory"
+	"github.com/enesanbar/go-service/cache/redis"
+	"github.com/enesanbar/go-service/core/cache"
+	"github.com/enesanbar/go-service/core/log"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type Params struct {
+	fx.In
+
+	Config       *Config
+	Near         *inmemory.Cache
+	Remote       *redis.Cache
+	Bus          cache.InvalidationBus `optional:"true"`
+	Instrumentor *cache.Instrumentor
+	Logger       log.Factory
+}
+
+// NewTieredCache returns an in-memory cache in front of the redis cache.
+func NewTieredCache(p Params) *cache.Tiered {
+	p.Logger.Bg().
+		With(zap.Duration("near_ttl", p.Config.NearTTL)).
+		With(zap.Bool("broadcast", p.Bus != nil)).
+		Info("creating tiered cache")
+
+	return cache.NewTiered(cache.TieredParams{
+		Near:         p.Near,
+		Remote:       p.Remote,
+		Bus:          p.Bus,
+		NearTTL:      p.Config.NearTTL,
+		Instrumentor: p.Instrumentor,
+		Logger:       p.Logger,
+	})
+}
@@ -0,0 +1,232 @@
+package tiered
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/enesanbar/go-service/cache/inmemory"
+	"github.com/enesanbar/go-service/cache/redis"
+	"github.com/enesanbar/go-service/core/cache"
+	"github.com/enesanbar/go-service/core/log"
+	goredis "github.com/redis/go-redis/v9"
+	otelmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.uber.org/zap"
+)
+
+type instance struct {
+	cache *cache.Tiered
+	near  *inmemory.Cache
+}
+
+// newInstance returns an instance of the service, whose metrics are recorded to the meter provider if not nil.
+func newInstance(t *testing.T, server *miniredis.Miniredis, meterProvider *otelmetric.MeterProvider) instance {
+	t.Helper()
+
+	logger := log.NewFactory(zap.NewNop())
+	instrumentor, err := cache.NewInstrumentor(cache.InstrumentorParams{MeterProvider: meterProvider})
+	if err != nil {
+		t.Fatalf("unable to create instrumentor: %v", err)
+	}
+	cfg := &redis.Config{
+		Mode:                redis.ModeStandalone,
+		Addresses:           []string{server.Addr()},
+		Codec:               cache.JSONCodec{},
+		Expiration:          time.Minute,
+		InvalidationChannel: "invalidations",
+	}
+
+	client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	near := inmemory.NewInMemoryCache(&inmemory.Config{
+		Expiration:      time.Minute,
+		CleanupInterval: time.Minute,
+	}, logger, instrumentor)
+
+	remote := redis.NewRedisCache(redis.CacheParams{
+		Config:       cfg,
+		Client:       client,
+		Logger:       logger,
+		Instrumentor: instrumentor,
+	})
+
+	tiered := NewTieredCache(Params{
+		Config:       &Config{NearTTL: 30 * time.Second},
+		Near:         near,
+		Remote:       remote,
+		Bus:          redis.NewInvalidationBus(client, cfg, logger),
+		Instrumentor: instrumentor,
+		Logger:       logger,
+	})
+
+	go func() { _ = tiered.Start(context.Background()) }()
+	t.Cleanup(func() { _ = tiered.Stop(context.Background()) })
+
+	return instance{cache: tiered, near: near}
+}
+
+// waitForSubscribers waits for both instances to subscribe to the invalidation channel.
+func waitForSubscribers(t *testing.T, server *miniredis.Miniredis) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for server.PubSubNumSub("invalidations")["invalidations"] < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected both instances to subscribe to the invalidation channel")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitForEviction waits for the key to be evicted from the near cache.
+func waitForEviction(t *testing.T, near *inmemory.Cache, key string) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, err := near.Get(context.Background(), key)
+		if errors.Is(err, cache.ErrKeyNotFound) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the near copy of '%s' to be evicted", key)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTiered_InvalidationBroadcast(t *testing.T) {
+	server := miniredis.RunT(t)
+	first := newInstance(t, server, nil)
+	second := newInstance(t, server, nil)
+	ctx := context.Background()
+
+	waitForSubscribers(t, server)
+
+	if err := first.cache.Set(ctx, "key", "v1"); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	value, err := second.cache.Get(ctx, "key")
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if string(value.([]byte)) != "v1" {
+		t.Errorf("Expected value to be 'v1', got '%s'", value)
+	}
+
+	if _, err := second.near.Get(ctx, "key"); err != nil {
+		t.Fatalf("Expected the remote value to be copied to the near cache, got '%v'", err)
+	}
+
+	if err := first.cache.Set(ctx, "key", "v2"); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	waitForEviction(t, second.near, "key")
+
+	value, err = second.cache.Get(ctx, "key")
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if string(value.([]byte)) != "v2" {
+		t.Errorf("Expected value to be 'v2', got '%s'", value)
+	}
+
+	if _, err := first.near.Get(ctx, "key"); !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Errorf("Expected the near copy of the publisher to be evicted, got '%v'", err)
+	}
+}
+
+func TestTiered_InvalidateTagsAndPrefix(t *testing.T) {
+	server := miniredis.RunT(t)
+	first := newInstance(t, server, nil)
+	second := newInstance(t, server, nil)
+	ctx := context.Background()
+
+	waitForSubscribers(t, server)
+
+	_ = first.cache.Set(ctx, "product:1", "product", cache.WithTags("product:1"))
+	_ = first.cache.Set(ctx, "products:page:1", "list", cache.WithTags("product:1"))
+	_ = first.cache.Set(ctx, "user:1", "user")
+	for _, key := range []string{"product:1", "products:page:1", "user:1"} {
+		if _, err := second.cache.Get(ctx, key); err != nil {
+			t.Fatalf("Expected no error, got '%v'", err)
+		}
+	}
+
+	if err := first.cache.InvalidatePrefix(ctx, "user:"); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	waitForEviction(t, second.near, "user:1")
+	if _, err := second.cache.Get(ctx, "user:1"); !errors.Is(err, cache.ErrKeyNotFound) {
+		t.Errorf("Expected '%s' to be invalidated, got '%v'", "user:1", err)
+	}
+
+	if err := first.cache.InvalidateTags(ctx, "product:1"); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	waitForEviction(t, second.near, "products:page:1")
+	for _, key := range []string{"product:1", "products:page:1"} {
+		if _, err := second.cache.Get(ctx, key); !errors.Is(err, cache.ErrKeyNotFound) {
+			t.Errorf("Expected '%s' to be invalidated, got '%v'", key, err)
+		}
+	}
+}
+
+func TestTiered_CountsTiersApartFromTheirCaches(t *testing.T) {
+	server := miniredis.RunT(t)
+	reader := otelmetric.NewManualReader()
+	instance := newInstance(t, server, otelmetric.NewMeterProvider(otelmetric.WithReader(reader)))
+	ctx := context.Background()
+
+	if err := instance.cache.Set(ctx, "user:1", "ada"); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	// a miss of the near cache and a hit of the remote one, then a hit of the near cache
+	for i := 0; i < 2; i++ {
+		if _, err := instance.cache.Get(ctx, "user:1"); err != nil {
+			t.Fatalf("Expected no error, got '%v'", err)
+		}
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatalf("unable to collect metrics: %v", err)
+	}
+	counts := make(map[string]int64)
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		sum, ok := m.Data.(metricdata.Sum[int64])
+		if !ok {
+			continue
+		}
+		for _, point := range sum.DataPoints {
+			label, _ := point.Attributes.Value("type")
+			if tier, ok := point.Attributes.Value("tier"); ok {
+				label = tier
+			}
+			counts[m.Name+"/"+label.AsString()] += point.Value
+		}
+	}
+
+	expected := map[string]int64{
+		"cache.hits/inmemory":   1,
+		"cache.misses/inmemory": 1,
+		"cache.hits/redis":      1,
+		"cache.tier.hits/l1":    1,
+		"cache.tier.misses/l1":  1,
+		"cache.tier.hits/l2":    1,
+	}
+	for name, count := range expected {
+		if counts[name] != count {
+			t.Errorf("Expected '%s' to be '%d', got '%d'", name, count, counts[name])
+		}
+	}
+	if counts["cache.hits/l1"] != 0 || counts["cache.hits/l2"] != 0 {
+		t.Errorf("Expected the tiers not to be counted as cache hits, got '%v'", counts)
+	}
+}
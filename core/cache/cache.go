@@ -2,10 +2,53 @@ package cache
 
 import (
 	"context"
+	"errors"
+	"time"
 )
 
+// ErrKeyNotFound is returned by the implementations of Cache when the key does not exist or has expired.
+var ErrKeyNotFound = errors.New("'key' not found in cache")
+
 type Cache interface {
-	Set(ctx context.Context, key string, value interface{}) error
+	Set(ctx context.Context, key string, value interface{}, options ...SetOption) error
 	Get(ctx context.Context, key string) (interface{}, error)
 	Invalidate(ctx context.Context, key string) error
+	// InvalidateTags invalidates all the entries set with any of the tags, see WithTags.
+	InvalidateTags(ctx context.Context, tags ...string) error
+	// InvalidatePrefix invalidates all the entries whose keys start with the prefix.
+	InvalidatePrefix(ctx context.Context, prefix string) error
+}
+
+// SetOptions holds the per-entry options of Cache.Set.
+type SetOptions struct {
+	// TTL is the lifetime of the entry. Zero means the default expiration of the cache.
+	TTL time.Duration
+	// Tags group the entries that are invalidated together with InvalidateTags.
+	Tags []string
+}
+
+type SetOption func(*SetOptions)
+
+// WithTTL sets the lifetime of the entry, overriding the default expiration of the cache.
+func WithTTL(ttl time.Duration) SetOption {
+	return func(o *SetOptions) {
+		o.TTL = ttl
+	}
+}
+
+// WithTags tags the entry, e.g. with the entities it is derived from,
+// so that it is invalidated when any of them changes.
+func WithTags(tags ...string) SetOption {
+	return func(o *SetOptions) {
+		o.Tags = append(o.Tags, tags...)
+	}
+}
+
+// NewSetOptions applies the given options. It is meant to be used by the implementations of Cache.
+func NewSetOptions(options ...SetOption) SetOptions {
+	o := SetOptions{}
+	for _, option := range options {
+		option(&o)
+	}
+	return o
 }
@@ -0,0 +1,32 @@
+package cache
+
+import (
+	"fmt"
+
+	"github.com/enesanbar/go-service/core/config"
+)
+
+const (
+	KeySeparatorKey     = "key-separator"
+	KeySeparatorDefault = ":"
+)
+
+// MetricsConfig configures the labels of the cache metrics.
+type MetricsConfig struct {
+	// KeySeparator separates the prefix used as the label of a key from the rest of it.
+	KeySeparator string
+}
+
+func NewMetricsConfig(cfg config.Config) *MetricsConfig {
+	prefix := "cache.metrics"
+	keyTemplate := "%s.%s"
+
+	separator := cfg.GetString(fmt.Sprintf(keyTemplate, prefix, KeySeparatorKey))
+	if separator == "" {
+		separator = KeySeparatorDefault
+	}
+
+	return &MetricsConfig{
+		KeySeparator: separator,
+	}
+}
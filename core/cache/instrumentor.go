@@ -0,0 +1,203 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/enesanbar/go-service/core/info"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	otelmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.uber.org/fx"
+)
+
+const instrumentationName = "github.com/enesanbar/go-service/core/cache"
+
+type Collector interface {
+	Hit(key string, cacheType string)
+	Miss(key string, cacheType string)
+	Evict(reason string, cacheType string)
+	Observe(operation string, cacheType string, start time.Time)
+	RegisterSize(cacheType string, size func() int64)
+}
+
+// KeyLabeler maps a cache key to the label of the hit and miss metrics.
+// It must return a small, bounded set of values, e.g. the namespace of the key,
+// as every distinct label creates a new time series.
+type KeyLabeler func(key string) string
+
+// PrefixKeyLabeler labels the keys with the part before the first separator,
+// e.g. 'user' for 'user:42'. Keys without the separator are labeled as 'other'.
+func PrefixKeyLabeler(separator string) KeyLabeler {
+	return func(key string) string {
+		prefix, _, found := strings.Cut(key, separator)
+		if !found || prefix == "" {
+			return "other"
+		}
+		return prefix
+	}
+}
+
+// Instrumentor records the cache metrics through the OTel MeterProvider,
+// which exports them to the registry served by the telemetry server.
+type Instrumentor struct {
+	keyLabeler KeyLabeler
+	hits       metric.Int64Counter
+	misses     metric.Int64Counter
+	evictions  metric.Int64Counter
+	durations  metric.Float64Histogram
+	tierHits   metric.Int64Counter
+	tierMisses metric.Int64Counter
+
+	mu    sync.RWMutex
+	sizes map[string]func() int64
+}
+
+type InstrumentorParams struct {
+	fx.In
+
+	Config        *MetricsConfig            `optional:"true"`
+	MeterProvider *otelmetric.MeterProvider `optional:"true"`
+	KeyLabeler    KeyLabeler                `optional:"true"`
+}
+
+// NewInstrumentor returns a pointer to the new instance of Instrumentor.
+// The metrics are discarded when no MeterProvider is given.
+func NewInstrumentor(p InstrumentorParams) (*Instrumentor, error) {
+	var provider metric.MeterProvider = noop.NewMeterProvider()
+	if p.MeterProvider != nil {
+		provider = p.MeterProvider
+	}
+
+	keyLabeler := p.KeyLabeler
+	if keyLabeler == nil {
+		separator := KeySeparatorDefault
+		if p.Config != nil {
+			separator = p.Config.KeySeparator
+		}
+		keyLabeler = PrefixKeyLabeler(separator)
+	}
+
+	meter := provider.Meter(instrumentationName)
+
+	i := &Instrumentor{
+		keyLabeler: keyLabeler,
+		sizes:      make(map[string]func() int64),
+	}
+
+	var err error
+	i.hits, err = meter.Int64Counter(info.MetricName("cache.hits"), metric.WithDescription("The number of cache hits"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create cache hits counter: %w", err)
+	}
+
+	i.misses, err = meter.Int64Counter(info.MetricName("cache.misses"), metric.WithDescription("The number of cache misses"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create cache misses counter: %w", err)
+	}
+
+	i.evictions, err = meter.Int64Counter(info.MetricName("cache.evictions"), metric.WithDescription("The number of entries evicted from bounded caches"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create cache evictions counter: %w", err)
+	}
+
+	i.durations, err = meter.Float64Histogram(
+		info.MetricName("cache.operation.duration"),
+		metric.WithDescription("The duration of cache operations"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.0001, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create cache duration histogram: %w", err)
+	}
+
+	// the tiers are counted apart, as their caches count their own hits and misses
+	i.tierHits, err = meter.Int64Counter(info.MetricName("cache.tier.hits"), metric.WithDescription("The number of hits of the tiers of the tiered caches"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create cache tier hits counter: %w", err)
+	}
+
+	i.tierMisses, err = meter.Int64Counter(info.MetricName("cache.tier.misses"), metric.WithDescription("The number of misses of the tiers of the tiered caches"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create cache tier misses counter: %w", err)
+	}
+
+	_, err = meter.Int64ObservableGauge(
+		info.MetricName("cache.entries"),
+		metric.WithDescription("The number of entries held by the cache"),
+		metric.WithInt64Callback(i.observeSizes),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create cache entries gauge: %w", err)
+	}
+
+	return i, nil
+}
+
+func (i *Instrumentor) Hit(key string, cacheType string) {
+	i.hits.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String("prefix", i.keyLabeler(key)),
+		attribute.String("type", cacheType),
+	))
+}
+
+func (i *Instrumentor) Miss(key string, cacheType string) {
+	i.misses.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String("prefix", i.keyLabeler(key)),
+		attribute.String("type", cacheType),
+	))
+}
+
+// TierHit records a hit of a tier of a tiered cache, e.g. 'l1' for its near cache.
+func (i *Instrumentor) TierHit(key string, tier string) {
+	i.tierHits.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String("prefix", i.keyLabeler(key)),
+		attribute.String("tier", tier),
+	))
+}
+
+// TierMiss records a miss of a tier of a tiered cache, e.g. 'l2' for its remote cache.
+func (i *Instrumentor) TierMiss(key string, tier string) {
+	i.tierMisses.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String("prefix", i.keyLabeler(key)),
+		attribute.String("tier", tier),
+	))
+}
+
+func (i *Instrumentor) Evict(reason string, cacheType string) {
+	i.evictions.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String("reason", reason),
+		attribute.String("type", cacheType),
+	))
+}
+
+// Observe records the duration of an operation started at start,
+// e.g. defer i.Observe("get", "redis", time.Now())
+func (i *Instrumentor) Observe(operation string, cacheType string, start time.Time) {
+	i.durations.Record(context.Background(), time.Since(start).Seconds(), metric.WithAttributes(
+		attribute.String("operation", operation),
+		attribute.String("type", cacheType),
+	))
+}
+
+// RegisterSize reports the number of entries of a cache, read on every collection.
+func (i *Instrumentor) RegisterSize(cacheType string, size func() int64) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.sizes[cacheType] = size
+}
+
+func (i *Instrumentor) observeSizes(_ context.Context, observer metric.Int64Observer) error {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	for cacheType, size := range i.sizes {
+		observer.Observe(size(), metric.WithAttributes(attribute.String("type", cacheType)))
+	}
+	return nil
+}
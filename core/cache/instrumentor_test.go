@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestPrefixKeyLabeler(t *testing.T) {
+	labeler := PrefixKeyLabeler(":")
+
+	tests := map[string]string{
+		"user:42":         "user",
+		"user:42:profile": "user",
+		"session":         "other",
+		":42":             "other",
+	}
+	for key, expected := range tests {
+		if label := labeler(key); label != expected {
+			t.Errorf("Expected label of '%s' to be '%s', got '%s'", key, expected, label)
+		}
+	}
+}
+
+func TestInstrumentor_LabelsHitsWithKeyPrefix(t *testing.T) {
+	reader := otelmetric.NewManualReader()
+	instrumentor, err := NewInstrumentor(InstrumentorParams{
+		MeterProvider: otelmetric.NewMeterProvider(otelmetric.WithReader(reader)),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		instrumentor.Hit("user:"+string(rune('a'+i)), "inmemory")
+	}
+	instrumentor.RegisterSize("inmemory", func() int64 { return 3 })
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("unable to collect metrics: %v", err)
+	}
+
+	var hits metricdata.Sum[int64]
+	var entries metricdata.Gauge[int64]
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		switch {
+		case strings.HasSuffix(m.Name, "cache.hits"):
+			hits = m.Data.(metricdata.Sum[int64])
+		case strings.HasSuffix(m.Name, "cache.entries"):
+			entries = m.Data.(metricdata.Gauge[int64])
+		}
+	}
+
+	if len(hits.DataPoints) != 1 {
+		t.Fatalf("Expected '%d' hit series, got '%d'", 1, len(hits.DataPoints))
+	}
+	point := hits.DataPoints[0]
+	if point.Value != 3 {
+		t.Errorf("Expected hits to be '%d', got '%d'", 3, point.Value)
+	}
+	if prefix, _ := point.Attributes.Value(attribute.Key("prefix")); prefix.AsString() != "user" {
+		t.Errorf("Expected prefix label to be '%s', got '%s'", "user", prefix.AsString())
+	}
+
+	if len(entries.DataPoints) != 1 || entries.DataPoints[0].Value != 3 {
+		t.Errorf("Expected entries to be '%d', got '%v'", 3, entries.DataPoints)
+	}
+}
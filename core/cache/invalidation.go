@@ -0,0 +1,22 @@
+package cache
+
+import "context"
+
+// InvalidationMessage is broadcast to the other instances of the service
+// when entries change on one instance, so they evict their near copies.
+type InvalidationMessage struct {
+	// Source identifies the instance that published the message.
+	Source string   `json:"source"`
+	Keys   []string `json:"keys"`
+	// Tags and Prefixes are set when entries are invalidated with InvalidateTags and InvalidatePrefix.
+	Tags     []string `json:"tags,omitempty"`
+	Prefixes []string `json:"prefixes,omitempty"`
+}
+
+// InvalidationBus broadcasts invalidations between the instances of a service.
+type InvalidationBus interface {
+	Publish(ctx context.Context, message InvalidationMessage) error
+	// Subscribe calls the handler for every message published by any instance,
+	// including the subscriber itself, and blocks until ctx is done.
+	Subscribe(ctx context.Context, handler func(ctx context.Context, message InvalidationMessage)) error
+}
@@ -7,6 +7,8 @@ import (
 var Module = fx.Module(
 	"core.cache",
 	fx.Provide(
+		NewMetricsConfig,
 		NewInstrumentor,
+		NewCaches,
 	),
 )
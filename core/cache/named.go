@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/enesanbar/go-service/core/config"
+	"go.uber.org/fx"
+)
+
+// NamedCache is a cache instance configured under 'cache.<backend>.<name>'.
+type NamedCache struct {
+	Name  string
+	Cache Cache
+}
+
+// AsNamedCaches provides the constructor function to the fx container under the group "named-caches".
+// The provided function must return a []NamedCache.
+func AsNamedCaches(p any) any {
+	return fx.Annotate(
+		p,
+		fx.ResultTags(`group:"named-caches,flatten"`),
+	)
+}
+
+type CachesParams struct {
+	fx.In
+
+	Caches []NamedCache `group:"named-caches"`
+}
+
+// NewCaches returns the named caches of all the backends by their names.
+// Names must be unique across the backends.
+func NewCaches(p CachesParams) (map[string]Cache, error) {
+	caches := make(map[string]Cache, len(p.Caches))
+	for _, named := range p.Caches {
+		if _, ok := caches[named.Name]; ok {
+			return nil, fmt.Errorf("cache '%s' is configured more than once", named.Name)
+		}
+		caches[named.Name] = named.Cache
+	}
+	return caches, nil
+}
+
+// Named provides the named cache as a cache.Cache tagged with `name:"cache.<name>"`, e.g.
+//
+//	type Params struct {
+//		fx.In
+//		Sessions cache.Cache `name:"cache.sessions"`
+//	}
+func Named(name string) fx.Option {
+	return fx.Provide(
+		fx.Annotate(
+			func(caches map[string]Cache) (Cache, error) {
+				c, ok := caches[name]
+				if !ok {
+					return nil, fmt.Errorf("cache '%s' is not configured", name)
+				}
+				return c, nil
+			},
+			fx.ResultTags(fmt.Sprintf(`name:"cache.%s"`, name)),
+		),
+	)
+}
+
+// InstanceNames returns the names of the instances configured under 'cache.<backend>',
+// which are the keys holding a block of properties rather than a single value.
+func InstanceNames(cfg config.Config, backend string) []string {
+	names := make([]string, 0)
+	for key, value := range cfg.GetStringMap(fmt.Sprintf("cache.%s", backend)) {
+		if _, ok := value.(map[string]interface{}); ok {
+			names = append(names, key)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
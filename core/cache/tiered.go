@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/enesanbar/go-service/core/log"
+	"go.uber.org/zap"
+)
+
+// Tiered is a two-tier implementation of Cache interface.
+// A near cache, typically in-memory, holds short-lived copies of the entries of a remote cache
+// shared by all the instances of the service.
+//
+// Changes are written to the remote cache and broadcast through the InvalidationBus,
+// so that every instance evicts its near copy.
+type Tiered struct {
+	near         Cache
+	remote       Cache
+	bus          InvalidationBus
+	nearTTL      time.Duration
+	instrumentor *Instrumentor
+	logger       log.Factory
+	id           string
+	done         context.Context
+	cancel       context.CancelFunc
+}
+
+type TieredParams struct {
+	Near    Cache
+	Remote  Cache
+	Bus     InvalidationBus
+	NearTTL time.Duration
+
+	Instrumentor *Instrumentor
+	Logger       log.Factory
+}
+
+// NewTiered returns a pointer to the new instance of Tiered.
+// Bus may be nil when the service runs a single instance.
+func NewTiered(p TieredParams) *Tiered {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+
+	done, cancel := context.WithCancel(context.Background())
+
+	return &Tiered{
+		near:         p.Near,
+		remote:       p.Remote,
+		bus:          p.Bus,
+		nearTTL:      p.NearTTL,
+		instrumentor: p.Instrumentor,
+		logger:       p.Logger,
+		id:           hex.EncodeToString(id),
+		done:         done,
+		cancel:       cancel,
+	}
+}
+
+// Set sets a value in the remote cache, evicts the near copy and broadcasts the change.
+// The near cache is filled on the next Get, so that all the near copies hold the value
+// as returned by the remote cache.
+func (t *Tiered) Set(ctx context.Context, key string, value interface{}, options ...SetOption) error {
+	defer t.instrumentor.Observe("set", "tiered", time.Now())
+
+	if err := t.remote.Set(ctx, key, value, options...); err != nil {
+		return err
+	}
+
+	if err := t.near.Invalidate(ctx, key); err != nil {
+		return err
+	}
+
+	t.broadcast(ctx, InvalidationMessage{Keys: []string{key}})
+	return nil
+}
+
+// Get gets a value from the near cache, falling back to the remote cache.
+// Values found in the remote cache are copied to the near cache.
+// The hits and misses of the tiers are counted apart from those of their caches, see Instrumentor.TierHit.
+func (t *Tiered) Get(ctx context.Context, key string) (interface{}, error) {
+	defer t.instrumentor.Observe("get", "tiered", time.Now())
+
+	value, err := t.near.Get(ctx, key)
+	if err == nil {
+		t.instrumentor.TierHit(key, "l1")
+		return value, nil
+	}
+	t.instrumentor.TierMiss(key, "l1")
+
+	value, err = t.remote.Get(ctx, key)
+	if errors.Is(err, ErrKeyNotFound) {
+		t.instrumentor.TierMiss(key, "l2")
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.instrumentor.TierHit(key, "l2")
+
+	if err := t.near.Set(ctx, key, value, WithTTL(t.nearTTL)); err != nil {
+		t.logger.For(ctx).With(zap.String("key", key), zap.Error(err)).Error("unable to fill near cache")
+	}
+
+	return value, nil
+}
+
+// Invalidate invalidates a value in both tiers and broadcasts the change.
+func (t *Tiered) Invalidate(ctx context.Context, key string) error {
+	if err := t.remote.Invalidate(ctx, key); err != nil {
+		return err
+	}
+
+	if err := t.near.Invalidate(ctx, key); err != nil {
+		return err
+	}
+
+	t.broadcast(ctx, InvalidationMessage{Keys: []string{key}})
+	return nil
+}
+
+// InvalidateTags invalidates the tagged entries of the remote cache and broadcasts the change.
+// The near copies are filled without their tags, so the near caches are cleared entirely.
+func (t *Tiered) InvalidateTags(ctx context.Context, tags ...string) error {
+	if err := t.remote.InvalidateTags(ctx, tags...); err != nil {
+		return err
+	}
+
+	if err := t.near.InvalidatePrefix(ctx, ""); err != nil {
+		return err
+	}
+
+	t.broadcast(ctx, InvalidationMessage{Tags: tags})
+	return nil
+}
+
+// InvalidatePrefix invalidates the entries starting with the prefix in both tiers and broadcasts the change.
+func (t *Tiered) InvalidatePrefix(ctx context.Context, prefix string) error {
+	if err := t.remote.InvalidatePrefix(ctx, prefix); err != nil {
+		return err
+	}
+
+	if err := t.near.InvalidatePrefix(ctx, prefix); err != nil {
+		return err
+	}
+
+	t.broadcast(ctx, InvalidationMessage{Prefixes: []string{prefix}})
+	return nil
+}
+
+// Start listens to the invalidations broadcast by the other instances until Stop is called.
+func (t *Tiered) Start(ctx context.Context) error {
+	if t.bus == nil {
+		return nil
+	}
+
+	t.logger.For(ctx).With(zap.String("instance", t.id)).Info("listening to cache invalidations")
+	return t.bus.Subscribe(t.done, t.evict)
+}
+
+// Stop stops listening to the invalidations.
+func (t *Tiered) Stop(_ context.Context) error {
+	t.cancel()
+	return nil
+}
+
+func (t *Tiered) evict(ctx context.Context, message InvalidationMessage) {
+	if message.Source == t.id {
+		return
+	}
+
+	for _, key := range message.Keys {
+		if err := t.near.Invalidate(ctx, key); err != nil {
+			t.logger.For(ctx).With(zap.String("key", key), zap.Error(err)).Error("unable to evict near cache entry")
+		}
+	}
+
+	prefixes := message.Prefixes
+	if len(message.Tags) > 0 {
+		prefixes = []string{""}
+	}
+	for _, prefix := range prefixes {
+		if err := t.near.InvalidatePrefix(ctx, prefix); err != nil {
+			t.logger.For(ctx).With(zap.String("prefix", prefix), zap.Error(err)).Error("unable to evict near cache entries")
+		}
+	}
+}
+
+func (t *Tiered) broadcast(ctx context.Context, message InvalidationMessage) {
+	if t.bus == nil {
+		return
+	}
+
+	message.Source = t.id
+	err := t.bus.Publish(ctx, message)
+	if err != nil {
+		t.logger.For(ctx).
+			With(zap.Strings("keys", message.Keys)).
+			With(zap.Strings("tags", message.Tags)).
+			With(zap.Strings("prefixes", message.Prefixes)).
+			With(zap.Error(err)).
+			Error("unable to broadcast cache invalidation")
+	}
+}
@@ -0,0 +1,223 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrTypeMismatch is returned by Typed when the cached value cannot be converted to the type of the cache.
+var ErrTypeMismatch = errors.New("cached value has an unexpected type")
+
+// negativeMarker is stored in place of the values that are known not to exist.
+// Values are stored as a struct in the in-memory backends and as bytes when a Codec is used.
+var negativeMarker = []byte("\x00go-service:cache:negative\x00")
+
+type negativeEntry struct{}
+
+// DefaultLoadTimeout bounds the loads of GetOrLoad whose callers have no deadline, see WithLoadTimeout.
+const DefaultLoadTimeout = 30 * time.Second
+
+// Loader loads the value of a key that is missing in the cache, e.g. from a database.
+// It should return ErrKeyNotFound when the value does not exist,
+// so that the absence can be cached with WithNegativeTTL.
+type Loader[T any] func(ctx context.Context, key string) (T, error)
+
+// Typed is a type-safe wrapper of Cache, supporting read-through loading.
+//
+// Concurrent misses of the same key are de-duplicated,
+// so the loader runs only once for all the callers waiting for that key.
+type Typed[T any] struct {
+	cache       Cache
+	codec       Codec
+	negativeTTL time.Duration
+	loadTimeout time.Duration
+	group       singleflight.Group
+}
+
+type typedOptions struct {
+	codec       Codec
+	negativeTTL time.Duration
+	loadTimeout time.Duration
+}
+
+type TypedOption func(*typedOptions)
+
+// WithCodec serializes the values before storing them in the underlying cache.
+// It is required for remote backends, which can only hold bytes.
+func WithCodec(codec Codec) TypedOption {
+	return func(o *typedOptions) {
+		o.codec = codec
+	}
+}
+
+// WithNegativeTTL caches the absence of a value for the given duration
+// when the loader returns ErrKeyNotFound, preventing repeated loads of missing keys.
+func WithNegativeTTL(ttl time.Duration) TypedOption {
+	return func(o *typedOptions) {
+		o.negativeTTL = ttl
+	}
+}
+
+// WithLoadTimeout bounds the loads of GetOrLoad whose callers have no deadline, DefaultLoadTimeout by default.
+// The loads of the callers with a deadline are bounded by that deadline.
+func WithLoadTimeout(timeout time.Duration) TypedOption {
+	return func(o *typedOptions) {
+		o.loadTimeout = timeout
+	}
+}
+
+// NewTyped returns a pointer to the new instance of Typed wrapping the given cache.
+func NewTyped[T any](cache Cache, options ...TypedOption) *Typed[T] {
+	o := typedOptions{loadTimeout: DefaultLoadTimeout}
+	for _, option := range options {
+		option(&o)
+	}
+
+	return &Typed[T]{
+		cache:       cache,
+		codec:       o.codec,
+		negativeTTL: o.negativeTTL,
+		loadTimeout: o.loadTimeout,
+	}
+}
+
+// Get gets a value from the cache.
+// It returns ErrKeyNotFound for missing keys and keys that are cached as missing.
+func (t *Typed[T]) Get(ctx context.Context, key string) (T, error) {
+	value, _, err := t.get(ctx, key)
+	return value, err
+}
+
+// Set sets a value in the cache.
+func (t *Typed[T]) Set(ctx context.Context, key string, value T, options ...SetOption) error {
+	if t.codec == nil {
+		return t.cache.Set(ctx, key, value, options...)
+	}
+
+	data, err := t.codec.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("unable to encode the value of '%s' with %s codec: %w", key, t.codec.Name(), err)
+	}
+
+	return t.cache.Set(ctx, key, data, options...)
+}
+
+// Invalidate invalidates a value in the cache.
+func (t *Typed[T]) Invalidate(ctx context.Context, key string) error {
+	return t.cache.Invalidate(ctx, key)
+}
+
+// GetOrLoad gets a value from the cache, or loads it with the loader and stores it for the given ttl.
+// A zero ttl means the default expiration of the underlying cache.
+//
+// The loader is shared by all the callers missing the same key at the same time,
+// so it runs with a context that is not canceled when the calling context is. It keeps the deadline of the calling context,
+// or the load timeout if it has none, so that a hung loader does not block the later callers of the key forever.
+// Each caller still returns as soon as its own context is done.
+func (t *Typed[T]) GetOrLoad(ctx context.Context, key string, loader Loader[T], ttl time.Duration) (T, error) {
+	var zero T
+
+	value, negative, err := t.get(ctx, key)
+	if err == nil {
+		return value, nil
+	}
+	if negative {
+		return zero, err
+	}
+
+	result := t.group.DoChan(key, func() (any, error) {
+		loadCtx, cancel := t.loadContext(ctx)
+		defer cancel()
+
+		value, err := loader(loadCtx, key)
+		if errors.Is(err, ErrKeyNotFound) {
+			if t.negativeTTL > 0 {
+				_ = t.setNegative(loadCtx, key)
+			}
+			return value, err
+		}
+		if err != nil {
+			return value, err
+		}
+
+		// failing to fill the cache must not fail the load
+		_ = t.Set(loadCtx, key, value, WithTTL(ttl))
+		return value, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case res := <-result:
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		value, _ := res.Val.(T)
+		return value, nil
+	}
+}
+
+// loadContext returns the context of a shared load, which is not canceled with ctx but ends at its deadline,
+// or once the load timeout elapses if ctx has no deadline.
+func (t *Typed[T]) loadContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	loadCtx := context.WithoutCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(loadCtx, deadline)
+	}
+	return context.WithTimeout(loadCtx, t.loadTimeout)
+}
+
+// get returns the value of the key, reporting whether the key is cached as missing.
+func (t *Typed[T]) get(ctx context.Context, key string) (T, bool, error) {
+	var zero T
+
+	cached, err := t.cache.Get(ctx, key)
+	if err != nil {
+		return zero, false, err
+	}
+
+	if _, ok := cached.(negativeEntry); ok {
+		return zero, true, ErrKeyNotFound
+	}
+
+	if t.codec == nil {
+		value, ok := cached.(T)
+		if !ok {
+			return zero, false, fmt.Errorf("%w: expected %T, got %T", ErrTypeMismatch, zero, cached)
+		}
+		return value, false, nil
+	}
+
+	var data []byte
+	switch v := cached.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return zero, false, fmt.Errorf("%w: expected encoded bytes, got %T", ErrTypeMismatch, cached)
+	}
+
+	if bytes.Equal(data, negativeMarker) {
+		return zero, true, ErrKeyNotFound
+	}
+
+	var value T
+	if err := t.codec.Unmarshal(data, &value); err != nil {
+		return zero, false, fmt.Errorf("unable to decode the value of '%s' with %s codec: %w", key, t.codec.Name(), err)
+	}
+
+	return value, false, nil
+}
+
+func (t *Typed[T]) setNegative(ctx context.Context, key string) error {
+	if t.codec == nil {
+		return t.cache.Set(ctx, key, negativeEntry{}, WithTTL(t.negativeTTL))
+	}
+	return t.cache.Set(ctx, key, negativeMarker, WithTTL(t.negativeTTL))
+}
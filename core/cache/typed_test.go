@@ -0,0 +1,280 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// mapCache is a Cache holding its entries in a map, recording their TTLs.
+type mapCache struct {
+	mu      sync.Mutex
+	entries map[string]interface{}
+	ttls    map[string]time.Duration
+}
+
+func newMapCache() *mapCache {
+	return &mapCache{entries: make(map[string]interface{}), ttls: make(map[string]time.Duration)}
+}
+
+func (c *mapCache) Set(_ context.Context, key string, value interface{}, options ...SetOption) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = value
+	c.ttls[key] = NewSetOptions(options...).TTL
+	return nil
+}
+
+func (c *mapCache) Get(_ context.Context, key string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	value, ok := c.entries[key]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return value, nil
+}
+
+func (c *mapCache) Invalidate(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+	return nil
+}
+
+func (c *mapCache) InvalidateTags(context.Context, ...string) error {
+	return nil
+}
+
+func (c *mapCache) InvalidatePrefix(_ context.Context, prefix string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+	return nil
+}
+
+func TestTyped_GetOrLoadDeduplicatesConcurrentLoads(t *testing.T) {
+	typed := NewTyped[user](newMapCache())
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func(context.Context, string) (user, error) {
+		calls.Add(1)
+		<-release
+		return user{ID: 1, Name: "Ada"}, nil
+	}
+
+	const callers = 20
+	var wg sync.WaitGroup
+	results := make(chan user, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := typed.GetOrLoad(context.Background(), "user:1", loader, time.Minute)
+			if err != nil {
+				t.Errorf("Expected no error, got '%v'", err)
+			}
+			results <- value
+		}()
+	}
+
+	// the callers are waiting for the load of the first one
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	if calls.Load() != 1 {
+		t.Errorf("Expected the loader to be called '%d' time, got '%d'", 1, calls.Load())
+	}
+	for value := range results {
+		if value.Name != "Ada" {
+			t.Errorf("Expected the loaded value, got '%+v'", value)
+		}
+	}
+
+	value, err := typed.Get(context.Background(), "user:1")
+	if err != nil || value.Name != "Ada" {
+		t.Errorf("Expected the loaded value to be cached, got '%+v' (%v)", value, err)
+	}
+}
+
+func TestTyped_GetOrLoadCachesMissingValues(t *testing.T) {
+	for name, codec := range map[string]Codec{"without codec": nil, "with codec": JSONCodec{}} {
+		c := newMapCache()
+		options := []TypedOption{WithNegativeTTL(time.Second)}
+		if codec != nil {
+			options = append(options, WithCodec(codec))
+		}
+		typed := NewTyped[user](c, options...)
+
+		var calls atomic.Int32
+		loader := func(context.Context, string) (user, error) {
+			calls.Add(1)
+			return user{}, ErrKeyNotFound
+		}
+
+		for i := 0; i < 3; i++ {
+			if _, err := typed.GetOrLoad(context.Background(), "user:2", loader, time.Minute); !errors.Is(err, ErrKeyNotFound) {
+				t.Errorf("Expected '%v' %s, got '%v'", ErrKeyNotFound, name, err)
+			}
+		}
+		if calls.Load() != 1 {
+			t.Errorf("Expected the loader to be called '%d' time %s, got '%d'", 1, name, calls.Load())
+		}
+		if c.ttls["user:2"] != time.Second {
+			t.Errorf("Expected the absence to be cached for '%s' %s, got '%s'", time.Second, name, c.ttls["user:2"])
+		}
+		if _, err := typed.Get(context.Background(), "user:2"); !errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("Expected Get to return '%v' %s, got '%v'", ErrKeyNotFound, name, err)
+		}
+	}
+
+	// without a negative ttl, the absence is not cached
+	typed := NewTyped[user](newMapCache())
+	var calls atomic.Int32
+	for i := 0; i < 2; i++ {
+		_, _ = typed.GetOrLoad(context.Background(), "user:2", func(context.Context, string) (user, error) {
+			calls.Add(1)
+			return user{}, ErrKeyNotFound
+		}, time.Minute)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected the loader to be called '%d' times, got '%d'", 2, calls.Load())
+	}
+}
+
+func TestTyped_GetOrLoadContinuesLoadingAfterCallerCancels(t *testing.T) {
+	typed := NewTyped[user](newMapCache())
+
+	release := make(chan struct{})
+	loaded := make(chan error, 1)
+	loader := func(ctx context.Context, _ string) (user, error) {
+		<-release
+		loaded <- ctx.Err()
+		return user{ID: 3, Name: "Grace"}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := typed.GetOrLoad(ctx, "user:3", loader, time.Minute)
+		done <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the caller to return '%v', got '%v'", context.Canceled, err)
+	}
+
+	close(release)
+	if err := <-loaded; err != nil {
+		t.Errorf("Expected the loader context not to be canceled, got '%v'", err)
+	}
+
+	// the shared load fills the cache once it completes
+	deadline := time.Now().Add(time.Second)
+	for {
+		value, err := typed.Get(context.Background(), "user:3")
+		if err == nil && value.Name == "Grace" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the loaded value to be cached, got '%+v' (%v)", value, err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestTyped_GetOrLoadBoundsSharedLoads(t *testing.T) {
+	// the loader hangs until its context is done
+	ended := make(chan error, 1)
+	hanging := func(ctx context.Context, _ string) (user, error) {
+		<-ctx.Done()
+		ended <- ctx.Err()
+		return user{}, ctx.Err()
+	}
+	loader := func(context.Context, string) (user, error) {
+		return user{ID: 5, Name: "Barbara"}, nil
+	}
+	waitForLoad := func(name string) {
+		select {
+		case err := <-ended:
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Expected the load %s to end with '%v', got '%v'", name, context.DeadlineExceeded, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected the load %s to end", name)
+		}
+	}
+
+	typed := NewTyped[user](newMapCache(), WithLoadTimeout(time.Hour))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, _ = typed.GetOrLoad(ctx, "user:5", hanging, time.Minute)
+	waitForLoad("with the deadline of the caller")
+
+	typed = NewTyped[user](newMapCache(), WithLoadTimeout(20*time.Millisecond))
+	_, _ = typed.GetOrLoad(context.Background(), "user:5", hanging, time.Minute)
+	waitForLoad("after the load timeout")
+
+	// the key is loaded again by the later callers
+	value, err := typed.GetOrLoad(context.Background(), "user:5", loader, time.Minute)
+	if err != nil || value.ID != 5 {
+		t.Errorf("Expected the value to be loaded, got '%+v' (%v)", value, err)
+	}
+}
+
+func TestTyped_GetOrLoadReloadsUndecodableValues(t *testing.T) {
+	c := newMapCache()
+	typed := NewTyped[user](c, WithCodec(JSONCodec{}))
+	loader := func(context.Context, string) (user, error) {
+		return user{ID: 4, Name: "Linus"}, nil
+	}
+
+	for name, cached := range map[string]interface{}{
+		"corrupt bytes": []byte("{not json"),
+		"unexpected":    42,
+	} {
+		_ = c.Set(context.Background(), "user:4", cached)
+
+		if _, err := typed.Get(context.Background(), "user:4"); err == nil {
+			t.Errorf("Expected Get to fail for %s", name)
+		}
+
+		value, err := typed.GetOrLoad(context.Background(), "user:4", loader, time.Minute)
+		if err != nil || value.Name != "Linus" {
+			t.Errorf("Expected the value to be loaded for %s, got '%+v' (%v)", name, value, err)
+		}
+		if value, err := typed.Get(context.Background(), "user:4"); err != nil || value.ID != 4 {
+			t.Errorf("Expected the cached value to be replaced for %s, got '%+v' (%v)", name, value, err)
+		}
+	}
+
+	// without a codec, a value of another type is loaded again
+	c = newMapCache()
+	_ = c.Set(context.Background(), "user:4", "Linus")
+	value, err := NewTyped[user](c).GetOrLoad(context.Background(), "user:4", loader, time.Minute)
+	if err != nil || value.ID != 4 {
+		t.Errorf("Expected the value to be loaded, got '%+v' (%v)", value, err)
+	}
+}
@@ -0,0 +1,31 @@
+// Package configtest provides a config.Config to be used in tests.
+package configtest
+
+import (
+	"testing"
+
+	"github.com/enesanbar/go-service/core/config"
+	"github.com/enesanbar/go-service/core/log"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// New returns a config.Config holding the given properties, keyed by their dotted paths,
+// e.g. {"cache.redis.address": "localhost:6379"}. The test fails when the config cannot be created.
+func New(t testing.TB, properties map[string]any) config.Config {
+	t.Helper()
+
+	v := viper.New()
+	cfg, err := config.NewFileConfigProvider(config.FileConfigProviderParams{
+		Logger: log.NewFactory(zap.NewNop()),
+		Viper:  v,
+		Env:    "test",
+	})
+	if err != nil {
+		t.Fatalf("unable to create config: %v", err)
+	}
+	for key, value := range properties {
+		v.Set(key, value)
+	}
+	return cfg
+}
@@ -2,6 +2,8 @@ package info
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
 )
 
 // Compile time variables
@@ -38,3 +40,12 @@ func (b BuildInfo) String() string {
 
 	return string(bytes)
 }
+
+// MetricName returns the name of a metric prefixed with the name of the service,
+// e.g. 'my_service.cache.hits' for 'cache.hits' in the 'my-service' service.
+func MetricName(name string) string {
+	if ServiceName == "" {
+		return name
+	}
+	return fmt.Sprintf("%s.%s", strings.ReplaceAll(ServiceName, "-", "_"), name)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/enesanbar/go-service/core/osutil"
+	promclient "github.com/prometheus/client_golang/prometheus"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/exporters/prometheus"
@@ -31,8 +32,20 @@ func NewZipkinExporter() (*zipkin.Exporter, error) {
 	return zipkin.New("http://localhost:9411/api/v2/spans")
 }
 
-func NewPrometheusExporter() (*prometheus.Exporter, error) {
-	return prometheus.New()
+type PrometheusExporterParams struct {
+	fx.In
+
+	Registry *promclient.Registry `optional:"true"`
+}
+
+// NewPrometheusExporter returns the exporter of the OTel metrics.
+// The metrics are registered to the registry of the telemetry server when it's provided,
+// and to the default registry otherwise.
+func NewPrometheusExporter(p PrometheusExporterParams) (*prometheus.Exporter, error) {
+	if p.Registry == nil {
+		return prometheus.New()
+	}
+	return prometheus.New(prometheus.WithRegisterer(p.Registry))
 }
 
 func NewExporter() fx.Option {
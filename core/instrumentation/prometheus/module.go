@@ -9,6 +9,7 @@ var Module = fx.Module(
 	"core/instrumentation/prometheus",
 	fx.Provide(
 		NewTelemetryServerConfig,
+		NewRegistry,
 		fx.Annotate(
 			NewTelemetryServer,
 			fx.As(new(wiring.Runnable)),
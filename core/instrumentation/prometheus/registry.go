@@ -0,0 +1,11 @@
+package prometheus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// NewRegistry returns the registry of the metrics exported through OTel,
+// served by the telemetry server along with the default registry.
+func NewRegistry() *prometheus.Registry {
+	return prometheus.NewRegistry()
+}
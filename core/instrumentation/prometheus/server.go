@@ -12,6 +12,7 @@ import (
 
 	"github.com/enesanbar/go-service/core/config"
 	"github.com/enesanbar/go-service/core/log"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
@@ -27,10 +28,15 @@ func NewTelemetryServer(
 	logger log.Factory,
 	baseConfig *config.Base,
 	telemetryConfig *TelemetryServerConfig,
+	registry *prometheus.Registry,
 ) (*TelemetryServer, error) {
 	telemetryRouter := http.NewServeMux()
 
-	telemetryRouter.Handle("/metrics", promhttp.Handler())
+	gatherers := prometheus.Gatherers{prometheus.DefaultGatherer, registry}
+	telemetryRouter.Handle("/metrics", promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{}),
+	))
 
 	server := &TelemetryServer{
 		Router:     telemetryRouter,
@@ -0,0 +1,188 @@
+package cloudevents
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/enesanbar/go-service/core/messaging/codec"
+	"github.com/enesanbar/go-service/core/messaging/messages"
+)
+
+const (
+	SpecVersion = "1.0"
+	// ContentType is the content type of the events in the structured mode, i.e. the JSON event format.
+	ContentType = "application/cloudevents+json"
+
+	AttributeID              = "id"
+	AttributeSource          = "source"
+	AttributeType            = "type"
+	AttributeSpecVersion     = "specversion"
+	AttributeTime            = "time"
+	AttributeDataContentType = "datacontenttype"
+	// AttributeTraceparent and AttributeTracestate are the distributed tracing extension.
+	AttributeTraceparent = "traceparent"
+	AttributeTracestate  = "tracestate"
+	// AttributeCorrelationID and AttributeCausationID are the extensions of the correlation and causation ids.
+	AttributeCorrelationID = "correlationid"
+	AttributeCausationID   = "causationid"
+	// AttributeMessageVersion is the extension of the version of the payload.
+	AttributeMessageVersion = "messageversion"
+)
+
+// ErrInvalidEvent is returned when an event misses a required attribute or has an unsupported spec version.
+var ErrInvalidEvent = errors.New("invalid cloud event")
+
+// Attributes returns the context attributes of the message, mapping the metadata of the message:
+// the message id to id, the publisher to source, the message name to type and the trace to the distributed tracing extension.
+func Attributes(metadata messages.Metadata, contentType string) map[string]string {
+	attributes := map[string]string{
+		AttributeSpecVersion: SpecVersion,
+		AttributeID:          metadata.MessageID,
+		AttributeSource:      metadata.PublisherName,
+		AttributeType:        metadata.MessageName,
+	}
+
+	optional := map[string]string{
+		AttributeDataContentType: contentType,
+		AttributeTraceparent:     metadata.Traceparent,
+		AttributeTracestate:      metadata.Tracestate,
+		AttributeCorrelationID:   metadata.CorrelationID,
+		AttributeCausationID:     metadata.CausationID,
+	}
+	if metadata.MessageVersion > 0 {
+		optional[AttributeMessageVersion] = strconv.Itoa(metadata.MessageVersion)
+	}
+	if !metadata.PublishDate.IsZero() {
+		optional[AttributeTime] = metadata.PublishDate.UTC().Format(time.RFC3339Nano)
+	}
+	for name, value := range optional {
+		if value != "" {
+			attributes[name] = value
+		}
+	}
+	return attributes
+}
+
+// Metadata returns the metadata of the message and its content type from the context attributes of an event.
+func Metadata(attributes map[string]string) (messages.Metadata, string, error) {
+	if version := attributes[AttributeSpecVersion]; version != SpecVersion {
+		return messages.Metadata{}, "", fmt.Errorf("%w: unsupported spec version '%s'", ErrInvalidEvent, version)
+	}
+	for _, name := range []string{AttributeID, AttributeSource, AttributeType} {
+		if attributes[name] == "" {
+			return messages.Metadata{}, "", fmt.Errorf("%w: missing %s", ErrInvalidEvent, name)
+		}
+	}
+
+	metadata := messages.Metadata{
+		MessageID:     attributes[AttributeID],
+		CorrelationID: attributes[AttributeCorrelationID],
+		CausationID:   attributes[AttributeCausationID],
+		PublisherName: attributes[AttributeSource],
+		MessageName:   attributes[AttributeType],
+		Traceparent:   attributes[AttributeTraceparent],
+		Tracestate:    attributes[AttributeTracestate],
+		SpanID:        parentSpanID(attributes[AttributeTraceparent]),
+	}
+	if value := attributes[AttributeTime]; value != "" {
+		publishDate, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return messages.Metadata{}, "", fmt.Errorf("%w: invalid time '%s'", ErrInvalidEvent, value)
+		}
+		metadata.PublishDate = publishDate
+	}
+	if value := attributes[AttributeMessageVersion]; value != "" {
+		version, err := strconv.Atoi(value)
+		if err != nil {
+			return messages.Metadata{}, "", fmt.Errorf("%w: invalid message version '%s'", ErrInvalidEvent, value)
+		}
+		metadata.MessageVersion = version
+	}
+	return metadata, attributes[AttributeDataContentType], nil
+}
+
+// parentSpanID returns the id of the span that published the event, i.e. the parent id of the traceparent.
+func parentSpanID(traceparent string) string {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[2]
+}
+
+// Event is an event in the JSON event format, used in the structured mode.
+type Event struct {
+	attributes map[string]string
+	// Data is the payload, if it is JSON.
+	Data json.RawMessage
+	// DataBase64 is the payload in any other content type.
+	DataBase64 []byte
+}
+
+// NewEvent returns the event of the message with the encoded payload.
+func NewEvent(metadata messages.Metadata, contentType string, data []byte) Event {
+	event := Event{attributes: Attributes(metadata, contentType)}
+	if codec.IsJSON(contentType) {
+		event.Data = data
+	} else {
+		event.DataBase64 = data
+	}
+	return event
+}
+
+// Metadata returns the metadata of the message, and the content type of its payload.
+func (e Event) Metadata() (messages.Metadata, string, error) {
+	return Metadata(e.attributes)
+}
+
+// Payload returns the encoded payload.
+func (e Event) Payload() []byte {
+	if e.DataBase64 != nil {
+		return e.DataBase64
+	}
+	return e.Data
+}
+
+func (e Event) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]any, len(e.attributes)+1)
+	for name, value := range e.attributes {
+		fields[name] = value
+	}
+	if e.DataBase64 != nil {
+		fields["data_base64"] = e.DataBase64
+	} else if e.Data != nil {
+		fields["data"] = e.Data
+	}
+	return json.Marshal(fields)
+}
+
+func (e *Event) UnmarshalJSON(data []byte) error {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	e.attributes = make(map[string]string, len(fields))
+	for name, value := range fields {
+		switch name {
+		case "data":
+			e.Data = value
+		case "data_base64":
+			if err := json.Unmarshal(value, &e.DataBase64); err != nil {
+				return fmt.Errorf("%w: invalid data_base64: %w", ErrInvalidEvent, err)
+			}
+		default:
+			// the extensions may be numbers or booleans, they are kept in their JSON form
+			var s string
+			if err := json.Unmarshal(value, &s); err != nil {
+				s = string(value)
+			}
+			e.attributes[name] = s
+		}
+	}
+	return nil
+}
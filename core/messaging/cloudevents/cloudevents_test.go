@@ -0,0 +1,94 @@
+package cloudevents
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/enesanbar/go-service/core/messaging/messages"
+)
+
+func newTestMetadata() messages.Metadata {
+	return messages.Metadata{
+		MessageID:     "message-1",
+		CorrelationID: "correlation-1",
+		PublisherName: "orders",
+		PublishDate:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		MessageName:   "order-created",
+		Traceparent:   "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	attributes := Attributes(newTestMetadata(), "application/json")
+
+	expected := map[string]string{
+		AttributeSpecVersion:     SpecVersion,
+		AttributeID:              "message-1",
+		AttributeSource:          "orders",
+		AttributeType:            "order-created",
+		AttributeTime:            "2024-01-02T03:04:05Z",
+		AttributeDataContentType: "application/json",
+		AttributeTraceparent:     "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		AttributeCorrelationID:   "correlation-1",
+	}
+	if len(attributes) != len(expected) {
+		t.Errorf("Expected the attributes to be '%v', got '%v'", expected, attributes)
+	}
+	for name, value := range expected {
+		if attributes[name] != value {
+			t.Errorf("Expected %s to be '%s', got '%s'", name, value, attributes[name])
+		}
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	metadata, contentType, err := Metadata(Attributes(newTestMetadata(), "application/avro"))
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	expected := newTestMetadata()
+	expected.SpanID = "00f067aa0ba902b7"
+	if metadata != expected {
+		t.Errorf("Expected the metadata to be '%+v', got '%+v'", expected, metadata)
+	}
+	if contentType != "application/avro" {
+		t.Errorf("Expected the content type to be '%s', got '%s'", "application/avro", contentType)
+	}
+
+	_, _, err = Metadata(map[string]string{AttributeSpecVersion: "0.3", AttributeID: "1", AttributeSource: "s", AttributeType: "t"})
+	if !errors.Is(err, ErrInvalidEvent) {
+		t.Errorf("Expected '%v', got '%v'", ErrInvalidEvent, err)
+	}
+	_, _, err = Metadata(map[string]string{AttributeSpecVersion: SpecVersion, AttributeID: "1"})
+	if !errors.Is(err, ErrInvalidEvent) {
+		t.Errorf("Expected '%v', got '%v'", ErrInvalidEvent, err)
+	}
+}
+
+func TestEvent_JSON(t *testing.T) {
+	tests := map[string][]byte{
+		"application/json":    []byte(`{"id":1}`),
+		"application/msgpack": {0x81, 0xa2, 0x69, 0x64, 0x01},
+	}
+	for contentType, data := range tests {
+		body, err := json.Marshal(NewEvent(newTestMetadata(), contentType, data))
+		if err != nil {
+			t.Fatalf("Expected no error, got '%v'", err)
+		}
+
+		var event Event
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Fatalf("Expected no error, got '%v'", err)
+		}
+		if string(event.Payload()) != string(data) {
+			t.Errorf("Expected the payload of '%s' to be '%v', got '%v'", contentType, data, event.Payload())
+		}
+		metadata, dataContentType, err := event.Metadata()
+		if err != nil || metadata.MessageID != "message-1" || dataContentType != contentType {
+			t.Errorf("Expected the attributes of '%s' to be decoded, got '%+v' with '%v'", contentType, metadata, err)
+		}
+	}
+}
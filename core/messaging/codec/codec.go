@@ -0,0 +1,113 @@
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"sync"
+)
+
+const (
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeAvro     = "application/avro"
+	ContentTypeMsgpack  = "application/msgpack"
+)
+
+// ErrUnsupportedContentType is returned when no codec is registered for a content type.
+var ErrUnsupportedContentType = errors.New("unsupported content type")
+
+// Codec encodes the payloads of the messages, it is selected by the content type of the messages.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+	ContentType() string
+}
+
+// Registry holds the codecs by their content types.
+type Registry struct {
+	mu      sync.RWMutex
+	codecs  map[string]Codec
+	aliases map[string]string
+}
+
+// NewRegistry returns a pointer to the new instance of Registry, with the JSON, protobuf, Avro and msgpack codecs
+// and the given ones, which replace the built-in codecs of the same content types.
+func NewRegistry(codecs ...Codec) *Registry {
+	r := &Registry{
+		codecs: make(map[string]Codec),
+		aliases: map[string]string{
+			"text/json":                       ContentTypeJSON,
+			"application/protobuf":            ContentTypeProtobuf,
+			"application/vnd.google.protobuf": ContentTypeProtobuf,
+			"avro/binary":                     ContentTypeAvro,
+			"application/x-msgpack":           ContentTypeMsgpack,
+			"application/vnd.msgpack":         ContentTypeMsgpack,
+		},
+	}
+
+	for _, c := range []Codec{JSONCodec{}, ProtobufCodec{}, NewAvroCodec(), MsgpackCodec{}} {
+		r.Register(c)
+	}
+	for _, c := range codecs {
+		r.Register(c)
+	}
+	return r
+}
+
+// Register adds the codec, replacing the codec of the same content type.
+func (r *Registry) Register(c Codec) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.codecs[normalize(c.ContentType())] = c
+}
+
+// Get returns the codec of the content type, ignoring its parameters, e.g. 'application/json; charset=utf-8'.
+// An empty content type is JSON, the encoding of the messages that predate the codecs.
+func (r *Registry) Get(contentType string) (Codec, error) {
+	if contentType == "" {
+		contentType = ContentTypeJSON
+	}
+
+	key := normalize(contentType)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if alias, ok := r.aliases[key]; ok {
+		key = alias
+	}
+	c, ok := r.codecs[key]
+	if !ok {
+		// structured syntaxes, e.g. application/vnd.order+json
+		if i := strings.LastIndex(key, "+"); i >= 0 {
+			c, ok = r.codecs["application/"+key[i+1:]]
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedContentType, contentType)
+	}
+	return c, nil
+}
+
+// IsJSON reports whether the payloads of the content type are JSON, ignoring its parameters,
+// e.g. 'application/json; charset=utf-8', 'text/json' or 'application/vnd.order+json'.
+// An empty content type is JSON, as for Registry.Get.
+func IsJSON(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+
+	mediaType := normalize(contentType)
+	return mediaType == ContentTypeJSON || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// normalize strips the parameters of the content type.
+func normalize(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(contentType))
+	}
+	return mediaType
+}
@@ -0,0 +1,121 @@
+package codec
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type orderCreated struct {
+	ID    int    `json:"id" avro:"id"`
+	Buyer string `json:"buyer" avro:"buyer"`
+}
+
+func (orderCreated) AvroSchema() string {
+	return `{"type":"record","name":"OrderCreated","fields":[{"name":"id","type":"int"},{"name":"buyer","type":"string"}]}`
+}
+
+func TestRegistry_Get(t *testing.T) {
+	r := NewRegistry()
+
+	tests := map[string]string{
+		"":                                ContentTypeJSON,
+		"application/json; charset=utf-8": ContentTypeJSON,
+		"application/vnd.order+json":      ContentTypeJSON,
+		"text/json":                       ContentTypeJSON,
+		"application/protobuf":            ContentTypeProtobuf,
+		"avro/binary":                     ContentTypeAvro,
+		"application/x-msgpack":           ContentTypeMsgpack,
+	}
+	for contentType, expected := range tests {
+		c, err := r.Get(contentType)
+		if err != nil {
+			t.Errorf("Expected no error for '%s', got '%v'", contentType, err)
+			continue
+		}
+		if c.ContentType() != expected {
+			t.Errorf("Expected the codec of '%s' to be '%s', got '%s'", contentType, expected, c.ContentType())
+		}
+	}
+
+	if _, err := r.Get("text/csv"); !errors.Is(err, ErrUnsupportedContentType) {
+		t.Errorf("Expected '%v', got '%v'", ErrUnsupportedContentType, err)
+	}
+}
+
+func TestIsJSON(t *testing.T) {
+	tests := map[string]bool{
+		"":                                true,
+		"application/json":                true,
+		"Application/JSON; charset=utf-8": true,
+		"text/json":                       true,
+		"application/vnd.order+json":      true,
+		"application/x-protobuf":          false,
+		"application/jsonl":               false,
+		"text/plain":                      false,
+	}
+	for contentType, expected := range tests {
+		if IsJSON(contentType) != expected {
+			t.Errorf("Expected IsJSON of '%s' to be '%t', got '%t'", contentType, expected, !expected)
+		}
+	}
+}
+
+func TestRegistry_Register(t *testing.T) {
+	r := NewRegistry(customCodec{})
+
+	c, err := r.Get("text/csv")
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if _, ok := c.(customCodec); !ok {
+		t.Errorf("Expected the registered codec, got '%T'", c)
+	}
+}
+
+func TestCodecs_RoundTrip(t *testing.T) {
+	for _, c := range []Codec{JSONCodec{}, NewAvroCodec(), MsgpackCodec{}} {
+		data, err := c.Marshal(orderCreated{ID: 1, Buyer: "jane"})
+		if err != nil {
+			t.Fatalf("Expected no error from '%s', got '%v'", c.ContentType(), err)
+		}
+
+		var decoded orderCreated
+		if err := c.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Expected no error from '%s', got '%v'", c.ContentType(), err)
+		}
+		if decoded != (orderCreated{ID: 1, Buyer: "jane"}) {
+			t.Errorf("Expected '%s' to decode the payload, got '%+v'", c.ContentType(), decoded)
+		}
+	}
+}
+
+func TestProtobufCodec(t *testing.T) {
+	c := ProtobufCodec{}
+	data, err := c.Marshal(wrapperspb.String("jane"))
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	// the payloads of the handlers of messages are pointers to nil messages
+	var decoded *wrapperspb.StringValue
+	if err := c.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if decoded.GetValue() != "jane" {
+		t.Errorf("Expected the value to be '%s', got '%s'", "jane", decoded.GetValue())
+	}
+
+	if _, err := c.Marshal(orderCreated{}); err == nil {
+		t.Error("Expected an error for a payload that is not a proto.Message")
+	}
+}
+
+type customCodec struct {
+	JSONCodec
+}
+
+func (customCodec) ContentType() string {
+	return "text/csv"
+}
@@ -0,0 +1,144 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/hamba/avro/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// JSONCodec encodes the payloads with encoding/json.
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (JSONCodec) ContentType() string {
+	return ContentTypeJSON
+}
+
+// ProtobufCodec encodes the payloads that are proto.Messages.
+// Payloads can be decoded into a pointer to a nil message, e.g. the payload of a Handler[*pb.OrderCreated].
+type ProtobufCodec struct{}
+
+func (ProtobufCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+func (ProtobufCodec) Unmarshal(data []byte, v any) error {
+	m, ok := allocate(v).(proto.Message)
+	if !ok {
+		return fmt.Errorf("%T is not a proto.Message", v)
+	}
+	return proto.Unmarshal(data, m)
+}
+
+func (ProtobufCodec) ContentType() string {
+	return ContentTypeProtobuf
+}
+
+// AvroRecord is a payload encoded with Avro, which brings its own schema.
+type AvroRecord interface {
+	AvroSchema() string
+}
+
+// AvroCodec encodes the payloads that are AvroRecords, in the Avro binary encoding.
+type AvroCodec struct {
+	schemas sync.Map
+}
+
+// NewAvroCodec returns a pointer to the new instance of AvroCodec
+func NewAvroCodec() *AvroCodec {
+	return &AvroCodec{}
+}
+
+func (c *AvroCodec) Marshal(v any) ([]byte, error) {
+	schema, err := c.schema(v)
+	if err != nil {
+		return nil, err
+	}
+	return avro.Marshal(schema, v)
+}
+
+func (c *AvroCodec) Unmarshal(data []byte, v any) error {
+	v = allocate(v)
+	schema, err := c.schema(v)
+	if err != nil {
+		return err
+	}
+	return avro.Unmarshal(schema, data, v)
+}
+
+func (c *AvroCodec) ContentType() string {
+	return ContentTypeAvro
+}
+
+// schema returns the parsed schema of the record, the schemas are parsed once.
+func (c *AvroCodec) schema(v any) (avro.Schema, error) {
+	record, ok := v.(AvroRecord)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a codec.AvroRecord", v)
+	}
+
+	definition := record.AvroSchema()
+	if schema, ok := c.schemas.Load(definition); ok {
+		return schema.(avro.Schema), nil
+	}
+
+	schema, err := avro.Parse(definition)
+	if err != nil {
+		return nil, fmt.Errorf("invalid avro schema of %T: %w", v, err)
+	}
+	c.schemas.Store(definition, schema)
+	return schema, nil
+}
+
+// MsgpackCodec encodes the payloads with MessagePack, using the json tags of the structs.
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) Marshal(v any) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	enc := msgpack.NewEncoder(buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (MsgpackCodec) Unmarshal(data []byte, v any) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+func (MsgpackCodec) ContentType() string {
+	return ContentTypeMsgpack
+}
+
+// allocate returns the pointer that v points to, allocating it if it is nil,
+// so that a payload of a pointer type can be decoded into the address of the payload.
+func allocate(v any) any {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Pointer {
+		return v
+	}
+	if rv.Elem().IsNil() {
+		rv.Elem().Set(reflect.New(rv.Elem().Type().Elem()))
+	}
+	return rv.Elem().Interface()
+}
@@ -0,0 +1,184 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/enesanbar/go-service/core/cache"
+	"github.com/enesanbar/go-service/core/config"
+	"github.com/enesanbar/go-service/core/info"
+	"github.com/enesanbar/go-service/core/log"
+	"github.com/enesanbar/go-service/core/messaging/messages"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	otelmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	PropertyDeduplicationRetention = "messaging.deduplication.retention"
+
+	// DefaultDeduplicationRetention is the time the processed messages are remembered for,
+	// it should be longer than the time a message can be redelivered in.
+	DefaultDeduplicationRetention = 24 * time.Hour
+)
+
+// ProcessedStore records the messages processed by the handlers, so that their duplicates are skipped.
+type ProcessedStore interface {
+	// IsProcessed reports whether the message with the key is processed within the retention.
+	IsProcessed(ctx context.Context, key string) (bool, error)
+	// MarkProcessed records the message with the key as processed for the retention.
+	MarkProcessed(ctx context.Context, key string, retention time.Duration) error
+}
+
+// CacheStore records the processed messages in a cache.Cache,
+// which should be shared by the instances of the service, e.g. redis.
+type CacheStore struct {
+	Cache cache.Cache
+}
+
+// NewCacheStore returns a pointer to the new instance of CacheStore
+func NewCacheStore(c cache.Cache) *CacheStore {
+	return &CacheStore{Cache: c}
+}
+
+func (s *CacheStore) IsProcessed(ctx context.Context, key string) (bool, error) {
+	_, err := s.Cache.Get(ctx, key)
+	if errors.Is(err, cache.ErrKeyNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *CacheStore) MarkProcessed(ctx context.Context, key string, retention time.Duration) error {
+	return s.Cache.Set(ctx, key, true, cache.WithTTL(retention))
+}
+
+// Deduplicator makes the message handlers idempotent, skipping the messages that are already processed.
+// Only the messages with an id are deduplicated. The duplicates delivered at the same time may both be processed,
+// as a message is recorded once it is processed.
+type Deduplicator struct {
+	Logger    log.Factory
+	Store     ProcessedStore
+	Retention time.Duration
+
+	duplicates metric.Int64Counter
+}
+
+type DeduplicatorParams struct {
+	fx.In
+
+	Config        config.Config
+	Logger        log.Factory
+	Store         ProcessedStore
+	MeterProvider *otelmetric.MeterProvider `optional:"true"`
+}
+
+// NewDeduplicator returns a pointer to the new instance of Deduplicator
+func NewDeduplicator(p DeduplicatorParams) (*Deduplicator, error) {
+	retention := DefaultDeduplicationRetention
+	if value := p.Config.GetString(PropertyDeduplicationRetention); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value '%s' for %s: %w", value, PropertyDeduplicationRetention, err)
+		}
+		retention = d
+	}
+
+	var provider metric.MeterProvider = noop.NewMeterProvider()
+	if p.MeterProvider != nil {
+		provider = p.MeterProvider
+	}
+
+	duplicates, err := provider.Meter(instrumentationName).Int64Counter(
+		info.MetricName("messaging.consumer.messages.duplicate"),
+		metric.WithDescription("The number of duplicate messages that are skipped"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create duplicate messages counter: %w", err)
+	}
+
+	return &Deduplicator{
+		Logger:     p.Logger,
+		Store:      p.Store,
+		Retention:  retention,
+		duplicates: duplicates,
+	}, nil
+}
+
+// Wrap returns the handler that skips the messages the given handler has already processed.
+func (d *Deduplicator) Wrap(handler MessageHandler) MessageHandler {
+	return &idempotentHandler{MessageHandler: handler, deduplicator: d}
+}
+
+type idempotentHandler struct {
+	MessageHandler
+	deduplicator *Deduplicator
+}
+
+// Handle handles the message unless it is processed, and records it as processed if it is handled successfully.
+// The message is handled if the store cannot be read, duplicates are preferred to lost messages.
+func (h *idempotentHandler) Handle(ctx context.Context, message messages.Message[any]) error {
+	return h.handleOnce(ctx, message.Metadata, func() error {
+		return h.MessageHandler.Handle(ctx, message)
+	})
+}
+
+// HandlePayload is Handle for the encoded payloads, which are decoded only if the message is not processed.
+func (h *idempotentHandler) HandlePayload(ctx context.Context, metadata messages.Metadata, payload Payload) error {
+	return h.handleOnce(ctx, metadata, func() error {
+		return Dispatch(ctx, h.MessageHandler, metadata, payload)
+	})
+}
+
+func (h *idempotentHandler) handleOnce(ctx context.Context, metadata messages.Metadata, handle func() error) error {
+	if metadata.MessageID == "" {
+		return handle()
+	}
+
+	d := h.deduplicator
+	properties := h.Properties()
+	key := fmt.Sprintf("processed:%s:%s:%s", properties.QueueName, properties.MessageName, metadata.MessageID)
+	logger := d.Logger.For(ctx).With(zap.String("message_id", metadata.MessageID), zap.String("queue", properties.QueueName))
+
+	processed, err := d.Store.IsProcessed(ctx, key)
+	if err != nil {
+		logger.With(zap.Error(err)).Error("unable to check whether the message is processed")
+	}
+	if processed {
+		d.duplicates.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("queue", properties.QueueName),
+			attribute.String("message", properties.MessageName),
+		))
+		logger.Info("skipping duplicate message")
+		return nil
+	}
+
+	if err := handle(); err != nil {
+		return err
+	}
+
+	if err := d.Store.MarkProcessed(ctx, key, d.Retention); err != nil {
+		logger.With(zap.Error(err)).Error("unable to record the message as processed")
+	}
+	return nil
+}
+
+// DeduplicationModule provides the Deduplicator, used by the consumers to make their handlers idempotent.
+// It requires a ProcessedStore, e.g. with CacheStoreModule.
+var DeduplicationModule = fx.Provide(NewDeduplicator)
+
+// CacheStoreModule records the processed messages in the cache.Cache.
+var CacheStoreModule = fx.Provide(
+	fx.Annotate(
+		NewCacheStore,
+		fx.As(new(ProcessedStore)),
+	),
+)
@@ -0,0 +1,149 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/enesanbar/go-service/core/cache"
+	"github.com/enesanbar/go-service/core/config/configtest"
+	"github.com/enesanbar/go-service/core/log"
+	"github.com/enesanbar/go-service/core/messaging/messages"
+	otelmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.uber.org/zap"
+)
+
+// mapCache is a cache.Cache that records the TTLs of its entries.
+type mapCache struct {
+	mu      sync.Mutex
+	entries map[string]time.Duration
+}
+
+func (c *mapCache) Set(_ context.Context, key string, _ interface{}, options ...cache.SetOption) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	o := cache.SetOptions{}
+	for _, option := range options {
+		option(&o)
+	}
+	c.entries[key] = o.TTL
+	return nil
+}
+
+func (c *mapCache) Get(_ context.Context, key string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok {
+		return nil, cache.ErrKeyNotFound
+	}
+	return true, nil
+}
+
+func (c *mapCache) Invalidate(context.Context, string) error        { return nil }
+func (c *mapCache) InvalidateTags(context.Context, ...string) error { return nil }
+func (c *mapCache) InvalidatePrefix(context.Context, string) error  { return nil }
+
+type countingHandler struct {
+	handled int
+	err     error
+}
+
+func (h *countingHandler) Handle(context.Context, messages.Message[any]) error {
+	h.handled++
+	return h.err
+}
+
+func (h *countingHandler) Properties() MessageProperties {
+	return MessageProperties{QueueName: "orders", MessageName: "order-created"}
+}
+
+func (h *countingHandler) GetMessageType() any {
+	return &map[string]any{}
+}
+
+func newTestDeduplicator(t *testing.T, c *mapCache, reader otelmetric.Reader) *Deduplicator {
+	t.Helper()
+
+	d, err := NewDeduplicator(DeduplicatorParams{
+		Config:        configtest.New(t, map[string]any{PropertyDeduplicationRetention: "1h"}),
+		Logger:        log.NewFactory(zap.NewNop()),
+		Store:         NewCacheStore(c),
+		MeterProvider: otelmetric.NewMeterProvider(otelmetric.WithReader(reader)),
+	})
+	if err != nil {
+		t.Fatalf("unable to create deduplicator: %v", err)
+	}
+	return d
+}
+
+func TestDeduplicator_SkipsProcessedMessages(t *testing.T) {
+	c := &mapCache{entries: make(map[string]time.Duration)}
+	reader := otelmetric.NewManualReader()
+	handler := &countingHandler{}
+	idempotent := newTestDeduplicator(t, c, reader).Wrap(handler)
+
+	message := messages.Message[any]{Metadata: messages.Metadata{MessageID: "message-1", MessageName: "order-created"}}
+	for i := 0; i < 3; i++ {
+		if err := idempotent.Handle(context.Background(), message); err != nil {
+			t.Fatalf("Expected no error, got '%v'", err)
+		}
+	}
+
+	if handler.handled != 1 {
+		t.Errorf("Expected the message to be handled '%d' time, got '%d'", 1, handler.handled)
+	}
+	if ttl := c.entries["processed:orders:order-created:message-1"]; ttl != time.Hour {
+		t.Errorf("Expected the message to be recorded for '%s', got '%s'", time.Hour, ttl)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("unable to collect metrics: %v", err)
+	}
+	var duplicates metricdata.Sum[int64]
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		if strings.HasSuffix(m.Name, "messages.duplicate") {
+			duplicates = m.Data.(metricdata.Sum[int64])
+		}
+	}
+	if len(duplicates.DataPoints) != 1 || duplicates.DataPoints[0].Value != 2 {
+		t.Errorf("Expected '%d' duplicates to be counted, got '%+v'", 2, duplicates.DataPoints)
+	}
+}
+
+func TestDeduplicator_HandlesFailedMessagesAgain(t *testing.T) {
+	c := &mapCache{entries: make(map[string]time.Duration)}
+	handler := &countingHandler{err: errors.New("database is down")}
+	idempotent := newTestDeduplicator(t, c, otelmetric.NewManualReader()).Wrap(handler)
+
+	message := messages.Message[any]{Metadata: messages.Metadata{MessageID: "message-1"}}
+	for i := 0; i < 2; i++ {
+		if err := idempotent.Handle(context.Background(), message); err == nil {
+			t.Errorf("Expected the error of the handler")
+		}
+	}
+
+	if handler.handled != 2 || len(c.entries) != 0 {
+		t.Errorf("Expected failed messages not to be recorded, got '%d' handled", handler.handled)
+	}
+}
+
+func TestDeduplicator_HandlesMessagesWithoutID(t *testing.T) {
+	c := &mapCache{entries: make(map[string]time.Duration)}
+	handler := &countingHandler{}
+	idempotent := newTestDeduplicator(t, c, otelmetric.NewManualReader()).Wrap(handler)
+
+	for i := 0; i < 2; i++ {
+		_ = idempotent.Handle(context.Background(), messages.Message[any]{})
+	}
+
+	if handler.handled != 2 {
+		t.Errorf("Expected the messages without id to be handled '%d' times, got '%d'", 2, handler.handled)
+	}
+}
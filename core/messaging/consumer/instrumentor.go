@@ -0,0 +1,146 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/enesanbar/go-service/core/info"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	otelmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.uber.org/fx"
+)
+
+const instrumentationName = "github.com/enesanbar/go-service/core/messaging/consumer"
+
+// Instrumentor records the metrics of the message handlers through the OTel MeterProvider.
+type Instrumentor struct {
+	inFlight    metric.Int64UpDownCounter
+	processed   metric.Int64Counter
+	failed      metric.Int64Counter
+	durations   metric.Float64Histogram
+	consumed    metric.Int64Counter
+	redelivered metric.Int64Counter
+	acked       metric.Int64Counter
+	nacked      metric.Int64Counter
+}
+
+type InstrumentorParams struct {
+	fx.In
+
+	MeterProvider *otelmetric.MeterProvider `optional:"true"`
+}
+
+// NewInstrumentor creates the counters and the handler duration histogram of the consumed messages,
+// prefixed with 'messaging.consumer'. Without a MeterProvider, the measurements are recorded to a no-op meter.
+func NewInstrumentor(p InstrumentorParams) (*Instrumentor, error) {
+	var provider metric.MeterProvider = noop.NewMeterProvider()
+	if p.MeterProvider != nil {
+		provider = p.MeterProvider
+	}
+
+	meter := provider.Meter(instrumentationName)
+
+	i := &Instrumentor{}
+
+	var err error
+	i.inFlight, err = meter.Int64UpDownCounter(info.MetricName("messaging.consumer.messages.in_flight"), metric.WithDescription("The number of messages being handled"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create in-flight messages counter: %w", err)
+	}
+
+	i.processed, err = meter.Int64Counter(info.MetricName("messaging.consumer.messages.processed"), metric.WithDescription("The number of messages handled successfully"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create processed messages counter: %w", err)
+	}
+
+	i.failed, err = meter.Int64Counter(info.MetricName("messaging.consumer.messages.failed"), metric.WithDescription("The number of messages that failed to be handled"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create failed messages counter: %w", err)
+	}
+
+	i.durations, err = meter.Float64Histogram(
+		info.MetricName("messaging.consumer.handler.duration"),
+		metric.WithDescription("The duration of the message handlers"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create handler duration histogram: %w", err)
+	}
+
+	i.consumed, err = meter.Int64Counter(info.MetricName("messaging.consumer.messages.consumed"), metric.WithDescription("The number of messages delivered by the broker"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create consumed messages counter: %w", err)
+	}
+
+	i.redelivered, err = meter.Int64Counter(info.MetricName("messaging.consumer.messages.redelivered"), metric.WithDescription("The number of messages delivered again by the broker"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create redelivered messages counter: %w", err)
+	}
+
+	i.acked, err = meter.Int64Counter(info.MetricName("messaging.consumer.messages.acked"), metric.WithDescription("The number of messages acked to the broker"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create acked messages counter: %w", err)
+	}
+
+	i.nacked, err = meter.Int64Counter(info.MetricName("messaging.consumer.messages.nacked"), metric.WithDescription("The number of messages rejected to the broker"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create nacked messages counter: %w", err)
+	}
+
+	return i, nil
+}
+
+// Begin records a message of the queue as in-flight.
+func (i *Instrumentor) Begin(queue string) {
+	i.inFlight.Add(context.Background(), 1, metric.WithAttributes(attribute.String("queue", queue)))
+}
+
+// End records the result and the duration of a message handled since start,
+// e.g. defer i.End("orders", "order-created", time.Now(), err)
+func (i *Instrumentor) End(queue string, messageName string, start time.Time, err error) {
+	i.inFlight.Add(context.Background(), -1, metric.WithAttributes(attribute.String("queue", queue)))
+
+	attributes := metric.WithAttributes(
+		attribute.String("queue", queue),
+		attribute.String("message", messageName),
+	)
+	if err != nil {
+		i.failed.Add(context.Background(), 1, attributes)
+	} else {
+		i.processed.Add(context.Background(), 1, attributes)
+	}
+	i.durations.Record(context.Background(), time.Since(start).Seconds(), attributes)
+}
+
+// Consumed records a message delivered from the queue, and whether the broker delivered it before.
+func (i *Instrumentor) Consumed(queue string, messageName string, redelivered bool) {
+	attributes := metric.WithAttributes(
+		attribute.String("queue", queue),
+		attribute.String("message", messageName),
+	)
+	i.consumed.Add(context.Background(), 1, attributes)
+	if redelivered {
+		i.redelivered.Add(context.Background(), 1, attributes)
+	}
+}
+
+// Acked records a message of the queue that is acked, so the broker removes it from the queue.
+func (i *Instrumentor) Acked(queue string, messageName string) {
+	i.acked.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String("queue", queue),
+		attribute.String("message", messageName),
+	))
+}
+
+// Nacked records a message of the queue that is rejected, and whether it is requeued.
+func (i *Instrumentor) Nacked(queue string, messageName string, requeue bool) {
+	i.nacked.Add(context.Background(), 1, metric.WithAttributes(
+		attribute.String("queue", queue),
+		attribute.String("message", messageName),
+		attribute.Bool("requeue", requeue),
+	))
+}
@@ -0,0 +1,93 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/enesanbar/go-service/core/messaging/codec"
+	"github.com/enesanbar/go-service/core/messaging/messages"
+	"github.com/enesanbar/go-service/core/messaging/schema"
+	"github.com/enesanbar/go-service/core/validation"
+	"go.uber.org/fx"
+)
+
+// PayloadValidator validates the payloads of the messages before they are handled,
+// against the JSON Schemas of their versions and the validation tags of their message types.
+// The messages with invalid payloads fail with an error wrapping ErrInvalidPayload.
+type PayloadValidator struct {
+	Schemas   *schema.Registry
+	Validator validation.Validator
+}
+
+type PayloadValidatorParams struct {
+	fx.In
+
+	Schemas   *schema.Registry     `optional:"true"`
+	Validator validation.Validator `name:"go_playground" optional:"true"`
+}
+
+// NewPayloadValidator creates a pointer to the new instance of the PayloadValidator
+func NewPayloadValidator(p PayloadValidatorParams) *PayloadValidator {
+	return &PayloadValidator{
+		Schemas:   p.Schemas,
+		Validator: p.Validator,
+	}
+}
+
+// Wrap returns the handler that validates the payloads before they are handled by the handler.
+func (v *PayloadValidator) Wrap(handler MessageHandler) MessageHandler {
+	return &validatingHandler{MessageHandler: handler, validator: v}
+}
+
+// validate validates the decoded payload with the validation tags of its struct, the other types are not validated.
+func (v *PayloadValidator) validate(ctx context.Context, payload any) error {
+	if v.Validator == nil {
+		return nil
+	}
+
+	value := reflect.ValueOf(payload)
+	for value.Kind() == reflect.Pointer && !value.IsNil() && value.Elem().Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+	if value.Kind() == reflect.Pointer && value.IsNil() {
+		return nil
+	}
+	if reflect.Indirect(value).Kind() != reflect.Struct {
+		return nil
+	}
+	return v.Validator.ValidateCtx(ctx, value.Interface())
+}
+
+type validatingHandler struct {
+	MessageHandler
+	validator *PayloadValidator
+}
+
+// HandlePayload validates the JSON payloads against the schema of their versions,
+// and the decoded payloads with the validation tags of their structs.
+func (h *validatingHandler) HandlePayload(ctx context.Context, metadata messages.Metadata, payload Payload) error {
+	if h.validator.Schemas != nil && codec.IsJSON(payload.codec().ContentType()) && len(payload.Data) > 0 {
+		err := h.validator.Schemas.Validate(metadata.MessageName, metadata.GetMessageVersion(), payload.Data)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+		}
+	}
+
+	payload.Validate = func(decoded any) error {
+		return h.validator.validate(ctx, decoded)
+	}
+	return Dispatch(ctx, h.MessageHandler, metadata, payload)
+}
+
+// Handle validates the decoded payload with the validation tags of its struct.
+func (h *validatingHandler) Handle(ctx context.Context, message messages.Message[any]) error {
+	if err := h.validator.validate(ctx, message.Payload); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+	}
+	return h.MessageHandler.Handle(ctx, message)
+}
+
+// PayloadValidationModule validates the payloads of the consumed messages, see PayloadValidator.
+// The schemas are registered with schema.Register, and require the schema.Module.
+var PayloadValidationModule = fx.Provide(NewPayloadValidator)
@@ -0,0 +1,70 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/enesanbar/go-service/core/messaging/codec"
+	"github.com/enesanbar/go-service/core/messaging/messages"
+	"github.com/enesanbar/go-service/core/messaging/schema"
+	"github.com/enesanbar/go-service/core/validation"
+	"github.com/go-playground/validator/v10"
+)
+
+// structValidator is a validation.Validator that validates the structs only.
+type structValidator struct {
+	validation.Validator
+	validate *validator.Validate
+}
+
+func (v structValidator) ValidateCtx(ctx context.Context, i interface{}) error {
+	return v.validate.StructCtx(ctx, i)
+}
+
+func TestPayloadValidator_ValidationTags(t *testing.T) {
+	current := &orderCreatedV2Handler{}
+	v := NewPayloadValidator(PayloadValidatorParams{Validator: structValidator{validate: validator.New()}})
+	handler := v.Wrap(NewMessageHandler[orderCreatedV2](current))
+
+	metadata := messages.Metadata{MessageName: "order-created", MessageVersion: 2}
+	err := Dispatch(context.Background(), handler, metadata, Payload{Data: []byte(`{"id":1}`)})
+	if !errors.Is(err, ErrInvalidPayload) {
+		t.Errorf("Expected '%v', got '%v'", ErrInvalidPayload, err)
+	}
+
+	err = Dispatch(context.Background(), handler, metadata, Payload{Data: []byte(`{"id":1,"buyer":"jane"}`)})
+	if err != nil || len(current.handled) != 1 {
+		t.Errorf("Expected the valid payload to be handled, got '%v'", err)
+	}
+}
+
+func TestPayloadValidator_Schemas(t *testing.T) {
+	schemas, err := schema.NewRegistry(schema.Definition{
+		MessageName: "order-created",
+		Version:     2,
+		Schema:      `{"type":"object","required":["id"],"properties":{"id":{"type":"integer","minimum":1}}}`,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	current := &orderCreatedV2Handler{}
+	handler := NewPayloadValidator(PayloadValidatorParams{Schemas: schemas}).Wrap(NewMessageHandler[orderCreatedV2](current))
+
+	err = Dispatch(context.Background(), handler, messages.Metadata{MessageName: "order-created", MessageVersion: 2}, Payload{Data: []byte(`{"id":0}`)})
+	if !errors.Is(err, ErrInvalidPayload) || !errors.Is(err, schema.ErrSchemaViolation) {
+		t.Errorf("Expected '%v', got '%v'", schema.ErrSchemaViolation, err)
+	}
+
+	// the schemas apply to the JSON payloads of their versions only
+	data, _ := codec.MsgpackCodec{}.Marshal(orderCreatedV2{})
+	err = Dispatch(context.Background(), handler, messages.Metadata{MessageName: "order-created", MessageVersion: 2}, Payload{Data: data, Codec: codec.MsgpackCodec{}})
+	if err != nil {
+		t.Errorf("Expected no error for a msgpack payload, got '%v'", err)
+	}
+	err = Dispatch(context.Background(), handler, messages.Metadata{MessageName: "order-created", MessageVersion: 3}, Payload{Data: []byte(`{"id":0}`)})
+	if err != nil || len(current.handled) != 2 {
+		t.Errorf("Expected the versions without a schema to be handled, got '%v'", err)
+	}
+}
@@ -5,12 +5,32 @@ import "go.uber.org/fx"
 type MessageProperties struct {
 	QueueName   string
 	MessageName string
+	// MinVersion and MaxVersion are the versions of the message handled by the handler, inclusive.
+	// Zero values mean the handler accepts any version.
+	MinVersion int
+	MaxVersion int
 }
 
+// Accepts reports whether the handler handles the version of the message.
+func (p MessageProperties) Accepts(version int) bool {
+	return (p.MinVersion == 0 || version >= p.MinVersion) && (p.MaxVersion == 0 || version <= p.MaxVersion)
+}
+
+// AsMessageHandler registers the handler returned by the constructor, a MessageHandler or a Handler[T].
+// e.g. fx.Provide(consumer.AsMessageHandler(NewOrderCreatedHandler))
 func AsMessageHandler(p any) any {
 	return fx.Annotate(
-		p,
+		asMessageHandler(p),
 		fx.As(new(MessageHandler)),
 		fx.ResultTags(`group:"message-handlers"`),
 	)
 }
+
+// AsUpcaster registers the upcaster returned by the constructor.
+// e.g. fx.Provide(consumer.AsUpcaster(NewOrderCreatedUpcaster))
+func AsUpcaster(p any) any {
+	return fx.Annotate(
+		p,
+		fx.ResultTags(`group:"message-upcasters"`),
+	)
+}
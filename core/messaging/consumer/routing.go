@@ -0,0 +1,61 @@
+package consumer
+
+import (
+	"fmt"
+
+	"go.uber.org/fx"
+)
+
+// HandlerKey returns the key of the handler of a message of a queue in the map of MapHandlers.
+func HandlerKey(queueName, messageName string) string {
+	return fmt.Sprintf("%s-%s", queueName, messageName)
+}
+
+type HandlersParams struct {
+	fx.In
+
+	Handlers     []MessageHandler  `group:"message-handlers"`
+	Upcasters    []Upcaster        `group:"message-upcasters"`
+	Deduplicator *Deduplicator     `optional:"true"`
+	Validator    *PayloadValidator `optional:"true"`
+}
+
+// MapHandlers maps the handlers by their queues and messages, see HandlerKey.
+// The handlers skip the duplicate messages if a Deduplicator is provided, see DeduplicationModule,
+// and validate the payloads if a PayloadValidator is provided, see PayloadValidationModule.
+// The messages are routed to the handlers of their versions, see NewVersionRouter.
+func MapHandlers(p HandlersParams) (map[string]MessageHandler, error) {
+	grouped := make(map[string][]MessageHandler)
+	for _, handler := range p.Handlers {
+		if p.Deduplicator != nil {
+			handler = p.Deduplicator.Wrap(handler)
+		}
+		if p.Validator != nil {
+			handler = p.Validator.Wrap(handler)
+		}
+		key := HandlerKey(handler.Properties().QueueName, handler.Properties().MessageName)
+		grouped[key] = append(grouped[key], handler)
+	}
+
+	upcast := make(map[string]bool)
+	for _, upcaster := range p.Upcasters {
+		upcast[upcaster.MessageName] = true
+	}
+
+	handlers := make(map[string]MessageHandler)
+	for key, group := range grouped {
+		properties := group[0].Properties()
+		versioned := properties.MinVersion != 0 || properties.MaxVersion != 0
+		if len(group) == 1 && !versioned && !upcast[properties.MessageName] {
+			handlers[key] = group[0]
+			continue
+		}
+
+		router, err := NewVersionRouter(group, p.Upcasters)
+		if err != nil {
+			return nil, err
+		}
+		handlers[key] = router
+	}
+	return handlers, nil
+}
@@ -0,0 +1,246 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/enesanbar/go-service/core/messaging/codec"
+	"github.com/enesanbar/go-service/core/messaging/messages"
+)
+
+// ErrInvalidPayload is returned when the payload of a message cannot be decoded into the message type of its handler.
+var ErrInvalidPayload = errors.New("invalid message payload")
+
+// Handler handles the messages whose payloads are decoded directly into T.
+// It is registered with AsMessageHandler, or adapted to a MessageHandler with NewMessageHandler.
+type Handler[T any] interface {
+	Handle(ctx context.Context, message messages.Message[T]) error
+	Properties() MessageProperties
+}
+
+// Payload is the encoded payload of a message, with the codec of its content type.
+type Payload struct {
+	Data []byte
+	// Codec decodes the payload, JSON if it is nil.
+	Codec codec.Codec
+	// Validate validates the decoded payload before it is handled, if set.
+	Validate func(payload any) error
+}
+
+// Decode decodes the payload into the target, leaving it as it is if the message has no payload.
+// It returns an error wrapping ErrInvalidPayload if the payload cannot be decoded.
+func (p Payload) Decode(target any) error {
+	if len(p.Data) > 0 {
+		if err := p.codec().Unmarshal(p.Data, target); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+		}
+	}
+
+	if p.Validate != nil {
+		if err := p.Validate(target); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+		}
+	}
+	return nil
+}
+
+func (p Payload) codec() codec.Codec {
+	if p.Codec == nil {
+		return codec.JSONCodec{}
+	}
+	return p.Codec
+}
+
+// PayloadHandler is a MessageHandler that decodes the payloads of its messages itself.
+// The consumers pass the encoded payloads to them, see Dispatch.
+type PayloadHandler interface {
+	MessageHandler
+	HandlePayload(ctx context.Context, metadata messages.Metadata, payload Payload) error
+}
+
+// Dispatch decodes the payload of the message once, into the message type of the handler, and handles the message.
+// It returns an error wrapping ErrInvalidPayload if the payload cannot be decoded.
+func Dispatch(ctx context.Context, handler MessageHandler, metadata messages.Metadata, payload Payload) error {
+	if h, ok := handler.(PayloadHandler); ok {
+		return h.HandlePayload(ctx, metadata, payload)
+	}
+
+	target := handler.GetMessageType()
+	if err := payload.Decode(target); err != nil {
+		return err
+	}
+	return handler.Handle(ctx, messages.Message[any]{Metadata: metadata, Payload: target})
+}
+
+// NewMessageHandler adapts the Handler to a MessageHandler.
+func NewMessageHandler[T any](handler Handler[T]) MessageHandler {
+	return &typedHandler[T]{handler: handler}
+}
+
+type typedHandler[T any] struct {
+	handler Handler[T]
+}
+
+func (h *typedHandler[T]) HandlePayload(ctx context.Context, metadata messages.Metadata, payload Payload) error {
+	var message messages.Message[T]
+	if err := payload.Decode(&message.Payload); err != nil {
+		return err
+	}
+	message.Metadata = metadata
+	return h.handler.Handle(ctx, message)
+}
+
+// Handle handles the message decoded into GetMessageType, or into any other type by encoding it again.
+func (h *typedHandler[T]) Handle(ctx context.Context, message messages.Message[any]) error {
+	typed := messages.Message[T]{Metadata: message.Metadata}
+	switch payload := message.Payload.(type) {
+	case T:
+		typed.Payload = payload
+	case *T:
+		typed.Payload = *payload
+	default:
+		if err := message.UnmarshalPayload(&typed.Payload); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+		}
+	}
+	return h.handler.Handle(ctx, typed)
+}
+
+func (h *typedHandler[T]) Properties() MessageProperties {
+	return h.handler.Properties()
+}
+
+func (h *typedHandler[T]) GetMessageType() any {
+	return new(T)
+}
+
+var (
+	contextType        = reflect.TypeFor[context.Context]()
+	errorType          = reflect.TypeFor[error]()
+	metadataType       = reflect.TypeFor[messages.Metadata]()
+	messageHandlerType = reflect.TypeFor[MessageHandler]()
+	propertiesType     = reflect.TypeFor[MessageProperties]()
+)
+
+// reflectHandler adapts a Handler[T] whose T is known at runtime only, i.e. the result of a constructor given to AsMessageHandler.
+// The messages are built with reflection, their payloads are still decoded once into T.
+type reflectHandler struct {
+	handle      reflect.Value
+	properties  func() MessageProperties
+	messageType reflect.Type
+	payloadType reflect.Type
+}
+
+// newReflectHandler adapts the handler, if it is a Handler[T].
+func newReflectHandler(handler reflect.Value) (*reflectHandler, error) {
+	handle := handler.MethodByName("Handle")
+	properties := handler.MethodByName("Properties")
+	if !handle.IsValid() || !properties.IsValid() {
+		return nil, fmt.Errorf("%s is neither a consumer.MessageHandler nor a consumer.Handler", handler.Type())
+	}
+
+	if t := properties.Type(); t.NumIn() != 0 || t.NumOut() != 1 || t.Out(0) != propertiesType {
+		return nil, fmt.Errorf("%s.Properties must return consumer.MessageProperties", handler.Type())
+	}
+
+	t := handle.Type()
+	if t.NumIn() != 2 || t.In(0) != contextType || t.NumOut() != 1 || t.Out(0) != errorType {
+		return nil, fmt.Errorf("%s.Handle must be func(context.Context, messages.Message[T]) error", handler.Type())
+	}
+	messageType := t.In(1)
+	metadataField, hasMetadata := messageType.FieldByName("Metadata")
+	payloadField, hasPayload := messageType.FieldByName("Payload")
+	if messageType.Kind() != reflect.Struct || messageType.PkgPath() != metadataType.PkgPath() ||
+		!hasMetadata || metadataField.Type != metadataType || !hasPayload {
+		return nil, fmt.Errorf("%s.Handle must be func(context.Context, messages.Message[T]) error", handler.Type())
+	}
+
+	return &reflectHandler{
+		handle:      handle,
+		properties:  properties.Interface().(func() MessageProperties),
+		messageType: messageType,
+		payloadType: payloadField.Type,
+	}, nil
+}
+
+func (h *reflectHandler) HandlePayload(ctx context.Context, metadata messages.Metadata, payload Payload) error {
+	message := reflect.New(h.messageType).Elem()
+	if err := payload.Decode(message.FieldByName("Payload").Addr().Interface()); err != nil {
+		return err
+	}
+	message.FieldByName("Metadata").Set(reflect.ValueOf(metadata))
+	return h.call(ctx, message)
+}
+
+// Handle handles the message decoded into GetMessageType, or into any other type by encoding it again.
+func (h *reflectHandler) Handle(ctx context.Context, message messages.Message[any]) error {
+	typed := reflect.New(h.messageType).Elem()
+	typed.FieldByName("Metadata").Set(reflect.ValueOf(message.Metadata))
+
+	payload := reflect.ValueOf(message.Payload)
+	switch {
+	case payload.IsValid() && payload.Type() == h.payloadType:
+		typed.FieldByName("Payload").Set(payload)
+	case payload.IsValid() && payload.Type() == reflect.PointerTo(h.payloadType) && !payload.IsNil():
+		typed.FieldByName("Payload").Set(payload.Elem())
+	default:
+		if err := message.UnmarshalPayload(typed.FieldByName("Payload").Addr().Interface()); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+		}
+	}
+	return h.call(ctx, typed)
+}
+
+func (h *reflectHandler) call(ctx context.Context, message reflect.Value) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	out := h.handle.Call([]reflect.Value{reflect.ValueOf(&ctx).Elem(), message})
+	err, _ := out[0].Interface().(error)
+	return err
+}
+
+func (h *reflectHandler) Properties() MessageProperties {
+	return h.properties()
+}
+
+func (h *reflectHandler) GetMessageType() any {
+	return reflect.New(h.payloadType).Interface()
+}
+
+// asMessageHandler wraps the constructor of a Handler[T] into a constructor of a MessageHandler.
+// The constructors of MessageHandlers are returned as they are.
+func asMessageHandler(constructor any) any {
+	c := reflect.ValueOf(constructor)
+	t := c.Type()
+	if t.Kind() != reflect.Func || t.NumOut() == 0 || t.Out(0).Implements(messageHandlerType) {
+		return constructor
+	}
+
+	in := make([]reflect.Type, t.NumIn())
+	for i := range in {
+		in[i] = t.In(i)
+	}
+	returnsError := t.NumOut() == 2 && t.Out(1) == errorType
+	out := []reflect.Type{messageHandlerType, errorType}
+
+	return reflect.MakeFunc(reflect.FuncOf(in, out, t.IsVariadic()), func(args []reflect.Value) []reflect.Value {
+		var results []reflect.Value
+		if t.IsVariadic() {
+			results = c.CallSlice(args)
+		} else {
+			results = c.Call(args)
+		}
+		if returnsError && !results[1].IsNil() {
+			return []reflect.Value{reflect.Zero(messageHandlerType), results[1]}
+		}
+
+		handler, err := newReflectHandler(results[0])
+		if err != nil {
+			return []reflect.Value{reflect.Zero(messageHandlerType), reflect.ValueOf(&err).Elem()}
+		}
+		return []reflect.Value{reflect.ValueOf(handler).Convert(messageHandlerType), reflect.Zero(errorType)}
+	}).Interface()
+}
@@ -0,0 +1,124 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/enesanbar/go-service/core/messaging/codec"
+	"github.com/enesanbar/go-service/core/messaging/messages"
+	"go.uber.org/fx"
+)
+
+type orderCreated struct {
+	ID    int    `json:"id"`
+	Buyer string `json:"buyer"`
+}
+
+type orderCreatedHandler struct {
+	handled []messages.Message[orderCreated]
+}
+
+func (h *orderCreatedHandler) Handle(_ context.Context, message messages.Message[orderCreated]) error {
+	h.handled = append(h.handled, message)
+	return nil
+}
+
+func (h *orderCreatedHandler) Properties() MessageProperties {
+	return MessageProperties{QueueName: "orders", MessageName: "order-created"}
+}
+
+func TestDispatch_TypedHandler(t *testing.T) {
+	typed := &orderCreatedHandler{}
+	handler := NewMessageHandler[orderCreated](typed)
+
+	metadata := messages.Metadata{MessageID: "message-1", MessageName: "order-created"}
+	err := Dispatch(context.Background(), handler, metadata, Payload{Data: []byte(`{"id":1,"buyer":"jane"}`)})
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	if len(typed.handled) != 1 || typed.handled[0].Payload.ID != 1 || typed.handled[0].Payload.Buyer != "jane" {
+		t.Fatalf("Expected the payload to be decoded into the message type, got '%+v'", typed.handled)
+	}
+	if typed.handled[0].Metadata.MessageID != "message-1" {
+		t.Errorf("Expected the metadata to be passed, got '%+v'", typed.handled[0].Metadata)
+	}
+
+	err = Dispatch(context.Background(), handler, metadata, Payload{Data: []byte(`{"id":"one"}`)})
+	if !errors.Is(err, ErrInvalidPayload) {
+		t.Errorf("Expected '%v', got '%v'", ErrInvalidPayload, err)
+	}
+}
+
+func TestDispatch_MessageHandler(t *testing.T) {
+	handler := &countingHandler{}
+
+	if err := Dispatch(context.Background(), handler, messages.Metadata{}, Payload{Data: []byte(`{"id":1}`)}); err != nil {
+		t.Errorf("Expected no error, got '%v'", err)
+	}
+	if err := Dispatch(context.Background(), handler, messages.Metadata{}, Payload{Data: []byte(`[1]`)}); !errors.Is(err, ErrInvalidPayload) {
+		t.Errorf("Expected '%v', got '%v'", ErrInvalidPayload, err)
+	}
+	if handler.handled != 1 {
+		t.Errorf("Expected the message to be handled '%d' time, got '%d'", 1, handler.handled)
+	}
+}
+
+func TestAsMessageHandler_TypedHandler(t *testing.T) {
+	typed := &orderCreatedHandler{}
+
+	var handlers []MessageHandler
+	app := fx.New(
+		fx.NopLogger,
+		fx.Provide(AsMessageHandler(func() *orderCreatedHandler { return typed })),
+		fx.Invoke(fx.Annotate(func(h []MessageHandler) { handlers = h }, fx.ParamTags(`group:"message-handlers"`))),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	if len(handlers) != 1 || handlers[0].Properties().QueueName != "orders" {
+		t.Fatalf("Expected the handler to be registered, got '%v'", handlers)
+	}
+
+	err := Dispatch(context.Background(), handlers[0], messages.Metadata{}, Payload{Data: []byte(`{"id":2}`)})
+	if err != nil || len(typed.handled) != 1 || typed.handled[0].Payload.ID != 2 {
+		t.Errorf("Expected the payload to be decoded into the message type, got '%+v' with '%v'", typed.handled, err)
+	}
+
+	// the consumers that predate Dispatch decode the payload into GetMessageType
+	payload := handlers[0].GetMessageType()
+	if _, ok := payload.(*orderCreated); !ok {
+		t.Errorf("Expected the message type to be '%T', got '%T'", &orderCreated{}, payload)
+	}
+	if err := handlers[0].Handle(context.Background(), messages.Message[any]{Payload: &orderCreated{ID: 3}}); err != nil || typed.handled[1].Payload.ID != 3 {
+		t.Errorf("Expected the decoded payload to be handled, got '%+v' with '%v'", typed.handled, err)
+	}
+}
+
+func TestAsMessageHandler_InvalidHandler(t *testing.T) {
+	app := fx.New(
+		fx.NopLogger,
+		fx.Provide(AsMessageHandler(func() *orderCreated { return &orderCreated{} })),
+		fx.Invoke(fx.Annotate(func([]MessageHandler) {}, fx.ParamTags(`group:"message-handlers"`))),
+	)
+	if app.Err() == nil {
+		t.Error("Expected an error for a type that is not a handler")
+	}
+}
+
+func TestDispatch_Codec(t *testing.T) {
+	typed := &orderCreatedHandler{}
+	handler := NewMessageHandler[orderCreated](typed)
+
+	data, err := codec.MsgpackCodec{}.Marshal(orderCreated{ID: 1, Buyer: "jane"})
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	err = Dispatch(context.Background(), handler, messages.Metadata{}, Payload{Data: data, Codec: codec.MsgpackCodec{}})
+	if err != nil || len(typed.handled) != 1 || typed.handled[0].Payload.Buyer != "jane" {
+		t.Errorf("Expected the payload to be decoded with its codec, got '%+v' with '%v'", typed.handled, err)
+	}
+}
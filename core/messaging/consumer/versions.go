@@ -0,0 +1,147 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/enesanbar/go-service/core/messaging/messages"
+)
+
+// ErrUnsupportedVersion is returned when no handler accepts the version of a message, even after it is upcast.
+var ErrUnsupportedVersion = errors.New("unsupported message version")
+
+// Upcaster migrates the payloads of a version of a message to the next version.
+// The versions no handler accepts are upcast one version at a time until a handler accepts them.
+type Upcaster struct {
+	MessageName string
+	FromVersion int
+
+	upcast func(payload Payload) (Payload, error)
+}
+
+// NewUpcaster returns the Upcaster of the message from the version to the next one.
+// The payloads are decoded into From and encoded from To with the codec of the message.
+func NewUpcaster[From, To any](messageName string, fromVersion int, upcast func(From) (To, error)) Upcaster {
+	return Upcaster{
+		MessageName: messageName,
+		FromVersion: fromVersion,
+		upcast: func(payload Payload) (Payload, error) {
+			var from From
+			if err := (Payload{Data: payload.Data, Codec: payload.Codec}).Decode(&from); err != nil {
+				return Payload{}, err
+			}
+
+			to, err := upcast(from)
+			if err != nil {
+				return Payload{}, fmt.Errorf("%w: failed to upcast version %d of message %s: %w", ErrInvalidPayload, fromVersion, messageName, err)
+			}
+			data, err := payload.codec().Marshal(to)
+			if err != nil {
+				return Payload{}, fmt.Errorf("%w: failed to upcast version %d of message %s: %w", ErrInvalidPayload, fromVersion, messageName, err)
+			}
+			return Payload{Data: data, Codec: payload.Codec, Validate: payload.Validate}, nil
+		},
+	}
+}
+
+// versionRouter routes the messages of a queue to the handler that accepts their versions.
+type versionRouter struct {
+	handlers  []MessageHandler
+	upcasters map[int]Upcaster
+}
+
+// NewVersionRouter returns the handler of the messages of a queue and a message name,
+// which routes the messages to the handler that accepts their versions, upcasting them if needed.
+// It returns an error if the versions of the handlers overlap, or the upcasters of a version are ambiguous.
+func NewVersionRouter(handlers []MessageHandler, upcasters []Upcaster) (MessageHandler, error) {
+	if len(handlers) == 0 {
+		return nil, errors.New("no handlers to route")
+	}
+
+	properties := handlers[0].Properties()
+	for i, handler := range handlers {
+		p := handler.Properties()
+		if p.QueueName != properties.QueueName || p.MessageName != properties.MessageName {
+			return nil, fmt.Errorf("handlers of message %s of queue %s cannot be routed with message %s of queue %s",
+				p.MessageName, p.QueueName, properties.MessageName, properties.QueueName)
+		}
+		if p.MaxVersion != 0 && p.MinVersion > p.MaxVersion {
+			return nil, fmt.Errorf("invalid versions %d-%d of the handler of message %s of queue %s", p.MinVersion, p.MaxVersion, p.MessageName, p.QueueName)
+		}
+		for _, other := range handlers[:i] {
+			if overlaps(p, other.Properties()) {
+				return nil, fmt.Errorf("handlers of message %s of queue %s accept the same versions", p.MessageName, p.QueueName)
+			}
+		}
+	}
+
+	r := &versionRouter{handlers: handlers, upcasters: make(map[int]Upcaster)}
+	for _, upcaster := range upcasters {
+		if upcaster.MessageName != properties.MessageName {
+			continue
+		}
+		if _, ok := r.upcasters[upcaster.FromVersion]; ok {
+			return nil, fmt.Errorf("multiple upcasters of version %d of message %s", upcaster.FromVersion, upcaster.MessageName)
+		}
+		r.upcasters[upcaster.FromVersion] = upcaster
+	}
+	return r, nil
+}
+
+// overlaps reports whether two handlers accept a version in common.
+func overlaps(a, b MessageProperties) bool {
+	lower := max(a.MinVersion, b.MinVersion)
+	upper := min(nonZero(a.MaxVersion), nonZero(b.MaxVersion))
+	return lower <= upper
+}
+
+func nonZero(version int) int {
+	if version == 0 {
+		return int(^uint(0) >> 1)
+	}
+	return version
+}
+
+func (r *versionRouter) HandlePayload(ctx context.Context, metadata messages.Metadata, payload Payload) error {
+	version := metadata.GetMessageVersion()
+	for {
+		for _, handler := range r.handlers {
+			if handler.Properties().Accepts(version) {
+				return Dispatch(ctx, handler, metadata, payload)
+			}
+		}
+
+		upcaster, ok := r.upcasters[version]
+		if !ok {
+			return fmt.Errorf("%w: no handler found for version %d of message %s", ErrUnsupportedVersion, version, metadata.MessageName)
+		}
+		var err error
+		if payload, err = upcaster.upcast(payload); err != nil {
+			return err
+		}
+		version++
+		metadata.MessageVersion = version
+	}
+}
+
+// Handle handles the decoded message by encoding it again, so that it can be upcast.
+func (r *versionRouter) Handle(ctx context.Context, message messages.Message[any]) error {
+	data, err := json.Marshal(message.Payload)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidPayload, err)
+	}
+	return r.HandlePayload(ctx, message.Metadata, Payload{Data: data})
+}
+
+// Properties returns the queue and the message name of the handlers, which accept any version together.
+func (r *versionRouter) Properties() MessageProperties {
+	properties := r.handlers[0].Properties()
+	return MessageProperties{QueueName: properties.QueueName, MessageName: properties.MessageName}
+}
+
+// GetMessageType returns the message type of the first handler, the messages are decoded by the handler of their versions.
+func (r *versionRouter) GetMessageType() any {
+	return r.handlers[0].GetMessageType()
+}
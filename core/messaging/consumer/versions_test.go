@@ -0,0 +1,113 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/enesanbar/go-service/core/messaging/messages"
+)
+
+type orderCreatedV1 struct {
+	Buyer string `json:"buyer"`
+}
+
+type orderCreatedV2 struct {
+	ID    int    `json:"id"`
+	Buyer string `json:"buyer" validate:"required"`
+}
+
+type orderCreatedV2Handler struct {
+	handled []messages.Message[orderCreatedV2]
+}
+
+func (h *orderCreatedV2Handler) Handle(_ context.Context, message messages.Message[orderCreatedV2]) error {
+	h.handled = append(h.handled, message)
+	return nil
+}
+
+func (h *orderCreatedV2Handler) Properties() MessageProperties {
+	return MessageProperties{QueueName: "orders", MessageName: "order-created", MinVersion: 2}
+}
+
+func newTestUpcaster() Upcaster {
+	return NewUpcaster("order-created", 1, func(v1 orderCreatedV1) (orderCreatedV2, error) {
+		return orderCreatedV2{Buyer: v1.Buyer}, nil
+	})
+}
+
+func TestMessageProperties_Accepts(t *testing.T) {
+	properties := MessageProperties{MinVersion: 2, MaxVersion: 3}
+	for version, expected := range map[int]bool{1: false, 2: true, 3: true, 4: false} {
+		if properties.Accepts(version) != expected {
+			t.Errorf("Expected Accepts(%d) to be '%t', got '%t'", version, expected, !expected)
+		}
+	}
+	if !(MessageProperties{}).Accepts(7) {
+		t.Error("Expected a handler without versions to accept any version")
+	}
+}
+
+func TestVersionRouter_RoutesByVersion(t *testing.T) {
+	legacy := &orderCreatedHandler{}
+	current := &orderCreatedV2Handler{}
+	router, err := NewVersionRouter([]MessageHandler{NewMessageHandler[orderCreated](versioned{legacy, 1}), NewMessageHandler[orderCreatedV2](current)}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	err = Dispatch(context.Background(), router, messages.Metadata{MessageName: "order-created"}, Payload{Data: []byte(`{"id":1}`)})
+	if err != nil || len(legacy.handled) != 1 {
+		t.Errorf("Expected the unversioned message to be handled as version 1, got '%v'", err)
+	}
+	err = Dispatch(context.Background(), router, messages.Metadata{MessageName: "order-created", MessageVersion: 3}, Payload{Data: []byte(`{"id":2}`)})
+	if err != nil || len(current.handled) != 1 || current.handled[0].Payload.ID != 2 {
+		t.Errorf("Expected version 3 to be handled by the handler of versions from 2, got '%v'", err)
+	}
+}
+
+func TestVersionRouter_Upcasts(t *testing.T) {
+	current := &orderCreatedV2Handler{}
+	router, err := NewVersionRouter([]MessageHandler{NewMessageHandler[orderCreatedV2](current)}, []Upcaster{newTestUpcaster()})
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	err = Dispatch(context.Background(), router, messages.Metadata{MessageName: "order-created"}, Payload{Data: []byte(`{"buyer":"jane"}`)})
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if len(current.handled) != 1 || current.handled[0].Payload.Buyer != "jane" || current.handled[0].Metadata.MessageVersion != 2 {
+		t.Errorf("Expected version 1 to be upcast to version 2, got '%+v'", current.handled)
+	}
+
+	router, _ = NewVersionRouter([]MessageHandler{NewMessageHandler[orderCreatedV2](current)}, nil)
+	err = Dispatch(context.Background(), router, messages.Metadata{MessageName: "order-created"}, Payload{Data: []byte(`{}`)})
+	if !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Expected '%v', got '%v'", ErrUnsupportedVersion, err)
+	}
+}
+
+func TestNewVersionRouter_OverlappingVersions(t *testing.T) {
+	_, err := NewVersionRouter([]MessageHandler{&countingHandler{}, NewMessageHandler[orderCreatedV2](&orderCreatedV2Handler{})}, nil)
+	if err == nil {
+		t.Error("Expected an error for handlers that accept the same versions")
+	}
+
+	_, err = NewVersionRouter([]MessageHandler{&countingHandler{}}, []Upcaster{newTestUpcaster(), newTestUpcaster()})
+	if err == nil {
+		t.Error("Expected an error for multiple upcasters of the same version")
+	}
+}
+
+// versioned limits the handler to a single version.
+type versioned struct {
+	*orderCreatedHandler
+	version int
+}
+
+func (h versioned) Properties() MessageProperties {
+	properties := h.orderCreatedHandler.Properties()
+	properties.MinVersion, properties.MaxVersion = h.version, h.version
+	return properties
+}
@@ -0,0 +1,37 @@
+package messages
+
+import "context"
+
+type metadataKey struct{}
+
+// WithMetadata returns a context that carries the metadata of the message being handled,
+// so that the messages published by its handler are correlated with it.
+func WithMetadata(ctx context.Context, metadata Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, metadata)
+}
+
+// MetadataFromContext returns the metadata of the message being handled, if any.
+func MetadataFromContext(ctx context.Context) (Metadata, bool) {
+	metadata, ok := ctx.Value(metadataKey{}).(Metadata)
+	return metadata, ok
+}
+
+// Correlate sets the correlation and causation ids that are not set yet, from the message being handled in the context.
+// A message published outside of a handler starts a new conversation, and is correlated with itself.
+func (m *Metadata) Correlate(ctx context.Context) {
+	if handled, ok := MetadataFromContext(ctx); ok {
+		if m.CausationID == "" {
+			m.CausationID = handled.MessageID
+		}
+		if m.CorrelationID == "" {
+			m.CorrelationID = handled.CorrelationID
+		}
+		if m.CorrelationID == "" {
+			m.CorrelationID = handled.MessageID
+		}
+	}
+
+	if m.CorrelationID == "" {
+		m.CorrelationID = m.MessageID
+	}
+}
@@ -0,0 +1,31 @@
+package messages
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMetadata_CorrelateWithoutHandledMessage(t *testing.T) {
+	metadata := Metadata{MessageID: "message-1"}
+	metadata.Correlate(context.Background())
+
+	if metadata.CorrelationID != "message-1" || metadata.CausationID != "" {
+		t.Errorf("Expected the message to be correlated with itself, got '%+v'", metadata)
+	}
+}
+
+func TestMetadata_CorrelateWithHandledMessage(t *testing.T) {
+	ctx := WithMetadata(context.Background(), Metadata{MessageID: "message-2", CorrelationID: "message-1"})
+
+	metadata := Metadata{MessageID: "message-3"}
+	metadata.Correlate(ctx)
+	if metadata.CorrelationID != "message-1" || metadata.CausationID != "message-2" {
+		t.Errorf("Expected correlation id '%s' and causation id '%s', got '%+v'", "message-1", "message-2", metadata)
+	}
+
+	metadata = Metadata{MessageID: "message-3", CorrelationID: "request-1"}
+	metadata.Correlate(ctx)
+	if metadata.CorrelationID != "request-1" || metadata.CausationID != "message-2" {
+		t.Errorf("Expected the given correlation id to be kept, got '%+v'", metadata)
+	}
+}
@@ -8,12 +8,20 @@ import (
 
 // WIP
 type Metadata struct {
-	PublisherName string    `json:"publisherName"`
-	MessageName   string    `json:"messageName"`
-	PublishDate   time.Time `json:"publishDate"`
-	Traceparent   string    `json:"traceparent"`
-	Tracestate    string    `json:"tracestate"`
-	SpanID        string    `json:"spanId"`
+	// MessageID identifies the message, its duplicates have the same id.
+	MessageID string `json:"messageId,omitempty"`
+	// CorrelationID identifies the conversation of the message, i.e. the id of the message that started it.
+	CorrelationID string `json:"correlationId,omitempty"`
+	// CausationID is the id of the message that is handled when the message is published.
+	CausationID   string `json:"causationId,omitempty"`
+	PublisherName string `json:"publisherName"`
+	MessageName   string `json:"messageName"`
+	// MessageVersion is the version of the payload of the message, see GetMessageVersion.
+	MessageVersion int       `json:"messageVersion,omitempty"`
+	PublishDate    time.Time `json:"publishDate"`
+	Traceparent    string    `json:"traceparent"`
+	Tracestate     string    `json:"tracestate"`
+	SpanID         string    `json:"spanId"`
 }
 
 type Message[T any] struct {
@@ -27,6 +35,18 @@ func (m *Message[T]) UnmarshalPayload(o any) error {
 	return json.NewDecoder(buf).Decode(o)
 }
 
+func (m *Metadata) GetMessageID() string {
+	return m.MessageID
+}
+
+func (m *Metadata) GetCorrelationID() string {
+	return m.CorrelationID
+}
+
+func (m *Metadata) GetCausationID() string {
+	return m.CausationID
+}
+
 func (m *Metadata) GetPublisherName() string {
 	return m.PublisherName
 }
@@ -35,6 +55,14 @@ func (m *Metadata) GetMessageName() string {
 	return m.MessageName
 }
 
+// GetMessageVersion returns the version of the payload, the messages that predate the versions are version 1.
+func (m *Metadata) GetMessageVersion() int {
+	if m.MessageVersion == 0 {
+		return 1
+	}
+	return m.MessageVersion
+}
+
 func (m *Metadata) GetPublishDate() string {
 	return m.PublishDate.Format(time.RFC3339)
 }
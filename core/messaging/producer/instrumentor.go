@@ -0,0 +1,92 @@
+package producer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/enesanbar/go-service/core/info"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	otelmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.uber.org/fx"
+)
+
+const instrumentationName = "github.com/enesanbar/go-service/core/messaging/producer"
+
+// Instrumentor records the metrics of the published messages through the OTel MeterProvider.
+type Instrumentor struct {
+	published metric.Int64Counter
+	confirmed metric.Int64Counter
+	returned  metric.Int64Counter
+	failed    metric.Int64Counter
+}
+
+type InstrumentorParams struct {
+	fx.In
+
+	MeterProvider *otelmetric.MeterProvider `optional:"true"`
+}
+
+// NewInstrumentor creates the counters of the published, confirmed, returned and failed messages,
+// prefixed with 'messaging.producer'. Without a MeterProvider, the measurements are recorded to a no-op meter.
+func NewInstrumentor(p InstrumentorParams) (*Instrumentor, error) {
+	var provider metric.MeterProvider = noop.NewMeterProvider()
+	if p.MeterProvider != nil {
+		provider = p.MeterProvider
+	}
+
+	meter := provider.Meter(instrumentationName)
+
+	i := &Instrumentor{}
+
+	var err error
+	i.published, err = meter.Int64Counter(info.MetricName("messaging.producer.messages.published"), metric.WithDescription("The number of messages sent to the broker"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create published messages counter: %w", err)
+	}
+
+	i.confirmed, err = meter.Int64Counter(info.MetricName("messaging.producer.messages.confirmed"), metric.WithDescription("The number of messages confirmed by the broker"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create confirmed messages counter: %w", err)
+	}
+
+	i.returned, err = meter.Int64Counter(info.MetricName("messaging.producer.messages.returned"), metric.WithDescription("The number of messages returned by the broker as unroutable"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create returned messages counter: %w", err)
+	}
+
+	i.failed, err = meter.Int64Counter(info.MetricName("messaging.producer.messages.failed"), metric.WithDescription("The number of messages refused or not confirmed by the broker"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create failed messages counter: %w", err)
+	}
+
+	return i, nil
+}
+
+func attributes(destination string, messageName string) metric.MeasurementOption {
+	return metric.WithAttributes(
+		attribute.String("destination", destination),
+		attribute.String("message", messageName),
+	)
+}
+
+// Published records a message sent to the destination, e.g. the exchange or the topic it is published to.
+func (i *Instrumentor) Published(destination string, messageName string) {
+	i.published.Add(context.Background(), 1, attributes(destination, messageName))
+}
+
+// Confirmed records a message the broker took responsibility for.
+func (i *Instrumentor) Confirmed(destination string, messageName string) {
+	i.confirmed.Add(context.Background(), 1, attributes(destination, messageName))
+}
+
+// Returned records a mandatory message that is not routed to any queue, see ErrUnroutable.
+func (i *Instrumentor) Returned(destination string, messageName string) {
+	i.returned.Add(context.Background(), 1, attributes(destination, messageName))
+}
+
+// Failed records a message the broker refused, or did not confirm in time.
+func (i *Instrumentor) Failed(destination string, messageName string) {
+	i.failed.Add(context.Background(), 1, attributes(destination, messageName))
+}
@@ -1,7 +1,168 @@
 package producer
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNacked is returned when the broker refuses to take the responsibility of a message.
+	ErrNacked = errors.New("message is nacked by the broker")
+	// ErrUnroutable is returned when a mandatory message is not routed to any queue.
+	ErrUnroutable = errors.New("message is not routed to any queue")
+)
 
 type Producer interface {
-	Publish(ctx context.Context, messageName string, message any) error
+	Publish(ctx context.Context, messageName string, message any, options ...PublishOption) error
+}
+
+// BatchProducer publishes many messages at once, waiting for the broker only once for all of them.
+type BatchProducer interface {
+	Producer
+	// PublishBatch publishes the messages in order, and returns the errors of the messages that are not published.
+	PublishBatch(ctx context.Context, publishings []Publishing) error
+}
+
+// Publishing is a message published with BatchProducer.PublishBatch.
+type Publishing struct {
+	MessageName string
+	Message     any
+	Options     []PublishOption
+}
+
+// PublishOptions holds the per-message options of Producer.Publish.
+// Zero values mean the defaults of the producer.
+type PublishOptions struct {
+	// Exchange the message is published to, e.g. a topic.
+	Exchange string
+	// RoutingKey of the message, the message name by default.
+	RoutingKey string
+	// Headers are sent along with the message.
+	Headers map[string]any
+	// Priority of the message, from 0 to 9.
+	Priority uint8
+	// Expiration discards the message if it is not consumed in time.
+	Expiration time.Duration
+	// Persistent messages survive broker restarts, if set.
+	Persistent *bool
+	// Mandatory messages fail with ErrUnroutable if they are not routed to any queue, if set.
+	Mandatory *bool
+	// MessageID identifies the message, it is generated by default.
+	MessageID string
+	// CorrelationID of the message, see messages.Metadata.Correlate for its default.
+	CorrelationID string
+	// CausationID of the message, see messages.Metadata.Correlate for its default.
+	CausationID string
+	// OrderingKey keeps the messages with the same key in order, by the producers that may reorder messages.
+	OrderingKey string
+	// MessageVersion is the version of the payload of the message, unversioned by default.
+	MessageVersion int
+	// ContentType selects the codec that encodes the message, the content type of the producer by default.
+	ContentType string
+}
+
+type PublishOption func(*PublishOptions)
+
+// WithExchange publishes the message to the exchange instead of the default one.
+func WithExchange(exchange string) PublishOption {
+	return func(o *PublishOptions) {
+		o.Exchange = exchange
+	}
+}
+
+// WithRoutingKey publishes the message with the routing key instead of the message name.
+func WithRoutingKey(routingKey string) PublishOption {
+	return func(o *PublishOptions) {
+		o.RoutingKey = routingKey
+	}
+}
+
+// WithHeader adds a header to the message.
+func WithHeader(name string, value any) PublishOption {
+	return func(o *PublishOptions) {
+		if o.Headers == nil {
+			o.Headers = make(map[string]any)
+		}
+		o.Headers[name] = value
+	}
+}
+
+// WithPriority sets the priority of the message, used by the queues that support priorities.
+func WithPriority(priority uint8) PublishOption {
+	return func(o *PublishOptions) {
+		o.Priority = priority
+	}
+}
+
+// WithExpiration discards the message if it is not consumed before the expiration.
+func WithExpiration(expiration time.Duration) PublishOption {
+	return func(o *PublishOptions) {
+		o.Expiration = expiration
+	}
+}
+
+// WithPersistence overrides the persistence of the message.
+func WithPersistence(persistent bool) PublishOption {
+	return func(o *PublishOptions) {
+		o.Persistent = &persistent
+	}
+}
+
+// WithMandatory overrides whether the message must be routed to a queue.
+func WithMandatory(mandatory bool) PublishOption {
+	return func(o *PublishOptions) {
+		o.Mandatory = &mandatory
+	}
+}
+
+// WithMessageID sets the id of the message, e.g. to deduplicate it.
+func WithMessageID(id string) PublishOption {
+	return func(o *PublishOptions) {
+		o.MessageID = id
+	}
+}
+
+// WithCorrelationID sets the correlation id of the message, instead of the one of the message being handled.
+func WithCorrelationID(id string) PublishOption {
+	return func(o *PublishOptions) {
+		o.CorrelationID = id
+	}
+}
+
+// WithCausationID sets the causation id of the message, instead of the id of the message being handled.
+func WithCausationID(id string) PublishOption {
+	return func(o *PublishOptions) {
+		o.CausationID = id
+	}
+}
+
+// WithOrderingKey publishes the message after the earlier messages with the same key, e.g. the id of an aggregate.
+func WithOrderingKey(key string) PublishOption {
+	return func(o *PublishOptions) {
+		o.OrderingKey = key
+	}
+}
+
+// WithMessageVersion publishes the message as the version of its payload, see messages.Metadata.GetMessageVersion.
+func WithMessageVersion(version int) PublishOption {
+	return func(o *PublishOptions) {
+		o.MessageVersion = version
+	}
+}
+
+// WithContentType encodes the message with the codec of the content type, e.g. codec.ContentTypeProtobuf.
+func WithContentType(contentType string) PublishOption {
+	return func(o *PublishOptions) {
+		o.ContentType = contentType
+	}
+}
+
+// NewPublishOptions applies the given options. It is meant to be used by the implementations of Producer.
+func NewPublishOptions(options ...PublishOption) PublishOptions {
+	o := PublishOptions{}
+	for _, option := range options {
+		option(&o)
+	}
+	return o
 }
@@ -0,0 +1,29 @@
+package producer
+
+import "context"
+
+// Publisher publishes the messages of type T with a name, so that a message is not published with the name of another,
+// e.g. to publish only the messages of a consumer.Handler[T].
+type Publisher[T any] struct {
+	Producer    Producer
+	MessageName string
+	Options     []PublishOption
+}
+
+// NewPublisher returns a pointer to the new instance of Publisher,
+// the options are applied to every message before the options of Publish.
+func NewPublisher[T any](p Producer, messageName string, options ...PublishOption) *Publisher[T] {
+	return &Publisher[T]{
+		Producer:    p,
+		MessageName: messageName,
+		Options:     options,
+	}
+}
+
+// Publish publishes the message with the name of the publisher.
+func (p *Publisher[T]) Publish(ctx context.Context, message T, options ...PublishOption) error {
+	all := make([]PublishOption, 0, len(p.Options)+len(options))
+	all = append(all, p.Options...)
+	all = append(all, options...)
+	return p.Producer.Publish(ctx, p.MessageName, message, all...)
+}
@@ -0,0 +1,40 @@
+package producer
+
+import (
+	"context"
+	"testing"
+)
+
+type orderCreated struct {
+	ID int
+}
+
+type recordingProducer struct {
+	messageName string
+	message     any
+	options     PublishOptions
+}
+
+func (p *recordingProducer) Publish(_ context.Context, messageName string, message any, options ...PublishOption) error {
+	p.messageName = messageName
+	p.message = message
+	p.options = NewPublishOptions(options...)
+	return nil
+}
+
+func TestPublisher_Publish(t *testing.T) {
+	p := &recordingProducer{}
+	publisher := NewPublisher[orderCreated](p, "order-created", WithExchange("orders"), WithRoutingKey("orders.created"))
+
+	err := publisher.Publish(context.Background(), orderCreated{ID: 1}, WithRoutingKey("orders.created.eu"))
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	if p.messageName != "order-created" || p.message != (orderCreated{ID: 1}) {
+		t.Errorf("Expected the message to be published as '%s', got '%s' with '%v'", "order-created", p.messageName, p.message)
+	}
+	if p.options.Exchange != "orders" || p.options.RoutingKey != "orders.created.eu" {
+		t.Errorf("Expected the options of Publish to override the options of the publisher, got '%+v'", p.options)
+	}
+}
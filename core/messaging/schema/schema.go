@@ -0,0 +1,116 @@
+package schema
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"go.uber.org/fx"
+)
+
+// ErrSchemaViolation is returned when a payload does not match the schema of its message.
+var ErrSchemaViolation = errors.New("payload does not match the schema")
+
+// Definition is the JSON Schema of a version of a message.
+type Definition struct {
+	MessageName string
+	// Version of the message, the messages that predate the versions are version 1.
+	Version int
+	Schema  string
+}
+
+type key struct {
+	messageName string
+	version     int
+}
+
+// Registry holds the JSON Schemas of the messages by their names and versions.
+type Registry struct {
+	mu      sync.RWMutex
+	schemas map[key]*jsonschema.Schema
+}
+
+// NewRegistry returns a pointer to the new instance of Registry with the given schemas.
+func NewRegistry(definitions ...Definition) (*Registry, error) {
+	r := &Registry{schemas: make(map[key]*jsonschema.Schema)}
+	for _, d := range definitions {
+		if err := r.Register(d); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Register compiles the schema, replacing the schema of the same message and version.
+func (r *Registry) Register(d Definition) error {
+	if d.MessageName == "" || d.Version < 1 {
+		return fmt.Errorf("invalid schema definition: message name and a positive version are required, got '%s' and '%d'", d.MessageName, d.Version)
+	}
+
+	document, err := jsonschema.UnmarshalJSON(strings.NewReader(d.Schema))
+	if err != nil {
+		return fmt.Errorf("invalid schema of version %d of message %s: %w", d.Version, d.MessageName, err)
+	}
+	url := fmt.Sprintf("urn:go-service:message:%s:%d", d.MessageName, d.Version)
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(url, document); err != nil {
+		return fmt.Errorf("invalid schema of version %d of message %s: %w", d.Version, d.MessageName, err)
+	}
+	compiled, err := compiler.Compile(url)
+	if err != nil {
+		return fmt.Errorf("invalid schema of version %d of message %s: %w", d.Version, d.MessageName, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.schemas[key{d.MessageName, d.Version}] = compiled
+	return nil
+}
+
+// Validate validates the JSON payload against the schema of the version of the message.
+// The payloads of the messages without a schema are valid.
+func (r *Registry) Validate(messageName string, version int, data []byte) error {
+	r.mu.RLock()
+	compiled, ok := r.schemas[key{messageName, version}]
+	r.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSchemaViolation, err)
+	}
+	if err := compiled.Validate(instance); err != nil {
+		return fmt.Errorf("%w: %w", ErrSchemaViolation, err)
+	}
+	return nil
+}
+
+type Params struct {
+	fx.In
+
+	Definitions []Definition `group:"message-schemas"`
+}
+
+// New creates the registry with the schemas registered with Register.
+func New(p Params) (*Registry, error) {
+	return NewRegistry(p.Definitions...)
+}
+
+// Module provides the Registry, which validates the payloads of the consumed messages, see consumer.PayloadValidationModule.
+var Module = fx.Provide(New)
+
+// Register registers the JSON Schema of a version of a message.
+// e.g. schema.Register("order-created", 2, orderCreatedV2Schema)
+func Register(messageName string, version int, schema string) fx.Option {
+	return fx.Provide(fx.Annotated{
+		Group: "message-schemas",
+		Target: func() Definition {
+			return Definition{MessageName: messageName, Version: version, Schema: schema}
+		},
+	})
+}
@@ -0,0 +1,36 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRegistry_Validate(t *testing.T) {
+	r, err := NewRegistry(Definition{
+		MessageName: "order-created",
+		Version:     1,
+		Schema:      `{"type":"object","required":["id"],"properties":{"id":{"type":"integer"}}}`,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	if err := r.Validate("order-created", 1, []byte(`{"id":1}`)); err != nil {
+		t.Errorf("Expected no error, got '%v'", err)
+	}
+	if err := r.Validate("order-created", 1, []byte(`{"id":"one"}`)); !errors.Is(err, ErrSchemaViolation) {
+		t.Errorf("Expected '%v', got '%v'", ErrSchemaViolation, err)
+	}
+	if err := r.Validate("order-created", 2, []byte(`{"id":"one"}`)); err != nil {
+		t.Errorf("Expected the versions without a schema to be valid, got '%v'", err)
+	}
+}
+
+func TestRegistry_RegisterInvalidSchema(t *testing.T) {
+	if _, err := NewRegistry(Definition{MessageName: "order-created", Version: 1, Schema: `{"type":`}); err == nil {
+		t.Error("Expected an error for an invalid schema")
+	}
+	if _, err := NewRegistry(Definition{MessageName: "order-created", Schema: `{}`}); err == nil {
+		t.Error("Expected an error for a schema without a version")
+	}
+}
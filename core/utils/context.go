@@ -7,6 +7,8 @@ import (
 var (
 	ContextKeyRequestID = NewContextKey("X-Request-Id")
 	ContextKeyUsername  = NewContextKey("username")
+
+	ContextKeyAcceptLanguage = NewContextKey("Accept-Language")
 )
 
 type ContextKey string
@@ -0,0 +1,51 @@
+package validation
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/enesanbar/go-service/core/config"
+)
+
+const (
+	LocalesKey     = "locales"
+	DefaultLocale  = "default-locale"
+	LocaleFallback = "en"
+)
+
+type Config struct {
+	Locales       []string
+	DefaultLocale string
+}
+
+// NewConfig reads the validation configuration under the 'validation' key.
+// When no locale is configured, only the english translator is loaded.
+func NewConfig(cfg config.Config) (*Config, error) {
+	prefix := "validation"
+	keyTemplate := "%s.%s"
+
+	locales := cfg.GetStringSlice(fmt.Sprintf(keyTemplate, prefix, LocalesKey))
+	if len(locales) == 0 {
+		locales = []string{LocaleFallback}
+	}
+
+	for _, locale := range locales {
+		if _, ok := supportedLocales[locale]; !ok {
+			return nil, fmt.Errorf("unsupported validation locale: '%s'", locale)
+		}
+	}
+
+	defaultLocale := cfg.GetString(fmt.Sprintf(keyTemplate, prefix, DefaultLocale))
+	if defaultLocale == "" {
+		defaultLocale = locales[0]
+	}
+
+	if !slices.Contains(locales, defaultLocale) {
+		return nil, fmt.Errorf("default validation locale '%s' is not one of the configured locales", defaultLocale)
+	}
+
+	return &Config{
+		Locales:       locales,
+		DefaultLocale: defaultLocale,
+	}, nil
+}
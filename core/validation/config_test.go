@@ -0,0 +1,41 @@
+package validation
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/enesanbar/go-service/core/config/configtest"
+)
+
+func TestNewConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		properties    map[string]any
+		locales       []string
+		defaultLocale string
+		fails         bool
+	}{
+		{"defaults", map[string]any{}, []string{"en"}, "en", false},
+		{"first locale is the default", map[string]any{"validation.locales": []string{"tr", "en"}}, []string{"tr", "en"}, "tr", false},
+		{"default locale", map[string]any{"validation.locales": []string{"tr", "en"}, "validation.default-locale": "en"}, []string{"tr", "en"}, "en", false},
+		{"default locale not in locales", map[string]any{"validation.locales": []string{"tr", "en"}, "validation.default-locale": "de"}, nil, "", true},
+		{"default locale without locales", map[string]any{"validation.default-locale": "tr"}, nil, "", true},
+		{"unsupported locale", map[string]any{"validation.locales": []string{"en", "xx"}}, nil, "", true},
+	}
+	for _, test := range tests {
+		cfg, err := NewConfig(configtest.New(t, test.properties))
+		if test.fails {
+			if err == nil {
+				t.Errorf("Expected an error for %s, got '%+v'", test.name, cfg)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Expected no error for %s, got '%v'", test.name, err)
+			continue
+		}
+		if !slices.Equal(cfg.Locales, test.locales) || cfg.DefaultLocale != test.defaultLocale {
+			t.Errorf("Expected locales of %s to be '%v' with default '%s', got '%v' with default '%s'", test.name, test.locales, test.defaultLocale, cfg.Locales, cfg.DefaultLocale)
+		}
+	}
+}
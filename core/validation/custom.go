@@ -2,8 +2,34 @@ package validation
 
 import "github.com/go-playground/validator/v10"
 
+// CustomValidation registers a validation tag along with its messages.
+// Exactly one of Func, FuncCtx or StructFuncCtx must be set. FuncCtx receives the context given to
+// Validator.ValidateCtx, which lets validators backed by injected dependencies,
+// e.g. a uniqueness check against a database, honor deadlines and cancellation.
+//
+// StructFuncCtx registers a struct level validation for Types instead of a field level one.
+// It is meant for rules spanning several fields, which report their errors with
+// validator.StructLevel.ReportError using Tag, so that they are translated with Messages.
+//
+// Messages are keyed by locale, e.g. {"en": "{0} is invalid", "tr": "{0} geçersiz"},
+// where {0} is the field name and {1} is the parameter of the tag.
+// Locales without a message use the message of the default locale, then the english one.
 type CustomValidation struct {
-	Tag      string
-	Func     validator.Func
-	Messages map[string]string
+	Tag            string
+	Func           validator.Func
+	FuncCtx        validator.FuncCtx
+	StructFuncCtx  validator.StructLevelFuncCtx
+	Types          []any
+	CallEvenIfNull bool
+	Messages       map[string]string
+}
+
+func (cv CustomValidation) message(locale string, defaultLocale string) string {
+	for _, l := range []string{locale, defaultLocale, LocaleFallback} {
+		if message, ok := cv.Messages[l]; ok {
+			return message
+		}
+	}
+
+	return ""
 }
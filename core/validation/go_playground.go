@@ -1,22 +1,25 @@
 package validation
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"strings"
 
 	"go.uber.org/fx"
 
-	"github.com/go-playground/locales/en"
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 
 	goplayground "github.com/go-playground/validator/v10"
-	translations "github.com/go-playground/validator/v10/translations/en"
+
+	"github.com/enesanbar/go-service/core/utils"
 )
 
 type goPlayground struct {
-	validator *goplayground.Validate
-	Translate ut.Translator
+	validator   *goplayground.Validate
+	Translate   ut.Translator
+	translators map[string]ut.Translator
 }
 
 func (g *goPlayground) GetValidator() *goplayground.Validate {
@@ -27,54 +30,146 @@ func (g *goPlayground) GetTranslator() ut.Translator {
 	return g.Translate
 }
 
+// GetTranslatorFor returns the translator matching the Accept-Language value
+// carried by ctx, falling back to the default locale.
+func (g *goPlayground) GetTranslatorFor(ctx context.Context) ut.Translator {
+	acceptLanguage, ok := utils.GetValueFromContext(ctx, utils.ContextKeyAcceptLanguage)
+	if !ok {
+		return g.Translate
+	}
+
+	locale, ok := matchLocale(acceptLanguage, g.translators)
+	if !ok {
+		return g.Translate
+	}
+
+	return g.translators[locale]
+}
+
 type Params struct {
 	fx.In
 
+	Config           *Config
 	CustomValidators []CustomValidation `group:"validators"`
 }
 
 // NewGoPlayground returns go-playground implementation of Validator interface
 func NewGoPlayground(p Params) (Validator, error) {
-	var (
-		language         = en.New()
-		uni              = ut.New(language, language)
-		translate, found = uni.GetTranslator("en")
-	)
-
-	if !found {
-		return nil, errors.New("translator not found")
+	defaultLocale := supportedLocales[p.Config.DefaultLocale].new()
+	uni := ut.New(defaultLocale, defaultLocale)
+	for _, name := range p.Config.Locales {
+		if err := uni.AddTranslator(supportedLocales[name].new(), true); err != nil {
+			return nil, fmt.Errorf("unable to add translator for locale '%s': %w", name, err)
+		}
 	}
 
 	v := goplayground.New()
-	if err := translations.RegisterDefaultTranslations(v, translate); err != nil {
-		return nil, errors.New("translator not found")
+	translators := make(map[string]ut.Translator, len(p.Config.Locales))
+	for _, name := range p.Config.Locales {
+		translate, found := uni.GetTranslator(name)
+		if !found {
+			return nil, fmt.Errorf("translator not found for locale '%s'", name)
+		}
+
+		if err := supportedLocales[name].register(v, translate); err != nil {
+			return nil, fmt.Errorf("unable to register translations for locale '%s': %w", name, err)
+		}
+		translators[name] = translate
 	}
 
 	for _, cv := range p.CustomValidators {
-		v.RegisterValidation(cv.Tag, cv.Func)
-		v.RegisterTranslation(cv.Tag, translate, func(ut ut.Translator) error {
-			return ut.Add(cv.Tag, cv.Messages["en"], true)
-		}, func(ut ut.Translator, fe validator.FieldError) string {
-			t, _ := ut.T(cv.Tag, fe.Field())
+		var err error
+		switch {
+		case countSet(cv.Func != nil, cv.FuncCtx != nil, cv.StructFuncCtx != nil) != 1:
+			err = errors.New("exactly one of Func, FuncCtx or StructFuncCtx must be set")
+		case cv.StructFuncCtx != nil && len(cv.Types) == 0:
+			err = errors.New("struct validation requires at least one type")
+		case cv.StructFuncCtx != nil:
+			v.RegisterStructValidationCtx(cv.StructFuncCtx, cv.Types...)
+		case cv.FuncCtx != nil:
+			err = v.RegisterValidationCtx(cv.Tag, cv.FuncCtx, cv.CallEvenIfNull)
+		default:
+			err = v.RegisterValidation(cv.Tag, cv.Func, cv.CallEvenIfNull)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to register custom validation '%s': %w", cv.Tag, err)
+		}
+
+		for name, translate := range translators {
+			err := registerTranslation(v, translate, cv.Tag, cv.message(name, p.Config.DefaultLocale))
+			if err != nil {
+				return nil, fmt.Errorf("unable to register translation of '%s' for locale '%s': %w", cv.Tag, name, err)
+			}
+		}
+	}
 
-			return t
-		})
+	return &goPlayground{
+		validator:   v,
+		Translate:   translators[p.Config.DefaultLocale],
+		translators: translators,
+	}, nil
+}
+
+// countSet returns the number of conditions that hold.
+func countSet(conditions ...bool) int {
+	count := 0
+	for _, condition := range conditions {
+		if condition {
+			count++
+		}
+	}
+	return count
+}
+
+// registerTranslation registers the message of a tag for a single translator.
+// Tags without a message keep the default message of the validator.
+func registerTranslation(v *goplayground.Validate, translate ut.Translator, tag string, message string) error {
+	if message == "" {
+		return nil
 	}
 
-	return &goPlayground{validator: v, Translate: translate}, nil
+	return v.RegisterTranslation(tag, translate, func(ut ut.Translator) error {
+		return ut.Add(tag, message, true)
+	}, func(ut ut.Translator, fe validator.FieldError) string {
+		t, _ := ut.T(tag, fe.Field(), fe.Param())
+
+		return t
+	})
 }
 
 func (g *goPlayground) Validate(i interface{}) error {
 	return g.validator.Struct(i)
 }
 
+// ValidateCtx validates the struct passing ctx down to the context-aware
+// validators. It is safe to call concurrently.
+func (g *goPlayground) ValidateCtx(ctx context.Context, i interface{}) error {
+	return g.validator.StructCtx(ctx, i)
+}
+
+// Messages translates the validation errors using the default locale.
 func (g *goPlayground) Messages(rawError error) []Error {
+	return g.translate(rawError, g.Translate)
+}
+
+// MessagesCtx translates the validation errors using the locale
+// requested by the Accept-Language value carried by ctx.
+func (g *goPlayground) MessagesCtx(ctx context.Context, rawError error) []Error {
+	return g.translate(rawError, g.GetTranslatorFor(ctx))
+}
+
+func (g *goPlayground) translate(rawError error, translate ut.Translator) []Error {
 	errs := make([]Error, 0)
-	validationErrors := rawError.(goplayground.ValidationErrors)
+
+	var validationErrors goplayground.ValidationErrors
+	if !errors.As(rawError, &validationErrors) {
+		return errs
+	}
+
 	for _, validationError := range validationErrors {
 		errs = append(errs, Error{
 			strings.ToLower(validationError.Field()),
-			validationError.Translate(g.Translate),
+			validationError.Translate(translate),
 		})
 	}
 
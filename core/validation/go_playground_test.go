@@ -0,0 +1,191 @@
+package validation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/fx"
+)
+
+type evenRequest struct {
+	Count int `validate:"even"`
+}
+
+func isEven(fl validator.FieldLevel) bool {
+	return fl.Field().Int()%2 == 0
+}
+
+func newTestValidator(t *testing.T, cfg *Config, validations ...CustomValidation) Validator {
+	t.Helper()
+
+	v, err := NewGoPlayground(Params{Config: cfg, CustomValidators: validations})
+	if err != nil {
+		t.Fatalf("unable to create validator: %v", err)
+	}
+	return v
+}
+
+func TestGoPlayground_MessagesFallBackToDefaultLocale(t *testing.T) {
+	v := newTestValidator(t, &Config{Locales: []string{"tr", "en", "de"}, DefaultLocale: "tr"}, CustomValidation{
+		Tag:  "even",
+		Func: isEven,
+		Messages: map[string]string{
+			"en": "{0} must be even",
+			"tr": "{0} çift olmalıdır",
+		},
+	})
+
+	err := v.Validate(evenRequest{Count: 1})
+	if err == nil {
+		t.Fatal("Expected a validation error")
+	}
+
+	tests := []struct {
+		acceptLanguage string
+		expected       string
+	}{
+		{"en-US", "Count must be even"},
+		{"tr", "Count çift olmalıdır"},
+		// de has no message, so the message of the default locale is used
+		{"de", "Count çift olmalıdır"},
+		// no match, so the default translator is used
+		{"fr", "Count çift olmalıdır"},
+		{"", "Count çift olmalıdır"},
+	}
+	for _, test := range tests {
+		ctx := WithAcceptLanguage(context.Background(), test.acceptLanguage)
+		messages := v.MessagesCtx(ctx, err)
+		if len(messages) != 1 || messages[0].Error != test.expected {
+			t.Errorf("Expected message for '%s' to be '%s', got '%v'", test.acceptLanguage, test.expected, messages)
+		}
+	}
+
+	if messages := v.Messages(err); len(messages) != 1 || messages[0].Field != "count" || messages[0].Error != "Count çift olmalıdır" {
+		t.Errorf("Expected the message of the default locale, got '%v'", messages)
+	}
+}
+
+func TestGoPlayground_MessagesFallBackToEnglish(t *testing.T) {
+	v := newTestValidator(t, &Config{Locales: []string{"tr", "de"}, DefaultLocale: "tr"}, CustomValidation{
+		Tag:      "even",
+		Func:     isEven,
+		Messages: map[string]string{"en": "{0} must be even"},
+	})
+
+	err := v.Validate(evenRequest{Count: 1})
+	messages := v.MessagesCtx(WithAcceptLanguage(context.Background(), "de"), err)
+	if len(messages) != 1 || messages[0].Error != "Count must be even" {
+		t.Errorf("Expected the english message, got '%v'", messages)
+	}
+}
+
+type tenantKey struct{}
+
+// emailRegistry is a dependency of the unique email validation.
+type emailRegistry struct {
+	tenants map[string][]string
+}
+
+func (r *emailRegistry) Exists(ctx context.Context, email string) bool {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	for _, registered := range r.tenants[tenant] {
+		if registered == email {
+			return true
+		}
+	}
+	return false
+}
+
+func newUniqueEmailValidation(registry *emailRegistry) CustomValidation {
+	return CustomValidation{
+		Tag: "unique_email",
+		FuncCtx: func(ctx context.Context, fl validator.FieldLevel) bool {
+			return ctx.Err() == nil && !registry.Exists(ctx, fl.Field().String())
+		},
+		Messages: map[string]string{"en": "{0} is already registered"},
+	}
+}
+
+type signupRequest struct {
+	Email    string `validate:"unique_email"`
+	Password string
+	Confirm  string
+}
+
+func newPasswordsMatchValidation() CustomValidation {
+	return CustomValidation{
+		Tag:   "passwords_match",
+		Types: []any{signupRequest{}},
+		StructFuncCtx: func(ctx context.Context, sl validator.StructLevel) {
+			r := sl.Current().Interface().(signupRequest)
+			if _, ok := ctx.Value(tenantKey{}).(string); !ok || r.Password != r.Confirm {
+				sl.ReportError(r.Confirm, "Confirm", "Confirm", "passwords_match", "Password")
+			}
+		},
+		Messages: map[string]string{"en": "{0} must match {1}"},
+	}
+}
+
+func TestGoPlayground_ValidatorsWithDependencies(t *testing.T) {
+	var v Validator
+	app := fx.New(
+		fx.NopLogger,
+		fx.Supply(
+			&Config{Locales: []string{"en"}, DefaultLocale: "en"},
+			&emailRegistry{tenants: map[string][]string{"acme": {"taken@acme.com"}}},
+		),
+		fx.Provide(
+			AsValidator(newUniqueEmailValidation),
+			AsValidator(newPasswordsMatchValidation),
+			fx.Annotated{Name: "go_playground", Target: NewGoPlayground},
+		),
+		fx.Invoke(fx.Annotate(func(validator Validator) { v = validator }, fx.ParamTags(`name:"go_playground"`))),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	acme := context.WithValue(context.Background(), tenantKey{}, "acme")
+	if err := v.ValidateCtx(acme, signupRequest{Email: "new@acme.com", Password: "secret", Confirm: "secret"}); err != nil {
+		t.Errorf("Expected no error, got '%v'", err)
+	}
+
+	// the context reaches the field level validator
+	err := v.ValidateCtx(acme, signupRequest{Email: "taken@acme.com", Password: "secret", Confirm: "secret"})
+	if messages := v.Messages(err); len(messages) != 1 || messages[0].Error != "Email is already registered" {
+		t.Errorf("Expected the email to be taken in tenant '%s', got '%v'", "acme", messages)
+	}
+	other := context.WithValue(context.Background(), tenantKey{}, "globex")
+	if err := v.ValidateCtx(other, signupRequest{Email: "taken@acme.com", Password: "secret", Confirm: "secret"}); err != nil {
+		t.Errorf("Expected the email to be free in tenant '%s', got '%v'", "globex", err)
+	}
+
+	// the context reaches the struct level validator
+	err = v.ValidateCtx(acme, signupRequest{Email: "new@acme.com", Password: "secret", Confirm: "typo"})
+	if messages := v.Messages(err); len(messages) != 1 || messages[0].Error != "Confirm must match Password" {
+		t.Errorf("Expected the passwords not to match, got '%v'", messages)
+	}
+	if err := v.ValidateCtx(context.Background(), signupRequest{Email: "new@acme.com", Password: "secret", Confirm: "secret"}); err == nil {
+		t.Error("Expected the struct level validator to fail without a tenant in the context")
+	}
+
+	canceled, cancel := context.WithCancel(acme)
+	cancel()
+	if err := v.ValidateCtx(canceled, signupRequest{Email: "new@acme.com", Password: "secret", Confirm: "secret"}); err == nil {
+		t.Error("Expected the field level validator to fail once the context is canceled")
+	}
+}
+
+func TestNewGoPlayground_InvalidValidations(t *testing.T) {
+	for name, cv := range map[string]CustomValidation{
+		"no function":         {Tag: "even"},
+		"several functions":   {Tag: "even", Func: isEven, FuncCtx: func(context.Context, validator.FieldLevel) bool { return true }},
+		"struct without type": {Tag: "even", StructFuncCtx: func(context.Context, validator.StructLevel) {}},
+	} {
+		_, err := NewGoPlayground(Params{Config: &Config{Locales: []string{"en"}, DefaultLocale: "en"}, CustomValidators: []CustomValidation{cv}})
+		if err == nil {
+			t.Errorf("Expected an error for %s, got '%v'", name, err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package validation
+
+import (
+	"context"
+	"strings"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/ar"
+	"github.com/go-playground/locales/de"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	"github.com/go-playground/locales/fr"
+	"github.com/go-playground/locales/it"
+	"github.com/go-playground/locales/ja"
+	"github.com/go-playground/locales/nl"
+	"github.com/go-playground/locales/pt"
+	"github.com/go-playground/locales/pt_BR"
+	"github.com/go-playground/locales/ru"
+	"github.com/go-playground/locales/tr"
+	"github.com/go-playground/locales/zh"
+	ut "github.com/go-playground/universal-translator"
+	goplayground "github.com/go-playground/validator/v10"
+	arTranslations "github.com/go-playground/validator/v10/translations/ar"
+	deTranslations "github.com/go-playground/validator/v10/translations/de"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+	esTranslations "github.com/go-playground/validator/v10/translations/es"
+	frTranslations "github.com/go-playground/validator/v10/translations/fr"
+	itTranslations "github.com/go-playground/validator/v10/translations/it"
+	jaTranslations "github.com/go-playground/validator/v10/translations/ja"
+	nlTranslations "github.com/go-playground/validator/v10/translations/nl"
+	ptTranslations "github.com/go-playground/validator/v10/translations/pt"
+	ptBRTranslations "github.com/go-playground/validator/v10/translations/pt_BR"
+	ruTranslations "github.com/go-playground/validator/v10/translations/ru"
+	trTranslations "github.com/go-playground/validator/v10/translations/tr"
+	zhTranslations "github.com/go-playground/validator/v10/translations/zh"
+	"golang.org/x/text/language"
+
+	"github.com/enesanbar/go-service/core/utils"
+)
+
+// locale couples a go-playground locale with the default validator
+// translations that are available for it.
+type locale struct {
+	new      func() locales.Translator
+	register func(v *goplayground.Validate, trans ut.Translator) error
+}
+
+// supportedLocales are the locales that can be listed under 'validation.locales'.
+var supportedLocales = map[string]locale{
+	"ar":    {ar.New, arTranslations.RegisterDefaultTranslations},
+	"de":    {de.New, deTranslations.RegisterDefaultTranslations},
+	"en":    {en.New, enTranslations.RegisterDefaultTranslations},
+	"es":    {es.New, esTranslations.RegisterDefaultTranslations},
+	"fr":    {fr.New, frTranslations.RegisterDefaultTranslations},
+	"it":    {it.New, itTranslations.RegisterDefaultTranslations},
+	"ja":    {ja.New, jaTranslations.RegisterDefaultTranslations},
+	"nl":    {nl.New, nlTranslations.RegisterDefaultTranslations},
+	"pt":    {pt.New, ptTranslations.RegisterDefaultTranslations},
+	"pt_BR": {pt_BR.New, ptBRTranslations.RegisterDefaultTranslations},
+	"ru":    {ru.New, ruTranslations.RegisterDefaultTranslations},
+	"tr":    {tr.New, trTranslations.RegisterDefaultTranslations},
+	"zh":    {zh.New, zhTranslations.RegisterDefaultTranslations},
+}
+
+// WithAcceptLanguage returns a copy of ctx carrying the raw value of an
+// Accept-Language header, used to pick the translator of validation messages.
+func WithAcceptLanguage(ctx context.Context, acceptLanguage string) context.Context {
+	if acceptLanguage == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, utils.ContextKeyAcceptLanguage, acceptLanguage)
+}
+
+// matchLocale returns the first locale in 'available' that satisfies the
+// preferences of the Accept-Language value, or false if none does.
+// A regional preference such as 'pt-PT' falls back to its base language 'pt'.
+func matchLocale(acceptLanguage string, available map[string]ut.Translator) (string, bool) {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil {
+		return "", false
+	}
+
+	normalized := make(map[string]string, len(available))
+	for name := range available {
+		normalized[strings.ToLower(name)] = name
+	}
+
+	for _, tag := range tags {
+		candidate := strings.ToLower(strings.ReplaceAll(tag.String(), "-", "_"))
+		if name, ok := normalized[candidate]; ok {
+			return name, true
+		}
+
+		base, _ := tag.Base()
+		if name, ok := normalized[base.String()]; ok {
+			return name, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,33 @@
+package validation
+
+import (
+	"testing"
+
+	ut "github.com/go-playground/universal-translator"
+)
+
+func TestMatchLocale(t *testing.T) {
+	available := map[string]ut.Translator{"en": nil, "tr": nil, "pt_BR": nil}
+
+	tests := []struct {
+		acceptLanguage string
+		expected       string
+		found          bool
+	}{
+		{"tr", "tr", true},
+		{"tr-TR", "tr", true},
+		{"de, tr;q=0.8, en;q=0.5", "tr", true},
+		{"en;q=0.5, tr;q=0.8", "tr", true},
+		{"pt-BR", "pt_BR", true},
+		{"pt-PT", "", false},
+		{"de", "", false},
+		{"*", "", false},
+		{"not a locale;;", "", false},
+	}
+	for _, test := range tests {
+		locale, found := matchLocale(test.acceptLanguage, available)
+		if locale != test.expected || found != test.found {
+			t.Errorf("Expected locale of '%s' to be '%s' (%t), got '%s' (%t)", test.acceptLanguage, test.expected, test.found, locale, found)
+		}
+	}
+}
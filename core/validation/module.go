@@ -11,6 +11,7 @@ var Module = fx.Options(
 
 var factories = fx.Provide(
 	validator.New,
+	NewConfig,
 	fx.Annotated{
 		Name:   "go_playground",
 		Target: NewGoPlayground,
@@ -0,0 +1,14 @@
+package validation
+
+import "go.uber.org/fx"
+
+// AsValidator is a function that provides the constructor function to
+// the fx container under the group "validators".
+// The provided function must return a [CustomValidation], either field or struct level,
+// and may depend on any other type in the container.
+func AsValidator(p any) any {
+	return fx.Annotate(
+		p,
+		fx.ResultTags(`group:"validators"`),
+	)
+}
@@ -1,6 +1,8 @@
 package validation
 
 import (
+	"context"
+
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 	goplayground "github.com/go-playground/validator/v10"
@@ -8,9 +10,12 @@ import (
 
 type Validator interface {
 	Validate(interface{}) error
+	ValidateCtx(ctx context.Context, i interface{}) error
 	Messages(err error) []Error
+	MessagesCtx(ctx context.Context, err error) []Error
 	Register(tag string, fn validator.Func)
 	GetTranslator() ut.Translator
+	GetTranslatorFor(ctx context.Context) ut.Translator
 	GetValidator() *goplayground.Validate
 }
 
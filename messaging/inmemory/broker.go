@@ -0,0 +1,483 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/enesanbar/go-service/core/info"
+	"github.com/enesanbar/go-service/core/log"
+	"github.com/enesanbar/go-service/core/messaging/codec"
+	"github.com/enesanbar/go-service/core/messaging/consumer"
+	"github.com/enesanbar/go-service/core/messaging/messages"
+	"github.com/enesanbar/go-service/core/messaging/producer"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// ErrStopped is returned when a message is published after the broker is stopped,
+// or when the broker is stopped before the messages are handled.
+var ErrStopped = errors.New("in-memory broker is stopped")
+
+// Publication is a message published to the broker.
+type Publication struct {
+	Metadata    messages.Metadata
+	Data        []byte
+	ContentType string
+	Options     producer.PublishOptions
+	codec       codec.Codec
+}
+
+// Decode decodes the payload of the message into the target, e.g. to assert what is published.
+func (p Publication) Decode(target any) error {
+	return p.codec.Unmarshal(p.Data, target)
+}
+
+// Delivery is a message delivered to a queue, i.e. to the handler of the queue and the message.
+type Delivery struct {
+	Publication
+	Queue string
+	// Handled reports whether the handler is called, or the delivery is failed by FailHandling.
+	Handled bool
+	// Err is the error of the handler.
+	Err error
+}
+
+// Broker is a message broker in the memory of the process, for the tests and the local development.
+// It publishes the messages to every queue that has a handler of their names, as if the queues were bound
+// with the message names as the routing keys, and records them to be inspected.
+type Broker struct {
+	logger     log.Factory
+	Config     *Config
+	Propagator propagation.TextMapPropagator
+	Codecs     *codec.Registry
+
+	handlers map[string]consumer.MessageHandler
+	// routes are the queues of the message names
+	routes map[string][]string
+	queues map[string]*queue
+
+	mu              sync.Mutex
+	published       []Publication
+	deliveries      []*Delivery
+	publishFailures map[string]error
+	handleFailures  map[string]error
+	pending         int
+	drained         chan struct{}
+	started         bool
+	stopped         bool
+	stop            chan struct{}
+	workers         sync.WaitGroup
+}
+
+type BrokerParams struct {
+	fx.In
+
+	Logger     log.Factory
+	Config     *Config
+	Propagator propagation.TextMapPropagator
+	Handlers   consumer.HandlersParams
+	// Codecs encode the payloads by their content types, the built-in codecs by default.
+	Codecs *codec.Registry `optional:"true"`
+}
+
+// NewBroker creates a pointer to the new instance of the Broker
+func NewBroker(p BrokerParams) (*Broker, error) {
+	handlers, err := consumer.MapHandlers(p.Handlers)
+	if err != nil {
+		return nil, err
+	}
+
+	codecs := p.Codecs
+	if codecs == nil {
+		codecs = codec.NewRegistry()
+	}
+
+	b := &Broker{
+		logger:          p.Logger,
+		Config:          p.Config,
+		Propagator:      p.Propagator,
+		Codecs:          codecs,
+		handlers:        handlers,
+		routes:          make(map[string][]string),
+		queues:          make(map[string]*queue),
+		publishFailures: make(map[string]error),
+		handleFailures:  make(map[string]error),
+		stop:            make(chan struct{}),
+	}
+	for _, handler := range p.Handlers.Handlers {
+		properties := handler.Properties()
+		if _, ok := b.queues[properties.QueueName]; !ok {
+			b.queues[properties.QueueName] = newQueue()
+		}
+		// the handlers of the versions of a message share the route
+		if !slices.Contains(b.routes[properties.MessageName], properties.QueueName) {
+			b.routes[properties.MessageName] = append(b.routes[properties.MessageName], properties.QueueName)
+		}
+	}
+	return b, nil
+}
+
+// Publish publishes the message to the queues of its name.
+// In the synchronous mode, it returns once the message is handled, with the errors of the handlers.
+func (b *Broker) Publish(ctx context.Context, messageName string, message any, options ...producer.PublishOption) error {
+	return b.PublishBatch(ctx, []producer.Publishing{{
+		MessageName: messageName,
+		Message:     message,
+		Options:     options,
+	}})
+}
+
+// PublishBatch publishes the messages in order, stopping at the first message that cannot be published.
+func (b *Broker) PublishBatch(ctx context.Context, publishings []producer.Publishing) error {
+	var errs []error
+	for _, publishing := range publishings {
+		deliveries, err := b.publish(ctx, publishing)
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+
+		if b.Config.Synchronous {
+			for _, d := range deliveries {
+				if err := b.deliver(messages.WithMetadata(ctx, d.Metadata), d); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// publish records the message and its deliveries, which are queued unless the broker is synchronous.
+func (b *Broker) publish(ctx context.Context, publishing producer.Publishing) ([]*Delivery, error) {
+	options := producer.NewPublishOptions(publishing.Options...)
+
+	c, err := b.Codecs.Get(options.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.Marshal(publishing.Message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message %s: %w", publishing.MessageName, err)
+	}
+
+	messageID := options.MessageID
+	if messageID == "" {
+		messageID = uuid.NewString()
+	}
+	metadata := messages.Metadata{
+		MessageID:      messageID,
+		CorrelationID:  options.CorrelationID,
+		CausationID:    options.CausationID,
+		PublisherName:  info.ServiceName,
+		PublishDate:    time.Now().UTC(),
+		MessageName:    publishing.MessageName,
+		MessageVersion: options.MessageVersion,
+	}
+	metadata.Correlate(ctx)
+	b.enrichMetadataWithTrace(ctx, &metadata)
+
+	publication := Publication{
+		Metadata:    metadata,
+		Data:        data,
+		ContentType: c.ContentType(),
+		Options:     options,
+		codec:       c,
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stopped {
+		return nil, ErrStopped
+	}
+	if err := b.publishFailure(publishing.MessageName); err != nil {
+		return nil, fmt.Errorf("failed to publish message %s: %w", publishing.MessageName, err)
+	}
+
+	b.published = append(b.published, publication)
+	deliveries := make([]*Delivery, 0, len(b.routes[publishing.MessageName]))
+	for _, queueName := range b.routes[publishing.MessageName] {
+		d := &Delivery{Publication: publication, Queue: queueName}
+		b.deliveries = append(b.deliveries, d)
+		deliveries = append(deliveries, d)
+
+		if !b.Config.Synchronous {
+			b.addPending()
+			b.queues[queueName].push(d)
+		}
+	}
+	return deliveries, nil
+}
+
+func (b *Broker) enrichMetadataWithTrace(ctx context.Context, metadata *messages.Metadata) {
+	span := trace.SpanFromContext(ctx)
+	if !span.SpanContext().IsValid() {
+		return
+	}
+
+	carrier := propagation.MapCarrier{}
+	b.Propagator.Inject(ctx, carrier)
+	metadata.Traceparent = carrier["traceparent"]
+	metadata.Tracestate = carrier["tracestate"]
+	metadata.SpanID = span.SpanContext().SpanID().String()
+}
+
+// deliver passes the delivery to the handler of its queue and message, and records the result.
+func (b *Broker) deliver(ctx context.Context, d *Delivery) error {
+	key := consumer.HandlerKey(d.Queue, d.Metadata.MessageName)
+
+	b.mu.Lock()
+	err, failed := b.handleFailures[key]
+	b.mu.Unlock()
+
+	if !failed {
+		err = consumer.Dispatch(ctx, b.handlers[key], d.Metadata, consumer.Payload{Data: d.Data, Codec: d.codec})
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to handle message %s of queue %s: %w", d.Metadata.MessageName, d.Queue, err)
+		b.logger.For(ctx).
+			With(zap.String("queue", d.Queue)).
+			With(zap.String("message", d.Metadata.MessageName)).
+			With(zap.Error(err)).
+			Error("failed to handle message")
+	}
+
+	b.mu.Lock()
+	d.Handled = true
+	d.Err = err
+	b.mu.Unlock()
+	return err
+}
+
+// Start handles the messages of every queue in the background, unless the broker is synchronous.
+func (b *Broker) Start(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.Config.Synchronous || b.started {
+		return nil
+	}
+	b.started = true
+
+	for name, q := range b.queues {
+		b.workers.Add(1)
+		go b.consume(name, q)
+	}
+	b.logger.Bg().Info("in-memory broker started")
+	return nil
+}
+
+// consume handles the messages of the queue one at a time, until the broker stops.
+func (b *Broker) consume(name string, q *queue) {
+	defer b.workers.Done()
+
+	for {
+		d, ok := q.pop()
+		if !ok {
+			select {
+			case <-q.signal:
+				continue
+			case <-b.stop:
+				return
+			}
+		}
+
+		// the trace of the publisher is continued, as by the consumers of the brokers
+		carrier := propagation.MapCarrier{
+			"traceparent": d.Metadata.Traceparent,
+			"tracestate":  d.Metadata.Tracestate,
+		}
+		ctx := b.Propagator.Extract(context.Background(), carrier)
+		_ = b.deliver(messages.WithMetadata(ctx, d.Metadata), d)
+
+		b.mu.Lock()
+		b.donePending()
+		b.mu.Unlock()
+	}
+}
+
+// Stop stops the broker after the messages being handled, the messages left in the queues are not handled.
+func (b *Broker) Stop(ctx context.Context) error {
+	b.mu.Lock()
+	if b.stopped {
+		b.mu.Unlock()
+		return nil
+	}
+	b.stopped = true
+	close(b.stop)
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		b.logger.Bg().Info("in-memory broker stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("in-memory broker could not be stopped (%w)", ctx.Err())
+	}
+}
+
+// Wait waits until the messages published so far are handled, in the asynchronous mode.
+// It returns ErrStopped once the broker is stopped, as the messages left in the queues are not handled.
+func (b *Broker) Wait(ctx context.Context) error {
+	b.mu.Lock()
+	if b.pending == 0 {
+		b.mu.Unlock()
+		return nil
+	}
+	if b.stopped {
+		b.mu.Unlock()
+		return ErrStopped
+	}
+	drained := b.drained
+	b.mu.Unlock()
+
+	select {
+	case <-drained:
+		return nil
+	case <-b.stop:
+		return b.Wait(ctx)
+	case <-ctx.Done():
+		return fmt.Errorf("messages are not handled (%w)", ctx.Err())
+	}
+}
+
+// addPending and donePending count the queued deliveries, they must be called with mu held.
+func (b *Broker) addPending() {
+	if b.pending == 0 {
+		b.drained = make(chan struct{})
+	}
+	b.pending++
+}
+
+func (b *Broker) donePending() {
+	b.pending--
+	if b.pending == 0 {
+		close(b.drained)
+	}
+}
+
+// Published returns the messages published, in order, or the messages of the names if any.
+func (b *Broker) Published(messageNames ...string) []Publication {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	result := make([]Publication, 0, len(b.published))
+	for _, p := range b.published {
+		if matches(p.Metadata.MessageName, messageNames) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// Deliveries returns the messages delivered to the queue, in order, or to any queue if it is empty.
+func (b *Broker) Deliveries(queueName string) []Delivery {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	result := make([]Delivery, 0, len(b.deliveries))
+	for _, d := range b.deliveries {
+		if queueName == "" || d.Queue == queueName {
+			result = append(result, *d)
+		}
+	}
+	return result
+}
+
+// Failed returns the deliveries whose handlers failed.
+func (b *Broker) Failed() []Delivery {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var result []Delivery
+	for _, d := range b.deliveries {
+		if d.Err != nil {
+			result = append(result, *d)
+		}
+	}
+	return result
+}
+
+// FailPublish makes the messages of the name fail to be published with the error, or all messages if the name is empty.
+func (b *Broker) FailPublish(messageName string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.publishFailures[messageName] = err
+}
+
+// FailHandling makes the deliveries of the message to the queue fail with the error, without calling their handler.
+func (b *Broker) FailHandling(queueName, messageName string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handleFailures[consumer.HandlerKey(queueName, messageName)] = err
+}
+
+// Reset forgets the messages published and the failures, the messages being handled are handled nevertheless.
+func (b *Broker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.published = nil
+	b.deliveries = nil
+	b.publishFailures = make(map[string]error)
+	b.handleFailures = make(map[string]error)
+}
+
+// publishFailure returns the error injected for the message, it must be called with mu held.
+func (b *Broker) publishFailure(messageName string) error {
+	if err, ok := b.publishFailures[messageName]; ok {
+		return err
+	}
+	return b.publishFailures[""]
+}
+
+func matches(messageName string, messageNames []string) bool {
+	return len(messageNames) == 0 || slices.Contains(messageNames, messageName)
+}
+
+// queue holds the deliveries of a queue until they are handled.
+type queue struct {
+	mu         sync.Mutex
+	deliveries []*Delivery
+	signal     chan struct{}
+}
+
+func newQueue() *queue {
+	return &queue{signal: make(chan struct{}, 1)}
+}
+
+func (q *queue) push(d *Delivery) {
+	q.mu.Lock()
+	q.deliveries = append(q.deliveries, d)
+	q.mu.Unlock()
+
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (q *queue) pop() (*Delivery, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.deliveries) == 0 {
+		return nil, false
+	}
+	d := q.deliveries[0]
+	q.deliveries = q.deliveries[1:]
+	return d, true
+}
@@ -0,0 +1,246 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/enesanbar/go-service/core/config"
+	"github.com/enesanbar/go-service/core/config/configtest"
+	"github.com/enesanbar/go-service/core/log"
+	"github.com/enesanbar/go-service/core/messaging/consumer"
+	"github.com/enesanbar/go-service/core/messaging/messages"
+	"github.com/enesanbar/go-service/core/messaging/producer"
+	"go.opentelemetry.io/otel/propagation"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type orderCreated struct {
+	ID int `json:"id"`
+}
+
+// orderHandler records the orders of its queue, and publishes order-shipped if a producer is given.
+type orderHandler struct {
+	queue    string
+	producer producer.Producer
+	err      error
+
+	mu       sync.Mutex
+	handled  []int
+	metadata []messages.Metadata
+}
+
+func (h *orderHandler) Handle(ctx context.Context, message messages.Message[orderCreated]) error {
+	if h.err != nil {
+		return h.err
+	}
+
+	h.mu.Lock()
+	h.handled = append(h.handled, message.Payload.ID)
+	h.metadata = append(h.metadata, message.Metadata)
+	h.mu.Unlock()
+
+	if h.producer != nil {
+		return h.producer.Publish(ctx, "order-shipped", message.Payload)
+	}
+	return nil
+}
+
+func (h *orderHandler) Properties() consumer.MessageProperties {
+	return consumer.MessageProperties{QueueName: h.queue, MessageName: "order-created"}
+}
+
+func (h *orderHandler) handledOrders() []int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]int{}, h.handled...)
+}
+
+func newTestBroker(t *testing.T, synchronous bool, handlers ...*orderHandler) *Broker {
+	t.Helper()
+
+	messageHandlers := make([]consumer.MessageHandler, 0, len(handlers))
+	for _, h := range handlers {
+		messageHandlers = append(messageHandlers, consumer.NewMessageHandler[orderCreated](h))
+	}
+	b, err := NewBroker(BrokerParams{
+		Logger:     log.NewFactory(zap.NewNop()),
+		Config:     &Config{Synchronous: synchronous},
+		Propagator: propagation.TraceContext{},
+		Handlers:   consumer.HandlersParams{Handlers: messageHandlers},
+	})
+	if err != nil {
+		t.Fatalf("unable to create broker: %v", err)
+	}
+	return b
+}
+
+func TestBroker_Synchronous(t *testing.T) {
+	shipping := &orderHandler{queue: "shipping"}
+	billing := &orderHandler{queue: "billing"}
+	b := newTestBroker(t, true, shipping, billing)
+	shipping.producer = b
+
+	err := b.Publish(context.Background(), "order-created", orderCreated{ID: 1}, producer.WithMessageID("order-1"))
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	if handled := shipping.handledOrders(); len(handled) != 1 || handled[0] != 1 {
+		t.Errorf("Expected the order to be handled by shipping, got '%v'", handled)
+	}
+	if handled := billing.handledOrders(); len(handled) != 1 || handled[0] != 1 {
+		t.Errorf("Expected the order to be handled by billing, got '%v'", handled)
+	}
+	if deliveries := b.Deliveries("shipping"); len(deliveries) != 1 || !deliveries[0].Handled {
+		t.Errorf("Expected '%d' handled delivery to shipping, got '%+v'", 1, deliveries)
+	}
+
+	shipped := b.Published("order-shipped")
+	if len(shipped) != 1 {
+		t.Fatalf("Expected '%d' order-shipped message, got '%d'", 1, len(shipped))
+	}
+	if shipped[0].Metadata.CausationID != "order-1" || shipped[0].Metadata.CorrelationID != "order-1" {
+		t.Errorf("Expected order-shipped to be caused by '%s', got '%+v'", "order-1", shipped[0].Metadata)
+	}
+	var order orderCreated
+	if err := shipped[0].Decode(&order); err != nil || order.ID != 1 {
+		t.Errorf("Expected the payload to decode to order '%d', got '%d' (%v)", 1, order.ID, err)
+	}
+}
+
+func TestBroker_SynchronousReturnsHandlerErrors(t *testing.T) {
+	errOutOfStock := errors.New("out of stock")
+	b := newTestBroker(t, true, &orderHandler{queue: "shipping", err: errOutOfStock})
+
+	err := b.Publish(context.Background(), "order-created", orderCreated{ID: 1})
+	if !errors.Is(err, errOutOfStock) {
+		t.Errorf("Expected error to be '%v', got '%v'", errOutOfStock, err)
+	}
+	if failed := b.Failed(); len(failed) != 1 || failed[0].Queue != "shipping" {
+		t.Errorf("Expected the delivery to shipping to fail, got '%+v'", failed)
+	}
+}
+
+func TestBroker_Asynchronous(t *testing.T) {
+	shipping := &orderHandler{queue: "shipping"}
+	b := newTestBroker(t, false, shipping)
+
+	ctx := context.Background()
+	if err := b.Start(ctx); err != nil {
+		t.Fatalf("unable to start broker: %v", err)
+	}
+	for id := 1; id <= 10; id++ {
+		if err := b.Publish(ctx, "order-created", orderCreated{ID: id}); err != nil {
+			t.Fatalf("Expected no error, got '%v'", err)
+		}
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := b.Wait(waitCtx); err != nil {
+		t.Fatalf("Expected the messages to be handled, got '%v'", err)
+	}
+	for i, id := range shipping.handledOrders() {
+		if id != i+1 {
+			t.Fatalf("Expected the orders to be handled in order, got '%v'", shipping.handledOrders())
+		}
+	}
+
+	if err := b.Stop(ctx); err != nil {
+		t.Errorf("Expected broker to stop, got '%v'", err)
+	}
+	if err := b.Publish(ctx, "order-created", orderCreated{ID: 11}); !errors.Is(err, ErrStopped) {
+		t.Errorf("Expected error to be '%v', got '%v'", ErrStopped, err)
+	}
+}
+
+func TestBroker_WaitReturnsOnceStopped(t *testing.T) {
+	b := newTestBroker(t, false, &orderHandler{queue: "shipping"})
+
+	ctx := context.Background()
+	if err := b.Publish(ctx, "order-created", orderCreated{ID: 1}); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	waited := make(chan error, 1)
+	go func() {
+		waited <- b.Wait(waitCtx)
+	}()
+
+	if err := b.Stop(ctx); err != nil {
+		t.Fatalf("Expected broker to stop, got '%v'", err)
+	}
+	if err := <-waited; !errors.Is(err, ErrStopped) {
+		t.Errorf("Expected error to be '%v', got '%v'", ErrStopped, err)
+	}
+	if err := b.Wait(waitCtx); !errors.Is(err, ErrStopped) {
+		t.Errorf("Expected error to be '%v', got '%v'", ErrStopped, err)
+	}
+}
+
+func TestBroker_FailureInjection(t *testing.T) {
+	shipping := &orderHandler{queue: "shipping"}
+	b := newTestBroker(t, true, shipping)
+	ctx := context.Background()
+
+	errUnavailable := errors.New("broker unavailable")
+	b.FailPublish("order-created", errUnavailable)
+	if err := b.Publish(ctx, "order-created", orderCreated{ID: 1}); !errors.Is(err, errUnavailable) {
+		t.Errorf("Expected error to be '%v', got '%v'", errUnavailable, err)
+	}
+	if published := b.Published(); len(published) != 0 {
+		t.Errorf("Expected the failed message not to be published, got '%d'", len(published))
+	}
+
+	b.Reset()
+	errTimeout := errors.New("timeout")
+	b.FailHandling("shipping", "order-created", errTimeout)
+	if err := b.Publish(ctx, "order-created", orderCreated{ID: 2}); !errors.Is(err, errTimeout) {
+		t.Errorf("Expected error to be '%v', got '%v'", errTimeout, err)
+	}
+	if handled := shipping.handledOrders(); len(handled) != 0 {
+		t.Errorf("Expected the handler not to be called, got '%v'", handled)
+	}
+
+	b.Reset()
+	if err := b.Publish(ctx, "order-created", orderCreated{ID: 3}); err != nil {
+		t.Errorf("Expected no error after reset, got '%v'", err)
+	}
+	if deliveries := b.Deliveries(""); len(deliveries) != 1 {
+		t.Errorf("Expected '%d' delivery after reset, got '%d'", 1, len(deliveries))
+	}
+}
+
+func TestModule(t *testing.T) {
+	conf := configtest.New(t, map[string]any{"messaging.inmemory.synchronous": true})
+
+	shipping := &orderHandler{queue: "shipping"}
+	var p producer.Producer
+	app := fx.New(
+		fx.NopLogger,
+		Module,
+		fx.Supply(
+			fx.Annotate(conf, fx.As(new(config.Config))),
+			log.NewFactory(zap.NewNop()),
+			fx.Annotate(propagation.TraceContext{}, fx.As(new(propagation.TextMapPropagator))),
+		),
+		fx.Provide(consumer.AsMessageHandler(func() consumer.Handler[orderCreated] { return shipping })),
+		fx.Populate(&p),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	if err := p.Publish(context.Background(), "order-created", orderCreated{ID: 1}); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if handled := shipping.handledOrders(); len(handled) != 1 {
+		t.Errorf("Expected the order to be handled, got '%v'", handled)
+	}
+}
@@ -0,0 +1,21 @@
+package inmemory
+
+import (
+	"github.com/enesanbar/go-service/core/config"
+)
+
+const (
+	PropertySynchronous = "synchronous"
+)
+
+type Config struct {
+	// Synchronous handles the messages while they are published, Publish returns the errors of their handlers.
+	// Otherwise, the messages are handled in the background, in the order they are published to every queue.
+	Synchronous bool
+}
+
+func NewConfig(cfg config.Config) (*Config, error) {
+	return &Config{
+		Synchronous: cfg.GetBool("messaging.inmemory." + PropertySynchronous),
+	}, nil
+}
@@ -0,0 +1,31 @@
+package inmemory
+
+import (
+	"github.com/enesanbar/go-service/core/messaging/producer"
+	"github.com/enesanbar/go-service/core/service"
+	"github.com/enesanbar/go-service/core/wiring"
+	"go.uber.org/fx"
+)
+
+// Module provides the *Broker, as producer.Producer and producer.BatchProducer,
+// and handles the messages with the consumer.MessageHandlers, in place of the modules of the brokers.
+var Module = fx.Module(
+	"messaging.inmemory",
+	fx.Provide(
+		NewConfig,
+		NewBroker,
+		func(b *Broker) producer.Producer { return b },
+		func(b *Broker) producer.BatchProducer { return b },
+		fx.Annotate(
+			func(b *Broker) wiring.Runnable { return b },
+			fx.ResultTags(`group:"runnables"`),
+		),
+	),
+)
+
+func Option(options ...fx.Option) service.Option {
+	return func(cfg *service.AppConfig) {
+		cfg.Options = append(cfg.Options, Module)
+		cfg.Options = append(cfg.Options, options...)
+	}
+}
@@ -0,0 +1,92 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/enesanbar/go-service/core/log"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.uber.org/zap"
+)
+
+// Connection is a client of a kafka cluster, used by the producer and to declare the topics.
+// The consumers have their own clients, to join their groups, see Connection.NewClient.
+// The client reconnects to the brokers by itself, so the connection is not watched.
+type Connection struct {
+	logger log.Factory
+	Config *ConnectionConfig
+
+	client *kgo.Client
+}
+
+// NewConnection creates the client of the cluster, which connects to the brokers lazily.
+func NewConnection(logger log.Factory, cfg *ConnectionConfig) (*Connection, error) {
+	c := &Connection{
+		logger: logger,
+		Config: cfg,
+	}
+
+	client, err := c.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	c.client = client
+	return c, nil
+}
+
+// NewClient creates a new client of the cluster with the options of the connection and the given ones.
+func (c *Connection) NewClient(options ...kgo.Opt) (*kgo.Client, error) {
+	options = append([]kgo.Opt{
+		kgo.SeedBrokers(c.Config.Brokers...),
+		kgo.ClientID(c.Config.ClientID),
+	}, options...)
+
+	client, err := kgo.NewClient(options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka client for connection %s: %w", c.Config.Name, err)
+	}
+	return client, nil
+}
+
+// GetClient returns the client of the connection.
+func (c *Connection) GetClient() *kgo.Client {
+	return c.client
+}
+
+// Start checks that the brokers are reachable.
+func (c *Connection) Start(ctx context.Context) error {
+	if err := c.client.Ping(ctx); err != nil {
+		c.logger.Bg().
+			With(zap.Strings("brokers", c.Config.Brokers)).
+			With(zap.String("name", c.Config.Name)).
+			With(zap.Error(err)).
+			Error("kafka brokers are not reachable, the client keeps retrying")
+		return nil
+	}
+
+	c.logger.Bg().
+		With(zap.Strings("brokers", c.Config.Brokers)).
+		With(zap.String("name", c.Config.Name)).
+		Info("connected to kafka")
+	return nil
+}
+
+// Close flushes the produced records and closes the client.
+func (c *Connection) Close(ctx context.Context) error {
+	if err := c.client.Flush(ctx); err != nil {
+		c.logger.Bg().
+			With(zap.String("name", c.Config.Name)).
+			With(zap.Error(err)).
+			Error("failed to flush the produced records")
+	}
+	c.client.Close()
+
+	c.logger.Bg().
+		With(zap.String("name", c.Config.Name)).
+		Info("closed connection to kafka")
+	return nil
+}
+
+func (c *Connection) Name() string {
+	return c.Config.Name
+}
@@ -0,0 +1,37 @@
+package kafka
+
+import (
+	"fmt"
+
+	"github.com/enesanbar/go-service/core/config"
+	"github.com/enesanbar/go-service/core/info"
+)
+
+type ConnectionConfig struct {
+	Name string
+	// Brokers are the seed brokers the cluster is discovered from.
+	Brokers []string
+	// ClientID identifies the service to the brokers, the name of the service by default.
+	ClientID string
+}
+
+func NewConnectionConfig(cfg config.Config, name string) (*ConnectionConfig, error) {
+	keyTemplate := "kafka.connections.%s.%s"
+
+	property := fmt.Sprintf(keyTemplate, name, PropertyBrokers)
+	brokers := cfg.GetStringSlice(property)
+	if len(brokers) == 0 {
+		return nil, config.NewMissingPropertyError(property)
+	}
+
+	clientID := cfg.GetString(fmt.Sprintf(keyTemplate, name, PropertyClientID))
+	if clientID == "" {
+		clientID = info.ServiceName
+	}
+
+	return &ConnectionConfig{
+		Name:     name,
+		Brokers:  brokers,
+		ClientID: clientID,
+	}, nil
+}
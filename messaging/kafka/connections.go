@@ -0,0 +1,45 @@
+package kafka
+
+import (
+	"github.com/enesanbar/go-service/core/config"
+	"github.com/enesanbar/go-service/core/log"
+	"go.uber.org/zap"
+)
+
+// Connections return a map of connections configured in the configuration file.
+func Connections(conf config.Config, logger log.Factory) (map[string]*Connection, error) {
+	prefix := "kafka.connections"
+	cfg := conf.GetStringMap(prefix)
+	connections := make(map[string]*Connection)
+	for k := range cfg {
+		config, err := NewConnectionConfig(conf, k)
+		if err != nil {
+			logger.Bg().
+				With(zap.String("connection", k)).
+				With(zap.Error(err)).
+				Error("failed to create connection config")
+			return nil, err
+		}
+
+		conn, err := NewConnection(logger, config)
+		if err != nil {
+			return nil, err
+		}
+		connections[k] = conn
+	}
+
+	return connections, nil
+}
+
+// connection returns the connection of the name, or any connection if the name is empty.
+func connection(connections map[string]*Connection, name string) *Connection {
+	if name != "" {
+		return connections[name]
+	}
+	for _, conn := range connections {
+		if conn != nil {
+			return conn
+		}
+	}
+	return nil
+}
@@ -0,0 +1,404 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/enesanbar/go-service/core/log"
+	"github.com/enesanbar/go-service/core/messaging/codec"
+	"github.com/enesanbar/go-service/core/messaging/consumer"
+	"github.com/enesanbar/go-service/core/messaging/messages"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	// HeaderOriginalTopic holds the topic of the dead-lettered message.
+	HeaderOriginalTopic = "x-original-topic"
+	// HeaderOriginalPartition holds the partition of the dead-lettered message.
+	HeaderOriginalPartition = "x-original-partition"
+	// HeaderOriginalOffset holds the offset of the dead-lettered message.
+	HeaderOriginalOffset = "x-original-offset"
+	// HeaderError holds the last error returned while handling the message.
+	HeaderError = "x-error"
+)
+
+// errUnprocessable is returned for the messages that cannot be handled no matter how many times they are retried.
+var errUnprocessable = errors.New("unprocessable message")
+
+// GroupConsumer consumes the topics in a consumer group.
+//
+// The messages of a partition are handled in order, and up to Concurrency partitions are handled at the same time.
+// The offsets are committed once the polled messages are handled, and the partitions are not revoked meanwhile,
+// so a message is redelivered only if the consumer stops before it is handled.
+type GroupConsumer struct {
+	logger          log.Factory
+	Config          *ConsumerConfig
+	Connection      *Connection
+	MessageHandlers map[string]consumer.MessageHandler
+	Propagator      propagation.TextMapPropagator
+	Tracer          trace.Tracer
+	Instrumentor    *consumer.Instrumentor
+
+	client *kgo.Client
+	ctx    context.Context
+	// the poll is interrupted as soon as the consumer stops, unlike the handlers
+	pollCtx     context.Context
+	stopPolling context.CancelFunc
+	stop        chan struct{}
+	stopOnce    sync.Once
+	done        chan struct{}
+	startOnce   sync.Once
+}
+
+type ConsumerParams struct {
+	Logger          log.Factory
+	Config          *ConsumerConfig
+	Connection      *Connection
+	MessageHandlers map[string]consumer.MessageHandler
+	Propagator      propagation.TextMapPropagator
+	TracerProvider  *tracesdk.TracerProvider
+	Instrumentor    *consumer.Instrumentor
+}
+
+// NewKafkaConsumer creates a pointer to the new instance of the GroupConsumer
+func NewKafkaConsumer(p ConsumerParams) *GroupConsumer {
+	return &GroupConsumer{
+		logger:          p.Logger,
+		Config:          p.Config,
+		Connection:      p.Connection,
+		MessageHandlers: p.MessageHandlers,
+		Propagator:      p.Propagator,
+		Tracer:          p.TracerProvider.Tracer(fmt.Sprintf("consumer-%s", p.Config.Group)),
+		Instrumentor:    p.Instrumentor,
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+}
+
+// Start joins the consumer group and consumes the topics in the background.
+func (h *GroupConsumer) Start(ctx context.Context) error {
+	var err error
+	h.startOnce.Do(func() {
+		h.client, err = h.Connection.NewClient(
+			kgo.ConsumerGroup(h.Config.Group),
+			kgo.ConsumeTopics(h.Config.Topics...),
+			kgo.SessionTimeout(h.Config.SessionTimeout),
+			kgo.DisableAutoCommit(),
+			// the partitions are not revoked while the polled messages are handled, before their offsets are committed
+			kgo.BlockRebalanceOnPoll(),
+		)
+		if err != nil {
+			close(h.done)
+			return
+		}
+
+		// the start context is canceled once the application is started, handlers must outlive it
+		h.ctx = context.WithoutCancel(ctx)
+		h.pollCtx, h.stopPolling = context.WithCancel(h.ctx)
+		go h.run()
+
+		h.logger.Bg().Info(fmt.Sprintf("Kafka consumer started for group %s", h.Config.Group), zap.Strings("topics", h.Config.Topics))
+	})
+	return err
+}
+
+// run polls the messages until the consumer stops.
+func (h *GroupConsumer) run() {
+	defer close(h.done)
+
+	for !h.stopping() {
+		fetches := h.client.PollRecords(h.pollCtx, h.Config.MaxPollRecords)
+		if fetches.IsClientClosed() || h.stopping() {
+			h.client.AllowRebalance()
+			return
+		}
+		fetches.EachError(func(topic string, partition int32, err error) {
+			if !errors.Is(err, context.Canceled) {
+				h.logger.Bg().
+					With(zap.String("topic", topic)).
+					With(zap.Int32("partition", partition)).
+					With(zap.Error(err)).
+					Error("failed to fetch messages")
+			}
+		})
+
+		h.commit(h.processFetches(fetches))
+		h.client.AllowRebalance()
+	}
+}
+
+// processFetches handles the messages of the partitions concurrently, and returns the last message handled of each partition.
+func (h *GroupConsumer) processFetches(fetches kgo.Fetches) []*kgo.Record {
+	var (
+		mu      sync.Mutex
+		handled []*kgo.Record
+		wg      sync.WaitGroup
+	)
+	slots := make(chan struct{}, h.Config.Concurrency)
+
+	fetches.EachPartition(func(p kgo.FetchTopicPartition) {
+		if len(p.Records) == 0 {
+			return
+		}
+
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(records []*kgo.Record) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			if last := h.processPartition(records); last != nil {
+				mu.Lock()
+				handled = append(handled, last)
+				mu.Unlock()
+			}
+		}(p.Records)
+	})
+
+	wg.Wait()
+	return handled
+}
+
+// processPartition handles the messages of a partition in order, until the consumer stops,
+// and returns the last message handled.
+func (h *GroupConsumer) processPartition(records []*kgo.Record) *kgo.Record {
+	var last *kgo.Record
+	for _, record := range records {
+		if h.stopping() || !h.process(record) {
+			break
+		}
+		last = record
+	}
+	return last
+}
+
+// process handles the message until it succeeds or MaxAttempts is reached, then dead-letters it.
+// The dead-letter write is retried until it succeeds, so that the offset of a message that is not dead-lettered
+// is never committed. It returns false if the consumer stops before the message is settled.
+func (h *GroupConsumer) process(record *kgo.Record) bool {
+	var (
+		messageName string
+		err         error
+	)
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		h.Instrumentor.Begin(record.Topic)
+
+		messageName, err = h.handle(h.ctx, record)
+		if messageName == "" {
+			messageName = "unknown"
+		}
+		h.Instrumentor.End(record.Topic, messageName, start, err)
+
+		if err == nil || errors.Is(err, errUnprocessable) || attempt >= h.Config.MaxAttempts {
+			break
+		}
+
+		select {
+		case <-time.After(h.Config.RetryDelay):
+		case <-h.stop:
+			return false
+		}
+	}
+
+	if err == nil {
+		return true
+	}
+
+	for {
+		deadLetterErr := h.deadLetter(record, messageName, err)
+		if deadLetterErr == nil {
+			return true
+		}
+		h.logger.Bg().
+			With(zap.String("topic", record.Topic)).
+			With(zap.Int32("partition", record.Partition)).
+			With(zap.Int64("offset", record.Offset)).
+			With(zap.String("message", messageName)).
+			With(zap.Error(deadLetterErr)).
+			Error(fmt.Sprintf("failed to dead-letter message, retrying in %s", h.Config.RetryDelay))
+
+		select {
+		case <-time.After(h.Config.RetryDelay):
+		case <-h.stop:
+			return false
+		}
+	}
+}
+
+// handle passes the message to the handler of its name.
+// Messages that cannot be decoded or have no handler are reported with errUnprocessable, so that they are not retried.
+func (h *GroupConsumer) handle(ctx context.Context, record *kgo.Record) (string, error) {
+	message := messages.Message[json.RawMessage]{}
+	if err := json.Unmarshal(record.Value, &message); err != nil {
+		return "", fmt.Errorf("%w: failed to unmarshal message: %w", errUnprocessable, err)
+	}
+	metadata := message.Metadata
+
+	handler, ok := h.MessageHandlers[consumer.HandlerKey(record.Topic, metadata.MessageName)]
+	if !ok {
+		// the names of the messages without a handler are not trusted as the labels of the metrics
+		return "", fmt.Errorf("%w: no handler found for message %s", errUnprocessable, metadata.MessageName)
+	}
+
+	// the messages of the producers that predate the message id are identified by their offsets
+	if metadata.MessageID == "" {
+		metadata.MessageID = fmt.Sprintf("%s-%d-%d", record.Topic, record.Partition, record.Offset)
+	}
+	ctx = messages.WithMetadata(ctx, metadata)
+
+	// the trace is propagated through the headers, or the metadata of the producers that do not set them
+	carrier := propagation.MapCarrier{
+		"traceparent": metadata.Traceparent,
+		"tracestate":  metadata.Tracestate,
+	}
+	for _, key := range []string{"traceparent", "tracestate"} {
+		if value := header(record, key); value != nil {
+			carrier[key] = string(value)
+		}
+	}
+	ctx = h.Propagator.Extract(ctx, carrier)
+
+	ctx, span := h.Tracer.Start(
+		ctx,
+		"processing: "+metadata.MessageName,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithLinks(trace.Link{
+			SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    parseTraceID(carrier["traceparent"]),
+				SpanID:     parseSpanID(metadata.SpanID),
+				TraceFlags: trace.FlagsSampled,
+				Remote:     true,
+			}),
+		}),
+	)
+	defer span.End()
+
+	err := consumer.Dispatch(ctx, handler, metadata, consumer.Payload{Data: message.Payload, Codec: codec.JSONCodec{}})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	switch {
+	case errors.Is(err, consumer.ErrInvalidPayload):
+		err = fmt.Errorf("%w: invalid payload: %w", errUnprocessable, err)
+	case errors.Is(err, consumer.ErrUnsupportedVersion):
+		err = fmt.Errorf("%w: %w", errUnprocessable, err)
+	}
+	return metadata.MessageName, err
+}
+
+// deadLetter publishes the message that failed to the dead-letter topic, or skips it if there is none.
+// It returns the error of the dead-letter write.
+func (h *GroupConsumer) deadLetter(record *kgo.Record, messageName string, handleErr error) error {
+	logger := h.logger.Bg().
+		With(zap.String("topic", record.Topic)).
+		With(zap.Int32("partition", record.Partition)).
+		With(zap.Int64("offset", record.Offset)).
+		With(zap.String("message", messageName)).
+		With(zap.Error(handleErr))
+
+	if h.Config.DeadLetterTopic == "" {
+		logger.Error("failed to handle message, skipping it")
+		return nil
+	}
+
+	headers := append([]kgo.RecordHeader{}, record.Headers...)
+	headers = append(headers,
+		kgo.RecordHeader{Key: HeaderOriginalTopic, Value: []byte(record.Topic)},
+		kgo.RecordHeader{Key: HeaderOriginalPartition, Value: []byte(strconv.Itoa(int(record.Partition)))},
+		kgo.RecordHeader{Key: HeaderOriginalOffset, Value: []byte(strconv.FormatInt(record.Offset, 10))},
+		kgo.RecordHeader{Key: HeaderError, Value: []byte(handleErr.Error())},
+	)
+	deadLetter := &kgo.Record{
+		Topic:   h.Config.DeadLetterTopic,
+		Key:     record.Key,
+		Value:   record.Value,
+		Headers: headers,
+	}
+	if err := h.client.ProduceSync(h.ctx, deadLetter).FirstErr(); err != nil {
+		return fmt.Errorf("failed to produce message to %s: %w", h.Config.DeadLetterTopic, err)
+	}
+	logger.Error(fmt.Sprintf("failed to handle message, dead-lettered it to %s", h.Config.DeadLetterTopic))
+	return nil
+}
+
+// commit commits the offsets of the handled messages.
+func (h *GroupConsumer) commit(handled []*kgo.Record) {
+	if len(handled) == 0 {
+		return
+	}
+	if err := h.client.CommitRecords(h.ctx, handled...); err != nil {
+		h.logger.Bg().
+			With(zap.String("group", h.Config.Group)).
+			With(zap.Error(err)).
+			Error("failed to commit offsets, the messages will be redelivered")
+	}
+}
+
+func (h *GroupConsumer) stopping() bool {
+	select {
+	case <-h.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stop stops polling and waits for the polled messages to be handled until the graceful stop timeout.
+// The offsets of the handled messages are committed before the consumer leaves the group,
+// and the other messages are redelivered to the consumers the partitions are assigned to.
+func (h *GroupConsumer) Stop(ctx context.Context) error {
+	if h.client == nil {
+		return nil
+	}
+	h.stopOnce.Do(func() {
+		close(h.stop)
+		h.stopPolling()
+	})
+
+	timer := time.NewTimer(h.Config.GracefulStopTimeout)
+	defer timer.Stop()
+
+	h.logger.For(ctx).Info(fmt.Sprintf("draining Kafka consumer for group %s", h.Config.Group))
+	var err error
+	select {
+	case <-h.done:
+	case <-timer.C:
+		err = fmt.Errorf("Kafka consumer for group %s could not be drained in %s", h.Config.Group, h.Config.GracefulStopTimeout)
+	case <-ctx.Done():
+		err = fmt.Errorf("Kafka consumer for group %s could not be drained (%w)", h.Config.Group, ctx.Err())
+	}
+
+	// the messages left are abandoned, their offsets are not committed
+	h.client.CloseAllowingRebalance()
+	h.logger.Bg().Info(fmt.Sprintf("Kafka consumer stopped for group %s", h.Config.Group))
+	return err
+}
+
+func parseTraceID(traceparent string) trace.TraceID {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) >= 2 {
+		tid, _ := trace.TraceIDFromHex(parts[1])
+		return tid
+	}
+	return trace.TraceID{}
+}
+
+func parseSpanID(spanID string) trace.SpanID {
+	sid, _ := trace.SpanIDFromHex(spanID)
+	return sid
+}
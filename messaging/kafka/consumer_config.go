@@ -0,0 +1,182 @@
+package kafka
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/enesanbar/go-service/core/info"
+)
+
+const (
+	DefaultConcurrency         = 10
+	DefaultMaxPollRecords      = 500
+	DefaultMaxAttempts         = 1
+	DefaultRetryDelay          = time.Second
+	DefaultSessionTimeout      = 45 * time.Second
+	DefaultGracefulStopTimeout = 10 * time.Second
+)
+
+type ConsumerConfig struct {
+	// Connection is the name of the connection of the consumer, any connection by default.
+	Connection string
+	// Group is the consumer group, the name of the service by default.
+	Group string
+	// Topics are consumed by the group.
+	Topics []string
+	// Concurrency is the number of partitions handled at the same time, the messages of a partition are handled in order.
+	Concurrency int
+	// MaxPollRecords is the number of messages fetched at once, their offsets are committed once they are all handled.
+	MaxPollRecords int
+	// MaxAttempts is the number of times a message is handled before it is dead-lettered, 1 disables retries.
+	MaxAttempts int
+	// RetryDelay is the time waited before a failed message is handled again.
+	RetryDelay time.Duration
+	// DeadLetterTopic receives the messages that are exhausted or cannot be handled at all, they are skipped by default.
+	DeadLetterTopic string
+	// SessionTimeout is the time the group waits for the consumer before its partitions are assigned to the others.
+	SessionTimeout time.Duration
+	// GracefulStopTimeout is the time given to the in-flight messages to be handled when the consumer stops.
+	GracefulStopTimeout time.Duration
+}
+
+func NewConsumerConfig(cfg interface{}) (*ConsumerConfig, error) {
+	config := cfg.(map[string]interface{})
+
+	topics, err := stringsProperty(config, PropertyTopics)
+	if err != nil {
+		return nil, err
+	}
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("%s of the consumer are required", PropertyTopics)
+	}
+
+	group, _ := config[PropertyGroup].(string)
+	if group == "" {
+		group = info.ServiceName
+	}
+
+	connectionName, _ := config[PropertyConnection].(string)
+	deadLetterTopic, _ := config[PropertyDeadLetterTopic].(string)
+
+	concurrency, err := intProperty(config, PropertyConcurrency, DefaultConcurrency)
+	if err != nil {
+		return nil, err
+	}
+	if concurrency < 1 {
+		return nil, fmt.Errorf("%s of the consumer of group %s must be at least 1", PropertyConcurrency, group)
+	}
+
+	maxPollRecords, err := intProperty(config, PropertyMaxPollRecords, DefaultMaxPollRecords)
+	if err != nil {
+		return nil, err
+	}
+	if maxPollRecords < 1 {
+		return nil, fmt.Errorf("%s of the consumer of group %s must be at least 1", PropertyMaxPollRecords, group)
+	}
+
+	maxAttempts, err := intProperty(config, PropertyMaxAttempts, DefaultMaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	if maxAttempts < 1 {
+		return nil, fmt.Errorf("%s of the consumer of group %s must be at least 1", PropertyMaxAttempts, group)
+	}
+
+	retryDelay, err := durationProperty(config, PropertyRetryDelay, DefaultRetryDelay)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionTimeout, err := durationProperty(config, PropertySessionTimeout, DefaultSessionTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	gracefulStopTimeout, err := durationProperty(config, PropertyGracefulStopTimeout, DefaultGracefulStopTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConsumerConfig{
+		Connection:          connectionName,
+		Group:               group,
+		Topics:              topics,
+		Concurrency:         concurrency,
+		MaxPollRecords:      maxPollRecords,
+		MaxAttempts:         maxAttempts,
+		RetryDelay:          retryDelay,
+		DeadLetterTopic:     deadLetterTopic,
+		SessionTimeout:      sessionTimeout,
+		GracefulStopTimeout: gracefulStopTimeout,
+	}, nil
+}
+
+// stringsProperty returns the list of strings of the property, which is decoded from the configuration file as []interface{}.
+func stringsProperty(config map[string]interface{}, property string) ([]string, error) {
+	value, ok := config[property]
+	if !ok {
+		return nil, nil
+	}
+
+	switch v := value.(type) {
+	case []string:
+		return v, nil
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid value '%v' for %s", value, property)
+			}
+			result = append(result, s)
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("invalid value '%v' for %s", value, property)
+	}
+}
+
+// intProperty returns the integer value of the property, which is decoded from the configuration file as int or float64.
+func intProperty(config map[string]interface{}, property string, defaultValue int) (int, error) {
+	value, ok := config[property]
+	if !ok {
+		return defaultValue, nil
+	}
+
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		return int(v), nil
+	case string:
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid value '%s' for %s: %w", v, property, err)
+		}
+		return i, nil
+	default:
+		return 0, fmt.Errorf("invalid value '%v' for %s", value, property)
+	}
+}
+
+// durationProperty returns the duration value of the property, e.g. 500ms or 10s.
+func durationProperty(config map[string]interface{}, property string, defaultValue time.Duration) (time.Duration, error) {
+	value, ok := config[property]
+	if !ok {
+		return defaultValue, nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return 0, fmt.Errorf("invalid value '%v' for %s, expected a duration such as 10s", value, property)
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value '%s' for %s: %w", s, property, err)
+	}
+	return d, nil
+}
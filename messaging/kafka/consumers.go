@@ -0,0 +1,58 @@
+package kafka
+
+import (
+	"fmt"
+
+	"github.com/enesanbar/go-service/core/config"
+	"github.com/enesanbar/go-service/core/log"
+	"github.com/enesanbar/go-service/core/messaging/consumer"
+	"github.com/enesanbar/go-service/core/wiring"
+	"go.opentelemetry.io/otel/propagation"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/fx"
+)
+
+type ConsumersParams struct {
+	fx.In
+
+	Conf            config.Config
+	Logger          log.Factory
+	Connections     map[string]*Connection
+	MessageHandlers map[string]consumer.MessageHandler
+	Propagator      propagation.TextMapPropagator
+	TracerProvider  *tracesdk.TracerProvider
+	Instrumentor    *consumer.Instrumentor
+}
+
+// Consumers creates the consumers defined in the configuration file.
+// It returns an error if a consumer is malformed or its connection is not found.
+func Consumers(p ConsumersParams) ([]wiring.Runnable, error) {
+	runnables := make([]wiring.Runnable, 0)
+
+	cfg := p.Conf.GetSliceOfObjects("kafka.consumers")
+
+	for i, v := range cfg {
+		cfg, err := NewConsumerConfig(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid configuration of consumer %d: %w", i, err)
+		}
+
+		conn := connection(p.Connections, cfg.Connection)
+		if conn == nil {
+			return nil, fmt.Errorf("connection %s not found for consumer of group %s. please check the connection configuration in your configuration", cfg.Connection, cfg.Group)
+		}
+
+		o := NewKafkaConsumer(ConsumerParams{
+			Logger:          p.Logger,
+			Config:          cfg,
+			Connection:      conn,
+			MessageHandlers: p.MessageHandlers,
+			Propagator:      p.Propagator,
+			TracerProvider:  p.TracerProvider,
+			Instrumentor:    p.Instrumentor,
+		})
+		runnables = append(runnables, o)
+	}
+
+	return runnables, nil
+}
@@ -0,0 +1,565 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/enesanbar/go-service/core/config"
+	"github.com/enesanbar/go-service/core/config/configtest"
+	"github.com/enesanbar/go-service/core/log"
+	"github.com/enesanbar/go-service/core/messaging/consumer"
+	"github.com/enesanbar/go-service/core/messaging/messages"
+	"github.com/enesanbar/go-service/core/messaging/producer"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/propagation"
+	otelmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+)
+
+type orderCreated struct {
+	ID int `json:"id"`
+}
+
+// orderHandler records the handled orders, failing the ones that are given an error in fail.
+type orderHandler struct {
+	mu       sync.Mutex
+	handled  []int
+	metadata []messages.Metadata
+	attempts map[int]int
+	fail     map[int]error
+	// block blocks the handler of the order until it is closed.
+	block map[int]chan struct{}
+	calls chan int
+}
+
+func newOrderHandler() *orderHandler {
+	return &orderHandler{
+		attempts: make(map[int]int),
+		fail:     make(map[int]error),
+		block:    make(map[int]chan struct{}),
+		calls:    make(chan int, 100),
+	}
+}
+
+func (h *orderHandler) Handle(_ context.Context, message messages.Message[orderCreated]) error {
+	id := message.Payload.ID
+
+	h.mu.Lock()
+	h.attempts[id]++
+	err, block := h.fail[id], h.block[id]
+	h.mu.Unlock()
+
+	h.calls <- id
+	if block != nil {
+		<-block
+	}
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	h.handled = append(h.handled, id)
+	h.metadata = append(h.metadata, message.Metadata)
+	h.mu.Unlock()
+	return nil
+}
+
+func (h *orderHandler) Properties() consumer.MessageProperties {
+	return consumer.MessageProperties{QueueName: "orders", MessageName: "order-created"}
+}
+
+func (h *orderHandler) handledOrders() []int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]int{}, h.handled...)
+}
+
+// testEnv is a kafka cluster in the process, with the topics and the connections of the configuration.
+type testEnv struct {
+	conf        config.Config
+	logger      log.Factory
+	connections map[string]*Connection
+}
+
+func newTestEnv(t *testing.T, properties map[string]any) *testEnv {
+	t.Helper()
+
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1))
+	if err != nil {
+		t.Fatalf("unable to start cluster: %v", err)
+	}
+	t.Cleanup(cluster.Close)
+
+	properties["kafka.connections.default"] = map[string]any{
+		PropertyBrokers:  cluster.ListenAddrs(),
+		PropertyClientID: "test",
+	}
+	properties["kafka.topics.orders"] = map[string]any{PropertyPartitions: 3}
+	properties["kafka.topics.orders-dead-letters"] = map[string]any{}
+
+	env := &testEnv{
+		conf:   configtest.New(t, properties),
+		logger: log.NewFactory(zap.NewNop()),
+	}
+	env.connections, err = Connections(env.conf, env.logger)
+	if err != nil {
+		t.Fatalf("unable to create connections: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = env.connections["default"].Close(context.Background())
+	})
+
+	if err := Topics(env.conf, env.logger, env.connections); err != nil {
+		t.Fatalf("unable to create topics: %v", err)
+	}
+	return env
+}
+
+func (e *testEnv) producer(t *testing.T) *Producer {
+	t.Helper()
+
+	cfg, err := NewProducerConfig(e.conf)
+	if err != nil {
+		t.Fatalf("unable to create producer config: %v", err)
+	}
+	p, err := NewKafkaProducer(ProducerParams{
+		Logger:      e.logger,
+		Connections: e.connections,
+		Propagator:  propagation.TraceContext{},
+		Config:      cfg,
+	})
+	if err != nil {
+		t.Fatalf("unable to create producer: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = p.Close(context.Background())
+	})
+	return p
+}
+
+func (e *testEnv) consumer(t *testing.T, handler *orderHandler) *GroupConsumer {
+	t.Helper()
+
+	instrumentor, err := consumer.NewInstrumentor(consumer.InstrumentorParams{})
+	if err != nil {
+		t.Fatalf("unable to create instrumentor: %v", err)
+	}
+	handlers, err := consumer.MapHandlers(consumer.HandlersParams{
+		Handlers: []consumer.MessageHandler{consumer.NewMessageHandler[orderCreated](handler)},
+	})
+	if err != nil {
+		t.Fatalf("unable to map handlers: %v", err)
+	}
+	runnables, err := Consumers(ConsumersParams{
+		Conf:            e.conf,
+		Logger:          e.logger,
+		Connections:     e.connections,
+		MessageHandlers: handlers,
+		Propagator:      propagation.TraceContext{},
+		TracerProvider:  tracesdk.NewTracerProvider(),
+		Instrumentor:    instrumentor,
+	})
+	if err != nil || len(runnables) != 1 {
+		t.Fatalf("Expected '%d' consumer, got '%d' (%v)", 1, len(runnables), err)
+	}
+	return runnables[0].(*GroupConsumer)
+}
+
+// committed returns the committed offsets of the group by partition of the topic.
+func (e *testEnv) committed(t *testing.T, group, topic string) map[int32]int64 {
+	t.Helper()
+
+	offsets, err := kadm.NewClient(e.connections["default"].GetClient()).FetchOffsets(context.Background(), group)
+	if err != nil {
+		t.Fatalf("unable to fetch offsets: %v", err)
+	}
+	result := make(map[int32]int64)
+	offsets.Each(func(o kadm.OffsetResponse) {
+		if o.Topic == topic {
+			result[o.Partition] = o.At
+		}
+	})
+	return result
+}
+
+// consume returns the records of the topic.
+func (e *testEnv) consume(t *testing.T, topic string, count int) []*kgo.Record {
+	t.Helper()
+
+	client, err := e.connections["default"].NewClient(kgo.ConsumeTopics(topic))
+	if err != nil {
+		t.Fatalf("unable to create client: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var records []*kgo.Record
+	for len(records) < count {
+		fetches := client.PollFetches(ctx)
+		if ctx.Err() != nil {
+			t.Fatalf("Expected '%d' records in %s, got '%d'", count, topic, len(records))
+		}
+		records = append(records, fetches.Records()...)
+	}
+	return records
+}
+
+func waitFor(t *testing.T, description string, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(15 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", description)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func publishOrders(t *testing.T, p *Producer, ids ...int) {
+	t.Helper()
+
+	publishings := make([]producer.Publishing, 0, len(ids))
+	for _, id := range ids {
+		publishings = append(publishings, producer.Publishing{
+			MessageName: "order-created",
+			Message:     orderCreated{ID: id},
+			Options:     []producer.PublishOption{producer.WithOrderingKey("customer-1")},
+		})
+	}
+	if err := p.PublishBatch(context.Background(), publishings); err != nil {
+		t.Fatalf("unable to publish orders: %v", err)
+	}
+}
+
+func TestNewConsumerConfig(t *testing.T) {
+	cfg, err := NewConsumerConfig(map[string]interface{}{
+		PropertyTopics:      []interface{}{"orders"},
+		PropertyGroup:       "shipping",
+		PropertyMaxAttempts: 3,
+		PropertyRetryDelay:  "100ms",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if cfg.Group != "shipping" || cfg.MaxAttempts != 3 || cfg.RetryDelay != 100*time.Millisecond {
+		t.Errorf("Expected the configured values, got '%+v'", cfg)
+	}
+	if cfg.Concurrency != DefaultConcurrency || cfg.MaxPollRecords != DefaultMaxPollRecords || cfg.SessionTimeout != DefaultSessionTimeout {
+		t.Errorf("Expected the default values, got '%+v'", cfg)
+	}
+
+	if _, err := NewConsumerConfig(map[string]interface{}{}); err == nil {
+		t.Error("Expected an error for a consumer without topics")
+	}
+	if _, err := NewConsumerConfig(map[string]interface{}{PropertyTopics: []interface{}{"orders"}, PropertyConcurrency: 0}); err == nil {
+		t.Error("Expected an error for a concurrency of 0")
+	}
+}
+
+func TestConsumers_ReportsInvalidConsumers(t *testing.T) {
+	connections := map[string]*Connection{"default": {}}
+
+	cases := []struct {
+		consumer map[string]any
+		expected string
+	}{
+		{
+			consumer: map[string]any{PropertyGroup: "shipping"},
+			expected: "consumer 0: topics of the consumer are required",
+		},
+		{
+			consumer: map[string]any{PropertyTopics: []any{"orders"}, PropertyGroup: "shipping", PropertyConnection: "payments"},
+			expected: "connection payments not found for consumer of group shipping",
+		},
+	}
+	for _, c := range cases {
+		_, err := Consumers(ConsumersParams{
+			Conf:        configtest.New(t, map[string]any{"kafka.consumers": []any{c.consumer}}),
+			Logger:      log.NewFactory(zap.NewNop()),
+			Connections: connections,
+		})
+		if err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Errorf("Expected an error containing '%s', got '%v'", c.expected, err)
+		}
+	}
+}
+
+func TestNewProducerConfig(t *testing.T) {
+	cfg, err := NewProducerConfig(configtest.New(t, map[string]any{"kafka.producer.topic": "orders"}))
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if cfg.Topic != "orders" || cfg.Acks != AcksAll || cfg.DeliveryTimeout != DefaultDeliveryTimeout {
+		t.Errorf("Expected the configured topic and the defaults, got '%+v'", cfg)
+	}
+
+	_, err = NewProducerConfig(configtest.New(t, map[string]any{"kafka.producer.acks": "some"}))
+	if err == nil {
+		t.Error("Expected an error for invalid acks")
+	}
+}
+
+func TestProducerConsumer_RoundTrip(t *testing.T) {
+	env := newTestEnv(t, map[string]any{
+		"kafka.producer.topic": "orders",
+		"kafka.consumers": []any{
+			map[string]any{PropertyTopics: []any{"orders"}, PropertyGroup: "shipping"},
+		},
+	})
+	p := env.producer(t)
+
+	tp := tracesdk.NewTracerProvider()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "publish")
+	err := p.Publish(ctx, "order-created", orderCreated{ID: 1}, producer.WithMessageID("order-1"), producer.WithHeader("tenant", "eu"))
+	span.End()
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	record := env.consume(t, "orders", 1)[0]
+	if string(header(record, HeaderMessageName)) != "order-created" || string(header(record, "tenant")) != "eu" {
+		t.Errorf("Expected the message name and tenant headers, got '%v'", record.Headers)
+	}
+	if header(record, "traceparent") == nil {
+		t.Error("Expected the trace to be propagated through the headers")
+	}
+
+	handler := newOrderHandler()
+	c := env.consumer(t, handler)
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatalf("unable to start consumer: %v", err)
+	}
+	defer func() { _ = c.Stop(context.Background()) }()
+
+	waitFor(t, "the order to be handled", func() bool { return len(handler.handledOrders()) == 1 })
+	metadata := handler.metadata[0]
+	if metadata.MessageID != "order-1" {
+		t.Errorf("Expected message id to be '%s', got '%s'", "order-1", metadata.MessageID)
+	}
+	if metadata.Traceparent != string(header(record, "traceparent")) {
+		t.Errorf("Expected traceparent to be '%s', got '%s'", header(record, "traceparent"), metadata.Traceparent)
+	}
+
+	waitFor(t, "the offset to be committed", func() bool {
+		return env.committed(t, "shipping", "orders")[record.Partition] == record.Offset+1
+	})
+}
+
+func TestConsumer_RetriesAndDeadLetters(t *testing.T) {
+	env := newTestEnv(t, map[string]any{
+		"kafka.producer.topic": "orders",
+		"kafka.consumers": []any{
+			map[string]any{
+				PropertyTopics:          []any{"orders"},
+				PropertyGroup:           "shipping",
+				PropertyMaxAttempts:     3,
+				PropertyRetryDelay:      "10ms",
+				PropertyDeadLetterTopic: "orders-dead-letters",
+			},
+		},
+	})
+	publishOrders(t, env.producer(t), 1, 2)
+
+	handler := newOrderHandler()
+	handler.fail[1] = errors.New("out of stock")
+	c := env.consumer(t, handler)
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatalf("unable to start consumer: %v", err)
+	}
+	defer func() { _ = c.Stop(context.Background()) }()
+
+	waitFor(t, "the second order to be handled", func() bool { return len(handler.handledOrders()) == 1 })
+	if handler.attempts[1] != 3 {
+		t.Errorf("Expected the failed order to be handled '%d' times, got '%d'", 3, handler.attempts[1])
+	}
+
+	deadLetter := env.consume(t, "orders-dead-letters", 1)[0]
+	if string(header(deadLetter, HeaderOriginalTopic)) != "orders" {
+		t.Errorf("Expected original topic to be '%s', got '%s'", "orders", header(deadLetter, HeaderOriginalTopic))
+	}
+	if string(header(deadLetter, HeaderError)) != "out of stock" {
+		t.Errorf("Expected error to be '%s', got '%s'", "out of stock", header(deadLetter, HeaderError))
+	}
+
+	// the dead-lettered message is settled, so its offset is committed along with the next one
+	waitFor(t, "the offsets to be committed", func() bool {
+		for _, offset := range env.committed(t, "shipping", "orders") {
+			if offset == 2 {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func TestConsumer_RetriesFailedDeadLetters(t *testing.T) {
+	env := newTestEnv(t, map[string]any{
+		"kafka.consumers": []any{
+			map[string]any{
+				PropertyTopics:          []any{"orders"},
+				PropertyGroup:           "shipping",
+				PropertyRetryDelay:      "10ms",
+				PropertyDeadLetterTopic: "orders-dead-letters",
+			},
+		},
+	})
+
+	c := env.consumer(t, newOrderHandler())
+	// the dead-letter writes fail with the client closed
+	client, err := env.connections["default"].NewClient()
+	if err != nil {
+		t.Fatalf("unable to create client: %v", err)
+	}
+	client.Close()
+	c.client = client
+	c.ctx = context.Background()
+
+	settled := make(chan bool, 1)
+	go func() {
+		settled <- c.process(&kgo.Record{Topic: "orders", Value: []byte("not json")})
+	}()
+
+	select {
+	case <-settled:
+		t.Fatal("Expected the message not to be settled until it is dead-lettered")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(c.stop)
+	select {
+	case ok := <-settled:
+		if ok {
+			t.Error("Expected the message not to be settled, so that its offset is not committed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the dead-letter retries to stop with the consumer")
+	}
+}
+
+func TestConsumer_LabelsMessagesWithoutHandler(t *testing.T) {
+	env := newTestEnv(t, map[string]any{
+		"kafka.consumers": []any{
+			map[string]any{PropertyTopics: []any{"orders"}, PropertyGroup: "shipping"},
+		},
+	})
+
+	reader := otelmetric.NewManualReader()
+	instrumentor, err := consumer.NewInstrumentor(consumer.InstrumentorParams{
+		MeterProvider: otelmetric.NewMeterProvider(otelmetric.WithReader(reader)),
+	})
+	if err != nil {
+		t.Fatalf("unable to create instrumentor: %v", err)
+	}
+	c := env.consumer(t, newOrderHandler())
+	c.Instrumentor = instrumentor
+
+	value, err := json.Marshal(messages.Message[orderCreated]{Metadata: messages.Metadata{MessageName: "order-deleted"}})
+	if err != nil {
+		t.Fatalf("unable to marshal message: %v", err)
+	}
+	if !c.process(&kgo.Record{Topic: "orders", Value: value}) {
+		t.Fatal("Expected the message without a handler to be skipped")
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("unable to collect metrics: %v", err)
+	}
+	labels := make(map[string]bool)
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if sum, ok := m.Data.(metricdata.Sum[int64]); ok {
+				for _, point := range sum.DataPoints {
+					if message, ok := point.Attributes.Value("message"); ok {
+						labels[message.AsString()] = true
+					}
+				}
+			}
+		}
+	}
+	if !labels["unknown"] || labels["order-deleted"] {
+		t.Errorf("Expected the message without a handler to be labeled '%s', got '%v'", "unknown", labels)
+	}
+}
+
+func TestConsumer_HandlesPartitionInOrder(t *testing.T) {
+	env := newTestEnv(t, map[string]any{
+		"kafka.producer.topic": "orders",
+		"kafka.consumers": []any{
+			map[string]any{PropertyTopics: []any{"orders"}, PropertyGroup: "shipping", PropertyMaxPollRecords: 3},
+		},
+	})
+	ids := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	publishOrders(t, env.producer(t), ids...)
+
+	handler := newOrderHandler()
+	c := env.consumer(t, handler)
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatalf("unable to start consumer: %v", err)
+	}
+	defer func() { _ = c.Stop(context.Background()) }()
+
+	waitFor(t, "the orders to be handled", func() bool { return len(handler.handledOrders()) == len(ids) })
+	for i, id := range handler.handledOrders() {
+		if id != ids[i] {
+			t.Fatalf("Expected the orders to be handled in order, got '%v'", handler.handledOrders())
+		}
+	}
+}
+
+func TestConsumer_StopCommitsHandledMessages(t *testing.T) {
+	env := newTestEnv(t, map[string]any{
+		"kafka.producer.topic": "orders",
+		"kafka.consumers": []any{
+			map[string]any{PropertyTopics: []any{"orders"}, PropertyGroup: "shipping"},
+		},
+	})
+	publishOrders(t, env.producer(t), 1, 2, 3)
+
+	handler := newOrderHandler()
+	release := make(chan struct{})
+	handler.block[1] = release
+	c := env.consumer(t, handler)
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatalf("unable to start consumer: %v", err)
+	}
+	if id := <-handler.calls; id != 1 {
+		t.Fatalf("Expected order '%d' to be handled first, got '%d'", 1, id)
+	}
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- c.Stop(context.Background()) }()
+	waitFor(t, "the consumer to stop polling", c.stopping)
+	close(release)
+	if err := <-stopped; err != nil {
+		t.Fatalf("Expected consumer to stop, got '%v'", err)
+	}
+	if handled := handler.handledOrders(); len(handled) != 1 {
+		t.Fatalf("Expected only the in-flight order to be handled, got '%v'", handled)
+	}
+
+	// the orders left are handled by the next consumer of the group
+	next := newOrderHandler()
+	c = env.consumer(t, next)
+	if err := c.Start(context.Background()); err != nil {
+		t.Fatalf("unable to start consumer: %v", err)
+	}
+	defer func() { _ = c.Stop(context.Background()) }()
+
+	waitFor(t, "the orders left to be handled", func() bool { return len(next.handledOrders()) == 2 })
+	if handled := next.handledOrders(); handled[0] != 2 || handled[1] != 3 {
+		t.Errorf("Expected the orders left to be '%v', got '%v'", []int{2, 3}, handled)
+	}
+}
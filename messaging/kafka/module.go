@@ -0,0 +1,63 @@
+package kafka
+
+import (
+	"context"
+
+	"github.com/enesanbar/go-service/core/messaging/consumer"
+	"github.com/enesanbar/go-service/core/messaging/producer"
+	"github.com/enesanbar/go-service/core/service"
+	"github.com/enesanbar/go-service/core/wiring"
+	"go.uber.org/fx"
+)
+
+var Module = fx.Module(
+	"kafka",
+	fx.Provide(Connections),
+	fx.Provide(
+		fx.Annotate(
+			func(connections map[string]*Connection) []wiring.Connection {
+				result := make([]wiring.Connection, 0, len(connections))
+				for _, conn := range connections {
+					result = append(result, conn)
+				}
+				return result
+			},
+			fx.ResultTags(`group:"connection-group"`),
+		),
+	),
+	fx.Invoke(Topics),
+)
+
+func Option(options ...fx.Option) service.Option {
+	return func(cfg *service.AppConfig) {
+		cfg.Options = append(cfg.Options, Module)
+		cfg.Options = append(cfg.Options, options...)
+	}
+}
+
+var ProducerModule = fx.Module(
+	"messaging.kafka.producer",
+	fx.Provide(NewProducerConfig),
+	fx.Provide(fx.Annotate(
+		NewKafkaProducer,
+		// the producer has its own client, which is flushed when the application stops
+		fx.OnStop(func(ctx context.Context, p *Producer) error {
+			return p.Close(ctx)
+		}),
+		fx.As(new(producer.Producer)),
+		fx.As(new(producer.BatchProducer)),
+	)),
+)
+
+var ConsumerModule = fx.Module(
+	"messaging.kafka.consumer",
+	// the handlers are mapped by their topics, as QueueName
+	fx.Provide(consumer.MapHandlers, fx.Private),
+	fx.Provide(consumer.NewInstrumentor, fx.Private),
+	fx.Provide(
+		fx.Annotate(
+			Consumers,
+			fx.ResultTags(`group:"runnable-group"`),
+		),
+	),
+)
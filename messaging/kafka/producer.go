@@ -0,0 +1,224 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/enesanbar/go-service/core/info"
+	"github.com/enesanbar/go-service/core/log"
+	"github.com/enesanbar/go-service/core/messaging/codec"
+	"github.com/enesanbar/go-service/core/messaging/messages"
+	"github.com/enesanbar/go-service/core/messaging/producer"
+	"github.com/google/uuid"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	// HeaderContentType holds the content type of the messages.
+	HeaderContentType = "content-type"
+	// HeaderMessageName holds the name of the messages, so that they can be routed without decoding them.
+	HeaderMessageName = "message-name"
+)
+
+// Producer publishes messages to kafka and waits for the brokers to acknowledge them.
+// The messages are published in the envelope of go-service, and their traces are propagated through the headers.
+type Producer struct {
+	Logger     log.Factory
+	Connection *Connection
+	Propagator propagation.TextMapPropagator
+	Config     *ProducerConfig
+
+	client *kgo.Client
+}
+
+type ProducerParams struct {
+	fx.In
+
+	Logger      log.Factory
+	Connections map[string]*Connection
+	Propagator  propagation.TextMapPropagator
+	Config      *ProducerConfig
+}
+
+// NewKafkaProducer creates a pointer to the new instance of the Producer
+func NewKafkaProducer(params ProducerParams) (*Producer, error) {
+	p := &Producer{
+		Logger:     params.Logger,
+		Propagator: params.Propagator,
+		Config:     params.Config,
+	}
+
+	if len(params.Connections) == 0 {
+		return nil, fmt.Errorf("no connections found. please check the connection configuration in your configuration")
+	}
+
+	p.Connection = connection(params.Connections, p.Config.Connection)
+	if p.Connection == nil {
+		return nil, fmt.Errorf("connection %s not found for producer. please check the connection configuration in your configuration", p.Config.Connection)
+	}
+	p.Logger.Bg().Info("using connection for producer", zap.String("connection", p.Connection.Name()))
+
+	options := []kgo.Opt{kgo.RecordDeliveryTimeout(p.Config.DeliveryTimeout)}
+	switch p.Config.Acks {
+	case AcksLeader:
+		// the idempotent writes require the acknowledgements of all replicas
+		options = append(options, kgo.RequiredAcks(kgo.LeaderAck()), kgo.DisableIdempotentWrite())
+	case AcksNone:
+		options = append(options, kgo.RequiredAcks(kgo.NoAck()), kgo.DisableIdempotentWrite())
+	default:
+		options = append(options, kgo.RequiredAcks(kgo.AllISRAcks()))
+	}
+
+	client, err := p.Connection.NewClient(options...)
+	if err != nil {
+		return nil, err
+	}
+	p.client = client
+	return p, nil
+}
+
+// Publish publishes the message and waits for the brokers to acknowledge it.
+func (p *Producer) Publish(ctx context.Context, messageName string, payload any, options ...producer.PublishOption) error {
+	return p.PublishBatch(ctx, []producer.Publishing{{
+		MessageName: messageName,
+		Message:     payload,
+		Options:     options,
+	}})
+}
+
+// PublishBatch publishes the messages at once and waits for the brokers to acknowledge all of them.
+// The messages with the same key are published in order, to the same partition.
+func (p *Producer) PublishBatch(ctx context.Context, publishings []producer.Publishing) error {
+	var errs []error
+	records := make([]*kgo.Record, 0, len(publishings))
+	for _, publishing := range publishings {
+		record, err := p.record(ctx, publishing)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		records = append(records, record)
+	}
+
+	for _, result := range p.client.ProduceSync(ctx, records...) {
+		if result.Err != nil {
+			messageName := string(header(result.Record, HeaderMessageName))
+			errs = append(errs, fmt.Errorf("failed to publish message %s: %w", messageName, result.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// record builds the kafka record of the publishing, applying its options over the defaults of the producer.
+// The exchange of the options is the topic, and the ordering key, or the routing key, is the key of the record.
+func (p *Producer) record(ctx context.Context, publishing producer.Publishing) (*kgo.Record, error) {
+	options := producer.NewPublishOptions(publishing.Options...)
+
+	messageID := options.MessageID
+	if messageID == "" {
+		messageID = uuid.NewString()
+	}
+
+	message := messages.Message[any]{
+		Metadata: messages.Metadata{
+			MessageID:      messageID,
+			CorrelationID:  options.CorrelationID,
+			CausationID:    options.CausationID,
+			PublisherName:  info.ServiceName,
+			PublishDate:    time.Now().UTC(),
+			MessageName:    publishing.MessageName,
+			MessageVersion: options.MessageVersion,
+		},
+		Payload: publishing.Message,
+	}
+	message.Metadata.Correlate(ctx)
+
+	// the trace is propagated through the headers, and the metadata for the consumers of the envelope
+	carrier := propagation.MapCarrier{}
+	p.enrichMetadataWithTrace(ctx, &message.Metadata, carrier)
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message %s: %w", publishing.MessageName, err)
+	}
+
+	topic := p.Config.Topic
+	if options.Exchange != "" {
+		topic = options.Exchange
+	}
+
+	key := options.OrderingKey
+	if key == "" {
+		key = options.RoutingKey
+	}
+
+	record := &kgo.Record{
+		Topic:     topic,
+		Value:     body,
+		Timestamp: message.Metadata.PublishDate,
+		Headers: []kgo.RecordHeader{
+			{Key: HeaderContentType, Value: []byte(codec.ContentTypeJSON)},
+			{Key: HeaderMessageName, Value: []byte(publishing.MessageName)},
+		},
+	}
+	if key != "" {
+		record.Key = []byte(key)
+	}
+	for name, value := range carrier {
+		record.Headers = append(record.Headers, kgo.RecordHeader{Key: name, Value: []byte(value)})
+	}
+	for name, value := range options.Headers {
+		record.Headers = append(record.Headers, kgo.RecordHeader{Key: name, Value: headerValue(value)})
+	}
+	return record, nil
+}
+
+func (p *Producer) enrichMetadataWithTrace(ctx context.Context, metadata *messages.Metadata, carrier propagation.MapCarrier) {
+	span := trace.SpanFromContext(ctx)
+	if !span.SpanContext().IsValid() {
+		return
+	}
+
+	p.Propagator.Inject(ctx, carrier)
+	metadata.Traceparent = carrier["traceparent"]
+	metadata.Tracestate = carrier["tracestate"]
+
+	// This is the CURRENT span ID (the one sending the message)
+	metadata.SpanID = span.SpanContext().SpanID().String()
+}
+
+// Close flushes the messages being published and closes the client of the producer.
+func (p *Producer) Close(ctx context.Context) error {
+	err := p.client.Flush(ctx)
+	p.client.Close()
+	return err
+}
+
+// headerValue encodes the value of a header of the publish options.
+func headerValue(value any) []byte {
+	switch v := value.(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	default:
+		return []byte(fmt.Sprint(v))
+	}
+}
+
+// header returns the value of the header of the record, or nil if it has none.
+func header(record *kgo.Record, key string) []byte {
+	for _, h := range record.Headers {
+		if h.Key == key {
+			return h.Value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package kafka
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/enesanbar/go-service/core/config"
+	"github.com/enesanbar/go-service/core/info"
+)
+
+const (
+	AcksAll    = "all"
+	AcksLeader = "leader"
+	AcksNone   = "none"
+
+	DefaultDeliveryTimeout = 30 * time.Second
+)
+
+// ProducerConfig configures the Producer, under kafka.producer.
+type ProducerConfig struct {
+	// Connection is the name of the connection used by the producer, any connection by default.
+	Connection string
+	// Topic is the default topic of the messages, the name of the service by default.
+	Topic string
+	// Acks is the number of replicas that must receive the messages before they are acknowledged: all, leader or none.
+	Acks string
+	// DeliveryTimeout is the time the messages are retried for until they are acknowledged.
+	DeliveryTimeout time.Duration
+}
+
+func NewProducerConfig(cfg config.Config) (*ProducerConfig, error) {
+	keyTemplate := "kafka.producer.%s"
+
+	topic := cfg.GetString(fmt.Sprintf(keyTemplate, PropertyTopic))
+	if topic == "" {
+		topic = info.ServiceName
+	}
+
+	acks := cfg.GetString(fmt.Sprintf(keyTemplate, PropertyAcks))
+	switch acks {
+	case "":
+		acks = AcksAll
+	case AcksAll, AcksLeader, AcksNone:
+	default:
+		return nil, fmt.Errorf("invalid value '%s' for %s", acks, fmt.Sprintf(keyTemplate, PropertyAcks))
+	}
+
+	deliveryTimeout := DefaultDeliveryTimeout
+	property := fmt.Sprintf(keyTemplate, PropertyDeliveryTimeout)
+	if value := cfg.GetString(property); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value '%s' for %s: %w", value, property, err)
+		}
+		deliveryTimeout = d
+	}
+
+	return &ProducerConfig{
+		Connection:      cfg.GetString(fmt.Sprintf(keyTemplate, PropertyConnection)),
+		Topic:           topic,
+		Acks:            acks,
+		DeliveryTimeout: deliveryTimeout,
+	}, nil
+}
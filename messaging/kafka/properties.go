@@ -0,0 +1,21 @@
+package kafka
+
+const (
+	PropertyBrokers             = "brokers"
+	PropertyClientID            = "client-id"
+	PropertyConnection          = "connection"
+	PropertyPartitions          = "partitions"
+	PropertyReplicationFactor   = "replication-factor"
+	PropertyTopic               = "topic"
+	PropertyTopics              = "topics"
+	PropertyAcks                = "acks"
+	PropertyDeliveryTimeout     = "delivery-timeout"
+	PropertyGroup               = "group"
+	PropertyConcurrency         = "concurrency"
+	PropertyMaxPollRecords      = "max-poll-records"
+	PropertyMaxAttempts         = "max-attempts"
+	PropertyRetryDelay          = "retry-delay"
+	PropertyDeadLetterTopic     = "dead-letter-topic"
+	PropertySessionTimeout      = "session-timeout"
+	PropertyGracefulStopTimeout = "graceful-stop-timeout"
+)
@@ -0,0 +1,87 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/enesanbar/go-service/core/config"
+	"github.com/enesanbar/go-service/core/log"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultPartitions        = 1
+	DefaultReplicationFactor = 1
+)
+
+// declareTimeout is the time the brokers are waited for to create the topics.
+var declareTimeout = 30 * time.Second
+
+type TopicConfig struct {
+	Name string
+	// Connection is the name of the connection the topic is declared on, any connection by default.
+	Connection        string
+	Partitions        int32
+	ReplicationFactor int16
+}
+
+func NewTopicConfig(cfg config.Config, name string) (*TopicConfig, error) {
+	keyTemplate := "kafka.topics.%s.%s"
+
+	partitions := cfg.GetInt(fmt.Sprintf(keyTemplate, name, PropertyPartitions))
+	if partitions == 0 {
+		partitions = DefaultPartitions
+	}
+	if partitions < 0 {
+		return nil, fmt.Errorf("invalid value '%d' for %s", partitions, fmt.Sprintf(keyTemplate, name, PropertyPartitions))
+	}
+
+	replicationFactor := cfg.GetInt(fmt.Sprintf(keyTemplate, name, PropertyReplicationFactor))
+	if replicationFactor == 0 {
+		replicationFactor = DefaultReplicationFactor
+	}
+	if replicationFactor < 0 {
+		return nil, fmt.Errorf("invalid value '%d' for %s", replicationFactor, fmt.Sprintf(keyTemplate, name, PropertyReplicationFactor))
+	}
+
+	return &TopicConfig{
+		Name:              name,
+		Connection:        cfg.GetString(fmt.Sprintf(keyTemplate, name, PropertyConnection)),
+		Partitions:        int32(partitions),
+		ReplicationFactor: int16(replicationFactor),
+	}, nil
+}
+
+// Topics creates the topics configured in the configuration file, unless they exist.
+// The existing topics are left as they are, e.g. their partitions are not increased.
+func Topics(conf config.Config, logger log.Factory, connections map[string]*Connection) error {
+	ctx, cancel := context.WithTimeout(context.Background(), declareTimeout)
+	defer cancel()
+
+	for name := range conf.GetStringMap("kafka.topics") {
+		cfg, err := NewTopicConfig(conf, name)
+		if err != nil {
+			return err
+		}
+
+		conn := connection(connections, cfg.Connection)
+		if conn == nil {
+			return fmt.Errorf("connection %s not found for topic %s. please check the connection configuration in your configuration", cfg.Connection, name)
+		}
+
+		_, err = kadm.NewClient(conn.GetClient()).CreateTopic(ctx, cfg.Partitions, cfg.ReplicationFactor, nil, name)
+		if err != nil && !errors.Is(err, kerr.TopicAlreadyExists) {
+			return fmt.Errorf("failed to create topic %s: %w", name, err)
+		}
+
+		logger.Bg().
+			With(zap.String("topic", name)).
+			With(zap.String("connection", conn.Name())).
+			Info("declared kafka topic")
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package outbox
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/enesanbar/go-service/core/config"
+)
+
+const (
+	PropertyConnection      = "connection"
+	PropertyTable           = "table"
+	PropertyPollInterval    = "poll-interval"
+	PropertyBatchSize       = "batch-size"
+	PropertyRetention       = "retention"
+	PropertyCleanupInterval = "cleanup-interval"
+	PropertyMaxAttempts     = "max-attempts"
+
+	DefaultTable           = "outbox"
+	DefaultPollInterval    = time.Second
+	DefaultBatchSize       = 100
+	DefaultRetention       = 7 * 24 * time.Hour
+	DefaultCleanupInterval = time.Hour
+	DefaultMaxAttempts     = 10
+)
+
+// tableName restricts the table names, as they are written into the queries.
+var tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Config configures the outbox, under outbox.
+type Config struct {
+	// Connection is the name of the mysql connection of the outbox table, required if there are many connections.
+	Connection string
+	// Table is the name of the outbox table.
+	Table string
+	// PollInterval is the time the relay waits for when there are no pending messages.
+	PollInterval time.Duration
+	// BatchSize is the maximum number of messages relayed at once.
+	BatchSize int
+	// Retention is the time the sent messages are kept for, 0 keeps them forever.
+	Retention time.Duration
+	// CleanupInterval is the time between the deletions of the sent messages.
+	CleanupInterval time.Duration
+	// MaxAttempts is the number of times a message is published before it is parked,
+	// the parked messages are kept in the table but not relayed anymore.
+	MaxAttempts int
+}
+
+func NewConfig(cfg config.Config) (*Config, error) {
+	keyTemplate := "outbox.%s"
+
+	table := cfg.GetString(fmt.Sprintf(keyTemplate, PropertyTable))
+	if table == "" {
+		table = DefaultTable
+	}
+	if !tableName.MatchString(table) {
+		return nil, fmt.Errorf("invalid value '%s' for %s", table, fmt.Sprintf(keyTemplate, PropertyTable))
+	}
+
+	batchSize := cfg.GetInt(fmt.Sprintf(keyTemplate, PropertyBatchSize))
+	if batchSize == 0 {
+		batchSize = DefaultBatchSize
+	}
+	if batchSize < 0 {
+		return nil, fmt.Errorf("invalid value '%d' for %s", batchSize, fmt.Sprintf(keyTemplate, PropertyBatchSize))
+	}
+
+	pollInterval, err := duration(cfg, fmt.Sprintf(keyTemplate, PropertyPollInterval), DefaultPollInterval)
+	if err != nil {
+		return nil, err
+	}
+	if pollInterval <= 0 {
+		return nil, fmt.Errorf("invalid value '%s' for %s", pollInterval, fmt.Sprintf(keyTemplate, PropertyPollInterval))
+	}
+
+	retention, err := duration(cfg, fmt.Sprintf(keyTemplate, PropertyRetention), DefaultRetention)
+	if err != nil {
+		return nil, err
+	}
+
+	cleanupInterval, err := duration(cfg, fmt.Sprintf(keyTemplate, PropertyCleanupInterval), DefaultCleanupInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	maxAttempts := cfg.GetInt(fmt.Sprintf(keyTemplate, PropertyMaxAttempts))
+	if maxAttempts == 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	if maxAttempts < 0 {
+		return nil, fmt.Errorf("invalid value '%d' for %s", maxAttempts, fmt.Sprintf(keyTemplate, PropertyMaxAttempts))
+	}
+
+	return &Config{
+		Connection:      cfg.GetString(fmt.Sprintf(keyTemplate, PropertyConnection)),
+		Table:           table,
+		PollInterval:    pollInterval,
+		BatchSize:       batchSize,
+		Retention:       retention,
+		CleanupInterval: cleanupInterval,
+		MaxAttempts:     maxAttempts,
+	}, nil
+}
+
+// duration reads a duration property such as '500ms' or '1m30s'.
+func duration(cfg config.Config, property string, defaultValue time.Duration) (time.Duration, error) {
+	value := cfg.GetString(property)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value '%s' for %s: %w", value, property, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid value '%s' for %s", value, property)
+	}
+	return d, nil
+}
@@ -0,0 +1,132 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/enesanbar/go-service/core/config"
+	"github.com/enesanbar/go-service/core/messaging/consumer"
+	"github.com/enesanbar/go-service/persistence/mysql"
+	"go.uber.org/fx"
+)
+
+const (
+	PropertyInboxTable           = "inbox.table"
+	PropertyInboxCleanupInterval = "inbox.cleanup-interval"
+
+	DefaultInboxTable = "inbox"
+)
+
+// InboxConfig configures the InboxStore, under outbox.inbox. It uses the connection of the outbox.
+type InboxConfig struct {
+	// Connection is the name of the mysql connection of the inbox table, required if there are many connections.
+	Connection string
+	// Table is the name of the inbox table.
+	Table string
+	// CleanupInterval is the time between the deletions of the expired messages.
+	CleanupInterval time.Duration
+}
+
+func NewInboxConfig(cfg config.Config) (*InboxConfig, error) {
+	keyTemplate := "outbox.%s"
+
+	table := cfg.GetString(fmt.Sprintf(keyTemplate, PropertyInboxTable))
+	if table == "" {
+		table = DefaultInboxTable
+	}
+	if !tableName.MatchString(table) {
+		return nil, fmt.Errorf("invalid value '%s' for %s", table, fmt.Sprintf(keyTemplate, PropertyInboxTable))
+	}
+
+	cleanupInterval, err := duration(cfg, fmt.Sprintf(keyTemplate, PropertyInboxCleanupInterval), DefaultCleanupInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InboxConfig{
+		Connection:      cfg.GetString(fmt.Sprintf(keyTemplate, PropertyConnection)),
+		Table:           table,
+		CleanupInterval: cleanupInterval,
+	}, nil
+}
+
+// InboxStore records the messages processed by the handlers in a mysql table, as a consumer.ProcessedStore.
+// The expired messages are deleted while the messages are recorded, once in the cleanup interval.
+type InboxStore struct {
+	DB     *sql.DB
+	Config *InboxConfig
+
+	processed     string
+	markProcessed string
+	cleanup       string
+
+	mu          sync.Mutex
+	now         func() time.Time
+	lastCleanup time.Time
+}
+
+type InboxStoreParams struct {
+	fx.In
+
+	Connections map[string]*mysql.Connection
+	Config      *InboxConfig
+}
+
+// NewInboxStore creates a pointer to the new instance of the InboxStore
+func NewInboxStore(p InboxStoreParams) (*InboxStore, error) {
+	conn, err := connection(p.Connections, p.Config.Connection)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InboxStore{
+		DB:     conn.GetConn(),
+		Config: p.Config,
+		// REPLACE keeps the latest expiration of a message that is processed again after it is expired
+		processed:     fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE message_key = ? AND expires_at > ?", p.Config.Table),
+		markProcessed: fmt.Sprintf("REPLACE INTO %s (message_key, expires_at) VALUES (?, ?)", p.Config.Table),
+		cleanup:       fmt.Sprintf("DELETE FROM %s WHERE expires_at <= ?", p.Config.Table),
+		now:           time.Now,
+	}, nil
+}
+
+func (s *InboxStore) IsProcessed(ctx context.Context, key string) (bool, error) {
+	var count int
+	if err := s.DB.QueryRowContext(ctx, s.processed, key, s.now().UTC()).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to read the inbox: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (s *InboxStore) MarkProcessed(ctx context.Context, key string, retention time.Duration) error {
+	now := s.now().UTC()
+	if _, err := s.DB.ExecContext(ctx, s.markProcessed, key, now.Add(retention)); err != nil {
+		return fmt.Errorf("failed to write to the inbox: %w", err)
+	}
+
+	s.mu.Lock()
+	if now.Sub(s.lastCleanup) < s.Config.CleanupInterval {
+		s.mu.Unlock()
+		return nil
+	}
+	s.lastCleanup = now
+	s.mu.Unlock()
+
+	if _, err := s.DB.ExecContext(ctx, s.cleanup, now); err != nil {
+		return fmt.Errorf("failed to delete the expired messages of the inbox: %w", err)
+	}
+	return nil
+}
+
+// InboxModule records the messages processed by the handlers in the inbox table.
+// It is used along with consumer.DeduplicationModule.
+var InboxModule = fx.Provide(
+	NewInboxConfig,
+	fx.Annotate(
+		NewInboxStore,
+		fx.As(new(consumer.ProcessedStore)),
+	),
+)
@@ -0,0 +1,72 @@
+package outbox
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/enesanbar/go-service/core/config/configtest"
+	"github.com/enesanbar/go-service/persistence/mysql"
+)
+
+const inboxSchema = `CREATE TABLE inbox (
+	message_key VARCHAR(255) NOT NULL PRIMARY KEY,
+	expires_at DATETIME NOT NULL
+)`
+
+func newTestInbox(t *testing.T) *InboxStore {
+	t.Helper()
+
+	cfg, err := NewInboxConfig(configtest.New(t, map[string]any{}))
+	if err != nil {
+		t.Fatalf("unable to create config: %v", err)
+	}
+
+	db := newTestDB(t)
+	if _, err := db.Exec(inboxSchema); err != nil {
+		t.Fatalf("unable to create the inbox table: %v", err)
+	}
+
+	store, err := NewInboxStore(InboxStoreParams{
+		Connections: map[string]*mysql.Connection{
+			"default": {Conn: db, Config: &mysql.Config{Name: "default", Database: "test"}},
+		},
+		Config: cfg,
+	})
+	if err != nil {
+		t.Fatalf("unable to create inbox store: %v", err)
+	}
+	return store
+}
+
+func TestInboxStore_MarkProcessed(t *testing.T) {
+	store := newTestInbox(t)
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if processed, err := store.IsProcessed(ctx, "orders:message-1"); err != nil || processed {
+		t.Fatalf("Expected the message not to be processed, got '%t' with '%v'", processed, err)
+	}
+
+	if err := store.MarkProcessed(ctx, "orders:message-1", time.Hour); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if processed, err := store.IsProcessed(ctx, "orders:message-1"); err != nil || !processed {
+		t.Errorf("Expected the message to be processed, got '%t' with '%v'", processed, err)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if processed, _ := store.IsProcessed(ctx, "orders:message-1"); processed {
+		t.Errorf("Expected the message to be forgotten after the retention")
+	}
+
+	// the expired message is deleted while another one is recorded
+	if err := store.MarkProcessed(ctx, "orders:message-2", time.Hour); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	var count int
+	if err := store.DB.QueryRow("SELECT COUNT(*) FROM inbox").Scan(&count); err != nil || count != 1 {
+		t.Errorf("Expected '%d' message to be kept, got '%d' with '%v'", 1, count, err)
+	}
+}
@@ -0,0 +1,42 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// locker makes sure that a single relay publishes the messages of a table at a time,
+// otherwise the messages with the same ordering key could be published out of order.
+type locker interface {
+	// lock returns false if the lock is held by another relay.
+	lock(ctx context.Context, db *sql.DB) (unlock func(), acquired bool, err error)
+}
+
+// namedLock is a mysql named lock, held by a session, i.e. a single connection of the pool.
+type namedLock struct {
+	name string
+}
+
+func (l namedLock) lock(ctx context.Context, db *sql.DB) (func(), bool, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get a connection for the lock %s: %w", l.name, err)
+	}
+
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", l.name).Scan(&acquired); err != nil {
+		_ = conn.Close()
+		return nil, false, fmt.Errorf("failed to get the lock %s: %w", l.name, err)
+	}
+	if !acquired.Valid || acquired.Int64 != 1 {
+		_ = conn.Close()
+		return nil, false, nil
+	}
+
+	return func() {
+		var released sql.NullInt64
+		_ = conn.QueryRowContext(context.Background(), "SELECT RELEASE_LOCK(?)", l.name).Scan(&released)
+		_ = conn.Close()
+	}, true, nil
+}
@@ -0,0 +1,30 @@
+package outbox
+
+import (
+	"github.com/enesanbar/go-service/core/service"
+	"github.com/enesanbar/go-service/core/wiring"
+	"go.uber.org/fx"
+)
+
+// Module provides the outbox *Producer and starts the Relay.
+// It requires the mysql connection of the outbox table and a producer.Producer of the broker,
+// e.g. with mysql.Option() and rabbitmq.ProducerModule.
+var Module = fx.Module(
+	"messaging.outbox",
+	fx.Provide(
+		NewConfig,
+		NewProducer,
+		NewRelay,
+		fx.Annotate(
+			func(r *Relay) wiring.Runnable { return r },
+			fx.ResultTags(`group:"runnables"`),
+		),
+	),
+)
+
+func Option(options ...fx.Option) service.Option {
+	return func(cfg *service.AppConfig) {
+		cfg.Options = append(cfg.Options, Module)
+		cfg.Options = append(cfg.Options, options...)
+	}
+}
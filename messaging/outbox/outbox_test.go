@@ -0,0 +1,470 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/enesanbar/go-service/core/config/configtest"
+	"github.com/enesanbar/go-service/core/log"
+	"github.com/enesanbar/go-service/core/messaging/codec"
+	"github.com/enesanbar/go-service/core/messaging/messages"
+	"github.com/enesanbar/go-service/core/messaging/producer"
+	"github.com/enesanbar/go-service/persistence/mysql"
+	"go.opentelemetry.io/otel/propagation"
+	"go.uber.org/zap"
+	_ "modernc.org/sqlite"
+)
+
+// schema is the sqlite counterpart of the mysql table in the README.
+const schema = `CREATE TABLE outbox (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	message_id VARCHAR(64) NOT NULL,
+	message_name VARCHAR(255) NOT NULL,
+	ordering_key VARCHAR(255) NOT NULL DEFAULT '',
+	payload BLOB NOT NULL,
+	properties BLOB NOT NULL,
+	attempts INT NOT NULL DEFAULT 0,
+	last_error TEXT NULL,
+	created_at DATETIME NOT NULL,
+	sent_at DATETIME NULL
+)`
+
+type orderCreated struct {
+	ID int `json:"id"`
+}
+
+// published is a message published by the fakeProducer.
+type published struct {
+	messageName string
+	payload     json.RawMessage
+	options     producer.PublishOptions
+}
+
+// fakeProducer records the published messages, and fails the messages with the ids in fail.
+type fakeProducer struct {
+	mu        sync.Mutex
+	published []published
+	fail      map[string]bool
+	// attempts counts the calls to Publish, including the failed ones
+	attempts int
+}
+
+func (p *fakeProducer) Publish(_ context.Context, messageName string, message any, options ...producer.PublishOption) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.attempts++
+	o := producer.NewPublishOptions(options...)
+	if p.fail[o.MessageID] {
+		return producer.ErrNacked
+	}
+	p.published = append(p.published, published{messageName: messageName, payload: message.(json.RawMessage), options: o})
+	return nil
+}
+
+func (p *fakeProducer) failing(ids ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.fail = make(map[string]bool)
+	for _, id := range ids {
+		p.fail[id] = true
+	}
+}
+
+func (p *fakeProducer) publishAttempts() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.attempts
+}
+
+func (p *fakeProducer) messageIDs() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ids := make([]string, 0, len(p.published))
+	for _, message := range p.published {
+		ids = append(ids, message.options.MessageID)
+	}
+	return ids
+}
+
+// noLock lets the relay run on sqlite, which has no named locks.
+type noLock struct{}
+
+func (noLock) lock(context.Context, *sql.DB) (func(), bool, error) {
+	return func() {}, true, nil
+}
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "outbox.db"))
+	if err != nil {
+		t.Fatalf("unable to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatalf("unable to create the outbox table: %v", err)
+	}
+	return db
+}
+
+func newTestOutbox(t *testing.T, properties map[string]any) (*sql.DB, *Producer, *Relay, *fakeProducer) {
+	t.Helper()
+
+	cfg, err := NewConfig(configtest.New(t, properties))
+	if err != nil {
+		t.Fatalf("unable to create config: %v", err)
+	}
+
+	db := newTestDB(t)
+	broker := &fakeProducer{}
+	relay, err := NewRelay(RelayParams{
+		Logger: log.NewFactory(zap.NewNop()),
+		Connections: map[string]*mysql.Connection{
+			"default": {Conn: db, Config: &mysql.Config{Name: "default", Database: "test"}},
+		},
+		Producer:   broker,
+		Propagator: propagation.TraceContext{},
+		Config:     cfg,
+	})
+	if err != nil {
+		t.Fatalf("unable to create relay: %v", err)
+	}
+	relay.locker = noLock{}
+
+	p := NewProducer(ProducerParams{Config: cfg, Propagator: propagation.TraceContext{}})
+	return db, p, relay, broker
+}
+
+// publishInTx publishes the messages in a transaction, committed unless rollback is set.
+func publishInTx(t *testing.T, db *sql.DB, p *Producer, rollback bool, publishings ...producer.Publishing) {
+	t.Helper()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("unable to begin transaction: %v", err)
+	}
+	if err := p.PublishBatch(WithTx(context.Background(), tx), publishings); err != nil {
+		_ = tx.Rollback()
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	if rollback {
+		err = tx.Rollback()
+	} else {
+		err = tx.Commit()
+	}
+	if err != nil {
+		t.Fatalf("unable to end transaction: %v", err)
+	}
+}
+
+func orderPublishing(id int, options ...producer.PublishOption) producer.Publishing {
+	return producer.Publishing{MessageName: "order-created", Message: orderCreated{ID: id}, Options: options}
+}
+
+func countRows(t *testing.T, db *sql.DB, where string) int {
+	t.Helper()
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM outbox WHERE " + where).Scan(&count); err != nil {
+		t.Fatalf("unable to count rows: %v", err)
+	}
+	return count
+}
+
+func TestNewConfig(t *testing.T) {
+	cfg, err := NewConfig(configtest.New(t, map[string]any{
+		"outbox.table":         "events_outbox",
+		"outbox.poll-interval": "100ms",
+		"outbox.retention":     "0s",
+	}))
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if cfg.Table != "events_outbox" || cfg.PollInterval != 100*time.Millisecond || cfg.Retention != 0 || cfg.BatchSize != DefaultBatchSize || cfg.MaxAttempts != DefaultMaxAttempts {
+		t.Errorf("Expected the configured values and the default batch size and max attempts, got '%+v'", cfg)
+	}
+
+	_, err = NewConfig(configtest.New(t, map[string]any{"outbox.table": "outbox; DROP TABLE users"}))
+	if err == nil {
+		t.Error("Expected an error for an invalid table name")
+	}
+
+	_, err = NewConfig(configtest.New(t, map[string]any{"outbox.max-attempts": -1}))
+	if err == nil {
+		t.Error("Expected an error for negative max attempts")
+	}
+}
+
+func TestProducer_PublishRequiresTransaction(t *testing.T) {
+	_, p, _, _ := newTestOutbox(t, map[string]any{})
+
+	err := p.Publish(context.Background(), "order-created", orderCreated{ID: 1})
+	if !errors.Is(err, ErrNoTransaction) {
+		t.Errorf("Expected '%v', got '%v'", ErrNoTransaction, err)
+	}
+}
+
+func TestProducer_PublishInTransaction(t *testing.T) {
+	db, p, _, _ := newTestOutbox(t, map[string]any{})
+
+	publishInTx(t, db, p, true, orderPublishing(1))
+	if count := countRows(t, db, "1 = 1"); count != 0 {
+		t.Errorf("Expected '%d' messages after rollback, got '%d'", 0, count)
+	}
+
+	publishInTx(t, db, p, false, orderPublishing(1, producer.WithOrderingKey("order-1"), producer.WithMessageID("message-1")))
+	if count := countRows(t, db, "message_id = 'message-1' AND ordering_key = 'order-1' AND sent_at IS NULL"); count != 1 {
+		t.Errorf("Expected '%d' pending message after commit, got '%d'", 1, count)
+	}
+}
+
+func TestProducer_PublishRejectsNonJSONPayloads(t *testing.T) {
+	db, p, relay, broker := newTestOutbox(t, map[string]any{})
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("unable to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	err = p.Publish(WithTx(context.Background(), tx), "order-created", orderCreated{ID: 1}, producer.WithContentType(codec.ContentTypeProtobuf))
+	if !errors.Is(err, codec.ErrUnsupportedContentType) {
+		t.Errorf("Expected '%v', got '%v'", codec.ErrUnsupportedContentType, err)
+	}
+
+	// the structured syntaxes of JSON are kept
+	if err := p.Publish(WithTx(context.Background(), tx), "order-created", orderCreated{ID: 1}, producer.WithContentType("application/vnd.order+json")); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("unable to commit transaction: %v", err)
+	}
+	if _, err := relay.relay(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if len(broker.published) != 1 || broker.published[0].options.ContentType != "application/vnd.order+json" {
+		t.Errorf("Expected the message to be published with its content type, got '%+v'", broker.published)
+	}
+}
+
+func TestRelay_PublishesPendingMessages(t *testing.T) {
+	db, p, relay, broker := newTestOutbox(t, map[string]any{})
+
+	publishInTx(t, db, p, false,
+		orderPublishing(1,
+			producer.WithMessageID("message-1"),
+			producer.WithExchange("shop"),
+			producer.WithRoutingKey("orders.created"),
+			producer.WithHeader("tenant", "acme"),
+			producer.WithMandatory(true),
+		),
+	)
+
+	relayed, err := relay.relay(context.Background())
+	if err != nil || relayed != 1 {
+		t.Fatalf("Expected '%d' message to be relayed, got '%d' with '%v'", 1, relayed, err)
+	}
+
+	if len(broker.published) != 1 {
+		t.Fatalf("Expected '%d' published message, got '%d'", 1, len(broker.published))
+	}
+	message := broker.published[0]
+	if message.messageName != "order-created" || string(message.payload) != `{"id":1}` {
+		t.Errorf("Expected the message to be published as written, got '%s' with '%s'", message.messageName, message.payload)
+	}
+	o := message.options
+	if o.MessageID != "message-1" || o.Exchange != "shop" || o.RoutingKey != "orders.created" || o.Headers["tenant"] != "acme" || o.Mandatory == nil || !*o.Mandatory || o.ContentType != codec.ContentTypeJSON {
+		t.Errorf("Expected the options to be published as written, got '%+v'", o)
+	}
+
+	if count := countRows(t, db, "sent_at IS NOT NULL"); count != 1 {
+		t.Errorf("Expected '%d' message to be marked sent, got '%d'", 1, count)
+	}
+
+	if relayed, _ := relay.relay(context.Background()); relayed != 0 || len(broker.published) != 1 {
+		t.Errorf("Expected sent messages not to be published again, got '%d' published", len(broker.published))
+	}
+}
+
+func TestRelay_PublishesCorrelatedMessages(t *testing.T) {
+	db, p, relay, broker := newTestOutbox(t, map[string]any{})
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("unable to begin transaction: %v", err)
+	}
+	ctx := messages.WithMetadata(WithTx(context.Background(), tx), messages.Metadata{MessageID: "message-1"})
+	if err := p.Publish(ctx, "order-shipped", orderCreated{ID: 1}, producer.WithMessageID("message-2")); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("unable to commit transaction: %v", err)
+	}
+
+	if _, err := relay.relay(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	o := broker.published[0].options
+	if o.CorrelationID != "message-1" || o.CausationID != "message-1" {
+		t.Errorf("Expected the message to be caused by '%s', got '%+v'", "message-1", o)
+	}
+}
+
+func TestRelay_OrderingKey(t *testing.T) {
+	db, p, relay, broker := newTestOutbox(t, map[string]any{})
+
+	publishInTx(t, db, p, false,
+		orderPublishing(1, producer.WithMessageID("a-1"), producer.WithOrderingKey("a")),
+		orderPublishing(2, producer.WithMessageID("b-1"), producer.WithOrderingKey("b")),
+		orderPublishing(3, producer.WithMessageID("a-2"), producer.WithOrderingKey("a")),
+		orderPublishing(4, producer.WithMessageID("b-2"), producer.WithOrderingKey("b")),
+		orderPublishing(5, producer.WithMessageID("a-3"), producer.WithOrderingKey("a")),
+	)
+
+	broker.failing("a-2")
+	if _, err := relay.relay(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	published := make(map[string]bool)
+	for _, id := range broker.messageIDs() {
+		published[id] = true
+	}
+	if len(published) != 3 || !published["a-1"] || !published["b-1"] || !published["b-2"] {
+		t.Errorf("Expected the messages before the failed one and of the other keys to be published, got '%v'", broker.messageIDs())
+	}
+	if count := countRows(t, db, "message_id = 'a-2' AND attempts = 1 AND last_error IS NOT NULL"); count != 1 {
+		t.Errorf("Expected the failure of the message to be recorded")
+	}
+
+	broker.failing()
+	if _, err := relay.relay(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	ids := broker.messageIDs()
+	if len(ids) != 5 || ids[3] != "a-2" || ids[4] != "a-3" {
+		t.Errorf("Expected the held back messages to be published in order, got '%v'", ids)
+	}
+}
+
+func TestRelay_ParksFailingMessages(t *testing.T) {
+	db, p, relay, broker := newTestOutbox(t, map[string]any{"outbox.batch-size": 2, "outbox.max-attempts": 3})
+
+	publishInTx(t, db, p, false,
+		orderPublishing(1, producer.WithMessageID("failing-1")),
+		orderPublishing(2, producer.WithMessageID("failing-2")),
+		orderPublishing(3, producer.WithMessageID("message-3")),
+	)
+	broker.failing("failing-1", "failing-2")
+
+	// the failing messages fill the batches until they are parked
+	for i := 0; i < 3; i++ {
+		if relayed, _ := relay.relay(context.Background()); relayed != 0 {
+			t.Fatalf("Expected no message to be relayed, got '%d'", relayed)
+		}
+	}
+	if count := countRows(t, db, "attempts = 3 AND sent_at IS NULL"); count != 2 {
+		t.Errorf("Expected '%d' parked messages, got '%d'", 2, count)
+	}
+
+	relayed, err := relay.relay(context.Background())
+	if err != nil || relayed != 1 {
+		t.Fatalf("Expected '%d' message to be relayed, got '%d' with '%v'", 1, relayed, err)
+	}
+	if ids := broker.messageIDs(); len(ids) != 1 || ids[0] != "message-3" {
+		t.Errorf("Expected the message behind the parked ones to be published, got '%v'", ids)
+	}
+
+	attempts := broker.publishAttempts()
+	if relayed, _ := relay.relay(context.Background()); relayed != 0 || broker.publishAttempts() != attempts {
+		t.Errorf("Expected the parked messages not to be published again, got '%d' attempts", broker.publishAttempts()-attempts)
+	}
+}
+
+func TestRelay_WaitsWhenBatchFails(t *testing.T) {
+	db, p, relay, broker := newTestOutbox(t, map[string]any{
+		"outbox.batch-size":    1,
+		"outbox.poll-interval": "50ms",
+		"outbox.max-attempts":  1000,
+	})
+
+	publishInTx(t, db, p, false, orderPublishing(1, producer.WithMessageID("failing-1")))
+	broker.failing("failing-1")
+
+	go func() { _ = relay.Start(context.Background()) }()
+	time.Sleep(200 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := relay.Stop(ctx); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	// a batch is tried once in the poll interval
+	if attempts := broker.publishAttempts(); attempts < 1 || attempts > 10 {
+		t.Errorf("Expected the failing message to be published once in the poll interval, got '%d' attempts", attempts)
+	}
+}
+
+func TestRelay_Cleanup(t *testing.T) {
+	db, p, relay, _ := newTestOutbox(t, map[string]any{"outbox.retention": "24h"})
+
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	relay.now = func() time.Time { return now }
+
+	publishInTx(t, db, p, false, orderPublishing(1), orderPublishing(2), orderPublishing(3))
+	if _, err := db.Exec("UPDATE outbox SET sent_at = ? WHERE id = 1", now.Add(-48*time.Hour)); err != nil {
+		t.Fatalf("unable to update message: %v", err)
+	}
+	if _, err := db.Exec("UPDATE outbox SET sent_at = ? WHERE id = 2", now.Add(-time.Hour)); err != nil {
+		t.Fatalf("unable to update message: %v", err)
+	}
+
+	if err := relay.cleanup(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	if count := countRows(t, db, "1 = 1"); count != 2 {
+		t.Errorf("Expected '%d' messages to be kept, got '%d'", 2, count)
+	}
+	if count := countRows(t, db, "id = 1"); count != 0 {
+		t.Errorf("Expected the message sent before the retention to be deleted")
+	}
+}
+
+func TestRelay_StartStop(t *testing.T) {
+	db, p, relay, broker := newTestOutbox(t, map[string]any{"outbox.poll-interval": "10ms"})
+
+	go func() { _ = relay.Start(context.Background()) }()
+
+	publishInTx(t, db, p, false, orderPublishing(1), orderPublishing(2))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(broker.messageIDs()) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected '%d' messages to be published, got '%d'", 2, len(broker.messageIDs()))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := relay.Stop(ctx); err != nil {
+		t.Errorf("Expected no error, got '%v'", err)
+	}
+}
@@ -0,0 +1,187 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/enesanbar/go-service/core/messaging/codec"
+	"github.com/enesanbar/go-service/core/messaging/messages"
+	"github.com/enesanbar/go-service/core/messaging/producer"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/propagation"
+	"go.uber.org/fx"
+)
+
+// ErrNoTransaction is returned when the message is published without a transaction, see WithTx.
+var ErrNoTransaction = errors.New("no transaction found in the context")
+
+type txKey struct{}
+
+// WithTx returns a context that makes the Producer write the messages in the transaction,
+// so that they are relayed only if the transaction is committed.
+func WithTx(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+func txFromContext(ctx context.Context) (*sql.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(*sql.Tx)
+	return tx, ok && tx != nil
+}
+
+// Producer writes the messages into the outbox table, in the transaction of the context.
+// The messages are published to the broker later by the Relay.
+type Producer struct {
+	Config     *Config
+	Propagator propagation.TextMapPropagator
+
+	insert string
+}
+
+type ProducerParams struct {
+	fx.In
+
+	Config     *Config
+	Propagator propagation.TextMapPropagator
+}
+
+// NewProducer creates a pointer to the new instance of the Producer
+func NewProducer(p ProducerParams) *Producer {
+	return &Producer{
+		Config:     p.Config,
+		Propagator: p.Propagator,
+		insert: fmt.Sprintf(
+			"INSERT INTO %s (message_id, message_name, ordering_key, payload, properties, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+			p.Config.Table,
+		),
+	}
+}
+
+// Publish writes the message into the outbox table.
+// It returns ErrNoTransaction if the context is not created with WithTx.
+func (p *Producer) Publish(ctx context.Context, messageName string, message any, options ...producer.PublishOption) error {
+	return p.PublishBatch(ctx, []producer.Publishing{{
+		MessageName: messageName,
+		Message:     message,
+		Options:     options,
+	}})
+}
+
+// PublishBatch writes the messages into the outbox table, stopping at the first message that cannot be written.
+// The payloads are stored as JSON, the messages with another content type are rejected, see producer.WithContentType.
+func (p *Producer) PublishBatch(ctx context.Context, publishings []producer.Publishing) error {
+	tx, ok := txFromContext(ctx)
+	if !ok {
+		return ErrNoTransaction
+	}
+
+	createdAt := time.Now().UTC()
+	for _, publishing := range publishings {
+		options := producer.NewPublishOptions(publishing.Options...)
+		if !codec.IsJSON(options.ContentType) {
+			return fmt.Errorf("%w: the outbox stores JSON payloads only, got %s for message %s",
+				codec.ErrUnsupportedContentType, options.ContentType, publishing.MessageName)
+		}
+
+		payload, err := json.Marshal(publishing.Message)
+		if err != nil {
+			return fmt.Errorf("failed to marshal message %s: %w", publishing.MessageName, err)
+		}
+
+		messageID := options.MessageID
+		if messageID == "" {
+			messageID = uuid.NewString()
+		}
+
+		// the message is correlated with the message being handled now, not when it is relayed
+		metadata := messages.Metadata{
+			MessageID:     messageID,
+			CorrelationID: options.CorrelationID,
+			CausationID:   options.CausationID,
+		}
+		metadata.Correlate(ctx)
+
+		props := newProperties(options, metadata)
+		// the relay publishes the message in the trace it is written in
+		p.Propagator.Inject(ctx, propagation.MapCarrier(props.Trace))
+		encodedProps, err := json.Marshal(props)
+		if err != nil {
+			return fmt.Errorf("failed to marshal the options of message %s: %w", publishing.MessageName, err)
+		}
+
+		_, err = tx.ExecContext(ctx, p.insert, messageID, publishing.MessageName, options.OrderingKey, payload, encodedProps, createdAt)
+		if err != nil {
+			return fmt.Errorf("failed to write message %s to the outbox: %w", publishing.MessageName, err)
+		}
+	}
+	return nil
+}
+
+// properties are the publish options stored along with a message, to be applied by the relay.
+type properties struct {
+	Exchange      string            `json:"exchange,omitempty"`
+	RoutingKey    string            `json:"routing_key,omitempty"`
+	Headers       map[string]any    `json:"headers,omitempty"`
+	Priority      uint8             `json:"priority,omitempty"`
+	Expiration    time.Duration     `json:"expiration,omitempty"`
+	Persistent    *bool             `json:"persistent,omitempty"`
+	Mandatory     *bool             `json:"mandatory,omitempty"`
+	CorrelationID string            `json:"correlation_id,omitempty"`
+	CausationID   string            `json:"causation_id,omitempty"`
+	Version       int               `json:"version,omitempty"`
+	ContentType   string            `json:"content_type,omitempty"`
+	Trace         map[string]string `json:"trace,omitempty"`
+}
+
+func newProperties(options producer.PublishOptions, metadata messages.Metadata) properties {
+	return properties{
+		Exchange:      options.Exchange,
+		RoutingKey:    options.RoutingKey,
+		Headers:       options.Headers,
+		Priority:      options.Priority,
+		Expiration:    options.Expiration,
+		Persistent:    options.Persistent,
+		Mandatory:     options.Mandatory,
+		CorrelationID: metadata.CorrelationID,
+		CausationID:   metadata.CausationID,
+		Version:       options.MessageVersion,
+		ContentType:   options.ContentType,
+		Trace:         make(map[string]string),
+	}
+}
+
+// options returns the publish options of the message, the message id and the ordering key are stored in their columns.
+// The payload is published with the JSON codec whatever the content type of the producer of the broker,
+// as it is stored as JSON.
+func (p properties) options(messageID, orderingKey string) []producer.PublishOption {
+	contentType := p.ContentType
+	if contentType == "" {
+		contentType = codec.ContentTypeJSON
+	}
+
+	options := []producer.PublishOption{
+		producer.WithMessageID(messageID),
+		producer.WithOrderingKey(orderingKey),
+		producer.WithExchange(p.Exchange),
+		producer.WithRoutingKey(p.RoutingKey),
+		producer.WithPriority(p.Priority),
+		producer.WithExpiration(p.Expiration),
+		producer.WithCorrelationID(p.CorrelationID),
+		producer.WithCausationID(p.CausationID),
+		producer.WithMessageVersion(p.Version),
+		producer.WithContentType(contentType),
+	}
+	for name, value := range p.Headers {
+		options = append(options, producer.WithHeader(name, value))
+	}
+	if p.Persistent != nil {
+		options = append(options, producer.WithPersistence(*p.Persistent))
+	}
+	if p.Mandatory != nil {
+		options = append(options, producer.WithMandatory(*p.Mandatory))
+	}
+	return options
+}
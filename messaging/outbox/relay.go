@@ -0,0 +1,340 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/enesanbar/go-service/core/log"
+	"github.com/enesanbar/go-service/core/messaging/producer"
+	"github.com/enesanbar/go-service/persistence/mysql"
+	"go.opentelemetry.io/otel/propagation"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// Relay publishes the messages written by the Producer to the broker, and marks them sent.
+// A message is published at least once: it is published again if it cannot be marked sent.
+//
+// The messages with the same ordering key are published in the order they are written,
+// a message that cannot be published holds back the later messages with its key until it is published or parked.
+// The messages without an ordering key are published concurrently.
+//
+// A message that fails Config.MaxAttempts times is parked: it is kept in the table, but not relayed anymore,
+// so that it does not hold back the later messages forever.
+type Relay struct {
+	Logger     log.Factory
+	DB         *sql.DB
+	Producer   producer.Producer
+	Propagator propagation.TextMapPropagator
+	Config     *Config
+
+	locker      locker
+	now         func() time.Time
+	lastCleanup time.Time
+
+	queries   queries
+	stop      chan struct{}
+	stopOnce  sync.Once
+	done      chan struct{}
+	startOnce sync.Once
+}
+
+type RelayParams struct {
+	fx.In
+
+	Logger      log.Factory
+	Connections map[string]*mysql.Connection
+	Producer    producer.Producer
+	Propagator  propagation.TextMapPropagator
+	Config      *Config
+}
+
+// NewRelay creates a pointer to the new instance of the Relay
+func NewRelay(p RelayParams) (*Relay, error) {
+	conn, err := connection(p.Connections, p.Config.Connection)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Relay{
+		Logger:     p.Logger,
+		DB:         conn.GetConn(),
+		Producer:   p.Producer,
+		Propagator: p.Propagator,
+		Config:     p.Config,
+		locker:     namedLock{name: fmt.Sprintf("%s.%s", conn.Config.Database, p.Config.Table)},
+		now:        time.Now,
+		queries:    newQueries(p.Config.Table),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}, nil
+}
+
+// connection returns the mysql connection of the outbox table.
+func connection(connections map[string]*mysql.Connection, name string) (*mysql.Connection, error) {
+	if name != "" {
+		conn := connections[name]
+		if conn == nil {
+			return nil, fmt.Errorf("connection %s not found for outbox. please check the connection configuration in your configuration", name)
+		}
+		return conn, nil
+	}
+
+	if len(connections) != 1 {
+		return nil, fmt.Errorf("outbox.%s is required when there are %d mysql connections", PropertyConnection, len(connections))
+	}
+	for _, conn := range connections {
+		return conn, nil
+	}
+	return nil, nil
+}
+
+type queries struct {
+	pending    string
+	markSent   string
+	markFailed string
+	cleanup    string
+}
+
+func newQueries(table string) queries {
+	return queries{
+		pending: fmt.Sprintf(
+			"SELECT id, message_id, message_name, ordering_key, payload, properties, attempts FROM %s WHERE sent_at IS NULL AND attempts < ? ORDER BY id LIMIT ?",
+			table,
+		),
+		markSent:   fmt.Sprintf("UPDATE %s SET sent_at = ? WHERE id IN (%%s)", table),
+		markFailed: fmt.Sprintf("UPDATE %s SET attempts = attempts + 1, last_error = ? WHERE id = ?", table),
+		cleanup:    fmt.Sprintf("DELETE FROM %s WHERE sent_at IS NOT NULL AND sent_at < ?", table),
+	}
+}
+
+// record is a message in the outbox table.
+type record struct {
+	id          int64
+	messageID   string
+	messageName string
+	orderingKey string
+	payload     []byte
+	properties  []byte
+	attempts    int
+}
+
+// failure is a record that could not be published.
+type failure struct {
+	record record
+	err    error
+}
+
+// Start relays the messages until the relay is stopped.
+func (r *Relay) Start(ctx context.Context) error {
+	r.startOnce.Do(func() {
+		defer close(r.done)
+
+		r.Logger.For(ctx).With(zap.String("table", r.Config.Table)).Info("starting outbox relay")
+
+		// the context of the application start is canceled once the application is started
+		ctx := context.WithoutCancel(ctx)
+		for {
+			relayed, err := r.relay(ctx)
+			if err != nil {
+				r.Logger.For(ctx).With(zap.Error(err)).Error("failed to relay outbox messages")
+			}
+
+			if err := r.cleanup(ctx); err != nil {
+				r.Logger.For(ctx).With(zap.Error(err)).Error("failed to delete sent outbox messages")
+			}
+
+			// there might be more pending messages, unless some of the batch failed,
+			// in which case the relay waits for the poll interval before trying them again
+			if err == nil && relayed == r.Config.BatchSize {
+				select {
+				case <-r.stop:
+					return
+				default:
+					continue
+				}
+			}
+
+			select {
+			case <-r.stop:
+				return
+			case <-time.After(r.Config.PollInterval):
+			}
+		}
+	})
+	return nil
+}
+
+// Stop stops polling, waiting for the messages being relayed to be published.
+func (r *Relay) Stop(ctx context.Context) error {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("outbox relay is not stopped: %w", ctx.Err())
+	}
+}
+
+// relay publishes a batch of pending messages, and returns the number of the messages it published.
+func (r *Relay) relay(ctx context.Context) (int, error) {
+	unlock, acquired, err := r.locker.lock(ctx, r.DB)
+	if err != nil || !acquired {
+		return 0, err
+	}
+	defer unlock()
+
+	records, err := r.pending(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		mu     sync.Mutex
+		sent   []int64
+		failed []failure
+		wg     sync.WaitGroup
+	)
+	for _, group := range groupByOrderingKey(records) {
+		wg.Add(1)
+		go func(group []record) {
+			defer wg.Done()
+			for _, rec := range group {
+				err := r.publish(ctx, rec)
+
+				mu.Lock()
+				if err != nil {
+					failed = append(failed, failure{record: rec, err: err})
+				} else {
+					sent = append(sent, rec.id)
+				}
+				mu.Unlock()
+
+				if err != nil {
+					// the later messages with the same key wait for this one
+					return
+				}
+			}
+		}(group)
+	}
+	wg.Wait()
+
+	var errs []error
+	if err := r.markSent(ctx, sent); err != nil {
+		errs = append(errs, err)
+	}
+	for _, f := range failed {
+		logger := r.Logger.For(ctx).With(zap.Int64("id", f.record.id), zap.String("message_id", f.record.messageID), zap.Error(f.err))
+		if f.record.attempts+1 >= r.Config.MaxAttempts {
+			logger.Error(fmt.Sprintf("failed to publish outbox message %d times, parking it", r.Config.MaxAttempts))
+		} else {
+			logger.Error("failed to publish outbox message")
+		}
+		if _, err := r.DB.ExecContext(ctx, r.queries.markFailed, f.err.Error(), f.record.id); err != nil {
+			errs = append(errs, fmt.Errorf("failed to record the failure of outbox message %d: %w", f.record.id, err))
+		}
+	}
+
+	return len(sent), errors.Join(errs...)
+}
+
+func (r *Relay) pending(ctx context.Context) ([]record, error) {
+	rows, err := r.DB.QueryContext(ctx, r.queries.pending, r.Config.MaxAttempts, r.Config.BatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pending outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	var records []record
+	for rows.Next() {
+		var rec record
+		if err := rows.Scan(&rec.id, &rec.messageID, &rec.messageName, &rec.orderingKey, &rec.payload, &rec.properties, &rec.attempts); err != nil {
+			return nil, fmt.Errorf("failed to read pending outbox messages: %w", err)
+		}
+		records = append(records, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read pending outbox messages: %w", err)
+	}
+	return records, nil
+}
+
+// groupByOrderingKey groups the records that must be published in order, keeping the order of the groups.
+// Every record without an ordering key is a group of its own.
+func groupByOrderingKey(records []record) [][]record {
+	var groups [][]record
+	index := make(map[string]int)
+	for _, rec := range records {
+		if rec.orderingKey == "" {
+			groups = append(groups, []record{rec})
+			continue
+		}
+
+		i, ok := index[rec.orderingKey]
+		if !ok {
+			i = len(groups)
+			index[rec.orderingKey] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], rec)
+	}
+	return groups
+}
+
+func (r *Relay) publish(ctx context.Context, rec record) error {
+	var props properties
+	if err := json.Unmarshal(rec.properties, &props); err != nil {
+		return fmt.Errorf("failed to unmarshal the options of message %s: %w", rec.messageName, err)
+	}
+
+	// continue the trace the message is written in
+	ctx = r.Propagator.Extract(ctx, propagation.MapCarrier(props.Trace))
+
+	return r.Producer.Publish(ctx, rec.messageName, json.RawMessage(rec.payload), props.options(rec.messageID, rec.orderingKey)...)
+}
+
+func (r *Relay) markSent(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	args := make([]any, 0, len(ids)+1)
+	args = append(args, r.now().UTC())
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	if _, err := r.DB.ExecContext(ctx, fmt.Sprintf(r.queries.markSent, placeholders), args...); err != nil {
+		return fmt.Errorf("failed to mark %d outbox messages sent: %w", len(ids), err)
+	}
+	return nil
+}
+
+// cleanup deletes the messages sent before the retention, once in the cleanup interval.
+func (r *Relay) cleanup(ctx context.Context) error {
+	now := r.now()
+	if r.Config.Retention == 0 || now.Sub(r.lastCleanup) < r.Config.CleanupInterval {
+		return nil
+	}
+	r.lastCleanup = now
+
+	result, err := r.DB.ExecContext(ctx, r.queries.cleanup, now.Add(-r.Config.Retention).UTC())
+	if err != nil {
+		return err
+	}
+
+	if deleted, err := result.RowsAffected(); err == nil && deleted > 0 {
+		r.Logger.For(ctx).With(zap.Int64("deleted", deleted)).Info("deleted sent outbox messages")
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package rabbitmq
+
+import (
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type BindingConfig struct {
+	// Exchange is the source of the binding.
+	Exchange string
+	// Queue or DestinationExchange is the destination of the binding, exactly one of them is set.
+	Queue               string
+	DestinationExchange string
+	// RoutingKeys are bound one by one, the empty routing key by default, e.g. for fanout and headers exchanges.
+	RoutingKeys []string
+	NoWait      bool
+	// Arguments are the arguments of the binding, e.g. x-match and the headers to match for headers exchanges.
+	Arguments amqp.Table
+}
+
+// NewBindingConfig returns the config of the binding from its properties in the configuration file.
+func NewBindingConfig(cfg interface{}) (*BindingConfig, error) {
+	config, err := objectProperties(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &BindingConfig{}
+	if c.Exchange, err = stringProperty(config, PropertyExchange, ""); err != nil {
+		return nil, err
+	}
+	if c.Exchange == "" {
+		return nil, fmt.Errorf("%s is required", PropertyExchange)
+	}
+
+	if c.Queue, err = stringProperty(config, PropertyQueue, ""); err != nil {
+		return nil, err
+	}
+	if c.DestinationExchange, err = stringProperty(config, PropertyDestinationExchange, ""); err != nil {
+		return nil, err
+	}
+	if (c.Queue == "") == (c.DestinationExchange == "") {
+		return nil, fmt.Errorf("exactly one of %s and %s is required", PropertyQueue, PropertyDestinationExchange)
+	}
+
+	if c.RoutingKeys, err = stringsProperty(config, PropertyRoutingKeys); err != nil {
+		return nil, err
+	}
+	if len(c.RoutingKeys) == 0 {
+		c.RoutingKeys = []string{""}
+	}
+
+	if c.NoWait, err = boolProperty(config, PropertyNoWait, false); err != nil {
+		return nil, err
+	}
+	if c.Arguments, err = tableProperty(config, PropertyArguments); err != nil {
+		return nil, err
+	}
+	if len(c.Arguments) == 0 {
+		c.Arguments = nil
+	}
+	return c, nil
+}
+
+// destination returns the name of the queue or the exchange the binding routes to.
+func (c *BindingConfig) destination() string {
+	if c.Queue != "" {
+		return c.Queue
+	}
+	return c.DestinationExchange
+}
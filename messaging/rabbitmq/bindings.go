@@ -1,8 +1,11 @@
 package rabbitmq
 
 import (
+	"fmt"
+
 	"github.com/enesanbar/go-service/core/config"
 	"github.com/enesanbar/go-service/core/log"
+	amqp "github.com/rabbitmq/amqp091-go"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
@@ -12,65 +15,76 @@ type BindingsParams struct {
 
 	Conf      config.Config
 	Logger    log.Factory
-	Queues    map[string]*Queue    `optinal:"true"`
-	Exchanges map[string]*Exchange `optinal:"true"`
+	Queues    map[string]*Queue    `optional:"true"`
+	Exchanges map[string]*Exchange `optional:"true"`
 }
 
-// Bindings creates the bindings defined in the configuration file.
+// Bindings creates the bindings defined in the configuration file, from exchanges to queues or to other exchanges.
+// It returns an error if a binding is malformed, refers to an exchange or a queue that is not declared, or cannot be declared.
 func Bindings(p BindingsParams) error {
-	if len(p.Queues) == 0 || len(p.Exchanges) == 0 {
-		return nil
-	}
-
 	cfg := p.Conf.GetSliceOfObjects("rabbitmq.bindings")
 
-	for _, v := range cfg {
-		exchangeName := v.(map[string]interface{})[PropertyExchange].(string)
-		queueName := v.(map[string]interface{})[PropertyQueue].(string)
-
-		exchange, ok := p.Exchanges[exchangeName]
-		if !ok {
-			p.Logger.Bg().
-				With(zap.String("exchange", exchangeName)).
-				Error("exchange not found for binding. please check the exchange configuration in your configuration")
-			continue
+	for i, v := range cfg {
+		bindingConfig, err := NewBindingConfig(v)
+		if err != nil {
+			return fmt.Errorf("invalid configuration of binding %d: %w", i, err)
 		}
-		queue, ok := p.Queues[queueName]
+
+		exchange, ok := p.Exchanges[bindingConfig.Exchange]
 		if !ok {
-			p.Logger.Bg().
-				With(zap.String("queue", queueName)).
-				Error("queue not found for binding. please check the queue configuration in your configuration")
-			continue
+			return fmt.Errorf("invalid configuration of binding %d: exchange %s is not declared", i, bindingConfig.Exchange)
 		}
 
-		routingKeys := v.(map[string]interface{})[PropertyRoutingKeys].([]interface{})
+		// the binding is declared on the channel of its destination
+		var channel *Channel
+		var bind func(channel *amqp.Channel, routingKey string) error
+		if bindingConfig.Queue != "" {
+			queue, ok := p.Queues[bindingConfig.Queue]
+			if !ok {
+				return fmt.Errorf("invalid configuration of binding %d: queue %s is not declared", i, bindingConfig.Queue)
+			}
+			channel = queue.Channel
+			bind = func(c *amqp.Channel, routingKey string) error {
+				return c.QueueBind(queue.Config.Name, routingKey, exchange.Config.Name, bindingConfig.NoWait, bindingConfig.Arguments)
+			}
+		} else {
+			destination, ok := p.Exchanges[bindingConfig.DestinationExchange]
+			if !ok {
+				return fmt.Errorf("invalid configuration of binding %d: destination exchange %s is not declared", i, bindingConfig.DestinationExchange)
+			}
+			channel = destination.Channel
+			bind = func(c *amqp.Channel, routingKey string) error {
+				return c.ExchangeBind(destination.Config.Name, routingKey, exchange.Config.Name, bindingConfig.NoWait, bindingConfig.Arguments)
+			}
+		}
 
-		for _, routingKey := range routingKeys {
-			err := queue.Channel.Channel.QueueBind(
-				queue.Config.Name,
-				routingKey.(string),
-				exchange.Config.Name,
-				v.(map[string]interface{})[PropertyNoWait].(bool),
-				nil,
-			)
+		for _, routingKey := range bindingConfig.RoutingKeys {
+			// the binding is created again on the channel whenever the channel is recovered
+			declare := func(c *amqp.Channel) error {
+				if c == nil {
+					return fmt.Errorf("channel %s is not connected", channel.Config.Name)
+				}
+				return bind(c, routingKey)
+			}
 
+			err := declare(channel.GetChannel())
 			if err != nil {
 				p.Logger.Bg().
-					With(zap.String("exchange", exchangeName)).
-					With(zap.String("queue", queueName)).
-					With(zap.String("routingKey", routingKey.(string))).
+					With(zap.String("exchange", bindingConfig.Exchange)).
+					With(zap.String("destination", bindingConfig.destination())).
+					With(zap.String("routingKey", routingKey)).
 					With(zap.Error(err)).
 					Error("failed to create binding")
 				return err
 			}
+			channel.NotifyRecovery(declare)
 
 			p.Logger.Bg().
-				With(zap.String("binding", routingKey.(string))).
-				With(zap.String("exchange", exchangeName)).
-				With(zap.String("queue", queueName)).
+				With(zap.String("binding", routingKey)).
+				With(zap.String("exchange", bindingConfig.Exchange)).
+				With(zap.String("destination", bindingConfig.destination())).
 				Info("binding created")
 		}
-
 	}
 
 	return nil
@@ -0,0 +1,135 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/enesanbar/go-service/core/cache"
+	"github.com/enesanbar/go-service/core/info"
+	"github.com/enesanbar/go-service/core/log"
+	"github.com/enesanbar/go-service/core/messaging/producer"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// CacheInvalidationMessageName is the name of the messages carrying cache invalidations.
+const CacheInvalidationMessageName = "cache-invalidated"
+
+// CacheInvalidationBus broadcasts cache invalidations between the instances of a service over rabbitmq.
+// Messages are published with the Producer, and every instance consumes them
+// from its own exclusive, server-named queue bound to the exchange of the service,
+// on the connection of the Producer.
+type CacheInvalidationBus struct {
+	logger   log.Factory
+	producer *Producer
+}
+
+type CacheInvalidationBusParams struct {
+	fx.In
+
+	Logger   log.Factory
+	Producer *Producer
+}
+
+// NewCacheInvalidationBus creates a pointer to the new instance of the CacheInvalidationBus
+func NewCacheInvalidationBus(p CacheInvalidationBusParams) (*CacheInvalidationBus, error) {
+	if p.Producer.Connection == nil {
+		return nil, fmt.Errorf("no valid connection found for cache invalidation bus")
+	}
+
+	return &CacheInvalidationBus{
+		logger:   p.Logger,
+		producer: p.Producer,
+	}, nil
+}
+
+// Publish publishes the message to the exchange of the service
+func (b *CacheInvalidationBus) Publish(ctx context.Context, message cache.InvalidationMessage) error {
+	return b.producer.Publish(ctx, CacheInvalidationMessageName, message, producer.WithExchange(info.ServiceName))
+}
+
+// Subscribe calls the handler for every invalidation published by any instance until ctx is done.
+// The subscription is established again when the connection is recovered,
+// the invalidations published meanwhile are missed. Subscription errors are logged and retried,
+// they are never returned, so that an unavailable broker does not stop the service.
+func (b *CacheInvalidationBus) Subscribe(ctx context.Context, handler func(ctx context.Context, message cache.InvalidationMessage)) error {
+	for {
+		if err := b.subscribe(ctx, handler); err != nil {
+			b.logger.Bg().With(zap.Error(err)).Error(fmt.Sprintf("failed to subscribe to cache invalidations, retrying in %s", reconnectDelay))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// subscribe consumes the invalidations until ctx is done or the channel is closed.
+func (b *CacheInvalidationBus) subscribe(ctx context.Context, handler func(ctx context.Context, message cache.InvalidationMessage)) error {
+	conn := b.producer.Connection.GetConn()
+	if conn == nil || conn.IsClosed() {
+		return fmt.Errorf("connection %s is closed", b.producer.Connection.Name())
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to create channel: %w", err)
+	}
+	defer func() {
+		if err := channel.Close(); err != nil && !channel.IsClosed() {
+			b.logger.Bg().Error("failed to close channel", zap.Error(err))
+		}
+	}()
+
+	queue, err := channel.QueueDeclare(
+		"",    // name, generated by the server
+		false, // durable
+		true,  // delete when unused
+		true,  // exclusive
+		false, // no-wait
+		nil,   // arguments
+	)
+	if err != nil {
+		return fmt.Errorf("failed to declare cache invalidation queue: %w", err)
+	}
+
+	err = channel.QueueBind(queue.Name, CacheInvalidationMessageName, info.ServiceName, false, nil)
+	if err != nil {
+		return fmt.Errorf("failed to bind cache invalidation queue: %w", err)
+	}
+
+	deliveries, err := channel.Consume(queue.Name, "", true, true, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("failed to consume cache invalidation queue: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case d, ok := <-deliveries:
+			if !ok {
+				b.logger.Bg().Info("cache invalidation consumer stopped, resubscribing")
+				return nil
+			}
+
+			// the invalidations are decoded as the consumers do, whatever the format of the producer
+			_, payload, err := decodeDelivery(b.producer.Codecs, d)
+			if err != nil {
+				b.logger.Bg().With(zap.Error(err)).Error("failed to unmarshal cache invalidation message")
+				continue
+			}
+
+			message := cache.InvalidationMessage{}
+			if err := payload.Decode(&message); err != nil {
+				b.logger.Bg().With(zap.Error(err)).Error("failed to decode cache invalidation message")
+				continue
+			}
+
+			handler(ctx, message)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/enesanbar/go-service/core/cache"
+	"github.com/enesanbar/go-service/core/info"
+	"github.com/enesanbar/go-service/core/log"
+	"go.uber.org/zap"
+)
+
+func TestCacheInvalidationBus_SubscribeRetriesFailedSubscriptions(t *testing.T) {
+	reconnectDelay = 10 * time.Millisecond
+	bus, err := NewCacheInvalidationBus(CacheInvalidationBusParams{
+		Logger: log.NewFactory(zap.NewNop()),
+		// the connection is never established
+		Producer: &Producer{Connection: &Connection{Config: &ConnectionConfig{Name: "default"}}},
+	})
+	if err != nil {
+		t.Fatalf("unable to create bus: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- bus.Subscribe(ctx, func(context.Context, cache.InvalidationMessage) {})
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Expected Subscribe to retry the failed subscriptions, got '%v'", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected Subscribe to return no error, got '%v'", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected Subscribe to return when the context is done")
+	}
+}
+
+func TestCacheInvalidationBus_DeliversInvalidations(t *testing.T) {
+	// the invalidations are published to the exchange of the service, which is the source of the cloud events
+	serviceName := info.ServiceName
+	info.ServiceName = "shop"
+	defer func() { info.ServiceName = serviceName }()
+
+	for _, format := range []string{FormatEnvelope, FormatCloudEventsBinary, FormatCloudEventsStructured} {
+		t.Run(format, func(t *testing.T) {
+			server := newFakeServer(t)
+			p := newTestProducer(t, server, map[string]any{"rabbitmq.producer.format": format})
+			// the queue is named by the server, the fake server names it ""
+			server.forward("shop", CacheInvalidationMessageName, "")
+
+			bus, err := NewCacheInvalidationBus(CacheInvalidationBusParams{Logger: log.NewFactory(zap.NewNop()), Producer: p})
+			if err != nil {
+				t.Fatalf("unable to create bus: %v", err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			received := make(chan cache.InvalidationMessage, 1)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				_ = bus.Subscribe(ctx, func(_ context.Context, message cache.InvalidationMessage) {
+					received <- message
+				})
+			}()
+			defer func() {
+				cancel()
+				<-done
+			}()
+			server.waitFor("basic.consume ", 1)
+
+			if err := bus.Publish(context.Background(), cache.InvalidationMessage{Source: "instance-1", Keys: []string{"user:1"}}); err != nil {
+				t.Fatalf("Expected no error, got '%v'", err)
+			}
+
+			select {
+			case message := <-received:
+				if message.Source != "instance-1" || len(message.Keys) != 1 || message.Keys[0] != "user:1" {
+					t.Errorf("Expected the published invalidation, got '%+v'", message)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("Expected the invalidation to be delivered")
+			}
+		})
+	}
+}
@@ -2,6 +2,9 @@ package rabbitmq
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/enesanbar/go-service/core/log"
@@ -11,11 +14,16 @@ import (
 )
 
 type Channel struct {
-	logger           log.Factory
-	Channel          *amqp.Channel
-	ChannelCloseChan chan *amqp.Error
-	Config           *ChannelConfig
-	AppStopSignal    chan struct{}
+	logger        log.Factory
+	Config        *ChannelConfig
+	AppStopSignal chan struct{}
+
+	// channel is replaced by the channel watcher on recovery, it is read through GetChannel
+	mu            sync.RWMutex
+	channel       *amqp.Channel
+	closeChan     chan *amqp.Error
+	recoveryHooks []func(channel *amqp.Channel) error
+	stopOnce      sync.Once
 }
 
 type ChannelParams struct {
@@ -33,16 +41,20 @@ func NewChannelConnector(p ChannelParams) (*Channel, error) {
 	}, nil
 }
 
-func (c *Channel) connect() {
-	// TODO: Fix the data race (access and write to Conn) between the channel and the connection on restart
-	channel, err := c.Config.Connection.Conn.Channel()
+func (c *Channel) connect() error {
+	conn := c.Config.Connection.GetConn()
+	if conn == nil {
+		return errors.New("connection is not established")
+	}
+
+	channel, err := conn.Channel()
 	if err != nil {
 		c.logger.Bg().
-			With(zap.String("db", c.Config.Connection.Config.Host)).
+			With(zap.String("db", c.Config.Connection.connectedHost())).
 			With(zap.String("name", c.Config.Name)).
 			With(zap.Error(err)).
-			Error("failed to create channel, retrying in 5 seconds")
-		return
+			Error(fmt.Sprintf("failed to create channel, retrying in %s", reconnectDelay))
+		return err
 	}
 
 	c.logger.Bg().
@@ -50,32 +62,66 @@ func (c *Channel) connect() {
 		With(zap.String("connection", c.Config.Connection.Name())).
 		Info("created channel to rabbitmq")
 
-	c.Channel = channel
-	c.ChannelCloseChan = make(chan *amqp.Error)
-	c.Channel.NotifyClose(c.ChannelCloseChan)
+	// the notification is buffered so that the library never blocks on it
+	closeChan := make(chan *amqp.Error, 1)
+	channel.NotifyClose(closeChan)
+
+	c.mu.Lock()
+	c.channel = channel
+	c.closeChan = closeChan
+	c.mu.Unlock()
+
+	return nil
 }
 
+// GetChannel returns the current channel, which is replaced when the channel is recovered.
+func (c *Channel) GetChannel() *amqp.Channel {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.channel
+}
+
+// NotifyRecovery registers a function that is called with the new channel whenever the channel is recovered,
+// e.g. to redeclare the topology and to restart the consumers. Functions are called in the order they are registered.
+func (c *Channel) NotifyRecovery(hook func(channel *amqp.Channel) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.recoveryHooks = append(c.recoveryHooks, hook)
+}
+
+// Start watches the channel and opens a new one when it is closed, until Close is called.
+// When the connection is closed, it retries until the connection is recovered.
 func (c *Channel) Start(ctx context.Context) error {
 	c.logger.Bg().
 		With(zap.String("name", c.Config.Name)).
 		Info("starting channel watcher")
 	for {
-		if c.ChannelCloseChan == nil {
-			time.Sleep(5 * time.Second)
+		c.mu.RLock()
+		closeChan := c.closeChan
+		c.mu.RUnlock()
+
+		if closeChan == nil {
+			if !c.reconnect() {
+				return nil
+			}
+			continue
 		}
 
 		select {
-		case err := <-c.ChannelCloseChan:
+		case err := <-closeChan:
+			if c.stopping() {
+				return nil
+			}
 			c.logger.Bg().
 				With(zap.String("name", c.Config.Name)).
 				With(zap.String("db", c.Config.Connection.Name())).
 				With(zap.Error(err)).
 				Error("Channel closed, reconnecting")
-			if !c.Channel.IsClosed() {
-				return c.Close(ctx)
+			if !c.reconnect() {
+				return nil
 			}
-			c.connect()
-			time.Sleep(5 * time.Second)
 		case <-c.AppStopSignal:
 			c.logger.For(ctx).Info("context done, stopping the channel watcher")
 			return nil
@@ -83,14 +129,69 @@ func (c *Channel) Start(ctx context.Context) error {
 	}
 }
 
+// reconnect opens a new channel until it succeeds and runs the recovery hooks,
+// or returns false if the channel is closed meanwhile.
+func (c *Channel) reconnect() bool {
+	for {
+		select {
+		case <-c.AppStopSignal:
+			return false
+		case <-time.After(reconnectDelay):
+		}
+
+		if err := c.connect(); err != nil {
+			continue
+		}
+
+		c.recover()
+		return true
+	}
+}
+
+// recover calls the recovery hooks with the new channel.
+// A failing hook is logged and does not stop the others, the channel is recovered again if it is closed by the failure.
+func (c *Channel) recover() {
+	c.mu.RLock()
+	channel := c.channel
+	hooks := append([]func(channel *amqp.Channel) error{}, c.recoveryHooks...)
+	c.mu.RUnlock()
+
+	for _, hook := range hooks {
+		if err := hook(channel); err != nil {
+			c.logger.Bg().
+				With(zap.String("name", c.Config.Name)).
+				With(zap.Error(err)).
+				Error("failed to recover channel")
+		}
+	}
+
+	c.logger.Bg().
+		With(zap.String("name", c.Config.Name)).
+		With(zap.String("connection", c.Config.Connection.Name())).
+		Info("recovered channel to rabbitmq")
+}
+
+func (c *Channel) stopping() bool {
+	select {
+	case <-c.AppStopSignal:
+		return true
+	default:
+		return false
+	}
+}
+
 // Close closes the Channel to RabbitMQ
 func (c *Channel) Close(ctx context.Context) error {
-	c.AppStopSignal <- struct{}{}
-	if c.Config.Connection.Conn.IsClosed() || c.Channel.IsClosed() {
+	c.stopOnce.Do(func() {
+		close(c.AppStopSignal)
+	})
+
+	channel := c.GetChannel()
+	if channel == nil || channel.IsClosed() {
 		return nil
 	}
 
-	err := c.Channel.Close()
+	err := channel.Close()
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,136 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// confirmChannel is a channel in confirm mode, used by a single publisher at a time.
+type confirmChannel struct {
+	channel *amqp.Channel
+	// returns holds the mandatory messages that are not routed,
+	// they are sent by the broker before the confirmation of the message
+	returns chan amqp.Return
+	// discard closes the channel instead of putting it back to the pool, e.g. when confirmations are pending
+	discard bool
+
+	mu       sync.Mutex
+	closeErr *amqp.Error
+}
+
+func openConfirmChannel(conn *amqp.Connection, returnsSize int) (*confirmChannel, error) {
+	channel, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create channel: %w", err)
+	}
+
+	if err := channel.Confirm(false); err != nil {
+		_ = channel.Close()
+		return nil, fmt.Errorf("failed to put channel in confirm mode: %w", err)
+	}
+
+	c := &confirmChannel{
+		channel: channel,
+		returns: channel.NotifyReturn(make(chan amqp.Return, returnsSize)),
+	}
+
+	closeChan := channel.NotifyClose(make(chan *amqp.Error, 1))
+	go func() {
+		if err := <-closeChan; err != nil {
+			c.mu.Lock()
+			c.closeErr = err
+			c.mu.Unlock()
+		}
+	}()
+
+	return c, nil
+}
+
+// err returns the reason the channel is closed with, or nil if it is open.
+func (c *confirmChannel) err() error {
+	if !c.channel.IsClosed() {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closeErr != nil {
+		return c.closeErr
+	}
+	return amqp.ErrClosed
+}
+
+// channelPool bounds the number of channels publishing at the same time, and keeps them open between publishings.
+// Channels closed by the broker, e.g. after a connection recovery, are replaced with new ones.
+type channelPool struct {
+	connection  *Connection
+	returnsSize int
+	slots       chan struct{}
+	idle        chan *confirmChannel
+}
+
+func newChannelPool(connection *Connection, size int, returnsSize int) *channelPool {
+	return &channelPool{
+		connection:  connection,
+		returnsSize: returnsSize,
+		slots:       make(chan struct{}, size),
+		idle:        make(chan *confirmChannel, size),
+	}
+}
+
+// get returns an idle channel or opens a new one, waiting until fewer than size channels are in use.
+func (p *channelPool) get(ctx context.Context) (*confirmChannel, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	for {
+		select {
+		case c := <-p.idle:
+			if c.channel.IsClosed() {
+				continue
+			}
+			return c, nil
+		default:
+		}
+		break
+	}
+
+	conn := p.connection.GetConn()
+	if conn == nil {
+		<-p.slots
+		return nil, fmt.Errorf("connection %s is not established", p.connection.Name())
+	}
+
+	c, err := openConfirmChannel(conn, p.returnsSize)
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+	return c, nil
+}
+
+// put gives the channel back to the pool.
+func (p *channelPool) put(c *confirmChannel) {
+	defer func() { <-p.slots }()
+
+	if c.discard {
+		_ = c.channel.Close()
+		return
+	}
+	if c.channel.IsClosed() {
+		return
+	}
+
+	select {
+	case p.idle <- c:
+	default:
+		_ = c.channel.Close()
+	}
+}
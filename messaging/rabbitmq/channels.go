@@ -43,7 +43,8 @@ func Channels(p ChannelsParams) (map[string]*Channel, error) {
 			},
 			AppStopSignal: make(chan struct{}),
 		}
-		channel.connect()
+		// the channel watcher keeps retrying if the channel cannot be created now
+		_ = channel.connect()
 		channels[channelName] = channel
 	}
 
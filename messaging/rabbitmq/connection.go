@@ -2,7 +2,9 @@ package rabbitmq
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/enesanbar/go-service/core/log"
@@ -11,12 +13,24 @@ import (
 	"go.uber.org/zap"
 )
 
+// reconnectDelay is the time waited before every attempt to recover a connection or a channel.
+// The delay between the attempts to recover a connection doubles after every attempt, up to maxReconnectDelay.
+var (
+	reconnectDelay    = 5 * time.Second
+	maxReconnectDelay = time.Minute
+)
+
 type Connection struct {
 	logger        log.Factory
-	Conn          *amqp.Connection
-	ConnCloseChan chan *amqp.Error
 	Config        *ConnectionConfig
 	AppStopSignal chan struct{}
+
+	// conn is replaced by the connection watcher on recovery, it is read through GetConn
+	mu        sync.RWMutex
+	conn      *amqp.Connection
+	host      string
+	closeChan chan *amqp.Error
+	stopOnce  sync.Once
 }
 
 type ConnectionParams struct {
@@ -34,92 +48,160 @@ func NewConnector(p ConnectionParams) (*Connection, error) {
 	}, nil
 }
 
+// connect connects to the first node of the cluster that accepts the connection, trying them in order.
+// The credentials are passed in the config of the connection, so that they are never part of the urls that are logged.
 func (c *Connection) connect() error {
-	c.logger.Bg().
-		With(zap.String("host", c.Config.Host)).
-		With(zap.String("name", c.Config.Name)).
-		Info("connecting to rabbitmq")
-
-	url := fmt.Sprintf(
-		"amqp://%s:%s@%s:%s/",
-		c.Config.User,
-		c.Config.Pass,
-		c.Config.Host,
-		c.Config.Port,
+	var (
+		conn *amqp.Connection
+		host string
+		errs []error
 	)
+	for _, host = range c.Config.Hosts {
+		c.logger.Bg().
+			With(zap.String("host", host)).
+			With(zap.String("name", c.Config.Name)).
+			Info("connecting to rabbitmq")
 
-	conn, err := amqp.Dial(url)
-	if err != nil {
+		var err error
+		conn, err = amqp.DialConfig(c.Config.URL(host), c.Config.AMQPConfig())
+		if err == nil {
+			break
+		}
 		c.logger.Bg().
-			With(zap.String("db", c.Config.Host)).
+			With(zap.String("host", host)).
 			With(zap.String("name", c.Config.Name)).
 			With(zap.Error(err)).
-			Error("failed to connect to rabbitmq, retrying in 5 seconds")
-		return err
+			Error("failed to connect to rabbitmq")
+		errs = append(errs, fmt.Errorf("%s: %w", host, err))
+	}
+	if conn == nil {
+		return fmt.Errorf("failed to connect to rabbitmq connection %s: %w", c.Config.Name, errors.Join(errs...))
 	}
 
-	connChan := make(chan *amqp.Error)
-	conn.NotifyClose(connChan)
+	// the notification is buffered so that the library never blocks on it
+	closeChan := make(chan *amqp.Error, 1)
+	conn.NotifyClose(closeChan)
 
 	c.logger.Bg().
-		With(zap.String("host", c.Config.Host)).
+		With(zap.String("host", host)).
 		With(zap.String("name", c.Config.Name)).
 		Info("connected to rabbitmq")
 
-	// TODO: Fix the data race (access and write to Conn) between the channel and the connection on restart
-	c.Conn = conn
-	c.ConnCloseChan = connChan
+	c.mu.Lock()
+	c.conn = conn
+	c.host = host
+	c.closeChan = closeChan
+	c.mu.Unlock()
 
 	return nil
 }
 
+// GetConn returns the current connection to rabbitmq, which is replaced when the connection is recovered.
+func (c *Connection) GetConn() *amqp.Connection {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.conn
+}
+
+// connectedHost returns the node of the cluster the connection is connected to, or was last connected to.
+func (c *Connection) connectedHost() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.host
+}
+
+// Start watches the connection and reconnects to rabbitmq when it is closed, until Close is called.
+// Channels watch the connection themselves, see Channel.Start.
 func (c *Connection) Start(ctx context.Context) error {
 	c.logger.Bg().
 		With(zap.String("name", c.Config.Name)).
 		Info("starting connection watcher for rabbitmq connection")
 
 	for {
-		if c.ConnCloseChan == nil {
-			time.Sleep(5 * time.Second)
+		c.mu.RLock()
+		closeChan := c.closeChan
+		c.mu.RUnlock()
+
+		if closeChan == nil {
+			if !c.reconnect() {
+				return nil
+			}
+			continue
 		}
+
 		select {
-		case err := <-c.ConnCloseChan:
+		case err := <-closeChan:
+			if c.stopping() {
+				return nil
+			}
 			c.logger.Bg().
-				With(zap.String("host", c.Config.Host)).
+				With(zap.String("host", c.connectedHost())).
 				With(zap.String("name", c.Config.Name)).
 				With(zap.Error(err)).
-				Error("connection closed")
-			if !c.Conn.IsClosed() {
-				return c.Close(ctx)
+				Error("connection closed, reconnecting")
+			if !c.reconnect() {
+				return nil
 			}
-			c.connect()
-			time.Sleep(5 * time.Second)
 		case <-c.AppStopSignal:
 			c.logger.For(ctx).Info("context done, stopping the connection watcher")
 			return nil
 		}
+	}
+}
+
+// reconnect connects to rabbitmq until it succeeds, backing off between the attempts,
+// or returns false if the connection is closed meanwhile.
+func (c *Connection) reconnect() bool {
+	delay := reconnectDelay
+	for {
+		select {
+		case <-c.AppStopSignal:
+			return false
+		case <-time.After(delay):
+		}
+
+		if err := c.connect(); err == nil {
+			return true
+		}
+		delay = min(2*delay, maxReconnectDelay)
+		c.logger.Bg().
+			With(zap.String("name", c.Config.Name)).
+			Error(fmt.Sprintf("failed to connect to any node of rabbitmq, retrying in %s", delay))
+	}
+}
 
+func (c *Connection) stopping() bool {
+	select {
+	case <-c.AppStopSignal:
+		return true
+	default:
+		return false
 	}
 }
 
 // Close closes the connection to RabbitMQ
 func (c *Connection) Close(ctx context.Context) error {
-	c.AppStopSignal <- struct{}{}
+	c.stopOnce.Do(func() {
+		close(c.AppStopSignal)
+	})
 
-	if c.Conn.IsClosed() {
+	conn := c.GetConn()
+	if conn == nil || conn.IsClosed() {
 		c.logger.Bg().
-			With(zap.String("host", c.Config.Host)).
+			With(zap.String("host", c.connectedHost())).
 			With(zap.String("name", c.Config.Name)).
 			Info("connection already closed")
 		return nil
 	}
-	err := c.Conn.Close()
+	err := conn.Close()
 	if err != nil {
 		return err
 	}
 
 	c.logger.Bg().
-		With(zap.String("db", c.Config.Host)).
+		With(zap.String("host", c.connectedHost())).
 		With(zap.String("name", c.Config.Name)).
 		Info("closed connection to rabbitmq")
 
@@ -1,56 +1,266 @@
 package rabbitmq
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/enesanbar/go-service/core/config"
+	"github.com/enesanbar/go-service/core/info"
+	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 const (
-	PropertyHost     = "host"
-	PropertyPort     = "port"
-	PropertyUsername = "username"
-	PropertyPassword = "password"
+	PropertyURI                   = "uri"
+	PropertyHost                  = "host"
+	PropertyHosts                 = "hosts"
+	PropertyPort                  = "port"
+	PropertyUsername              = "username"
+	PropertyPassword              = "password"
+	PropertyVHost                 = "vhost"
+	PropertyAuthMechanism         = "auth-mechanism"
+	PropertyConnectionName        = "connection-name"
+	PropertyHeartbeat             = "heartbeat"
+	PropertyFrameSize             = "frame-size"
+	PropertyTLSEnabled            = "tls.enabled"
+	PropertyTLSCAFile             = "tls.ca-file"
+	PropertyTLSCertFile           = "tls.cert-file"
+	PropertyTLSKeyFile            = "tls.key-file"
+	PropertyTLSServerName         = "tls.server-name"
+	PropertyTLSInsecureSkipVerify = "tls.insecure-skip-verify"
+
+	AuthMechanismPlain    = "plain"
+	AuthMechanismExternal = "external"
+
+	DefaultHeartbeat = 10 * time.Second
 )
 
+var defaultPorts = map[string]string{"amqp": "5672", "amqps": "5671"}
+
 type ConnectionConfig struct {
 	Name string
 	Port string
 	Host string
 	User string
 	Pass string
+	// Hosts are the nodes of the cluster as host:port, tried in order until one of them accepts the connection.
+	// Host and Port are the first of them.
+	Hosts []string
+	// VHost is the virtual host of the connection, "/" by default.
+	VHost string
+	// TLS configures the amqps connections, it is nil for the amqp connections.
+	TLS *tls.Config
+	// External authenticates with the client certificate instead of the username and the password,
+	// see the rabbitmq_auth_mechanism_ssl plugin.
+	External bool
+	// ConnectionName is the name of the connection in the management UI, the name of the service by default.
+	ConnectionName string
+	// Heartbeat is the interval of the heartbeats, 10s by default.
+	Heartbeat time.Duration
+	// FrameSize is the maximum size of the frames, the limit of the server by default.
+	FrameSize int
 }
 
+// NewConnectionConfig returns the config of the connection from its uri, overridden by its other properties if they are set.
 func NewConnectionConfig(cfg config.Config, name string) (*ConnectionConfig, error) {
 	keyTemplate := "rabbitmq.connections.%s.%s"
+	key := func(property string) string {
+		return fmt.Sprintf(keyTemplate, name, property)
+	}
+
+	c := &ConnectionConfig{
+		Name:      name,
+		Host:      "localhost", // Default RabbitMQ host
+		VHost:     "/",
+		Heartbeat: DefaultHeartbeat,
+	}
+	scheme := "amqp"
+	var caFile, certFile, keyFile, serverName string
+
+	if uri := cfg.GetString(key(PropertyURI)); uri != "" {
+		parsed, err := amqp.ParseURI(uri)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", key(PropertyURI), redactURIError(err))
+		}
+		scheme = parsed.Scheme
+		c.Host, c.Port = parsed.Host, strconv.Itoa(parsed.Port)
+		c.User, c.Pass, c.VHost = parsed.Username, parsed.Password, parsed.Vhost
+		caFile, certFile, keyFile, serverName = parsed.CACertFile, parsed.CertFile, parsed.KeyFile, parsed.ServerName
+		for _, mechanism := range parsed.AuthMechanism {
+			c.External = c.External || strings.EqualFold(mechanism, AuthMechanismExternal)
+		}
+
+		// the uri is parsed already, so the query is valid
+		u, _ := url.Parse(uri)
+		if value := u.Query().Get("heartbeat"); value != "" {
+			seconds, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid heartbeat '%s' in %s", value, key(PropertyURI))
+			}
+			c.Heartbeat = time.Duration(seconds) * time.Second
+		}
+	}
+
+	if cfg.GetBool(key(PropertyTLSEnabled)) {
+		scheme = "amqps"
+	}
+	if c.Port == "" {
+		c.Port = defaultPorts[scheme]
+	}
+
+	for property, target := range map[string]*string{
+		PropertyHost:          &c.Host,
+		PropertyPort:          &c.Port,
+		PropertyUsername:      &c.User,
+		PropertyPassword:      &c.Pass,
+		PropertyVHost:         &c.VHost,
+		PropertyTLSCAFile:     &caFile,
+		PropertyTLSCertFile:   &certFile,
+		PropertyTLSKeyFile:    &keyFile,
+		PropertyTLSServerName: &serverName,
+	} {
+		if value := cfg.GetString(key(property)); value != "" {
+			*target = value
+		}
+	}
+
+	c.Hosts = []string{net.JoinHostPort(c.Host, c.Port)}
+	if hosts := cfg.GetStringSlice(key(PropertyHosts)); len(hosts) > 0 {
+		c.Hosts = make([]string, 0, len(hosts))
+		for _, host := range hosts {
+			if _, _, err := net.SplitHostPort(host); err != nil {
+				host = net.JoinHostPort(host, c.Port)
+			}
+			c.Hosts = append(c.Hosts, host)
+		}
+		c.Host, c.Port, _ = net.SplitHostPort(c.Hosts[0])
+	}
+
+	switch mechanism := cfg.GetString(key(PropertyAuthMechanism)); strings.ToLower(mechanism) {
+	case "":
+	case AuthMechanismPlain:
+		c.External = false
+	case AuthMechanismExternal:
+		c.External = true
+	default:
+		return nil, fmt.Errorf("invalid value '%s' for %s, must be one of plain or external", mechanism, key(PropertyAuthMechanism))
+	}
+
+	if !c.External && c.User == "" {
+		return nil, config.NewMissingPropertyError(key(PropertyUsername))
+	}
+	if !c.External && c.Pass == "" {
+		return nil, config.NewMissingPropertyError(key(PropertyPassword))
+	}
 
-	host := cfg.GetString(fmt.Sprintf(keyTemplate, name, PropertyHost))
-	if host == "" {
-		host = "localhost" // Default RabbitMQ host
+	if value := cfg.GetString(key(PropertyHeartbeat)); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value '%s' for %s: %w", value, key(PropertyHeartbeat), err)
+		}
+		c.Heartbeat = d
+	}
+	c.FrameSize = cfg.GetInt(key(PropertyFrameSize))
+	if c.Heartbeat < 0 || c.FrameSize < 0 {
+		return nil, fmt.Errorf("%s and %s cannot be negative", key(PropertyHeartbeat), key(PropertyFrameSize))
 	}
 
-	port := cfg.GetString(fmt.Sprintf(keyTemplate, name, PropertyPort))
-	if port == "" {
-		port = "5672" // Default RabbitMQ port
+	c.ConnectionName = cfg.GetString(key(PropertyConnectionName))
+	if c.ConnectionName == "" {
+		c.ConnectionName = info.ServiceName
 	}
 
-	property := fmt.Sprintf(keyTemplate, name, PropertyUsername)
-	username := cfg.GetString(property)
-	if username == "" {
-		return nil, config.NewMissingPropertyError(property)
+	if scheme == "amqps" {
+		tlsConfig, err := newTLSConfig(caFile, certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tls configuration of connection %s: %w", name, err)
+		}
+		tlsConfig.ServerName = serverName
+		tlsConfig.InsecureSkipVerify = cfg.GetBool(key(PropertyTLSInsecureSkipVerify))
+		c.TLS = tlsConfig
+	}
+	if c.External && (c.TLS == nil || len(c.TLS.Certificates) == 0) {
+		return nil, fmt.Errorf("the external auth mechanism of connection %s requires tls with a client certificate", name)
 	}
 
-	property = fmt.Sprintf(keyTemplate, name, PropertyPassword)
-	password := cfg.GetString(property)
-	if password == "" {
-		return nil, config.NewMissingPropertyError(property)
+	return c, nil
+}
+
+// newTLSConfig returns the TLS config with the CA and the client certificate, if they are given.
+// The certificates of the system are trusted without a CA.
+func newTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if caFile != "" {
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificate found in %s", caFile)
+		}
 	}
 
-	return &ConnectionConfig{
-		Name: name,
-		Host: host,
-		Port: port,
-		User: username,
-		Pass: password,
-	}, nil
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("both the client certificate and its key are required")
+	}
+	if certFile != "" {
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+// redactURIError removes the uri from the errors of url.Parse, since it contains the password.
+func redactURIError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// URL returns the url of the node, without the credentials which are passed by AMQPConfig.
+func (c *ConnectionConfig) URL(host string) string {
+	scheme := "amqp"
+	if c.TLS != nil {
+		scheme = "amqps"
+	}
+	return (&url.URL{Scheme: scheme, Host: host, Path: "/"}).String()
+}
+
+// AMQPConfig returns the config the nodes are dialed with.
+func (c *ConnectionConfig) AMQPConfig() amqp.Config {
+	sasl := []amqp.Authentication{&amqp.PlainAuth{Username: c.User, Password: c.Pass}}
+	if c.External {
+		sasl = []amqp.Authentication{&amqp.ExternalAuth{}}
+	}
+
+	properties := amqp.NewConnectionProperties()
+	properties.SetClientConnectionName(c.ConnectionName)
+
+	amqpConfig := amqp.Config{
+		SASL:       sasl,
+		Vhost:      c.VHost,
+		Heartbeat:  c.Heartbeat,
+		FrameSize:  c.FrameSize,
+		Properties: properties,
+	}
+	// the server name is set to the host of every node by the library, if it is not configured
+	if c.TLS != nil {
+		amqpConfig.TLSClientConfig = c.TLS.Clone()
+	}
+	return amqpConfig
 }
@@ -2,18 +2,26 @@ package rabbitmq
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	tracesdk "go.opentelemetry.io/otel/sdk/trace"
 	"strings"
+	"sync"
+	"time"
+
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
 
+	"github.com/enesanbar/go-service/core/info"
 	"github.com/enesanbar/go-service/core/log"
+	"github.com/enesanbar/go-service/core/messaging/codec"
 	"github.com/enesanbar/go-service/core/messaging/consumer"
 	"github.com/enesanbar/go-service/core/messaging/messages"
+	"github.com/google/uuid"
 	"github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
 	"go.opentelemetry.io/otel/trace"
-	"go.uber.org/zap"
 )
 
 type QueueConsumer struct {
@@ -24,6 +32,18 @@ type QueueConsumer struct {
 	MessageHandlers map[string]consumer.MessageHandler
 	Propagator      propagation.TextMapPropagator
 	Tracer          trace.Tracer
+	Instrumentor    *consumer.Instrumentor
+	Codecs          *codec.Registry
+	// producer replies to the requests rejected before they reach their handlers, see replyInvalid.
+	producer *Producer
+
+	// the consumer is restarted on a new channel by the channel watcher, the fields below are guarded by mu
+	mu      sync.Mutex
+	ctx     context.Context
+	channel *amqp091.Channel
+	tag     string
+	done    chan struct{}
+	stopped bool
 }
 
 type ConsumerParams struct {
@@ -34,11 +54,21 @@ type ConsumerParams struct {
 	MessageHandlers map[string]consumer.MessageHandler
 	Propagator      propagation.TextMapPropagator
 	TracerProvider  *tracesdk.TracerProvider
+	Instrumentor    *consumer.Instrumentor
+	// Codecs decode the payloads by their content types, the built-in codecs by default.
+	Codecs *codec.Registry
+	// Producer replies to the requests whose payloads are invalid, which never reach their RPCHandlers.
+	Producer *Producer
 }
 
 // NewRabbitMQConsumer creates a pointer to the new instance of the QueueConsumer
 // and a runnable group that can be used to start and stop the consumer
 func NewRabbitMQConsumer(p ConsumerParams) *QueueConsumer {
+	codecs := p.Codecs
+	if codecs == nil {
+		codecs = codec.NewRegistry()
+	}
+
 	return &QueueConsumer{
 		logger:          p.Logger,
 		Config:          p.Config,
@@ -47,6 +77,9 @@ func NewRabbitMQConsumer(p ConsumerParams) *QueueConsumer {
 		MessageHandlers: p.MessageHandlers,
 		Propagator:      p.Propagator,
 		Tracer:          p.TracerProvider.Tracer(fmt.Sprintf("consumer-%s", p.Queue.Config.Name)),
+		Instrumentor:    p.Instrumentor,
+		Codecs:          codecs,
+		producer:        p.Producer,
 	}
 }
 
@@ -58,10 +91,67 @@ func (h *QueueConsumer) Start(ctx context.Context) error {
 		return fmt.Errorf("queue is not set, check your configuration")
 	}
 
-	// add recovery logic to the channel when channel/connection is closed
-	msgs, err := h.Channel.Channel.Consume(
-		h.Queue.Queue.Name,
-		h.Config.ConsumerTag,
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// the start context is canceled once the application is started, handlers must outlive it
+	h.ctx = context.WithoutCancel(ctx)
+	h.Channel.NotifyRecovery(h.recover)
+
+	channel := h.Channel.GetChannel()
+	if channel == nil {
+		h.logger.Bg().Info(fmt.Sprintf("RabbitMQ consumer for queue %s waits for the channel to be recovered", h.Queue.Config.Name))
+		return nil
+	}
+
+	if err := h.consume(channel); err != nil {
+		return err
+	}
+	h.logger.Bg().Info(fmt.Sprintf("RabbitMQ consumer started for queue %s", h.Queue.Config.Name))
+	return nil
+}
+
+// recover restarts the consumer on the recovered channel, unless it is stopped.
+// The deliveries of the previous channel are closed by then, and their unacked messages are redelivered.
+func (h *QueueConsumer) recover(channel *amqp091.Channel) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.stopped {
+		return nil
+	}
+
+	if err := h.consume(channel); err != nil {
+		return err
+	}
+	h.logger.Bg().Info(fmt.Sprintf("RabbitMQ consumer recovered for queue %s", h.Queue.Config.Name))
+	return nil
+}
+
+// consume starts consuming the queue on the channel, it must be called with mu held.
+func (h *QueueConsumer) consume(channel *amqp091.Channel) error {
+	if h.Config.PrefetchCount > 0 {
+		err := channel.Qos(h.Config.PrefetchCount, 0, false)
+		if err != nil {
+			return fmt.Errorf("error setting prefetch count of RabbitMQ consumer (%w)", err)
+		}
+	}
+
+	if h.Config.MaxAttempts > 1 {
+		if err := h.declareRetryQueue(channel); err != nil {
+			return err
+		}
+	}
+
+	// the tag is needed to cancel the consumer, so it is not left to the library
+	tag := h.Config.ConsumerTag
+	if tag == "" {
+		tag = fmt.Sprintf("%s-%s-%s", info.ServiceName, h.Queue.Config.Name, uuid.NewString())
+	}
+
+	msgs, err := channel.Consume(
+		h.Queue.Config.Name,
+		tag,
 		h.Config.AutoAck,
 		h.Config.Exclusive,
 		h.Config.NoLocal,
@@ -72,68 +162,161 @@ func (h *QueueConsumer) Start(ctx context.Context) error {
 		return fmt.Errorf("error starting RabbitMQ consumer (%w)", err)
 	}
 
+	done := make(chan struct{})
+	h.channel = channel
+	h.tag = tag
+	h.done = done
+
+	ctx := h.ctx
+	pool := newWorkerPool(h.Config.Concurrency, h.Config.Ordered, func(d amqp091.Delivery) {
+		h.process(ctx, channel, d)
+	})
+
 	go func() {
+		defer close(done)
 		for d := range msgs {
-			// TODO: throttle the message processing with worker pool
-			go func(d amqp091.Delivery) {
-				message := messages.Message[any]{}
-				err := json.Unmarshal(d.Body, &message)
-				if err != nil {
-					h.logger.For(ctx).With(zap.Error(err)).Error("Failed to unmarshal message")
-					return
-				}
-
-				key := fmt.Sprintf("%s-%s", h.Queue.Config.Name, message.Metadata.MessageName)
-				handler, ok := h.MessageHandlers[key]
-				if !ok {
-					h.logger.For(ctx).With(zap.String("messageName", message.Metadata.MessageName)).Error("no handler found for message")
-					return
-				}
-
-				// Unmarshal the payload to the correct type
-				payload := handler.GetMessageType()
-				message.UnmarshalPayload(payload)
-				message.Payload = payload
-
-				// Extract parent context from traceparent
-				carrier := propagation.MapCarrier{
-					"traceparent": message.Metadata.Traceparent,
-					"tracestate":  message.Metadata.Tracestate,
-				}
-				ctx := h.Propagator.Extract(ctx, carrier)
-
-				// Start a new span that:
-				// 1. Continues the trace from traceparent
-				// 2. Links to the span that sent the message (message.Metadata.SpanID)
-				ctx, span := h.Tracer.Start(
-					ctx,
-					"processing: "+message.Metadata.MessageName,
-					trace.WithSpanKind(trace.SpanKindConsumer),
-					trace.WithLinks(trace.Link{
-						SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
-							TraceID:    parseTraceID(message.Metadata.Traceparent), // From traceparent
-							SpanID:     parseSpanID(message.Metadata.SpanID),       // From metadata
-							TraceFlags: trace.FlagsSampled,
-							Remote:     true,
-						}),
-					}),
-				)
-				defer span.End()
-
-				err = handler.Handle(ctx, message)
-				if err != nil {
-					h.logger.For(ctx).With(zap.Error(err)).Error("failed to handle message")
-				}
-			}(d)
+			pool.submit(d)
 		}
-		h.logger.Bg().Info("RabbitMQ consumer stopped")
+		pool.close()
+		h.logger.Bg().Info(fmt.Sprintf("RabbitMQ consumer stopped for queue %s", h.Queue.Config.Name))
 	}()
-	h.logger.Bg().Info(fmt.Sprintf("RabbitMQ consumer started for queue %s", h.Queue.Config.Name))
 	return nil
 }
 
+// Stop cancels the consumer and waits for the delivered messages to be handled until the graceful stop timeout.
+// The messages that are not acked by then are redelivered by the broker once the channel is closed.
 func (h *QueueConsumer) Stop(ctx context.Context) error {
-	return nil
+	h.mu.Lock()
+	h.stopped = true
+	channel, tag, done := h.channel, h.tag, h.done
+	h.mu.Unlock()
+
+	if done == nil {
+		return nil
+	}
+
+	// the deliveries are already closed if the channel is closed
+	if err := channel.Cancel(tag, false); err != nil && !channel.IsClosed() {
+		return fmt.Errorf("error canceling RabbitMQ consumer (%w)", err)
+	}
+
+	timer := time.NewTimer(h.Config.GracefulStopTimeout)
+	defer timer.Stop()
+
+	h.logger.For(ctx).Info(fmt.Sprintf("draining RabbitMQ consumer for queue %s", h.Queue.Config.Name))
+	select {
+	case <-done:
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("RabbitMQ consumer for queue %s could not be drained in %s", h.Queue.Config.Name, h.Config.GracefulStopTimeout)
+	case <-ctx.Done():
+		return fmt.Errorf("RabbitMQ consumer for queue %s could not be drained (%w)", h.Queue.Config.Name, ctx.Err())
+	}
+}
+
+// process handles and settles the delivery, recording its metrics.
+func (h *QueueConsumer) process(ctx context.Context, pub publisher, d amqp091.Delivery) {
+	start := time.Now()
+	h.Instrumentor.Begin(h.Queue.Config.Name)
+
+	messageName, err := h.handle(ctx, d)
+	if messageName == "" {
+		messageName = "unknown"
+	}
+
+	h.Instrumentor.End(h.Queue.Config.Name, messageName, start, err)
+	h.Instrumentor.Consumed(h.Queue.Config.Name, messageName, d.Redelivered)
+
+	switch h.settle(ctx, pub, d, err) {
+	case settlementAck:
+		h.Instrumentor.Acked(h.Queue.Config.Name, messageName)
+	case settlementReject:
+		h.Instrumentor.Nacked(h.Queue.Config.Name, messageName, false)
+	case settlementRequeue:
+		h.Instrumentor.Nacked(h.Queue.Config.Name, messageName, true)
+	}
+}
+
+// handle passes the delivery to the handler of its message.
+// Deliveries that cannot be decoded or have no handler are reported with errUnprocessable, so that they are not retried.
+func (h *QueueConsumer) handle(ctx context.Context, d amqp091.Delivery) (string, error) {
+	// the payload is decoded by the handler, into its message type
+	metadata, payload, err := decodeDelivery(h.Codecs, d)
+	if err != nil {
+		return "", fmt.Errorf("%w: failed to unmarshal message: %w", errUnprocessable, err)
+	}
+
+	key := consumer.HandlerKey(h.Queue.Config.Name, metadata.MessageName)
+	handler, ok := h.MessageHandlers[key]
+	if !ok {
+		// the names of the messages without a handler are not trusted as the labels of the metrics
+		return "", fmt.Errorf("%w: no handler found for message %s", errUnprocessable, metadata.MessageName)
+	}
+
+	// the messages of the producers that predate the message id in the metadata are identified by the broker id
+	if metadata.MessageID == "" {
+		metadata.MessageID = d.MessageId
+	}
+	ctx = messages.WithMetadata(ctx, metadata)
+	// the requests of the RPCClients are replied to by their RPCHandlers
+	ctx = withReplyAddress(ctx, d)
+
+	// Extract parent context from traceparent
+	carrier := propagation.MapCarrier{
+		"traceparent": metadata.Traceparent,
+		"tracestate":  metadata.Tracestate,
+	}
+	ctx = h.Propagator.Extract(ctx, carrier)
+
+	// Start a new span that:
+	// 1. Continues the trace from traceparent
+	// 2. Links to the span that sent the message (metadata.SpanID)
+	ctx, span := h.Tracer.Start(
+		ctx,
+		"processing: "+metadata.MessageName,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithLinks(trace.Link{
+			SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    parseTraceID(metadata.Traceparent), // From traceparent
+				SpanID:     parseSpanID(metadata.SpanID),       // From metadata
+				TraceFlags: trace.FlagsSampled,
+				Remote:     true,
+			}),
+		}),
+		trace.WithAttributes(h.spanAttributes(d, metadata)...),
+	)
+	defer span.End()
+
+	err = consumer.Dispatch(ctx, handler, metadata, payload)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	switch {
+	case errors.Is(err, consumer.ErrInvalidPayload):
+		err = h.replyInvalid(ctx, metadata.MessageName, fmt.Errorf("%w: invalid payload: %w", errUnprocessable, err))
+	case errors.Is(err, consumer.ErrUnsupportedVersion):
+		err = h.replyInvalid(ctx, metadata.MessageName, fmt.Errorf("%w: %w", errUnprocessable, err))
+	}
+	return metadata.MessageName, err
+}
+
+// spanAttributes returns the attributes of the messaging semantic conventions for the span of the delivery.
+func (h *QueueConsumer) spanAttributes(d amqp091.Delivery, metadata messages.Metadata) []attribute.KeyValue {
+	attributes := []attribute.KeyValue{
+		semconv.MessagingSystemRabbitMQ,
+		semconv.MessagingOperationTypeProcess,
+		semconv.MessagingOperationName("process"),
+		semconv.MessagingDestinationName(destinationName(d.Exchange, d.RoutingKey, h.Queue.Config.Name)),
+		semconv.MessagingRabbitMQDestinationRoutingKey(d.RoutingKey),
+		semconv.MessagingRabbitMQMessageDeliveryTag(int(d.DeliveryTag)),
+		semconv.MessagingMessageID(metadata.MessageID),
+		semconv.MessagingMessageBodySize(len(d.Body)),
+	}
+	if metadata.CorrelationID != "" {
+		attributes = append(attributes, semconv.MessagingMessageConversationID(metadata.CorrelationID))
+	}
+	return attributes
 }
 
 func parseTraceID(traceparent string) trace.TraceID {
@@ -1,5 +1,17 @@
 package rabbitmq
 
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultMaxAttempts         = 1
+	DefaultRetryDelay          = 5 * time.Second
+	DefaultConcurrency         = 10
+	DefaultGracefulStopTimeout = 10 * time.Second
+)
+
 type ConsumerConfig struct {
 	ConsumerTag   string
 	Channel       string
@@ -10,53 +22,141 @@ type ConsumerConfig struct {
 	NoWait        bool
 	Requeue       bool
 	PrefetchCount int
+	// MaxAttempts is the number of times a message is handled before it is dead-lettered, 1 disables retries.
+	MaxAttempts int
+	// RetryDelay is the time a failed message waits in the retry queue before it is delivered again.
+	RetryDelay time.Duration
+	// DeadLetterExchange receives the messages that are exhausted or cannot be handled at all.
+	DeadLetterExchange string
+	// DeadLetterRoutingKey overrides the routing key of the dead-lettered messages.
+	DeadLetterRoutingKey string
+	// Concurrency is the number of messages handled at the same time.
+	Concurrency int
+	// Ordered handles the messages with the same routing key one at a time, in the order they are delivered.
+	Ordered bool
+	// GracefulStopTimeout is the time given to the in-flight messages to be handled when the consumer stops.
+	GracefulStopTimeout time.Duration
 }
 
 func NewConsumerConfig(cfg interface{}) (*ConsumerConfig, error) {
-	config := cfg.(map[string]interface{})
+	config, err := objectProperties(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// the library generates a random tag if empty
+	consumerTag, err := stringProperty(config, PropertyConsumerTag, "")
+	if err != nil {
+		return nil, err
+	}
+
+	queueName, err := stringProperty(config, PropertyQueue, "")
+	if err != nil {
+		return nil, err
+	}
+	if queueName == "" {
+		return nil, fmt.Errorf("%s is required", PropertyQueue)
+	}
+
+	channelName, err := stringProperty(config, PropertyChannel, "")
+	if err != nil {
+		return nil, err
+	}
+	if channelName == "" {
+		return nil, fmt.Errorf("%s is required", PropertyChannel)
+	}
 
-	consumerTag, ok := config[PropertyConsumerTag]
-	if !ok {
-		consumerTag = "" // library generates a random tag
+	autoAck, err := boolProperty(config, PropertyAutoAck, true)
+	if err != nil {
+		return nil, err
 	}
 
-	queueName, ok := config[PropertyQueue]
-	if !ok {
-		panic("queue name is required")
+	exclusive, err := boolProperty(config, PropertyExclusive, false)
+	if err != nil {
+		return nil, err
 	}
 
-	channelName, ok := config[PropertyChannel]
-	if !ok {
-		panic("channel name is required")
+	noLocal, err := boolProperty(config, PropertyNoLocal, false)
+	if err != nil {
+		return nil, err
 	}
 
-	autoAck, ok := config[PropertyAutoAck]
-	if !ok {
-		autoAck = true
+	noWait, err := boolProperty(config, PropertyNoWait, false)
+	if err != nil {
+		return nil, err
+	}
+
+	requeue, err := boolProperty(config, PropertyRequeue, false)
+	if err != nil {
+		return nil, err
+	}
+
+	prefetchCount, err := intProperty(config, PropertyPrefetchCount, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	maxAttempts, err := intProperty(config, PropertyMaxAttempts, DefaultMaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	if maxAttempts < 1 {
+		return nil, fmt.Errorf("%s of the consumer for queue %s must be at least 1", PropertyMaxAttempts, queueName)
+	}
+
+	retryDelay, err := durationProperty(config, PropertyRetryDelay, DefaultRetryDelay)
+	if err != nil {
+		return nil, err
+	}
+
+	deadLetterExchange, err := stringProperty(config, PropertyDeadLetterExchange, "")
+	if err != nil {
+		return nil, err
+	}
+
+	deadLetterRoutingKey, err := stringProperty(config, PropertyDeadLetterRoutingKey, "")
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency, err := intProperty(config, PropertyConcurrency, DefaultConcurrency)
+	if err != nil {
+		return nil, err
+	}
+	if concurrency < 1 {
+		return nil, fmt.Errorf("%s of the consumer for queue %s must be at least 1", PropertyConcurrency, queueName)
 	}
 
-	exclusive, ok := config[PropertyExclusive]
-	if !ok {
-		exclusive = false
+	ordered, err := boolProperty(config, PropertyOrdered, false)
+	if err != nil {
+		return nil, err
 	}
 
-	noLocal, ok := config[PropertyNoLocal]
-	if !ok {
-		noLocal = false
+	gracefulStopTimeout, err := durationProperty(config, PropertyGracefulStopTimeout, DefaultGracefulStopTimeout)
+	if err != nil {
+		return nil, err
 	}
 
-	noWait, ok := config[PropertyNoWait]
-	if !ok {
-		noWait = false
+	if autoAck && (maxAttempts > 1 || deadLetterExchange != "") {
+		return nil, fmt.Errorf("retries and dead-lettering of the consumer for queue %s require %s: false", queueName, PropertyAutoAck)
 	}
 
 	return &ConsumerConfig{
-		ConsumerTag: consumerTag.(string),
-		Channel:     channelName.(string),
-		Queue:       queueName.(string),
-		AutoAck:     autoAck.(bool),
-		Exclusive:   exclusive.(bool),
-		NoLocal:     noLocal.(bool),
-		NoWait:      noWait.(bool),
+		ConsumerTag:          consumerTag,
+		Channel:              channelName,
+		Queue:                queueName,
+		AutoAck:              autoAck,
+		Exclusive:            exclusive,
+		NoLocal:              noLocal,
+		NoWait:               noWait,
+		Requeue:              requeue,
+		PrefetchCount:        prefetchCount,
+		MaxAttempts:          maxAttempts,
+		RetryDelay:           retryDelay,
+		DeadLetterExchange:   deadLetterExchange,
+		DeadLetterRoutingKey: deadLetterRoutingKey,
+		Concurrency:          concurrency,
+		Ordered:              ordered,
+		GracefulStopTimeout:  gracefulStopTimeout,
 	}, nil
 }
@@ -1,14 +1,16 @@
 package rabbitmq
 
 import (
+	"fmt"
+
 	"github.com/enesanbar/go-service/core/config"
 	"github.com/enesanbar/go-service/core/log"
+	"github.com/enesanbar/go-service/core/messaging/codec"
 	"github.com/enesanbar/go-service/core/messaging/consumer"
 	"github.com/enesanbar/go-service/core/wiring"
 	"go.opentelemetry.io/otel/propagation"
 	tracesdk "go.opentelemetry.io/otel/sdk/trace"
 	"go.uber.org/fx"
-	"go.uber.org/zap"
 )
 
 type ConsumersParams struct {
@@ -18,38 +20,42 @@ type ConsumersParams struct {
 	Logger          log.Factory
 	Queues          map[string]*Queue
 	Channels        map[string]*Channel
+	Exchanges       map[string]*Exchange `optional:"true"`
 	MessageHandlers map[string]consumer.MessageHandler
 	Propagator      propagation.TextMapPropagator
 	TracerProvider  *tracesdk.TracerProvider
+	Instrumentor    *consumer.Instrumentor
+	Codecs          *codec.Registry `optional:"true"`
+	// Producer replies to the requests whose payloads are invalid, see NewRPCHandler.
+	Producer *Producer `optional:"true"`
 }
 
+// Consumers creates the consumers defined in the configuration file.
+// It returns an error if a consumer is malformed or refers to a channel, a queue or a dead-letter exchange that is not declared.
 func Consumers(p ConsumersParams) ([]wiring.Runnable, error) {
 	runnables := make([]wiring.Runnable, 0)
 
 	cfg := p.Conf.GetSliceOfObjects("rabbitmq.consumers")
 
-	for _, v := range cfg {
+	for i, v := range cfg {
 		cfg, err := NewConsumerConfig(v)
 		if err != nil {
-			p.Logger.Bg().Error("failed to create consumer config")
-			continue
+			return nil, fmt.Errorf("invalid configuration of consumer %d: %w", i, err)
 		}
 
 		channel, ok := p.Channels[cfg.Channel]
 		if !ok {
-			p.Logger.Bg().
-				With(zap.String("channel", cfg.Channel)).
-				Error("channel not found for exchange. please check the channel configuration in your configuration")
-			continue
+			return nil, fmt.Errorf("invalid configuration of consumer %d: channel %s is not declared", i, cfg.Channel)
 		}
 
 		queue, ok := p.Queues[cfg.Queue]
 		if !ok {
-			p.Logger.Bg().
-				With(zap.String("channel", cfg.Channel)).
-				With(zap.String("queue", cfg.Queue)).
-				Error("queue not found for exchange. please check the queue configuration in your configuration")
-			continue
+			return nil, fmt.Errorf("invalid configuration of consumer %d: queue %s is not declared", i, cfg.Queue)
+		}
+
+		// publishing to an undeclared exchange closes the channel, so the dead-letter exchange must be declared
+		if _, ok := p.Exchanges[cfg.DeadLetterExchange]; cfg.DeadLetterExchange != "" && !ok {
+			return nil, fmt.Errorf("invalid configuration of consumer %d: dead-letter exchange %s is not declared", i, cfg.DeadLetterExchange)
 		}
 
 		o := NewRabbitMQConsumer(ConsumerParams{
@@ -60,6 +66,9 @@ func Consumers(p ConsumersParams) ([]wiring.Runnable, error) {
 			MessageHandlers: p.MessageHandlers,
 			Propagator:      p.Propagator,
 			TracerProvider:  p.TracerProvider,
+			Instrumentor:    p.Instrumentor,
+			Codecs:          p.Codecs,
+			Producer:        p.Producer,
 		})
 		runnables = append(runnables, o)
 	}
@@ -0,0 +1,138 @@
+package rabbitmq
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/enesanbar/go-service/core/messaging/cloudevents"
+	"github.com/enesanbar/go-service/core/messaging/codec"
+	"github.com/enesanbar/go-service/core/messaging/consumer"
+	"github.com/enesanbar/go-service/core/messaging/messages"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// cloudEventsHeaderPrefix prefixes the attributes of the events in the binary mode, as in the AMQP binding of CloudEvents.
+const cloudEventsHeaderPrefix = "cloudEvents:"
+
+// encodePublishing encodes the message into the body, the content type and the headers of an amqp message in the format.
+func encodePublishing(format string, c codec.Codec, metadata messages.Metadata, payload any, headers map[string]any) ([]byte, string, amqp091.Table, error) {
+	table := amqp091.Table{}
+	for name, value := range headers {
+		table[name] = value
+	}
+
+	switch format {
+	case FormatCloudEventsBinary, FormatCloudEventsStructured:
+		data, err := c.Marshal(payload)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		if format == FormatCloudEventsStructured {
+			body, err := json.Marshal(cloudevents.NewEvent(metadata, c.ContentType(), data))
+			return body, cloudevents.ContentType, table, err
+		}
+
+		// the content type of the payload is the content type of the amqp message
+		for name, value := range cloudevents.Attributes(metadata, "") {
+			table[cloudEventsHeaderPrefix+name] = value
+		}
+		return data, c.ContentType(), table, nil
+	default:
+		if !codec.IsJSON(c.ContentType()) {
+			return nil, "", nil, fmt.Errorf("the envelope format supports JSON payloads only, got %s", c.ContentType())
+		}
+		body, err := json.Marshal(messages.Message[any]{Metadata: metadata, Payload: payload})
+		return body, codec.ContentTypeJSON, table, err
+	}
+}
+
+// decodeDelivery decodes the metadata of the delivery and returns its encoded payload, detecting its format:
+// the CloudEvents in the structured and the binary modes, the envelope of go-service,
+// or any other message whose metadata is read from the amqp properties, e.g. the messages of other producers.
+func decodeDelivery(codecs *codec.Registry, d amqp091.Delivery) (messages.Metadata, consumer.Payload, error) {
+	contentType := mediaType(d.ContentType)
+
+	switch {
+	case contentType == cloudevents.ContentType:
+		var event cloudevents.Event
+		if err := json.Unmarshal(d.Body, &event); err != nil {
+			return messages.Metadata{}, consumer.Payload{}, err
+		}
+		metadata, dataContentType, err := event.Metadata()
+		if err != nil {
+			return messages.Metadata{}, consumer.Payload{}, err
+		}
+		return payloadOf(codecs, metadata, dataContentType, event.Payload())
+	case d.Headers[cloudEventsHeaderPrefix+cloudevents.AttributeSpecVersion] != nil:
+		attributes := make(map[string]string)
+		for name, value := range d.Headers {
+			if attribute, ok := strings.CutPrefix(name, cloudEventsHeaderPrefix); ok {
+				attributes[attribute] = headerString(value)
+			}
+		}
+		metadata, _, err := cloudevents.Metadata(attributes)
+		if err != nil {
+			return messages.Metadata{}, consumer.Payload{}, err
+		}
+		return payloadOf(codecs, metadata, d.ContentType, d.Body)
+	case contentType == "" || contentType == codec.ContentTypeJSON:
+		// plain JSON messages have no message name in an envelope
+		var message messages.Message[json.RawMessage]
+		if err := json.Unmarshal(d.Body, &message); err == nil && message.Metadata.MessageName != "" {
+			return message.Metadata, consumer.Payload{Data: message.Payload, Codec: codec.JSONCodec{}}, nil
+		}
+	}
+
+	return payloadOf(codecs, propertiesMetadata(d), d.ContentType, d.Body)
+}
+
+// payloadOf returns the metadata with the payload and the codec of its content type.
+func payloadOf(codecs *codec.Registry, metadata messages.Metadata, contentType string, data []byte) (messages.Metadata, consumer.Payload, error) {
+	c, err := codecs.Get(contentType)
+	if err != nil {
+		return messages.Metadata{}, consumer.Payload{}, err
+	}
+	return metadata, consumer.Payload{Data: data, Codec: c}, nil
+}
+
+// propertiesMetadata returns the metadata of a message without envelope from the amqp properties.
+// The message name is the type of the message, or its routing key if it has no type.
+func propertiesMetadata(d amqp091.Delivery) messages.Metadata {
+	messageName := d.Type
+	if messageName == "" {
+		messageName = d.RoutingKey
+	}
+	return messages.Metadata{
+		MessageID:     d.MessageId,
+		CorrelationID: d.CorrelationId,
+		PublisherName: d.AppId,
+		PublishDate:   d.Timestamp,
+		MessageName:   messageName,
+	}
+}
+
+func headerString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// mediaType returns the content type without its parameters.
+func mediaType(contentType string) string {
+	t, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(contentType))
+	}
+	return t
+}
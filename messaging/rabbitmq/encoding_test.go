@@ -0,0 +1,117 @@
+package rabbitmq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/enesanbar/go-service/core/config/configtest"
+	"github.com/enesanbar/go-service/core/messaging/codec"
+	"github.com/enesanbar/go-service/core/messaging/messages"
+	"github.com/rabbitmq/amqp091-go"
+)
+
+func newTestMetadata() messages.Metadata {
+	return messages.Metadata{
+		MessageID:     "message-1",
+		CorrelationID: "correlation-1",
+		PublisherName: "orders",
+		PublishDate:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		MessageName:   "order-created",
+		Traceparent:   "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		SpanID:        "00f067aa0ba902b7",
+	}
+}
+
+func TestEncoding_RoundTrip(t *testing.T) {
+	codecs := codec.NewRegistry()
+	tests := []struct {
+		format      string
+		contentType string
+	}{
+		{FormatEnvelope, codec.ContentTypeJSON},
+		{FormatCloudEventsBinary, codec.ContentTypeJSON},
+		{FormatCloudEventsBinary, codec.ContentTypeMsgpack},
+		{FormatCloudEventsStructured, codec.ContentTypeJSON},
+		{FormatCloudEventsStructured, codec.ContentTypeMsgpack},
+	}
+
+	for _, test := range tests {
+		c, _ := codecs.Get(test.contentType)
+		body, contentType, headers, err := encodePublishing(test.format, c, newTestMetadata(), orderCreated{ID: 1}, map[string]any{"tenant": "acme"})
+		if err != nil {
+			t.Fatalf("Expected no error for '%s' with '%s', got '%v'", test.format, test.contentType, err)
+		}
+		if headers["tenant"] != "acme" {
+			t.Errorf("Expected the headers of '%s' to be kept, got '%v'", test.format, headers)
+		}
+
+		metadata, payload, err := decodeDelivery(codecs, amqp091.Delivery{ContentType: contentType, Headers: headers, Body: body})
+		if err != nil {
+			t.Fatalf("Expected no error for '%s' with '%s', got '%v'", test.format, test.contentType, err)
+		}
+		if metadata != newTestMetadata() {
+			t.Errorf("Expected the metadata of '%s' to be '%+v', got '%+v'", test.format, newTestMetadata(), metadata)
+		}
+
+		var decoded orderCreated
+		if err := payload.Decode(&decoded); err != nil || decoded.ID != 1 {
+			t.Errorf("Expected the payload of '%s' with '%s' to be decoded, got '%+v' with '%v'", test.format, test.contentType, decoded, err)
+		}
+	}
+}
+
+func TestEncoding_EnvelopeRequiresJSON(t *testing.T) {
+	_, _, _, err := encodePublishing(FormatEnvelope, codec.MsgpackCodec{}, newTestMetadata(), orderCreated{ID: 1}, nil)
+	if err == nil {
+		t.Error("Expected an error for a payload that is not JSON in the envelope")
+	}
+
+	_, err = NewProducerConfig(configtest.New(t, map[string]any{"rabbitmq.producer.content-type": codec.ContentTypeMsgpack}))
+	if err == nil {
+		t.Error("Expected an error for a content type that is not JSON in the envelope")
+	}
+	_, err = NewProducerConfig(configtest.New(t, map[string]any{"rabbitmq.producer.format": "xml"}))
+	if err == nil {
+		t.Error("Expected an error for an invalid format")
+	}
+}
+
+func TestDecodeDelivery_ForeignMessage(t *testing.T) {
+	data, _ := codec.MsgpackCodec{}.Marshal(orderCreated{ID: 2})
+	d := amqp091.Delivery{
+		ContentType: "application/x-msgpack",
+		MessageId:   "message-2",
+		AppId:       "billing",
+		RoutingKey:  "order-created",
+		Body:        data,
+	}
+
+	metadata, payload, err := decodeDelivery(codec.NewRegistry(), d)
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if metadata.MessageName != "order-created" || metadata.MessageID != "message-2" || metadata.PublisherName != "billing" {
+		t.Errorf("Expected the metadata to be read from the properties, got '%+v'", metadata)
+	}
+
+	var decoded orderCreated
+	if err := payload.Decode(&decoded); err != nil || decoded.ID != 2 {
+		t.Errorf("Expected the payload to be decoded, got '%+v' with '%v'", decoded, err)
+	}
+
+	if _, _, err := decodeDelivery(codec.NewRegistry(), amqp091.Delivery{ContentType: "text/csv", Body: data}); err == nil {
+		t.Error("Expected an error for an unsupported content type")
+	}
+}
+
+func TestDecodeDelivery_PlainJSON(t *testing.T) {
+	d := amqp091.Delivery{ContentType: codec.ContentTypeJSON, Type: "order-created", Body: []byte(`[{"id":3}]`)}
+
+	metadata, payload, err := decodeDelivery(codec.NewRegistry(), d)
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if metadata.MessageName != "order-created" || string(payload.Data) != `[{"id":3}]` {
+		t.Errorf("Expected the message to be handled without envelope, got '%+v' with '%s'", metadata, payload.Data)
+	}
+}
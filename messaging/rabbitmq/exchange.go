@@ -1,7 +1,10 @@
 package rabbitmq
 
 import (
+	"fmt"
+
 	"github.com/enesanbar/go-service/core/log"
+	amqp "github.com/rabbitmq/amqp091-go"
 	"go.uber.org/zap"
 )
 
@@ -18,37 +21,58 @@ type ExchangeParams struct {
 }
 
 func NewExchange(p ExchangeParams) (*Exchange, error) {
-	err := p.Channel.Channel.ExchangeDeclare(
-		p.Config.Name,       // name
-		p.Config.Type,       // kind
-		p.Config.Durable,    // durable
-		p.Config.AutoDelete, // delete when unused
-		p.Config.Internal,   // exclusive
-		p.Config.NoWait,     // no-wait
-		nil,                 // arguments
+	e := &Exchange{
+		Channel: p.Channel,
+		logger:  p.Logger,
+		Config:  p.Config,
+	}
+
+	if err := e.declare(p.Channel.GetChannel()); err != nil {
+		return nil, err
+	}
+	p.Channel.NotifyRecovery(e.declare)
+
+	return e, nil
+}
+
+// declare declares the exchange on the channel, it is called again whenever the channel is recovered.
+func (e *Exchange) declare(channel *amqp.Channel) error {
+	if channel == nil {
+		return fmt.Errorf("channel %s is not connected", e.Channel.Config.Name)
+	}
+
+	// a passive declaration only checks that the exchange exists, with the same type
+	declare := channel.ExchangeDeclare
+	if e.Config.Passive {
+		declare = channel.ExchangeDeclarePassive
+	}
+	err := declare(
+		e.Config.Name,       // name
+		e.Config.Type,       // kind
+		e.Config.Durable,    // durable
+		e.Config.AutoDelete, // delete when unused
+		e.Config.Internal,   // internal
+		e.Config.NoWait,     // no-wait
+		e.Config.Table(),    // arguments
 	)
 
 	if err != nil {
-		p.Logger.Bg().
-			With(zap.String("name", p.Config.Name)).
-			With(zap.String("connection", p.Channel.Config.Connection.Config.Host)).
-			With(zap.String("channel", p.Channel.Config.Name)).
-			With(zap.String("exchange", p.Config.Name)).
+		e.logger.Bg().
+			With(zap.String("name", e.Config.Name)).
+			With(zap.String("connection", e.Channel.Config.Connection.connectedHost())).
+			With(zap.String("channel", e.Channel.Config.Name)).
+			With(zap.String("exchange", e.Config.Name)).
 			With(zap.Error(err)).
 			Error("failed to create Exchange")
-		return nil, err
+		return err
 	}
 
-	p.Logger.Bg().
-		With(zap.String("name", p.Config.Name)).
-		With(zap.String("connection", p.Channel.Config.Connection.Config.Host)).
-		With(zap.String("channel", p.Channel.Config.Name)).
-		With(zap.String("exchange", p.Config.Name)).
+	e.logger.Bg().
+		With(zap.String("name", e.Config.Name)).
+		With(zap.String("connection", e.Channel.Config.Connection.connectedHost())).
+		With(zap.String("channel", e.Channel.Config.Name)).
+		With(zap.String("exchange", e.Config.Name)).
 		Info("Exchange created")
 
-	return &Exchange{
-		Channel: p.Channel,
-		logger:  p.Logger,
-		Config:  p.Config,
-	}, nil
+	return nil
 }
@@ -1,11 +1,83 @@
 package rabbitmq
 
+import (
+	"fmt"
+	"strings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
 type ExchangeConfig struct {
-	Name       string
+	Name string
+	// Type is direct, fanout, topic, headers, or the type of a plugin, e.g. x-delayed-message.
 	Type       string
 	Channel    *Channel
 	Durable    bool
 	AutoDelete bool
 	Internal   bool
 	NoWait     bool
+	// Passive checks that the exchange exists instead of declaring it, e.g. for the exchanges of other services.
+	Passive bool
+	// AlternateExchange receives the messages that are not routed to any queue.
+	AlternateExchange string
+	// Arguments are the other arguments of the exchange, e.g. x-delayed-type.
+	Arguments amqp.Table
+}
+
+// NewExchangeConfig returns the config of the exchange from its properties in the configuration file.
+// The channel of the exchange is set by the caller.
+func NewExchangeConfig(name string, cfg interface{}) (*ExchangeConfig, error) {
+	config, err := objectProperties(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &ExchangeConfig{Name: name}
+	if c.Type, err = stringProperty(config, PropertyType, ""); err != nil {
+		return nil, err
+	}
+	switch {
+	case c.Type == amqp.ExchangeDirect, c.Type == amqp.ExchangeFanout, c.Type == amqp.ExchangeTopic, c.Type == amqp.ExchangeHeaders:
+	case strings.HasPrefix(c.Type, "x-"):
+	case c.Type == "":
+		return nil, fmt.Errorf("%s is required", PropertyType)
+	default:
+		return nil, fmt.Errorf("invalid value '%s' for %s, must be one of direct, fanout, topic, headers or the type of a plugin", c.Type, PropertyType)
+	}
+
+	for property, target := range map[string]*bool{
+		PropertyDurable:    &c.Durable,
+		PropertyAutoDelete: &c.AutoDelete,
+		PropertyInternal:   &c.Internal,
+		PropertyNoWait:     &c.NoWait,
+		PropertyPassive:    &c.Passive,
+	} {
+		if *target, err = boolProperty(config, property, false); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.AlternateExchange, err = stringProperty(config, PropertyAlternateExchange, ""); err != nil {
+		return nil, err
+	}
+	if c.Arguments, err = tableProperty(config, PropertyArguments); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Table returns the arguments the exchange is declared with, the properties of the exchange override its Arguments.
+func (c *ExchangeConfig) Table() amqp.Table {
+	table := amqp.Table{}
+	for name, value := range c.Arguments {
+		table[name] = value
+	}
+	if c.AlternateExchange != "" {
+		table["alternate-exchange"] = c.AlternateExchange
+	}
+
+	if len(table) == 0 {
+		return nil
+	}
+	return table
 }
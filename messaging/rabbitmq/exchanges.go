@@ -1,6 +1,8 @@
 package rabbitmq
 
 import (
+	"fmt"
+
 	"github.com/enesanbar/go-service/core/config"
 	"github.com/enesanbar/go-service/core/log"
 	"go.uber.org/fx"
@@ -16,6 +18,7 @@ type ExchangesParams struct {
 }
 
 // Exchanges create the exchanges defined in the configuration file.
+// It returns an error if an exchange is malformed or cannot be declared.
 func Exchanges(p ExchangesParams) (map[string]*Exchange, error) {
 	if len(p.Channels) == 0 {
 		return nil, nil
@@ -25,7 +28,16 @@ func Exchanges(p ExchangesParams) (map[string]*Exchange, error) {
 	exchanges := make(map[string]*Exchange)
 
 	for exchangeName, v := range cfg {
-		channelName := v.(map[string]interface{})[PropertyChannel].(string)
+		exchangeConfig, err := NewExchangeConfig(exchangeName, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid configuration of exchange %s: %w", exchangeName, err)
+		}
+		properties, _ := objectProperties(v)
+		channelName, err := stringProperty(properties, PropertyChannel, "")
+		if err != nil || channelName == "" {
+			return nil, fmt.Errorf("invalid configuration of exchange %s: %s is required", exchangeName, PropertyChannel)
+		}
+
 		channel, ok := p.Channels[channelName]
 		if !ok {
 			p.Logger.Bg().
@@ -34,28 +46,15 @@ func Exchanges(p ExchangesParams) (map[string]*Exchange, error) {
 				Error("channel not found for exchange. please check the channel configuration in your configuration")
 			continue
 		}
+		exchangeConfig.Channel = channel
 
 		Exchange, err := NewExchange(ExchangeParams{
 			Channel: channel,
 			Logger:  p.Logger,
-			Config: &ExchangeConfig{
-				Name:       exchangeName,
-				Channel:    channel,
-				Type:       v.(map[string]interface{})[PropertyType].(string),
-				Durable:    v.(map[string]interface{})[PropertyDurable].(bool),
-				AutoDelete: v.(map[string]interface{})[PropertyAutoDelete].(bool),
-				Internal:   v.(map[string]interface{})[PropertyExclusive].(bool),
-				NoWait:     v.(map[string]interface{})[PropertyNoWait].(bool),
-			},
+			Config:  exchangeConfig,
 		})
-
 		if err != nil {
-			p.Logger.Bg().
-				With(zap.String("exchange", exchangeName)).
-				With(zap.String("channel", channelName)).
-				With(zap.Error(err)).
-				Error("failed to create Exchange")
-			panic(err)
+			return nil, fmt.Errorf("failed to declare exchange %s: %w", exchangeName, err)
 		}
 
 		exchanges[exchangeName] = Exchange
@@ -0,0 +1,541 @@
+package rabbitmq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	frameMethod = 1
+	frameHeader = 2
+	frameBody   = 3
+	frameEnd    = 0xCE
+)
+
+// fakeServer speaks enough AMQP 0-9-1 to declare the topology, consume and deliver messages, and forward the published ones.
+// It records the methods called by the clients, and drops all connections on demand to test the recovery.
+type fakeServer struct {
+	t        *testing.T
+	listener net.Listener
+
+	mu          sync.Mutex
+	conns       map[*fakeConn]struct{}
+	consumers   map[string]fakeConsumer
+	calls       map[string]int
+	deliveryTag uint64
+	bindings    map[string]bool
+	published   []fakePublishing
+	// forwards are the queues the messages are delivered to, by exchange and routing key
+	forwards map[string]string
+	// nackExchanges are the exchanges whose messages are nacked
+	nackExchanges map[string]bool
+	// withholdConfirms never confirms the published messages
+	withholdConfirms bool
+}
+
+type fakePublishing struct {
+	exchange   string
+	routingKey string
+	mandatory  bool
+	messageID  string
+	// properties are the encoded properties of the content header, starting with their flags
+	properties []byte
+	body       []byte
+}
+
+type fakeConsumer struct {
+	conn    *fakeConn
+	channel uint16
+	tag     string
+}
+
+type fakeConn struct {
+	net.Conn
+	mu sync.Mutex
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+
+	s := &fakeServer{
+		t:         t,
+		listener:  listener,
+		conns:     make(map[*fakeConn]struct{}),
+		consumers: make(map[string]fakeConsumer),
+		calls:     make(map[string]int),
+		bindings:  make(map[string]bool),
+		forwards:  make(map[string]string),
+
+		nackExchanges: make(map[string]bool),
+	}
+	go s.accept()
+	t.Cleanup(func() {
+		_ = listener.Close()
+		s.drop()
+	})
+
+	return s
+}
+
+func (s *fakeServer) port() string {
+	return fmt.Sprint(s.listener.Addr().(*net.TCPAddr).Port)
+}
+
+// called returns the number of times the method was called, e.g. "queue.declare orders".
+func (s *fakeServer) called(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.calls[method]
+}
+
+// bind routes the messages published to the exchange with the routing key.
+func (s *fakeServer) bind(exchange, routingKey string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.bindings[exchange+" "+routingKey] = true
+}
+
+// forward delivers the messages published to the exchange with the routing key to the consumer of the queue,
+// with their properties. The replies to the direct reply-to are always delivered to its consumer.
+func (s *fakeServer) forward(exchange, routingKey, queue string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.bindings[exchange+" "+routingKey] = true
+	s.forwards[exchange+" "+routingKey] = queue
+}
+
+// nack refuses the messages published to the exchange.
+func (s *fakeServer) nack(exchange string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nackExchanges[exchange] = true
+}
+
+// withholdConfirmations stops confirming the published messages.
+func (s *fakeServer) withholdConfirmations() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.withholdConfirms = true
+}
+
+func (s *fakeServer) publishings() []fakePublishing {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]fakePublishing{}, s.published...)
+}
+
+func (s *fakeServer) waitFor(method string, times int) {
+	s.t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for s.called(method) < times {
+		if time.Now().After(deadline) {
+			s.t.Fatalf("Expected '%s' to be called '%d' times, got '%d'", method, times, s.called(method))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// drop closes all the connections without the closing handshake, as a broker restart would.
+func (s *fakeServer) drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.conns = make(map[*fakeConn]struct{})
+	s.consumers = make(map[string]fakeConsumer)
+}
+
+// deliver sends the body to the consumer of the queue.
+func (s *fakeServer) deliver(queue string, routingKey string, body []byte) {
+	s.t.Helper()
+
+	s.mu.Lock()
+	consumer, ok := s.consumers[queue]
+	s.mu.Unlock()
+
+	if !ok {
+		s.t.Fatalf("Expected a consumer for queue '%s'", queue)
+	}
+	s.send(consumer, "", routingKey, []byte{0, 0}, body) // no properties
+}
+
+// send delivers the message with the encoded properties to the consumer.
+func (s *fakeServer) send(consumer fakeConsumer, exchange, routingKey string, properties, body []byte) {
+	s.mu.Lock()
+	s.deliveryTag++
+	tag := s.deliveryTag
+	s.mu.Unlock()
+
+	deliver := newMethod(60, 60)
+	deliver.shortstr(consumer.tag)
+	deliver.longlong(tag)
+	deliver.octet(0) // redelivered
+	deliver.shortstr(exchange)
+	deliver.shortstr(routingKey)
+
+	header := &frameBuffer{}
+	header.short(60)
+	header.short(0)
+	header.longlong(uint64(len(body)))
+	header.Write(properties)
+
+	consumer.conn.mu.Lock()
+	defer consumer.conn.mu.Unlock()
+	_ = writeFrame(consumer.conn, frameMethod, consumer.channel, deliver.Bytes())
+	_ = writeFrame(consumer.conn, frameHeader, consumer.channel, header.Bytes())
+	_ = writeFrame(consumer.conn, frameBody, consumer.channel, body)
+}
+
+func (s *fakeServer) accept() {
+	for {
+		c, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		conn := &fakeConn{Conn: c}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		go s.serve(conn)
+	}
+}
+
+func (s *fakeServer) record(method string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls[method]++
+}
+
+func (s *fakeServer) serve(conn *fakeConn) {
+	defer conn.Close()
+
+	protocol := make([]byte, 8)
+	if _, err := io.ReadFull(conn, protocol); err != nil {
+		return
+	}
+
+	start := newMethod(10, 10)
+	start.octet(0)
+	start.octet(9)
+	start.table()
+	start.longstr("PLAIN")
+	start.longstr("en_US")
+	s.reply(conn, 0, start)
+
+	// the state of the channels of the connection, by channel id
+	confirms := make(map[uint16]uint64)
+	publishing := make(map[uint16]*fakePublishing)
+	remaining := make(map[uint16]uint64)
+
+	for {
+		typ, channel, payload, err := readFrame(conn)
+		if err != nil {
+			return
+		}
+
+		switch typ {
+		case frameHeader:
+			r := bytes.NewReader(payload)
+			readShort(r) // class
+			readShort(r) // weight
+			var size uint64
+			_ = binary.Read(r, binary.BigEndian, &size)
+			publishing[channel].properties = append([]byte{}, payload[12:]...)
+			publishing[channel].messageID = readMessageID(r, readShort(r))
+			remaining[channel] = size
+		case frameBody:
+			publishing[channel].body = append(publishing[channel].body, payload...)
+			remaining[channel] -= uint64(len(payload))
+		}
+		if typ == frameHeader || typ == frameBody {
+			if remaining[channel] == 0 {
+				s.route(conn, channel, *publishing[channel], confirms)
+			}
+			continue
+		}
+		if typ != frameMethod {
+			// heartbeats are ignored
+			continue
+		}
+
+		r := bytes.NewReader(payload)
+		class, method := readShort(r), readShort(r)
+		switch {
+		case class == 10 && method == 11: // connection.start-ok
+			tune := newMethod(10, 30)
+			tune.short(2047)
+			tune.long(131072)
+			tune.short(0)
+			s.reply(conn, 0, tune)
+		case class == 10 && method == 40: // connection.open
+			openOk := newMethod(10, 41)
+			openOk.shortstr("")
+			s.reply(conn, 0, openOk)
+		case class == 10 && method == 50: // connection.close
+			s.reply(conn, 0, newMethod(10, 51))
+			return
+		case class == 20 && method == 10: // channel.open
+			openOk := newMethod(20, 11)
+			openOk.longstr("")
+			s.reply(conn, channel, openOk)
+		case class == 20 && method == 40: // channel.close
+			s.reply(conn, channel, newMethod(20, 41))
+		case class == 40 && method == 10: // exchange.declare
+			readShort(r)
+			s.record("exchange.declare " + readShortstr(r))
+			s.reply(conn, channel, newMethod(40, 11))
+		case class == 40 && method == 30: // exchange.bind
+			readShort(r)
+			destination, source, key := readShortstr(r), readShortstr(r), readShortstr(r)
+			s.record(fmt.Sprintf("exchange.bind %s %s %s", destination, source, key))
+			s.reply(conn, channel, newMethod(40, 31))
+		case class == 50 && method == 10: // queue.declare
+			readShort(r)
+			queue := readShortstr(r)
+			s.record("queue.declare " + queue)
+			declareOk := newMethod(50, 11)
+			declareOk.shortstr(queue)
+			declareOk.long(0)
+			declareOk.long(0)
+			s.reply(conn, channel, declareOk)
+		case class == 50 && method == 20: // queue.bind
+			readShort(r)
+			queue, exchange, key := readShortstr(r), readShortstr(r), readShortstr(r)
+			s.record(fmt.Sprintf("queue.bind %s %s %s", queue, exchange, key))
+			s.bind(exchange, key)
+			s.reply(conn, channel, newMethod(50, 21))
+		case class == 60 && method == 10: // basic.qos
+			s.record("basic.qos")
+			s.reply(conn, channel, newMethod(60, 11))
+		case class == 60 && method == 20: // basic.consume
+			readShort(r)
+			queue, tag := readShortstr(r), readShortstr(r)
+			s.mu.Lock()
+			s.consumers[queue] = fakeConsumer{conn: conn, channel: channel, tag: tag}
+			s.calls["basic.consume "+queue]++
+			s.mu.Unlock()
+			consumeOk := newMethod(60, 21)
+			consumeOk.shortstr(tag)
+			s.reply(conn, channel, consumeOk)
+		case class == 60 && method == 30: // basic.cancel
+			tag := readShortstr(r)
+			s.record("basic.cancel")
+			cancelOk := newMethod(60, 31)
+			cancelOk.shortstr(tag)
+			s.reply(conn, channel, cancelOk)
+		case class == 60 && method == 40: // basic.publish, followed by the content
+			readShort(r)
+			exchange, key := readShortstr(r), readShortstr(r)
+			bits, _ := r.ReadByte()
+			publishing[channel] = &fakePublishing{exchange: exchange, routingKey: key, mandatory: bits&1 == 1}
+		case class == 85 && method == 10: // confirm.select
+			confirms[channel] = 0
+			s.record("confirm.select")
+			s.reply(conn, channel, newMethod(85, 11))
+		case class == 60 && method == 80: // basic.ack
+			s.record("basic.ack")
+		case class == 60 && method == 120: // basic.nack
+			s.record("basic.nack")
+		}
+	}
+}
+
+// route routes the message, returning it if it is mandatory and unroutable, and confirms it.
+func (s *fakeServer) route(conn *fakeConn, channel uint16, p fakePublishing, confirms map[uint16]uint64) {
+	s.mu.Lock()
+	s.published = append(s.published, p)
+	routed := s.bindings[p.exchange+" "+p.routingKey]
+	queue := s.forwards[p.exchange+" "+p.routingKey]
+	if p.exchange == "" && strings.HasPrefix(p.routingKey, DirectReplyTo) {
+		queue = DirectReplyTo
+	}
+	consumer, consumed := s.consumers[queue]
+	nack := s.nackExchanges[p.exchange]
+	withhold := s.withholdConfirms
+	s.mu.Unlock()
+
+	if consumed {
+		s.send(consumer, p.exchange, p.routingKey, p.properties, p.body)
+	}
+
+	if p.mandatory && !routed {
+		ret := newMethod(60, 50)
+		ret.short(312)
+		ret.shortstr("NO_ROUTE")
+		ret.shortstr(p.exchange)
+		ret.shortstr(p.routingKey)
+
+		header := &frameBuffer{}
+		header.short(60)
+		header.short(0)
+		header.longlong(uint64(len(p.body)))
+		header.short(1 << 7) // message-id
+		header.shortstr(p.messageID)
+
+		conn.mu.Lock()
+		_ = writeFrame(conn, frameMethod, channel, ret.Bytes())
+		_ = writeFrame(conn, frameHeader, channel, header.Bytes())
+		_ = writeFrame(conn, frameBody, channel, p.body)
+		conn.mu.Unlock()
+	}
+
+	tag, ok := confirms[channel]
+	if !ok || withhold {
+		return
+	}
+	tag++
+	confirms[channel] = tag
+
+	confirm := newMethod(60, 80)
+	if nack {
+		confirm = newMethod(60, 120)
+	}
+	confirm.longlong(tag)
+	confirm.octet(0)
+	s.reply(conn, channel, confirm)
+}
+
+func (s *fakeServer) reply(conn *fakeConn, channel uint16, method *frameBuffer) {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	_ = writeFrame(conn, frameMethod, channel, method.Bytes())
+}
+
+type frameBuffer struct {
+	bytes.Buffer
+}
+
+func newMethod(class, method uint16) *frameBuffer {
+	b := &frameBuffer{}
+	b.short(class)
+	b.short(method)
+	return b
+}
+
+func (b *frameBuffer) octet(v byte) {
+	b.WriteByte(v)
+}
+
+func (b *frameBuffer) short(v uint16) {
+	_ = binary.Write(b, binary.BigEndian, v)
+}
+
+func (b *frameBuffer) long(v uint32) {
+	_ = binary.Write(b, binary.BigEndian, v)
+}
+
+func (b *frameBuffer) longlong(v uint64) {
+	_ = binary.Write(b, binary.BigEndian, v)
+}
+
+func (b *frameBuffer) shortstr(v string) {
+	b.octet(byte(len(v)))
+	b.WriteString(v)
+}
+
+func (b *frameBuffer) longstr(v string) {
+	b.long(uint32(len(v)))
+	b.WriteString(v)
+}
+
+// table writes an empty field table.
+func (b *frameBuffer) table() {
+	b.long(0)
+}
+
+func writeFrame(w io.Writer, typ byte, channel uint16, payload []byte) error {
+	frame := &frameBuffer{}
+	frame.octet(typ)
+	frame.short(channel)
+	frame.long(uint32(len(payload)))
+	frame.Write(payload)
+	frame.octet(frameEnd)
+
+	_, err := w.Write(frame.Bytes())
+	return err
+}
+
+func readFrame(r io.Reader) (byte, uint16, []byte, error) {
+	header := make([]byte, 7)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, 0, nil, err
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint32(header[3:7])+1)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, 0, nil, err
+	}
+	if payload[len(payload)-1] != frameEnd {
+		return 0, 0, nil, fmt.Errorf("invalid frame end")
+	}
+
+	return header[0], binary.BigEndian.Uint16(header[1:3]), payload[:len(payload)-1], nil
+}
+
+func readShort(r *bytes.Reader) uint16 {
+	var v uint16
+	_ = binary.Read(r, binary.BigEndian, &v)
+	return v
+}
+
+func readShortstr(r *bytes.Reader) string {
+	size, _ := r.ReadByte()
+	v := make([]byte, size)
+	_, _ = io.ReadFull(r, v)
+	return string(v)
+}
+
+// readMessageID reads the properties of a content header up to the message id.
+func readMessageID(r *bytes.Reader, flags uint16) string {
+	if flags&(1<<15) != 0 { // content-type
+		readShortstr(r)
+	}
+	if flags&(1<<14) != 0 { // content-encoding
+		readShortstr(r)
+	}
+	if flags&(1<<13) != 0 { // headers
+		var size uint32
+		_ = binary.Read(r, binary.BigEndian, &size)
+		_, _ = r.Seek(int64(size), io.SeekCurrent)
+	}
+	if flags&(1<<12) != 0 { // delivery-mode
+		_, _ = r.ReadByte()
+	}
+	if flags&(1<<11) != 0 { // priority
+		_, _ = r.ReadByte()
+	}
+	for _, bit := range []uint16{10, 9, 8} { // correlation-id, reply-to, expiration
+		if flags&(1<<bit) != 0 {
+			readShortstr(r)
+		}
+	}
+	if flags&(1<<7) != 0 {
+		return readShortstr(r)
+	}
+	return ""
+}
@@ -1,9 +1,11 @@
 package rabbitmq
 
 import (
-	"fmt"
+	"context"
+
 	"github.com/enesanbar/go-service/core/wiring"
 
+	"github.com/enesanbar/go-service/core/cache"
 	"github.com/enesanbar/go-service/core/messaging/consumer"
 	"github.com/enesanbar/go-service/core/messaging/producer"
 	"github.com/enesanbar/go-service/core/service"
@@ -60,30 +62,57 @@ func Option(options ...fx.Option) service.Option {
 
 var ProducerModule = fx.Module(
 	"messaging.rabbitmq.producer",
+	fx.Provide(NewProducerConfig),
+	fx.Provide(producer.NewInstrumentor, fx.Private),
 	fx.Provide(fx.Annotate(
 		NewRabbitMQProducer,
+		fx.As(fx.Self()),
 		fx.As(new(producer.Producer)),
+		fx.As(new(producer.BatchProducer)),
+	)),
+)
+
+// RPCModule provides the RPCClient, it requires the ProducerModule.
+// The RPCHandlers are registered as the message handlers of the ConsumerModule, see NewRPCHandler.
+var RPCModule = fx.Module(
+	"messaging.rabbitmq.rpc",
+	fx.Provide(NewRPCConfig),
+	fx.Provide(fx.Annotate(
+		NewRPCClient,
+		fx.OnStop(func(ctx context.Context, c *RPCClient) error {
+			return c.Close(ctx)
+		}),
 	)),
 )
 
+// CacheInvalidationBusModule provides rabbitmq as the cache.InvalidationBus.
+// It requires the ProducerModule and an exchange named after the service,
+// the invalidations are consumed on the connection of the Producer.
+var CacheInvalidationBusModule = fx.Provide(
+	fx.Annotate(
+		NewCacheInvalidationBus,
+		fx.As(new(cache.InvalidationBus)),
+	),
+)
+
 type MessageHandlerParams struct {
 	fx.In
 
-	Handlers []consumer.MessageHandler `group:"message-handlers"`
+	Handlers     []consumer.MessageHandler  `group:"message-handlers"`
+	Upcasters    []consumer.Upcaster        `group:"message-upcasters"`
+	Deduplicator *consumer.Deduplicator     `optional:"true"`
+	Validator    *consumer.PayloadValidator `optional:"true"`
 }
 
-func MapMessageHandlers(p MessageHandlerParams) map[string]consumer.MessageHandler {
-	handlersMap := make(map[string]consumer.MessageHandler)
-	for _, handler := range p.Handlers {
-		key := fmt.Sprintf("%s-%s", handler.Properties().QueueName, handler.Properties().MessageName)
-		handlersMap[key] = handler
-	}
-	return handlersMap
+// MapMessageHandlers maps the handlers by their queues and messages, see consumer.MapHandlers.
+func MapMessageHandlers(p MessageHandlerParams) (map[string]consumer.MessageHandler, error) {
+	return consumer.MapHandlers(consumer.HandlersParams(p))
 }
 
 var ConsumerModule = fx.Module(
 	"messaging.rabbitmq.consumer",
 	fx.Provide(MapMessageHandlers),
+	fx.Provide(consumer.NewInstrumentor, fx.Private),
 	fx.Provide(
 		fx.Annotate(
 			Consumers,
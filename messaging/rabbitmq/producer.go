@@ -2,25 +2,48 @@ package rabbitmq
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"go.uber.org/zap"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/enesanbar/go-service/core/info"
 	"github.com/enesanbar/go-service/core/log"
+	"github.com/enesanbar/go-service/core/messaging/codec"
 	"github.com/enesanbar/go-service/core/messaging/messages"
+	"github.com/enesanbar/go-service/core/messaging/producer"
+	"github.com/google/uuid"
 	"github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/propagation"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
 	"go.opentelemetry.io/otel/trace"
-
+	"go.opentelemetry.io/otel/trace/noop"
 	"go.uber.org/fx"
+	"go.uber.org/zap"
 )
 
+// returnsBufferSize is the number of unroutable messages the broker can return to a channel before they are read.
+const returnsBufferSize = 64
+
+// Producer publishes messages on a pool of channels in confirm mode.
+// Publish returns once the broker confirms the message, so a nil error means the broker is responsible for it.
+//
+// Every message is published in a span of producer kind, which is the parent of the spans of its consumers,
+// and its outcome is recorded by the Instrumentor.
 type Producer struct {
-	Logger     log.Factory
-	Connection *Connection
-	Propagator propagation.TextMapPropagator
+	Logger       log.Factory
+	Connection   *Connection
+	Propagator   propagation.TextMapPropagator
+	Config       *ProducerConfig
+	Codecs       *codec.Registry
+	Tracer       trace.Tracer
+	Instrumentor *producer.Instrumentor
+
+	pool *channelPool
 }
 
 type ProducerParams struct {
@@ -29,79 +52,297 @@ type ProducerParams struct {
 	Logger      log.Factory
 	Connections map[string]*Connection
 	Propagator  propagation.TextMapPropagator
+	Config      *ProducerConfig
+	Codecs      *codec.Registry `optional:"true"`
+	// TracerProvider creates the spans of the published messages, they are not recorded without it.
+	TracerProvider *tracesdk.TracerProvider `optional:"true"`
+	// Instrumentor records the metrics of the published messages, they are discarded without it.
+	Instrumentor *producer.Instrumentor `optional:"true"`
 }
 
 // NewRabbitMQProducer creates a pointer to the new instance of the Producer
 func NewRabbitMQProducer(params ProducerParams) (*Producer, error) {
 	p := &Producer{
-		Logger:     params.Logger,
-		Propagator: params.Propagator,
+		Logger:       params.Logger,
+		Propagator:   params.Propagator,
+		Config:       params.Config,
+		Codecs:       params.Codecs,
+		Instrumentor: params.Instrumentor,
+	}
+	if p.Codecs == nil {
+		p.Codecs = codec.NewRegistry()
+	}
+	if p.Instrumentor == nil {
+		instrumentor, err := producer.NewInstrumentor(producer.InstrumentorParams{})
+		if err != nil {
+			return nil, err
+		}
+		p.Instrumentor = instrumentor
+	}
+
+	var tracerProvider trace.TracerProvider = noop.NewTracerProvider()
+	if params.TracerProvider != nil {
+		tracerProvider = params.TracerProvider
+	}
+	p.Tracer = tracerProvider.Tracer("producer")
+	if _, err := p.Codecs.Get(p.Config.ContentType); err != nil {
+		return nil, fmt.Errorf("invalid content type for producer: %w", err)
 	}
 
 	if len(params.Connections) == 0 {
 		return nil, fmt.Errorf("no connections found. please check the connection configuration in your configuration")
 	}
 
+	if p.Config.Connection != "" {
+		p.Connection = params.Connections[p.Config.Connection]
+		if p.Connection == nil {
+			return nil, fmt.Errorf("connection %s not found for producer. please check the connection configuration in your configuration", p.Config.Connection)
+		}
+	}
+
 	// Use the first connection for the producer
 	for _, conn := range params.Connections {
-		if conn == nil {
+		if conn == nil || p.Connection != nil {
 			continue
 		}
 		p.Connection = conn
-		p.Logger.Bg().Info("using connection for producer", zap.String("connection", conn.Name()))
-		break
 	}
 
 	if p.Connection == nil {
 		return nil, fmt.Errorf("no valid connection found for producer")
 	}
+	p.Logger.Bg().Info("using connection for producer", zap.String("connection", p.Connection.Name()))
 
+	p.pool = newChannelPool(p.Connection, p.Config.PoolSize, returnsBufferSize)
 	return p, nil
 }
 
-func (p *Producer) Publish(ctx context.Context, messageName string, payload any) error {
-	message := messages.Message[any]{
-		Metadata: messages.Metadata{
-			PublisherName: info.ServiceName,
-			PublishDate:   time.Now().UTC(),
-			MessageName:   messageName,
-		},
-		Payload: payload,
+// Publish publishes the message and waits for the broker to confirm it.
+// It returns producer.ErrNacked if the broker refuses the message,
+// and producer.ErrUnroutable if a mandatory message is not routed to any queue.
+func (p *Producer) Publish(ctx context.Context, messageName string, payload any, options ...producer.PublishOption) error {
+	return p.PublishBatch(ctx, []producer.Publishing{{
+		MessageName: messageName,
+		Message:     payload,
+		Options:     options,
+	}})
+}
+
+// pendingConfirmation is a message waiting for the confirmation of the broker.
+type pendingConfirmation struct {
+	messageName string
+	messageID   string
+	exchange    string
+	confirm     *amqp091.DeferredConfirmation
+	span        trace.Span
+}
+
+// PublishBatch publishes the messages on a single channel and waits for their confirmations at once.
+func (p *Producer) PublishBatch(ctx context.Context, publishings []producer.Publishing) error {
+	channel, err := p.pool.get(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.pool.put(channel)
+
+	var errs []error
+	pending := make([]pendingConfirmation, 0, len(publishings))
+	for _, publishing := range publishings {
+		// the span is started first, so that the trace context of the message refers to it
+		spanCtx, span := p.startPublish(ctx)
+		exchange, routingKey, mandatory, msg, err := p.publishing(spanCtx, publishing)
+		if err != nil {
+			endSpan(span, err)
+			errs = append(errs, err)
+			continue
+		}
+		annotatePublish(span, exchange, routingKey, msg)
+
+		// TODO: Optionally log the message
+		confirm, err := channel.channel.PublishWithDeferredConfirmWithContext(spanCtx, exchange, routingKey, mandatory, false, msg)
+		if err != nil {
+			err = fmt.Errorf("failed to publish message %s: %w", publishing.MessageName, err)
+			endSpan(span, err)
+			errs = append(errs, err)
+			continue
+		}
+		p.Instrumentor.Published(destinationName(exchange), publishing.MessageName)
+		pending = append(pending, pendingConfirmation{
+			messageName: publishing.MessageName,
+			messageID:   msg.MessageId,
+			exchange:    exchange,
+			confirm:     confirm,
+			span:        span,
+		})
+	}
+
+	errs = append(errs, p.waitForConfirmations(ctx, channel, pending)...)
+	return errors.Join(errs...)
+}
+
+// waitForConfirmations waits for the confirmations until the confirm timeout,
+// reading the returned messages meanwhile so that the broker is never blocked by them.
+// The outcome of every message is recorded, and its span is ended.
+func (p *Producer) waitForConfirmations(ctx context.Context, channel *confirmChannel, pending []pendingConfirmation) []error {
+	ctx, cancel := context.WithTimeout(ctx, p.Config.ConfirmTimeout)
+	defer cancel()
+
+	var errs []error
+	returned := make(map[string]bool)
+	readReturn := func(r amqp091.Return, ok bool) {
+		if !ok {
+			channel.returns = nil
+			return
+		}
+		returned[r.MessageId] = true
+	}
+
+	for i, message := range pending {
+	wait:
+		for {
+			select {
+			case r, ok := <-channel.returns:
+				readReturn(r, ok)
+			case <-message.confirm.Done():
+				break wait
+			case <-ctx.Done():
+				// later confirmations of the channel would be mistaken for the next publishings
+				channel.discard = true
+				for _, message := range pending[i:] {
+					err := fmt.Errorf("message %s is not confirmed in %s: %w", message.messageName, p.Config.ConfirmTimeout, ctx.Err())
+					p.confirmed(message, err)
+					errs = append(errs, err)
+				}
+				return errs
+			}
+		}
+
+		// the message is returned before it is confirmed
+	drain:
+		for {
+			select {
+			case r, ok := <-channel.returns:
+				readReturn(r, ok)
+			default:
+				break drain
+			}
+		}
+
+		var err error
+		switch {
+		case !message.confirm.Acked() && channel.err() != nil:
+			err = fmt.Errorf("message %s is not confirmed: %w", message.messageName, channel.err())
+		case !message.confirm.Acked():
+			err = fmt.Errorf("failed to publish message %s: %w", message.messageName, producer.ErrNacked)
+		case returned[message.messageID]:
+			err = fmt.Errorf("failed to publish message %s: %w", message.messageName, producer.ErrUnroutable)
+		}
+		p.confirmed(message, err)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// confirmed records the outcome of the message, and ends its span.
+func (p *Producer) confirmed(message pendingConfirmation, err error) {
+	destination := destinationName(message.exchange)
+	switch {
+	case err == nil:
+		p.Instrumentor.Confirmed(destination, message.messageName)
+	case errors.Is(err, producer.ErrUnroutable):
+		p.Instrumentor.Returned(destination, message.messageName)
+	default:
+		p.Instrumentor.Failed(destination, message.messageName)
 	}
+	endSpan(message.span, err)
+}
+
+// publishing builds the amqp message of the publishing, applying its options over the defaults of the producer.
+func (p *Producer) publishing(ctx context.Context, publishing producer.Publishing) (string, string, bool, amqp091.Publishing, error) {
+	options := producer.NewPublishOptions(publishing.Options...)
+
+	// unroutable messages are matched with their ids when they are returned
+	messageID := options.MessageID
+	if messageID == "" {
+		messageID = uuid.NewString()
+	}
+
+	metadata := messages.Metadata{
+		MessageID:      messageID,
+		CorrelationID:  options.CorrelationID,
+		CausationID:    options.CausationID,
+		PublisherName:  info.ServiceName,
+		PublishDate:    time.Now().UTC(),
+		MessageName:    publishing.MessageName,
+		MessageVersion: options.MessageVersion,
+	}
+	metadata.Correlate(ctx)
 
 	// Enrich the message with trace information
-	p.enrichMessageWithTrace(ctx, &message)
+	p.enrichMetadataWithTrace(ctx, &metadata)
 
-	body, err := json.Marshal(message)
+	contentType := p.Config.ContentType
+	if options.ContentType != "" {
+		contentType = options.ContentType
+	}
+	c, err := p.Codecs.Get(contentType)
 	if err != nil {
-		return err
+		return "", "", false, amqp091.Publishing{}, fmt.Errorf("failed to marshal message %s: %w", publishing.MessageName, err)
 	}
 
-	channel, err := p.Connection.Conn.Channel()
+	body, contentType, headers, err := encodePublishing(p.Config.Format, c, metadata, publishing.Message, options.Headers)
 	if err != nil {
-		return fmt.Errorf("failed to create channel: %w", err)
+		return "", "", false, amqp091.Publishing{}, fmt.Errorf("failed to marshal message %s: %w", publishing.MessageName, err)
 	}
-	defer func() {
-		if err := channel.Close(); err != nil {
-			p.Logger.Bg().Error("failed to close channel", zap.Error(err))
-		}
-	}()
-
-	// TODO: Optionally log the message
-	return channel.PublishWithContext(
-		ctx,
-		info.ServiceName, // exchange
-		messageName,      // routing key
-		false,
-		false,
-		amqp091.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-		},
-	)
+
+	exchange := p.Config.Exchange
+	if options.Exchange != "" {
+		exchange = options.Exchange
+	}
+
+	routingKey := publishing.MessageName
+	if options.RoutingKey != "" {
+		routingKey = options.RoutingKey
+	}
+
+	mandatory := p.Config.Mandatory
+	if options.Mandatory != nil {
+		mandatory = *options.Mandatory
+	}
+
+	persistent := p.Config.Persistent
+	if options.Persistent != nil {
+		persistent = *options.Persistent
+	}
+	deliveryMode := amqp091.Transient
+	if persistent {
+		deliveryMode = amqp091.Persistent
+	}
+
+	var expiration string
+	if options.Expiration > 0 {
+		expiration = strconv.FormatInt(options.Expiration.Milliseconds(), 10)
+	}
+
+	return exchange, routingKey, mandatory, amqp091.Publishing{
+		Headers:       headers,
+		ContentType:   contentType,
+		DeliveryMode:  deliveryMode,
+		Priority:      options.Priority,
+		Expiration:    expiration,
+		MessageId:     messageID,
+		CorrelationId: metadata.CorrelationID,
+		Timestamp:     metadata.PublishDate,
+		Type:          publishing.MessageName,
+		AppId:         info.ServiceName,
+		Body:          body,
+	}, nil
 }
 
-func (p *Producer) enrichMessageWithTrace(ctx context.Context, message *messages.Message[any]) {
+func (p *Producer) enrichMetadataWithTrace(ctx context.Context, metadata *messages.Metadata) {
 	span := trace.SpanFromContext(ctx)
 	if !span.SpanContext().IsValid() {
 		return
@@ -111,9 +352,57 @@ func (p *Producer) enrichMessageWithTrace(ctx context.Context, message *messages
 	carrier := propagation.MapCarrier{}
 	p.Propagator.Inject(ctx, carrier)
 
-	message.Metadata.Traceparent = carrier["traceparent"]
-	message.Metadata.Tracestate = carrier["tracestate"]
+	metadata.Traceparent = carrier["traceparent"]
+	metadata.Tracestate = carrier["tracestate"]
 
 	// This is the CURRENT span ID (the one sending the message)
-	message.Metadata.SpanID = span.SpanContext().SpanID().String()
+	metadata.SpanID = span.SpanContext().SpanID().String()
+}
+
+// startPublish starts the span of a message, it is named and annotated with annotatePublish once the message is built.
+func (p *Producer) startPublish(ctx context.Context) (context.Context, trace.Span) {
+	return p.Tracer.Start(ctx, "publish", trace.WithSpanKind(trace.SpanKindProducer))
+}
+
+// annotatePublish names the span after the exchange of the message and sets the attributes of the messaging semantic conventions.
+func annotatePublish(span trace.Span, exchange, routingKey string, msg amqp091.Publishing) {
+	span.SetName("publish " + destinationName(exchange))
+
+	attributes := []attribute.KeyValue{
+		semconv.MessagingSystemRabbitMQ,
+		semconv.MessagingOperationTypeSend,
+		semconv.MessagingOperationName("publish"),
+		semconv.MessagingDestinationName(destinationName(exchange, routingKey)),
+		semconv.MessagingRabbitMQDestinationRoutingKey(routingKey),
+		semconv.MessagingMessageID(msg.MessageId),
+		semconv.MessagingMessageBodySize(len(msg.Body)),
+	}
+	if msg.CorrelationId != "" {
+		attributes = append(attributes, semconv.MessagingMessageConversationID(msg.CorrelationId))
+	}
+	span.SetAttributes(attributes...)
+}
+
+// endSpan records the error of the span, if there is one, and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// destinationName joins the exchange, the routing key and the queue of a message as in the messaging semantic conventions,
+// omitting the empty ones. The default exchange is named amq.default.
+func destinationName(parts ...string) string {
+	names := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part != "" {
+			names = append(names, part)
+		}
+	}
+	if len(names) == 0 {
+		return "amq.default"
+	}
+	return strings.Join(names, ":")
 }
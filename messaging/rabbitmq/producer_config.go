@@ -0,0 +1,107 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/enesanbar/go-service/core/config"
+	"github.com/enesanbar/go-service/core/info"
+	"github.com/enesanbar/go-service/core/messaging/codec"
+)
+
+const (
+	PropertyConnection     = "connection"
+	PropertyPoolSize       = "pool-size"
+	PropertyConfirmTimeout = "confirm-timeout"
+	PropertyMandatory      = "mandatory"
+	PropertyPersistent     = "persistent"
+	PropertyFormat         = "format"
+	PropertyContentType    = "content-type"
+
+	DefaultPoolSize       = 4
+	DefaultConfirmTimeout = 5 * time.Second
+)
+
+const (
+	// FormatEnvelope publishes the metadata and the JSON payload of the messages in a JSON envelope.
+	FormatEnvelope = "envelope"
+	// FormatCloudEventsBinary publishes the payloads as they are encoded, and the metadata as CloudEvents headers.
+	FormatCloudEventsBinary = "cloudevents-binary"
+	// FormatCloudEventsStructured publishes the messages as CloudEvents in the JSON event format.
+	FormatCloudEventsStructured = "cloudevents-structured"
+)
+
+// ProducerConfig configures the Producer, under rabbitmq.producer.
+type ProducerConfig struct {
+	// Connection is the name of the connection used by the producer, any connection by default.
+	Connection string
+	// Exchange is the default exchange of the messages, the name of the service by default.
+	Exchange string
+	// PoolSize is the number of channels publishing at the same time.
+	PoolSize int
+	// ConfirmTimeout is the time the broker is waited for to confirm the messages.
+	ConfirmTimeout time.Duration
+	// Mandatory fails the messages that are not routed to any queue.
+	Mandatory bool
+	// Persistent messages survive broker restarts, if they are routed to durable queues.
+	Persistent bool
+	// Format of the messages, the envelope of go-service by default.
+	Format string
+	// ContentType selects the codec of the payloads, JSON by default.
+	ContentType string
+}
+
+func NewProducerConfig(cfg config.Config) (*ProducerConfig, error) {
+	keyTemplate := "rabbitmq.producer.%s"
+
+	exchange := cfg.GetString(fmt.Sprintf(keyTemplate, PropertyExchange))
+	if exchange == "" {
+		exchange = info.ServiceName
+	}
+
+	poolSize := cfg.GetInt(fmt.Sprintf(keyTemplate, PropertyPoolSize))
+	if poolSize == 0 {
+		poolSize = DefaultPoolSize
+	}
+	if poolSize < 0 {
+		return nil, fmt.Errorf("invalid value '%d' for %s", poolSize, fmt.Sprintf(keyTemplate, PropertyPoolSize))
+	}
+
+	confirmTimeout := DefaultConfirmTimeout
+	property := fmt.Sprintf(keyTemplate, PropertyConfirmTimeout)
+	if value := cfg.GetString(property); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value '%s' for %s: %w", value, property, err)
+		}
+		confirmTimeout = d
+	}
+
+	format := cfg.GetString(fmt.Sprintf(keyTemplate, PropertyFormat))
+	switch format {
+	case "":
+		format = FormatEnvelope
+	case FormatEnvelope, FormatCloudEventsBinary, FormatCloudEventsStructured:
+	default:
+		return nil, fmt.Errorf("invalid value '%s' for %s", format, fmt.Sprintf(keyTemplate, PropertyFormat))
+	}
+
+	contentType := cfg.GetString(fmt.Sprintf(keyTemplate, PropertyContentType))
+	if contentType == "" {
+		contentType = codec.ContentTypeJSON
+	}
+	if format == FormatEnvelope && !codec.IsJSON(contentType) {
+		return nil, fmt.Errorf("invalid value '%s' for %s: the envelope format supports JSON payloads only", contentType, fmt.Sprintf(keyTemplate, PropertyContentType))
+	}
+
+	return &ProducerConfig{
+		Connection:     cfg.GetString(fmt.Sprintf(keyTemplate, PropertyConnection)),
+		Exchange:       exchange,
+		PoolSize:       poolSize,
+		ConfirmTimeout: confirmTimeout,
+		Mandatory:      cfg.GetBool(fmt.Sprintf(keyTemplate, PropertyMandatory)),
+		Persistent:     cfg.GetBool(fmt.Sprintf(keyTemplate, PropertyPersistent)),
+		Format:         format,
+		ContentType:    contentType,
+	}, nil
+}
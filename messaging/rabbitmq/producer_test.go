@@ -0,0 +1,305 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/enesanbar/go-service/core/config/configtest"
+	"github.com/enesanbar/go-service/core/log"
+	"github.com/enesanbar/go-service/core/messaging/messages"
+	"github.com/enesanbar/go-service/core/messaging/producer"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	otelmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+func newTestProducer(t *testing.T, server *fakeServer, properties map[string]any) *Producer {
+	t.Helper()
+
+	properties["rabbitmq.connections.default"] = map[string]any{
+		PropertyHost:     "127.0.0.1",
+		PropertyPort:     server.port(),
+		PropertyUsername: "guest",
+		PropertyPassword: "guest",
+	}
+	conf := configtest.New(t, properties)
+	logger := log.NewFactory(zap.NewNop())
+
+	connections, err := Connections(conf, logger)
+	if err != nil {
+		t.Fatalf("unable to connect: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = connections["default"].Close(context.Background())
+	})
+
+	cfg, err := NewProducerConfig(conf)
+	if err != nil {
+		t.Fatalf("unable to create producer config: %v", err)
+	}
+
+	p, err := NewRabbitMQProducer(ProducerParams{
+		Logger:      logger,
+		Connections: connections,
+		Propagator:  propagation.TraceContext{},
+		Config:      cfg,
+	})
+	if err != nil {
+		t.Fatalf("unable to create producer: %v", err)
+	}
+	return p
+}
+
+// collectCounters returns the values of the counters by their names without the prefix of the service, e.g. messaging.producer.messages.published.
+func collectCounters(t *testing.T, reader otelmetric.Reader) map[string]int64 {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("unable to collect metrics: %v", err)
+	}
+
+	counters := make(map[string]int64)
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if !ok {
+				continue
+			}
+			name := m.Name[strings.Index(m.Name, "messaging."):]
+			for _, point := range sum.DataPoints {
+				counters[name] += point.Value
+			}
+		}
+	}
+	return counters
+}
+
+func TestNewProducerConfig(t *testing.T) {
+	cfg, err := NewProducerConfig(configtest.New(t, map[string]any{
+		"rabbitmq.producer.exchange":        "shop",
+		"rabbitmq.producer.confirm-timeout": "1s",
+		"rabbitmq.producer.mandatory":       true,
+	}))
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if cfg.Exchange != "shop" || cfg.ConfirmTimeout != time.Second || !cfg.Mandatory || cfg.PoolSize != DefaultPoolSize {
+		t.Errorf("Expected the configured values and the default pool size, got '%+v'", cfg)
+	}
+
+	_, err = NewProducerConfig(configtest.New(t, map[string]any{"rabbitmq.producer.confirm-timeout": "soon"}))
+	if err == nil {
+		t.Error("Expected an error for an invalid confirm timeout")
+	}
+}
+
+func TestProducer_PublishWaitsForConfirmation(t *testing.T) {
+	server := newFakeServer(t)
+	p := newTestProducer(t, server, map[string]any{
+		"rabbitmq.producer.exchange":   "shop",
+		"rabbitmq.producer.persistent": true,
+	})
+
+	err := p.Publish(context.Background(), "order-created", orderCreated{ID: 1},
+		producer.WithHeader("tenant", "acme"),
+		producer.WithMessageID("message-1"),
+	)
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	published := server.publishings()
+	if len(published) != 1 {
+		t.Fatalf("Expected '%d' published message, got '%d'", 1, len(published))
+	}
+	if published[0].exchange != "shop" || published[0].routingKey != "order-created" || published[0].messageID != "message-1" {
+		t.Errorf("Expected the message to be published to '%s' with '%s', got '%+v'", "shop", "order-created", published[0])
+	}
+
+	message := messages.Message[orderCreated]{}
+	if err := json.Unmarshal(published[0].body, &message); err != nil || message.Payload.ID != 1 {
+		t.Errorf("Expected the payload to be published, got '%s'", published[0].body)
+	}
+	if message.Metadata.MessageID != "message-1" || message.Metadata.CorrelationID != "message-1" {
+		t.Errorf("Expected the metadata to have the id of the message, got '%+v'", message.Metadata)
+	}
+}
+
+func TestProducer_PublishOptions(t *testing.T) {
+	server := newFakeServer(t)
+	p := newTestProducer(t, server, map[string]any{})
+
+	err := p.Publish(context.Background(), "order-created", orderCreated{ID: 1},
+		producer.WithExchange("audit"),
+		producer.WithRoutingKey("orders.created"),
+	)
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	published := server.publishings()
+	if published[0].exchange != "audit" || published[0].routingKey != "orders.created" || published[0].messageID == "" {
+		t.Errorf("Expected the message to be published to '%s' with '%s' and an id, got '%+v'", "audit", "orders.created", published[0])
+	}
+}
+
+func TestProducer_PublishUnroutable(t *testing.T) {
+	server := newFakeServer(t)
+	server.bind("shop", "order-created")
+	p := newTestProducer(t, server, map[string]any{"rabbitmq.producer.exchange": "shop"})
+
+	err := p.Publish(context.Background(), "order-deleted", orderCreated{ID: 1}, producer.WithMandatory(true))
+	if !errors.Is(err, producer.ErrUnroutable) {
+		t.Errorf("Expected '%v', got '%v'", producer.ErrUnroutable, err)
+	}
+
+	if err := p.Publish(context.Background(), "order-created", orderCreated{ID: 1}, producer.WithMandatory(true)); err != nil {
+		t.Errorf("Expected routed message to be published, got '%v'", err)
+	}
+
+	if err := p.Publish(context.Background(), "order-deleted", orderCreated{ID: 1}); err != nil {
+		t.Errorf("Expected unroutable message not to fail unless it is mandatory, got '%v'", err)
+	}
+}
+
+func TestProducer_PublishNacked(t *testing.T) {
+	server := newFakeServer(t)
+	server.nack("shop")
+	p := newTestProducer(t, server, map[string]any{"rabbitmq.producer.exchange": "shop"})
+
+	err := p.Publish(context.Background(), "order-created", orderCreated{ID: 1})
+	if !errors.Is(err, producer.ErrNacked) {
+		t.Errorf("Expected '%v', got '%v'", producer.ErrNacked, err)
+	}
+}
+
+func TestProducer_PublishConfirmTimeout(t *testing.T) {
+	server := newFakeServer(t)
+	server.withholdConfirmations()
+	p := newTestProducer(t, server, map[string]any{"rabbitmq.producer.confirm-timeout": "50ms"})
+
+	err := p.Publish(context.Background(), "order-created", orderCreated{ID: 1})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected '%v', got '%v'", context.DeadlineExceeded, err)
+	}
+}
+
+func TestProducer_PublishBatch(t *testing.T) {
+	server := newFakeServer(t)
+	server.bind("shop", "order-created")
+	p := newTestProducer(t, server, map[string]any{
+		"rabbitmq.producer.exchange":  "shop",
+		"rabbitmq.producer.mandatory": true,
+		"rabbitmq.producer.pool-size": 1,
+	})
+
+	publishings := make([]producer.Publishing, 0, 100)
+	for i := 0; i < 100; i++ {
+		publishings = append(publishings, producer.Publishing{MessageName: "order-created", Message: orderCreated{ID: i}})
+	}
+	publishings[42].MessageName = "order-deleted"
+
+	err := p.PublishBatch(context.Background(), publishings)
+	if !errors.Is(err, producer.ErrUnroutable) {
+		t.Errorf("Expected '%v' for the unroutable message, got '%v'", producer.ErrUnroutable, err)
+	}
+	if len(server.publishings()) != 100 {
+		t.Errorf("Expected '%d' published messages, got '%d'", 100, len(server.publishings()))
+	}
+
+	// the channel is reused
+	for i := 0; i < 5; i++ {
+		if err := p.Publish(context.Background(), "order-created", orderCreated{ID: i}); err != nil {
+			t.Errorf("Expected no error, got '%v'", err)
+		}
+	}
+	if opened := server.called("confirm.select"); opened != 1 {
+		t.Errorf("Expected '%d' channel to be opened, got '%d'", 1, opened)
+	}
+}
+
+func TestProducer_PublishRecordsSpansAndMetrics(t *testing.T) {
+	server := newFakeServer(t)
+	server.bind("shop", "order-created")
+	p := newTestProducer(t, server, map[string]any{
+		"rabbitmq.producer.exchange":  "shop",
+		"rabbitmq.producer.mandatory": true,
+	})
+
+	recorder := tracetest.NewSpanRecorder()
+	p.Tracer = tracesdk.NewTracerProvider(tracesdk.WithSpanProcessor(recorder)).Tracer("producer")
+	reader := otelmetric.NewManualReader()
+	instrumentor, err := producer.NewInstrumentor(producer.InstrumentorParams{
+		MeterProvider: otelmetric.NewMeterProvider(otelmetric.WithReader(reader)),
+	})
+	if err != nil {
+		t.Fatalf("unable to create instrumentor: %v", err)
+	}
+	p.Instrumentor = instrumentor
+
+	if err := p.Publish(context.Background(), "order-created", orderCreated{ID: 1}, producer.WithMessageID("message-1")); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if err := p.Publish(context.Background(), "order-deleted", orderCreated{ID: 1}); !errors.Is(err, producer.ErrUnroutable) {
+		t.Fatalf("Expected '%v', got '%v'", producer.ErrUnroutable, err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected '%d' spans, got '%d'", 2, len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "publish shop" || span.SpanKind() != trace.SpanKindProducer {
+		t.Errorf("Expected a producer span named '%s', got '%s' of kind '%s'", "publish shop", span.Name(), span.SpanKind())
+	}
+	published := server.publishings()[0]
+	expected := map[attribute.Key]attribute.Value{
+		"messaging.system":                           attribute.StringValue("rabbitmq"),
+		"messaging.destination.name":                 attribute.StringValue("shop:order-created"),
+		"messaging.rabbitmq.destination.routing_key": attribute.StringValue("order-created"),
+		"messaging.message.id":                       attribute.StringValue("message-1"),
+		"messaging.message.body.size":                attribute.IntValue(len(published.body)),
+	}
+	for _, kv := range span.Attributes() {
+		if value, ok := expected[kv.Key]; ok {
+			if kv.Value != value {
+				t.Errorf("Expected attribute '%s' to be '%s', got '%s'", kv.Key, value.Emit(), kv.Value.Emit())
+			}
+			delete(expected, kv.Key)
+		}
+	}
+	if len(expected) > 0 {
+		t.Errorf("Expected the span to have the attributes '%v'", expected)
+	}
+
+	// the consumers continue the trace from the producer span
+	message := messages.Message[orderCreated]{}
+	if err := json.Unmarshal(published.body, &message); err != nil || message.Metadata.SpanID != span.SpanContext().SpanID().String() {
+		t.Errorf("Expected the metadata to refer to span '%s', got '%+v'", span.SpanContext().SpanID(), message.Metadata)
+	}
+	if spans[1].Status().Code != codes.Error {
+		t.Errorf("Expected the span of the unroutable message to fail, got '%s'", spans[1].Status().Code)
+	}
+
+	counters := collectCounters(t, reader)
+	for name, value := range map[string]int64{
+		"messaging.producer.messages.published": 2,
+		"messaging.producer.messages.confirmed": 1,
+		"messaging.producer.messages.returned":  1,
+	} {
+		if counters[name] != value {
+			t.Errorf("Expected '%s' to be '%d', got '%d'", name, value, counters[name])
+		}
+	}
+}
@@ -1,16 +1,191 @@
 package rabbitmq
 
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
 const (
-	PropertyConsumerTag = "consumer-tag"
-	PropertyChannel     = "channel"
-	PropertyAutoAck     = "auto-ack"
-	PropertyDurable     = "durable"
-	PropertyType        = "type"
-	PropertyAutoDelete  = "auto-delete"
-	PropertyExclusive   = "exclusive"
-	PropertyNoLocal     = "no-local"
-	PropertyNoWait      = "no-wait"
-	PropertyQueue       = "queue"
-	PropertyExchange    = "exchange"
-	PropertyRoutingKeys = "routing-keys"
+	PropertyConsumerTag          = "consumer-tag"
+	PropertyChannel              = "channel"
+	PropertyAutoAck              = "auto-ack"
+	PropertyDurable              = "durable"
+	PropertyType                 = "type"
+	PropertyAutoDelete           = "auto-delete"
+	PropertyExclusive            = "exclusive"
+	PropertyNoLocal              = "no-local"
+	PropertyNoWait               = "no-wait"
+	PropertyQueue                = "queue"
+	PropertyExchange             = "exchange"
+	PropertyRoutingKeys          = "routing-keys"
+	PropertyRequeue              = "requeue"
+	PropertyPrefetchCount        = "prefetch-count"
+	PropertyMaxAttempts          = "max-attempts"
+	PropertyRetryDelay           = "retry-delay"
+	PropertyDeadLetterExchange   = "dead-letter-exchange"
+	PropertyDeadLetterRoutingKey = "dead-letter-routing-key"
+	PropertyConcurrency          = "concurrency"
+	PropertyOrdered              = "ordered"
+	PropertyGracefulStopTimeout  = "graceful-stop-timeout"
+	PropertyInternal             = "internal"
+	PropertyPassive              = "passive"
+	PropertyArguments            = "arguments"
+	PropertyMessageTTL           = "message-ttl"
+	PropertyMaxLength            = "max-length"
+	PropertyMaxLengthBytes       = "max-length-bytes"
+	PropertyAlternateExchange    = "alternate-exchange"
+	PropertyDestinationExchange  = "destination-exchange"
 )
+
+// intProperty returns the integer value of the property, which is decoded from the configuration file as int or float64.
+func intProperty(config map[string]interface{}, property string, defaultValue int) (int, error) {
+	value, ok := config[property]
+	if !ok {
+		return defaultValue, nil
+	}
+
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		return int(v), nil
+	case string:
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid value '%s' for %s: %w", v, property, err)
+		}
+		return i, nil
+	default:
+		return 0, fmt.Errorf("invalid value '%v' for %s", value, property)
+	}
+}
+
+// durationProperty returns the duration value of the property, e.g. 500ms or 10s.
+func durationProperty(config map[string]interface{}, property string, defaultValue time.Duration) (time.Duration, error) {
+	value, ok := config[property]
+	if !ok {
+		return defaultValue, nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return 0, fmt.Errorf("invalid value '%v' for %s, expected a duration such as 10s", value, property)
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value '%s' for %s: %w", s, property, err)
+	}
+	return d, nil
+}
+
+// boolProperty returns the boolean value of the property.
+func boolProperty(config map[string]interface{}, property string, defaultValue bool) (bool, error) {
+	value, ok := config[property]
+	if !ok || value == nil {
+		return defaultValue, nil
+	}
+
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, fmt.Errorf("invalid value '%s' for %s, expected true or false", v, property)
+		}
+		return b, nil
+	default:
+		return false, fmt.Errorf("invalid value '%v' for %s, expected true or false", value, property)
+	}
+}
+
+// stringProperty returns the string value of the property.
+func stringProperty(config map[string]interface{}, property string, defaultValue string) (string, error) {
+	value, ok := config[property]
+	if !ok || value == nil {
+		return defaultValue, nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid value '%v' for %s, expected a string", value, property)
+	}
+	return s, nil
+}
+
+// stringsProperty returns the list of strings of the property, which is decoded from the configuration file as []interface{}.
+func stringsProperty(config map[string]interface{}, property string) ([]string, error) {
+	value, ok := config[property]
+	if !ok || value == nil {
+		return nil, nil
+	}
+
+	switch v := value.(type) {
+	case []string:
+		return v, nil
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid value '%v' for %s, expected a list of strings", value, property)
+			}
+			result = append(result, s)
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("invalid value '%v' for %s, expected a list of strings", value, property)
+	}
+}
+
+// tableProperty returns the arguments of the property, e.g. the arguments of a queue, as an amqp table.
+func tableProperty(config map[string]interface{}, property string) (amqp.Table, error) {
+	value, ok := config[property]
+	if !ok || value == nil {
+		return amqp.Table{}, nil
+	}
+
+	arguments, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid value '%v' for %s, expected a map", value, property)
+	}
+
+	table := amqp.Table{}
+	for name, argument := range arguments {
+		// the numbers of the JSON and YAML files are decoded as float64 and int, which are not all valid in amqp tables
+		switch v := argument.(type) {
+		case int:
+			table[name] = int64(v)
+		case float64:
+			if v == float64(int64(v)) {
+				table[name] = int64(v)
+			} else {
+				table[name] = v
+			}
+		default:
+			table[name] = v
+		}
+	}
+	if err := table.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %w", property, err)
+	}
+	return table, nil
+}
+
+// objectProperties returns the properties of the object configured under a key, e.g. a queue.
+func objectProperties(value interface{}) (map[string]interface{}, error) {
+	if value == nil {
+		return map[string]interface{}{}, nil
+	}
+	config, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid value '%v', expected a map of properties", value)
+	}
+	return config, nil
+}
@@ -1,6 +1,8 @@
 package rabbitmq
 
 import (
+	"fmt"
+
 	"github.com/enesanbar/go-service/core/log"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"go.uber.org/zap"
@@ -20,37 +22,58 @@ type QueueParams struct {
 }
 
 func NewQueue(p QueueParams) (*Queue, error) {
-	q, err := p.Channel.Channel.QueueDeclare(
-		p.Config.Name,       // name
-		p.Config.Durable,    // durable
-		p.Config.AutoDelete, // delete when unused
-		p.Config.Exclusive,  // exclusive
-		p.Config.NoWait,     // no-wait
-		nil,                 // arguments
+	q := &Queue{
+		Channel: p.Channel,
+		logger:  p.Logger,
+		Config:  p.Config,
+	}
+
+	if err := q.declare(p.Channel.GetChannel()); err != nil {
+		return nil, err
+	}
+	p.Channel.NotifyRecovery(q.declare)
+
+	return q, nil
+}
+
+// declare declares the queue on the channel, it is called again whenever the channel is recovered.
+func (q *Queue) declare(channel *amqp.Channel) error {
+	if channel == nil {
+		return fmt.Errorf("channel %s is not connected", q.Channel.Config.Name)
+	}
+
+	// a passive declaration only checks that the queue exists, with the same properties
+	declare := channel.QueueDeclare
+	if q.Config.Passive {
+		declare = channel.QueueDeclarePassive
+	}
+	queue, err := declare(
+		q.Config.Name,       // name
+		q.Config.Durable,    // durable
+		q.Config.AutoDelete, // delete when unused
+		q.Config.Exclusive,  // exclusive
+		q.Config.NoWait,     // no-wait
+		q.Config.Table(),    // arguments
 	)
 
 	if err != nil {
-		p.Logger.Bg().
-			With(zap.String("name", p.Config.Name)).
-			With(zap.String("connection", p.Channel.Config.Connection.Config.Host)).
-			With(zap.String("channel", p.Channel.Config.Name)).
-			With(zap.String("queue", p.Config.Name)).
+		q.logger.Bg().
+			With(zap.String("name", q.Config.Name)).
+			With(zap.String("connection", q.Channel.Config.Connection.connectedHost())).
+			With(zap.String("channel", q.Channel.Config.Name)).
+			With(zap.String("queue", q.Config.Name)).
 			With(zap.Error(err)).
 			Error("failed to create queue")
-		return nil, err
+		return err
 	}
 
-	p.Logger.Bg().
-		With(zap.String("name", p.Config.Name)).
-		With(zap.String("connection", p.Channel.Config.Connection.Config.Host)).
-		With(zap.String("channel", p.Channel.Config.Name)).
-		With(zap.String("queue", p.Config.Name)).
+	q.logger.Bg().
+		With(zap.String("name", q.Config.Name)).
+		With(zap.String("connection", q.Channel.Config.Connection.connectedHost())).
+		With(zap.String("channel", q.Channel.Config.Name)).
+		With(zap.String("queue", q.Config.Name)).
 		Info("Queue created")
 
-	return &Queue{
-		Queue:   &q,
-		Channel: p.Channel,
-		logger:  p.Logger,
-		Config:  p.Config,
-	}, nil
+	q.Queue = &queue
+	return nil
 }
@@ -1,5 +1,18 @@
 package rabbitmq
 
+import (
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	QueueTypeClassic = "classic"
+	QueueTypeQuorum  = "quorum"
+	QueueTypeStream  = "stream"
+)
+
 type QueueConfig struct {
 	Name       string
 	Channel    *Channel
@@ -7,4 +20,116 @@ type QueueConfig struct {
 	AutoDelete bool
 	Exclusive  bool
 	NoWait     bool
+	// Passive checks that the queue exists instead of declaring it, e.g. for the queues of other services.
+	Passive bool
+	// Type is classic, quorum or stream, classic by default. Quorum and stream queues are durable.
+	Type string
+	// DeadLetterExchange receives the messages that are rejected or expired in the queue.
+	DeadLetterExchange string
+	// DeadLetterRoutingKey overrides the routing key of the dead-lettered messages.
+	DeadLetterRoutingKey string
+	// MessageTTL expires the messages that stay longer in the queue, zero means no expiration.
+	MessageTTL time.Duration
+	// MaxLength and MaxLengthBytes bound the queue, zero means no limit.
+	MaxLength      int
+	MaxLengthBytes int
+	// Arguments are the other arguments of the queue, e.g. x-overflow or x-single-active-consumer.
+	Arguments amqp.Table
+}
+
+// NewQueueConfig returns the config of the queue from its properties in the configuration file.
+// The channel of the queue is set by the caller.
+func NewQueueConfig(name string, cfg interface{}) (*QueueConfig, error) {
+	config, err := objectProperties(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	queueType, err := stringProperty(config, PropertyType, QueueTypeClassic)
+	if err != nil {
+		return nil, err
+	}
+	if queueType != QueueTypeClassic && queueType != QueueTypeQuorum && queueType != QueueTypeStream {
+		return nil, fmt.Errorf("invalid value '%s' for %s, must be one of classic, quorum or stream", queueType, PropertyType)
+	}
+	replicated := queueType != QueueTypeClassic
+
+	c := &QueueConfig{Name: name, Type: queueType}
+	for property, target := range map[string]*bool{
+		PropertyAutoDelete: &c.AutoDelete,
+		PropertyExclusive:  &c.Exclusive,
+		PropertyNoWait:     &c.NoWait,
+		PropertyPassive:    &c.Passive,
+	} {
+		if *target, err = boolProperty(config, property, false); err != nil {
+			return nil, err
+		}
+	}
+	if c.Durable, err = boolProperty(config, PropertyDurable, replicated); err != nil {
+		return nil, err
+	}
+	if replicated && (!c.Durable || c.Exclusive || c.AutoDelete) {
+		return nil, fmt.Errorf("%s queues must be durable, and can be neither exclusive nor auto-delete", queueType)
+	}
+
+	if c.DeadLetterExchange, err = stringProperty(config, PropertyDeadLetterExchange, ""); err != nil {
+		return nil, err
+	}
+	if c.DeadLetterRoutingKey, err = stringProperty(config, PropertyDeadLetterRoutingKey, ""); err != nil {
+		return nil, err
+	}
+	if c.DeadLetterRoutingKey != "" && c.DeadLetterExchange == "" {
+		return nil, fmt.Errorf("%s requires %s", PropertyDeadLetterRoutingKey, PropertyDeadLetterExchange)
+	}
+
+	if c.MessageTTL, err = durationProperty(config, PropertyMessageTTL, 0); err != nil {
+		return nil, err
+	}
+	if c.MaxLength, err = intProperty(config, PropertyMaxLength, 0); err != nil {
+		return nil, err
+	}
+	if c.MaxLengthBytes, err = intProperty(config, PropertyMaxLengthBytes, 0); err != nil {
+		return nil, err
+	}
+	if c.MessageTTL < 0 || c.MaxLength < 0 || c.MaxLengthBytes < 0 {
+		return nil, fmt.Errorf("%s, %s and %s cannot be negative", PropertyMessageTTL, PropertyMaxLength, PropertyMaxLengthBytes)
+	}
+
+	if c.Arguments, err = tableProperty(config, PropertyArguments); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Table returns the arguments the queue is declared with, the properties of the queue override its Arguments.
+func (c *QueueConfig) Table() amqp.Table {
+	table := amqp.Table{}
+	for name, value := range c.Arguments {
+		table[name] = value
+	}
+
+	// classic queues are declared without type, as the queues declared before the types
+	if c.Type != "" && c.Type != QueueTypeClassic {
+		table[amqp.QueueTypeArg] = c.Type
+	}
+	if c.DeadLetterExchange != "" {
+		table["x-dead-letter-exchange"] = c.DeadLetterExchange
+	}
+	if c.DeadLetterRoutingKey != "" {
+		table["x-dead-letter-routing-key"] = c.DeadLetterRoutingKey
+	}
+	if c.MessageTTL > 0 {
+		table[amqp.QueueMessageTTLArg] = c.MessageTTL.Milliseconds()
+	}
+	if c.MaxLength > 0 {
+		table[amqp.QueueMaxLenArg] = int64(c.MaxLength)
+	}
+	if c.MaxLengthBytes > 0 {
+		table[amqp.QueueMaxLenBytesArg] = int64(c.MaxLengthBytes)
+	}
+
+	if len(table) == 0 {
+		return nil
+	}
+	return table
 }
@@ -1,12 +1,15 @@
 package rabbitmq
 
 import (
+	"fmt"
+
 	"github.com/enesanbar/go-service/core/config"
 	"github.com/enesanbar/go-service/core/log"
 	"go.uber.org/zap"
 )
 
 // Queues creates a map of queues from the configuration file.
+// It returns an error if a queue is malformed or cannot be declared.
 func Queues(conf config.Config, logger log.Factory, channels map[string]*Channel) (map[string]*Queue, error) {
 	if len(channels) == 0 {
 		return nil, nil
@@ -17,7 +20,16 @@ func Queues(conf config.Config, logger log.Factory, channels map[string]*Channel
 	queues := make(map[string]*Queue)
 
 	for queueName, v := range cfg {
-		channelName := v.(map[string]interface{})[PropertyChannel].(string)
+		queueConfig, err := NewQueueConfig(queueName, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid configuration of queue %s: %w", queueName, err)
+		}
+		properties, _ := objectProperties(v)
+		channelName, err := stringProperty(properties, PropertyChannel, "")
+		if err != nil || channelName == "" {
+			return nil, fmt.Errorf("invalid configuration of queue %s: %s is required", queueName, PropertyChannel)
+		}
+
 		channel, ok := channels[channelName]
 		if !ok {
 			logger.Bg().
@@ -26,27 +38,15 @@ func Queues(conf config.Config, logger log.Factory, channels map[string]*Channel
 				Error("channel not found for queue. please check the channel configuration in your configuration")
 			continue
 		}
+		queueConfig.Channel = channel
 
 		queue, err := NewQueue(QueueParams{
 			Channel: channel,
 			Logger:  logger,
-			Config: &QueueConfig{
-				Name:       queueName,
-				Channel:    channel,
-				Durable:    v.(map[string]interface{})[PropertyDurable].(bool),
-				AutoDelete: v.(map[string]interface{})[PropertyAutoDelete].(bool),
-				Exclusive:  v.(map[string]interface{})[PropertyExclusive].(bool),
-				NoWait:     v.(map[string]interface{})[PropertyNoWait].(bool),
-			},
+			Config:  queueConfig,
 		})
-
 		if err != nil {
-			logger.Bg().
-				With(zap.String("queue", queueName)).
-				With(zap.String("channel", channelName)).
-				With(zap.Error(err)).
-				Error("failed to create queue")
-			panic(err)
+			return nil, fmt.Errorf("failed to declare queue %s: %w", queueName, err)
 		}
 
 		queues[queueName] = queue
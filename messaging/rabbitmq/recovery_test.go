@@ -0,0 +1,189 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/enesanbar/go-service/core/config/configtest"
+	"github.com/enesanbar/go-service/core/log"
+	"github.com/enesanbar/go-service/core/messaging/consumer"
+	"github.com/enesanbar/go-service/core/messaging/messages"
+	"go.opentelemetry.io/otel/propagation"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+)
+
+type orderCreated struct {
+	ID int `json:"id"`
+}
+
+type orderHandler struct {
+	handled chan int
+}
+
+func (h *orderHandler) Handle(_ context.Context, message messages.Message[any]) error {
+	h.handled <- message.Payload.(*orderCreated).ID
+	return nil
+}
+
+func (h *orderHandler) Properties() consumer.MessageProperties {
+	return consumer.MessageProperties{QueueName: "orders", MessageName: "order-created"}
+}
+
+func (h *orderHandler) GetMessageType() any {
+	return &orderCreated{}
+}
+
+func orderMessage(t *testing.T, id int) []byte {
+	t.Helper()
+
+	body, err := json.Marshal(messages.Message[orderCreated]{
+		Metadata: messages.Metadata{MessageName: "order-created"},
+		Payload:  orderCreated{ID: id},
+	})
+	if err != nil {
+		t.Fatalf("unable to marshal message: %v", err)
+	}
+	return body
+}
+
+func expectHandled(t *testing.T, handler *orderHandler, id int) {
+	t.Helper()
+
+	select {
+	case handled := <-handler.handled:
+		if handled != id {
+			t.Errorf("Expected message '%d' to be handled, got '%d'", id, handled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected message '%d' to be handled", id)
+	}
+}
+
+func TestRecovery_RedeclaresTopologyAndRestartsConsumers(t *testing.T) {
+	reconnectDelay = 10 * time.Millisecond
+	server := newFakeServer(t)
+	logger := log.NewFactory(zap.NewNop())
+
+	conf := configtest.New(t, map[string]any{
+		"rabbitmq.connections.default": map[string]any{
+			PropertyHost:     "127.0.0.1",
+			PropertyPort:     server.port(),
+			PropertyUsername: "guest",
+			PropertyPassword: "guest",
+		},
+		"rabbitmq.channels.default": map[string]any{"connection": "default"},
+		"rabbitmq.exchanges.shop": map[string]any{
+			PropertyChannel: "default", PropertyType: "direct", PropertyDurable: true,
+			PropertyAutoDelete: false, PropertyExclusive: false, PropertyNoWait: false,
+		},
+		"rabbitmq.queues.orders": map[string]any{
+			PropertyChannel: "default", PropertyDurable: true,
+			PropertyAutoDelete: false, PropertyExclusive: false, PropertyNoWait: false,
+		},
+		"rabbitmq.bindings": []any{
+			map[string]any{
+				PropertyExchange: "shop", PropertyQueue: "orders", PropertyNoWait: false,
+				PropertyRoutingKeys: []any{"order-created"},
+			},
+		},
+		"rabbitmq.consumers": []any{
+			map[string]any{
+				PropertyQueue: "orders", PropertyChannel: "default", PropertyAutoAck: false,
+				PropertyPrefetchCount: 5,
+			},
+		},
+	})
+
+	connections, err := Connections(conf, logger)
+	if err != nil {
+		t.Fatalf("unable to connect: %v", err)
+	}
+	channels, err := Channels(ChannelsParams{Conf: conf, Logger: logger, Connections: connections})
+	if err != nil {
+		t.Fatalf("unable to create channels: %v", err)
+	}
+	queues, err := Queues(conf, logger, channels)
+	if err != nil {
+		t.Fatalf("unable to create queues: %v", err)
+	}
+	exchanges, err := Exchanges(ExchangesParams{Conf: conf, Logger: logger, Channels: channels})
+	if err != nil {
+		t.Fatalf("unable to create exchanges: %v", err)
+	}
+	if err := Bindings(BindingsParams{Conf: conf, Logger: logger, Queues: queues, Exchanges: exchanges}); err != nil {
+		t.Fatalf("unable to create bindings: %v", err)
+	}
+
+	instrumentor, err := consumer.NewInstrumentor(consumer.InstrumentorParams{})
+	if err != nil {
+		t.Fatalf("unable to create instrumentor: %v", err)
+	}
+	handler := &orderHandler{handled: make(chan int, 1)}
+	handlers, err := MapMessageHandlers(MessageHandlerParams{Handlers: []consumer.MessageHandler{handler}})
+	if err != nil {
+		t.Fatalf("unable to map handlers: %v", err)
+	}
+	runnables, err := Consumers(ConsumersParams{
+		Conf:            conf,
+		Logger:          logger,
+		Queues:          queues,
+		Channels:        channels,
+		Exchanges:       exchanges,
+		MessageHandlers: handlers,
+		Propagator:      propagation.TraceContext{},
+		TracerProvider:  tracesdk.NewTracerProvider(),
+		Instrumentor:    instrumentor,
+	})
+	if err != nil || len(runnables) != 1 {
+		t.Fatalf("Expected '%d' consumer, got '%d' (%v)", 1, len(runnables), err)
+	}
+
+	ctx := context.Background()
+	watchers := make(chan error, 2)
+	go func() { watchers <- connections["default"].Start(ctx) }()
+	go func() { watchers <- channels["default"].Start(ctx) }()
+	if err := runnables[0].Start(ctx); err != nil {
+		t.Fatalf("unable to start consumer: %v", err)
+	}
+
+	server.waitFor("basic.consume orders", 1)
+	server.deliver("orders", "order-created", orderMessage(t, 1))
+	expectHandled(t, handler, 1)
+	server.waitFor("basic.ack", 1)
+
+	server.drop()
+
+	server.waitFor("basic.consume orders", 2)
+	for _, method := range []string{"exchange.declare shop", "queue.declare orders", "queue.bind orders shop order-created"} {
+		if server.called(method) != 2 {
+			t.Errorf("Expected '%s' to be called '%d' times, got '%d'", method, 2, server.called(method))
+		}
+	}
+	if server.called("basic.qos") != 2 {
+		t.Errorf("Expected the prefetch count to be set again, got '%d' calls", server.called("basic.qos"))
+	}
+
+	server.deliver("orders", "order-created", orderMessage(t, 2))
+	expectHandled(t, handler, 2)
+	server.waitFor("basic.ack", 2)
+
+	if err := runnables[0].Stop(ctx); err != nil {
+		t.Errorf("Expected consumer to stop, got '%v'", err)
+	}
+	if err := channels["default"].Close(ctx); err != nil {
+		t.Errorf("Expected channel to close, got '%v'", err)
+	}
+	if err := connections["default"].Close(ctx); err != nil {
+		t.Errorf("Expected connection to close, got '%v'", err)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-watchers:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected the watchers to stop")
+		}
+	}
+}
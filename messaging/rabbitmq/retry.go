@@ -0,0 +1,201 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+const (
+	// HeaderAttempts holds the number of times the message has been handled unsuccessfully.
+	HeaderAttempts = "x-attempts"
+	// HeaderOriginalExchange holds the exchange the message was originally published to.
+	HeaderOriginalExchange = "x-original-exchange"
+	// HeaderOriginalRoutingKey holds the routing key the message was originally published with.
+	HeaderOriginalRoutingKey = "x-original-routing-key"
+	// HeaderOriginalQueue holds the queue of the consumer that dead-lettered the message.
+	HeaderOriginalQueue = "x-original-queue"
+	// HeaderError holds the last error returned while handling the message.
+	HeaderError = "x-error"
+
+	retryQueueSuffix = ".retry"
+)
+
+// errUnprocessable is returned for the deliveries that cannot be handled no matter how many times they are retried.
+var errUnprocessable = errors.New("unprocessable message")
+
+// settlement is how a delivery is settled with the broker.
+type settlement int
+
+const (
+	// settlementNone is the settlement of the auto-acked deliveries,
+	// and of the deliveries that fail to be settled, which are redelivered once their channel is closed.
+	settlementNone settlement = iota
+	settlementAck
+	settlementReject
+	settlementRequeue
+)
+
+// publisher publishes retried and dead-lettered messages, it is implemented by *amqp091.Channel.
+type publisher interface {
+	PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp091.Publishing) error
+}
+
+// RetryQueueName returns the name of the queue holding the failed messages of the queue until they are retried.
+func RetryQueueName(queue string) string {
+	return queue + retryQueueSuffix
+}
+
+// declareRetryQueue declares the retry queue of the consumer.
+// Messages expire after the retry delay and are dead-lettered back to the consumed queue through the default exchange.
+func (h *QueueConsumer) declareRetryQueue(channel *amqp091.Channel) error {
+	_, err := channel.QueueDeclare(
+		RetryQueueName(h.Queue.Config.Name),
+		h.Queue.Config.Durable,
+		false, // delete when unused
+		false, // exclusive
+		false, // no-wait
+		amqp091.Table{
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": h.Queue.Config.Name,
+			"x-message-ttl":             h.Config.RetryDelay.Milliseconds(),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("error declaring retry queue for queue %s (%w)", h.Queue.Config.Name, err)
+	}
+	return nil
+}
+
+// settle acknowledges the delivery according to the result of its handler.
+//
+// Successful deliveries are acked. Failed deliveries are sent to the retry queue until they are handled
+// MaxAttempts times, then they are sent to the dead-letter exchange if there is one, or rejected.
+// Without retries, failed deliveries are rejected and requeued if Requeue is set.
+// It returns how the delivery is settled, settlementNone if it is auto-acked or cannot be settled.
+func (h *QueueConsumer) settle(ctx context.Context, pub publisher, d amqp091.Delivery, handleErr error) settlement {
+	logger := h.logger.For(ctx).
+		With(zap.String("queue", h.Queue.Config.Name)).
+		With(zap.String("routingKey", d.RoutingKey))
+
+	if h.Config.AutoAck {
+		if handleErr != nil {
+			logger.With(zap.Error(handleErr)).Error("failed to handle message")
+		}
+		return settlementNone
+	}
+
+	if handleErr == nil {
+		if err := d.Ack(false); err != nil {
+			logger.With(zap.Error(err)).Error("failed to ack message")
+			return settlementNone
+		}
+		return settlementAck
+	}
+
+	attempts := deliveryAttempts(d) + 1
+	logger = logger.With(zap.Int("attempts", attempts)).With(zap.Error(handleErr))
+	retryable := !errors.Is(handleErr, errUnprocessable)
+
+	// the retried and dead-lettered deliveries are acked once their copies are published
+	outcome := settlementAck
+	var err error
+	switch {
+	case retryable && attempts < h.Config.MaxAttempts:
+		logger.Info("failed to handle message, retrying")
+		err = h.publishAndAck(ctx, pub, d, "", RetryQueueName(h.Queue.Config.Name), h.headers(d, attempts, nil))
+	case h.Config.DeadLetterExchange != "":
+		logger.Error("failed to handle message, dead-lettering")
+		routingKey := h.Config.DeadLetterRoutingKey
+		if routingKey == "" {
+			routingKey = originalRoutingKey(d)
+		}
+		err = h.publishAndAck(ctx, pub, d, h.Config.DeadLetterExchange, routingKey, h.headers(d, attempts, handleErr))
+	default:
+		requeue := retryable && h.Config.Requeue && h.Config.MaxAttempts == 1
+		logger.With(zap.Bool("requeue", requeue)).Error("failed to handle message, rejecting")
+		err = d.Nack(false, requeue)
+		outcome = settlementReject
+		if requeue {
+			outcome = settlementRequeue
+		}
+	}
+
+	if err != nil {
+		logger.With(zap.NamedError("settleError", err)).Error("failed to settle message")
+		return settlementNone
+	}
+	return outcome
+}
+
+// publishAndAck publishes a copy of the delivery and acks the original.
+// The delivery is requeued if the copy cannot be published, so that it is not lost.
+func (h *QueueConsumer) publishAndAck(ctx context.Context, pub publisher, d amqp091.Delivery, exchange, routingKey string, headers amqp091.Table) error {
+	err := pub.PublishWithContext(ctx, exchange, routingKey, false, false, amqp091.Publishing{
+		Headers:         headers,
+		ContentType:     d.ContentType,
+		ContentEncoding: d.ContentEncoding,
+		DeliveryMode:    d.DeliveryMode,
+		Priority:        d.Priority,
+		CorrelationId:   d.CorrelationId,
+		ReplyTo:         d.ReplyTo,
+		MessageId:       d.MessageId,
+		Timestamp:       d.Timestamp,
+		Type:            d.Type,
+		UserId:          d.UserId,
+		AppId:           d.AppId,
+		Body:            d.Body,
+	})
+	if err != nil {
+		return errors.Join(err, d.Nack(false, true))
+	}
+	return d.Ack(false)
+}
+
+// headers returns the headers of the delivery with the attempts and the original destination of the message.
+func (h *QueueConsumer) headers(d amqp091.Delivery, attempts int, handleErr error) amqp091.Table {
+	headers := amqp091.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+
+	headers[HeaderAttempts] = int32(attempts)
+	if _, ok := headers[HeaderOriginalRoutingKey]; !ok {
+		headers[HeaderOriginalExchange] = d.Exchange
+		headers[HeaderOriginalRoutingKey] = d.RoutingKey
+	}
+	if handleErr != nil {
+		headers[HeaderOriginalQueue] = h.Queue.Config.Name
+		headers[HeaderError] = handleErr.Error()
+	}
+	return headers
+}
+
+// deliveryAttempts returns the number of times the delivery has been handled unsuccessfully before.
+func deliveryAttempts(d amqp091.Delivery) int {
+	switch v := d.Headers[HeaderAttempts].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	case string:
+		i, _ := strconv.Atoi(v)
+		return i
+	default:
+		return 0
+	}
+}
+
+// originalRoutingKey returns the routing key the message was published with before it was retried.
+func originalRoutingKey(d amqp091.Delivery) string {
+	if key, ok := d.Headers[HeaderOriginalRoutingKey].(string); ok {
+		return key
+	}
+	return d.RoutingKey
+}
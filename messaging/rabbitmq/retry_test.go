@@ -0,0 +1,346 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/enesanbar/go-service/core/config/configtest"
+	"github.com/enesanbar/go-service/core/log"
+	"github.com/enesanbar/go-service/core/messaging/codec"
+	"github.com/enesanbar/go-service/core/messaging/consumer"
+	"github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	otelmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+)
+
+type acknowledger struct {
+	acked    bool
+	nacked   bool
+	requeued bool
+}
+
+func (a *acknowledger) Ack(_ uint64, _ bool) error {
+	a.acked = true
+	return nil
+}
+
+func (a *acknowledger) Nack(_ uint64, _ bool, requeue bool) error {
+	a.nacked = true
+	a.requeued = requeue
+	return nil
+}
+
+func (a *acknowledger) Reject(_ uint64, requeue bool) error {
+	return a.Nack(0, false, requeue)
+}
+
+type published struct {
+	exchange string
+	key      string
+	msg      amqp091.Publishing
+}
+
+type recordingPublisher struct {
+	messages []published
+}
+
+func (p *recordingPublisher) PublishWithContext(_ context.Context, exchange, key string, _, _ bool, msg amqp091.Publishing) error {
+	p.messages = append(p.messages, published{exchange: exchange, key: key, msg: msg})
+	return nil
+}
+
+func newTestConsumer(cfg *ConsumerConfig) *QueueConsumer {
+	return &QueueConsumer{
+		logger: log.NewFactory(zap.NewNop()),
+		Config: cfg,
+		Queue:  &Queue{Config: &QueueConfig{Name: "orders"}},
+	}
+}
+
+func newDelivery(ack *acknowledger, attempts int) amqp091.Delivery {
+	d := amqp091.Delivery{
+		Acknowledger: ack,
+		Exchange:     "shop",
+		RoutingKey:   "order-created",
+		Body:         []byte(`{}`),
+	}
+	if attempts > 0 {
+		d.Headers = amqp091.Table{
+			HeaderAttempts:           int32(attempts),
+			HeaderOriginalExchange:   "shop",
+			HeaderOriginalRoutingKey: "order-created",
+		}
+		d.Exchange = ""
+		d.RoutingKey = "orders"
+	}
+	return d
+}
+
+func TestNewConsumerConfig(t *testing.T) {
+	cfg, err := NewConsumerConfig(map[string]interface{}{
+		PropertyQueue:              "orders",
+		PropertyChannel:            "default",
+		PropertyAutoAck:            false,
+		PropertyPrefetchCount:      10,
+		PropertyMaxAttempts:        3,
+		PropertyRetryDelay:         "30s",
+		PropertyDeadLetterExchange: "dead-letters",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if cfg.PrefetchCount != 10 || cfg.MaxAttempts != 3 || cfg.RetryDelay != 30*time.Second {
+		t.Errorf("Expected prefetch count, max attempts and retry delay to be '10', '3', '30s', got '%d', '%d', '%s'", cfg.PrefetchCount, cfg.MaxAttempts, cfg.RetryDelay)
+	}
+
+	cfg, err = NewConsumerConfig(map[string]interface{}{
+		PropertyQueue:   "orders",
+		PropertyChannel: "default",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if cfg.MaxAttempts != DefaultMaxAttempts || cfg.RetryDelay != DefaultRetryDelay {
+		t.Errorf("Expected the default max attempts and retry delay, got '%d', '%s'", cfg.MaxAttempts, cfg.RetryDelay)
+	}
+
+	invalid := []map[string]interface{}{
+		{PropertyQueue: "orders", PropertyChannel: "default", PropertyMaxAttempts: 3},
+		{PropertyQueue: "orders", PropertyChannel: "default", PropertyAutoAck: false, PropertyMaxAttempts: 0},
+		{PropertyQueue: "orders", PropertyChannel: "default", PropertyAutoAck: false, PropertyRetryDelay: 30},
+		{PropertyChannel: "default"},
+		{PropertyQueue: "orders"},
+		{PropertyQueue: "orders", PropertyChannel: "default", PropertyRequeue: "sometimes"},
+		{PropertyQueue: "orders", PropertyChannel: "default", PropertyAutoAck: false, PropertyDeadLetterExchange: 42},
+		{PropertyQueue: "orders", PropertyChannel: "default", PropertyDeadLetterRoutingKey: []interface{}{"orders"}},
+		{PropertyQueue: "orders", PropertyChannel: "default", PropertyOrdered: 1},
+	}
+	for _, c := range invalid {
+		if _, err := NewConsumerConfig(c); err == nil {
+			t.Errorf("Expected an error for '%v'", c)
+		}
+	}
+}
+
+func TestConsumers_ReportsInvalidConsumers(t *testing.T) {
+	channels := map[string]*Channel{"default": {}}
+	queues := map[string]*Queue{"orders": {Config: &QueueConfig{Name: "orders"}}}
+
+	cases := []struct {
+		consumer map[string]any
+		expected string
+	}{
+		{
+			// retries require manual acknowledgements
+			consumer: map[string]any{PropertyQueue: "orders", PropertyChannel: "default", PropertyMaxAttempts: 3},
+			expected: "consumer 0: retries and dead-lettering",
+		},
+		{
+			consumer: map[string]any{PropertyQueue: "orders", PropertyChannel: "orders"},
+			expected: "consumer 0: channel orders is not declared",
+		},
+		{
+			consumer: map[string]any{PropertyQueue: "payments", PropertyChannel: "default"},
+			expected: "consumer 0: queue payments is not declared",
+		},
+		{
+			consumer: map[string]any{PropertyQueue: "orders", PropertyChannel: "default", PropertyAutoAck: false, PropertyDeadLetterExchange: "dead-letters"},
+			expected: "consumer 0: dead-letter exchange dead-letters is not declared",
+		},
+	}
+	for _, c := range cases {
+		_, err := Consumers(ConsumersParams{
+			Conf:     configtest.New(t, map[string]any{"rabbitmq.consumers": []any{c.consumer}}),
+			Logger:   log.NewFactory(zap.NewNop()),
+			Queues:   queues,
+			Channels: channels,
+		})
+		if err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Errorf("Expected an error containing '%s', got '%v'", c.expected, err)
+		}
+	}
+}
+
+// collectLabels returns the values of the attribute of the counters.
+func collectLabels(t *testing.T, reader otelmetric.Reader, key attribute.Key) map[string]bool {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("unable to collect metrics: %v", err)
+	}
+
+	labels := make(map[string]bool)
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if !ok {
+				continue
+			}
+			for _, point := range sum.DataPoints {
+				if value, ok := point.Attributes.Value(key); ok {
+					labels[value.AsString()] = true
+				}
+			}
+		}
+	}
+	return labels
+}
+
+func TestSettle_AcksSuccessfulDeliveries(t *testing.T) {
+	h := newTestConsumer(&ConsumerConfig{MaxAttempts: 3})
+	ack := &acknowledger{}
+
+	h.settle(context.Background(), &recordingPublisher{}, newDelivery(ack, 0), nil)
+
+	if !ack.acked || ack.nacked {
+		t.Errorf("Expected the delivery to be acked, got acked '%t', nacked '%t'", ack.acked, ack.nacked)
+	}
+}
+
+func TestSettle_RetriesFailedDeliveries(t *testing.T) {
+	h := newTestConsumer(&ConsumerConfig{MaxAttempts: 3, DeadLetterExchange: "dead-letters"})
+	pub := &recordingPublisher{}
+	ack := &acknowledger{}
+
+	h.settle(context.Background(), pub, newDelivery(ack, 1), errors.New("boom"))
+
+	if len(pub.messages) != 1 {
+		t.Fatalf("Expected '%d' published message, got '%d'", 1, len(pub.messages))
+	}
+	msg := pub.messages[0]
+	if msg.exchange != "" || msg.key != RetryQueueName("orders") {
+		t.Errorf("Expected the message to be sent to '%s', got '%s'", RetryQueueName("orders"), msg.key)
+	}
+	if msg.msg.Headers[HeaderAttempts] != int32(2) {
+		t.Errorf("Expected attempts to be '%d', got '%v'", 2, msg.msg.Headers[HeaderAttempts])
+	}
+	if !ack.acked {
+		t.Error("Expected the original delivery to be acked")
+	}
+}
+
+func TestSettle_DeadLettersExhaustedDeliveries(t *testing.T) {
+	h := newTestConsumer(&ConsumerConfig{MaxAttempts: 3, DeadLetterExchange: "dead-letters"})
+	pub := &recordingPublisher{}
+	ack := &acknowledger{}
+
+	h.settle(context.Background(), pub, newDelivery(ack, 2), errors.New("boom"))
+
+	if len(pub.messages) != 1 {
+		t.Fatalf("Expected '%d' published message, got '%d'", 1, len(pub.messages))
+	}
+	msg := pub.messages[0]
+	if msg.exchange != "dead-letters" || msg.key != "order-created" {
+		t.Errorf("Expected the message to be sent to '%s' with '%s', got '%s' with '%s'", "dead-letters", "order-created", msg.exchange, msg.key)
+	}
+	if msg.msg.Headers[HeaderError] != "boom" || msg.msg.Headers[HeaderOriginalQueue] != "orders" {
+		t.Errorf("Expected the error and the queue in the headers, got '%v'", msg.msg.Headers)
+	}
+	if !ack.acked {
+		t.Error("Expected the original delivery to be acked")
+	}
+}
+
+func TestSettle_DeadLettersUnprocessableDeliveries(t *testing.T) {
+	h := newTestConsumer(&ConsumerConfig{MaxAttempts: 3, DeadLetterExchange: "dead-letters"})
+	pub := &recordingPublisher{}
+
+	h.settle(context.Background(), pub, newDelivery(&acknowledger{}, 0), errUnprocessable)
+
+	if len(pub.messages) != 1 || pub.messages[0].exchange != "dead-letters" {
+		t.Errorf("Expected the message to be dead-lettered without retries, got '%v'", pub.messages)
+	}
+}
+
+func TestSettle_RejectsFailedDeliveries(t *testing.T) {
+	h := newTestConsumer(&ConsumerConfig{MaxAttempts: 1, Requeue: true})
+	ack := &acknowledger{}
+
+	h.settle(context.Background(), &recordingPublisher{}, newDelivery(ack, 0), errors.New("boom"))
+	if !ack.nacked || !ack.requeued {
+		t.Errorf("Expected the delivery to be requeued, got nacked '%t', requeued '%t'", ack.nacked, ack.requeued)
+	}
+
+	ack = &acknowledger{}
+	h.settle(context.Background(), &recordingPublisher{}, newDelivery(ack, 0), errUnprocessable)
+	if !ack.nacked || ack.requeued {
+		t.Errorf("Expected the unprocessable delivery to be rejected, got nacked '%t', requeued '%t'", ack.nacked, ack.requeued)
+	}
+
+	h = newTestConsumer(&ConsumerConfig{MaxAttempts: 2, Requeue: true})
+	ack = &acknowledger{}
+	h.settle(context.Background(), &recordingPublisher{}, newDelivery(ack, 1), errors.New("boom"))
+	if !ack.nacked || ack.requeued {
+		t.Errorf("Expected the exhausted delivery to be rejected, got nacked '%t', requeued '%t'", ack.nacked, ack.requeued)
+	}
+}
+
+func TestProcess_RecordsMetricsAndSpan(t *testing.T) {
+	reader := otelmetric.NewManualReader()
+	instrumentor, err := consumer.NewInstrumentor(consumer.InstrumentorParams{
+		MeterProvider: otelmetric.NewMeterProvider(otelmetric.WithReader(reader)),
+	})
+	if err != nil {
+		t.Fatalf("unable to create instrumentor: %v", err)
+	}
+	recorder := tracetest.NewSpanRecorder()
+
+	h := newTestConsumer(&ConsumerConfig{MaxAttempts: 1})
+	h.Instrumentor = instrumentor
+	h.Tracer = tracesdk.NewTracerProvider(tracesdk.WithSpanProcessor(recorder)).Tracer("consumer-orders")
+	h.Propagator = propagation.TraceContext{}
+	h.Codecs = codec.NewRegistry()
+	handler := &orderHandler{handled: make(chan int, 1)}
+	h.MessageHandlers = map[string]consumer.MessageHandler{consumer.HandlerKey("orders", "order-created"): handler}
+
+	ack := &acknowledger{}
+	d := newDelivery(ack, 0)
+	d.Body = orderMessage(t, 1)
+	d.Redelivered = true
+	h.process(context.Background(), &recordingPublisher{}, d)
+
+	// the message without a handler is rejected
+	unknown := newDelivery(&acknowledger{}, 0)
+	unknown.RoutingKey = "order-deleted"
+	h.process(context.Background(), &recordingPublisher{}, unknown)
+
+	// the names of the messages without a handler are not used as labels
+	if labels := collectLabels(t, reader, "message"); !labels["unknown"] || labels["order-deleted"] {
+		t.Errorf("Expected the message without a handler to be labeled '%s', got '%v'", "unknown", labels)
+	}
+
+	counters := collectCounters(t, reader)
+	for name, value := range map[string]int64{
+		"messaging.consumer.messages.consumed":    2,
+		"messaging.consumer.messages.redelivered": 1,
+		"messaging.consumer.messages.acked":       1,
+		"messaging.consumer.messages.nacked":      1,
+	} {
+		if counters[name] != value {
+			t.Errorf("Expected '%s' to be '%d', got '%d'", name, value, counters[name])
+		}
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected '%d' span, got '%d'", 1, len(spans))
+	}
+	var destination attribute.Value
+	for _, kv := range spans[0].Attributes() {
+		if kv.Key == "messaging.destination.name" {
+			destination = kv.Value
+		}
+	}
+	if destination.AsString() != "shop:order-created:orders" {
+		t.Errorf("Expected destination to be '%s', got '%s'", "shop:order-created:orders", destination.Emit())
+	}
+}
@@ -0,0 +1,290 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	coreErr "github.com/enesanbar/go-service/core/errors"
+	"github.com/enesanbar/go-service/core/log"
+	"github.com/enesanbar/go-service/core/messaging/codec"
+	"github.com/enesanbar/go-service/core/messaging/producer"
+	"github.com/rabbitmq/amqp091-go"
+	"go.uber.org/fx"
+)
+
+const (
+	// DirectReplyTo is the pseudo-queue the replies are consumed from, without a queue declared for every client.
+	// See the direct reply-to of rabbitmq.
+	DirectReplyTo = "amq.rabbitmq.reply-to"
+	// HeaderErrorCode holds the code of the error a request failed with, see core/errors.
+	HeaderErrorCode = "x-error-code"
+	// HeaderErrorMessage holds the message of the error a request failed with.
+	HeaderErrorMessage = "x-error-message"
+)
+
+// ErrRPCClientClosed is returned for the calls made after the client is closed, or waiting for their replies meanwhile.
+var ErrRPCClientClosed = errors.New("rpc client is closed")
+
+// RPCClient calls the RPCHandlers of the services and waits for their replies.
+//
+// The requests are published as the messages of the Producer, to its exchange with their message names as routing keys by default.
+// The replies are consumed from DirectReplyTo on the channel of the client, and matched with the requests by their correlation ids,
+// which are the message ids of the requests. The correlation ids of the conversations are kept in the metadata of the messages.
+type RPCClient struct {
+	logger   log.Factory
+	producer *Producer
+	config   *RPCConfig
+
+	// the channel is opened by the first call, and by the next call once it is closed
+	mu      sync.Mutex
+	channel *amqp091.Channel
+	pending map[string]pendingCall
+	closed  bool
+}
+
+type RPCClientParams struct {
+	fx.In
+
+	Logger   log.Factory
+	Producer *Producer
+	Config   *RPCConfig
+}
+
+// pendingCall is a call waiting for its reply on the channel it is published on.
+type pendingCall struct {
+	channel *amqp091.Channel
+	replies chan rpcReply
+}
+
+// rpcReply is the reply of a call, or the reason it is not replied.
+type rpcReply struct {
+	delivery amqp091.Delivery
+	err      error
+}
+
+// NewRPCClient creates a pointer to the new instance of the RPCClient
+func NewRPCClient(p RPCClientParams) *RPCClient {
+	return &RPCClient{
+		logger:   p.Logger,
+		producer: p.Producer,
+		config:   p.Config,
+		pending:  make(map[string]pendingCall),
+	}
+}
+
+// Call is the typed form of RPCClient.Call, it returns the reply decoded into Resp.
+func Call[Resp any](ctx context.Context, client *RPCClient, messageName string, request any, options ...producer.PublishOption) (Resp, error) {
+	var response Resp
+	err := client.Call(ctx, messageName, request, &response, options...)
+	return response, err
+}
+
+// Call publishes the request and decodes the payload of its reply into the response,
+// waiting for it until the timeout of the client, or until the context is done if it is earlier.
+// The errors replied by the handler are returned as *errors.Error with their codes and messages, see core/errors.
+// The requests that are not routed to any queue fail with producer.ErrUnroutable, instead of timing out.
+func (c *RPCClient) Call(ctx context.Context, messageName string, request any, response any, options ...producer.PublishOption) error {
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+
+	ctx, span := c.producer.startPublish(ctx)
+	exchange, routingKey, _, msg, err := c.producer.publishing(ctx, producer.Publishing{
+		MessageName: messageName,
+		Message:     request,
+		Options:     options,
+	})
+	if err != nil {
+		endSpan(span, err)
+		return err
+	}
+
+	msg.ReplyTo = DirectReplyTo
+	msg.CorrelationId = msg.MessageId
+	// the requests are dropped by the broker once their callers stop waiting for them
+	if deadline, ok := ctx.Deadline(); ok && msg.Expiration == "" {
+		msg.Expiration = strconv.FormatInt(max(time.Until(deadline).Milliseconds(), 1), 10)
+	}
+
+	annotatePublish(span, exchange, routingKey, msg)
+
+	// the span of the request ends once it is sent, the handler continues its trace
+	replies, err := c.send(ctx, exchange, routingKey, msg)
+	if err != nil {
+		err = fmt.Errorf("failed to call %s: %w", messageName, err)
+		endSpan(span, err)
+		return err
+	}
+	endSpan(span, nil)
+	c.producer.Instrumentor.Published(destinationName(exchange), messageName)
+	defer c.forget(msg.MessageId)
+
+	var reply rpcReply
+	select {
+	case reply = <-replies:
+	case <-ctx.Done():
+		return fmt.Errorf("call %s is not replied in %s: %w", messageName, c.config.Timeout, ctx.Err())
+	}
+	if errors.Is(reply.err, producer.ErrUnroutable) {
+		c.producer.Instrumentor.Returned(destinationName(exchange), messageName)
+	}
+	if reply.err != nil {
+		return fmt.Errorf("failed to call %s: %w", messageName, reply.err)
+	}
+
+	return decodeReply(c.producer.Codecs, reply.delivery, response)
+}
+
+// send publishes the request on the channel of the client, and returns the channel its reply is passed to.
+func (c *RPCClient) send(ctx context.Context, exchange, routingKey string, msg amqp091.Publishing) (chan rpcReply, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, ErrRPCClientClosed
+	}
+	if _, ok := c.pending[msg.MessageId]; ok {
+		return nil, fmt.Errorf("the call with message id %s is already waiting for its reply", msg.MessageId)
+	}
+
+	channel, err := c.open()
+	if err != nil {
+		return nil, err
+	}
+
+	replies := make(chan rpcReply, 1)
+	c.pending[msg.MessageId] = pendingCall{channel: channel, replies: replies}
+
+	// the requests are mandatory, so that they are returned at once if there is no queue to handle them
+	if err := channel.PublishWithContext(ctx, exchange, routingKey, true, false, msg); err != nil {
+		delete(c.pending, msg.MessageId)
+		return nil, err
+	}
+	return replies, nil
+}
+
+// open returns the channel of the client, opening it if it is not open, it must be called with mu held.
+func (c *RPCClient) open() (*amqp091.Channel, error) {
+	if c.channel != nil && !c.channel.IsClosed() {
+		return c.channel, nil
+	}
+
+	conn := c.producer.Connection.GetConn()
+	if conn == nil {
+		return nil, fmt.Errorf("connection %s is not established", c.producer.Connection.Name())
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create channel: %w", err)
+	}
+
+	// the replies to DirectReplyTo are consumed in no-ack mode, on the channel the requests are published on
+	deliveries, err := channel.Consume(DirectReplyTo, "", true, false, false, false, nil)
+	if err != nil {
+		_ = channel.Close()
+		return nil, fmt.Errorf("failed to consume %s: %w", DirectReplyTo, err)
+	}
+	returns := channel.NotifyReturn(make(chan amqp091.Return, returnsBufferSize))
+
+	c.channel = channel
+	go c.receive(channel, deliveries, returns)
+	return channel, nil
+}
+
+// receive passes the replies and the returned requests of the channel to their calls, until the channel is closed.
+func (c *RPCClient) receive(channel *amqp091.Channel, deliveries <-chan amqp091.Delivery, returns <-chan amqp091.Return) {
+	for {
+		select {
+		case d, ok := <-deliveries:
+			if !ok {
+				c.abandon(channel)
+				return
+			}
+			c.resolve(d.CorrelationId, rpcReply{delivery: d})
+		case r, ok := <-returns:
+			if !ok {
+				returns = nil
+				continue
+			}
+			c.resolve(r.MessageId, rpcReply{err: producer.ErrUnroutable})
+		}
+	}
+}
+
+// resolve passes the reply to its call, the replies of the calls that are not waiting anymore are dropped.
+func (c *RPCClient) resolve(id string, reply rpcReply) {
+	c.mu.Lock()
+	call, ok := c.pending[id]
+	delete(c.pending, id)
+	c.mu.Unlock()
+
+	if ok {
+		call.replies <- reply
+	}
+}
+
+// abandon fails the calls waiting on the closed channel, their replies cannot be received on another channel.
+func (c *RPCClient) abandon(channel *amqp091.Channel) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	err := error(amqp091.ErrClosed)
+	if c.closed {
+		err = ErrRPCClientClosed
+	}
+	for id, call := range c.pending {
+		if call.channel == channel {
+			delete(c.pending, id)
+			call.replies <- rpcReply{err: err}
+		}
+	}
+	if c.channel == channel {
+		c.channel = nil
+	}
+}
+
+func (c *RPCClient) forget(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.pending, id)
+}
+
+// Close closes the channel of the client, failing the calls waiting for their replies.
+func (c *RPCClient) Close(ctx context.Context) error {
+	c.mu.Lock()
+	c.closed = true
+	channel := c.channel
+	c.mu.Unlock()
+
+	if channel == nil || channel.IsClosed() {
+		return nil
+	}
+
+	c.logger.For(ctx).Info("closing rabbitmq rpc client")
+	if err := channel.Close(); err != nil {
+		return fmt.Errorf("failed to close rpc client channel: %w", err)
+	}
+	return nil
+}
+
+// decodeReply decodes the payload of the reply into the response, or returns the error it holds.
+func decodeReply(codecs *codec.Registry, d amqp091.Delivery, response any) error {
+	if code, ok := d.Headers[HeaderErrorCode].(string); ok {
+		message, _ := d.Headers[HeaderErrorMessage].(string)
+		return coreErr.NewError(code, message, "", nil)
+	}
+
+	_, payload, err := decodeDelivery(codecs, d)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal reply: %w", err)
+	}
+	if response == nil {
+		return nil
+	}
+	return payload.Decode(response)
+}
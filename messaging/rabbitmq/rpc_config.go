@@ -0,0 +1,37 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/enesanbar/go-service/core/config"
+)
+
+const (
+	PropertyTimeout = "timeout"
+
+	DefaultRPCTimeout = 10 * time.Second
+)
+
+// RPCConfig configures the RPCClient, under rabbitmq.rpc.
+type RPCConfig struct {
+	// Timeout is the time the replies are waited for, unless the context of the call is done earlier.
+	Timeout time.Duration
+}
+
+func NewRPCConfig(cfg config.Config) (*RPCConfig, error) {
+	timeout := DefaultRPCTimeout
+	property := fmt.Sprintf("rabbitmq.rpc.%s", PropertyTimeout)
+	if value := cfg.GetString(property); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value '%s' for %s: %w", value, property, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("invalid value '%s' for %s, must be positive", value, property)
+		}
+		timeout = d
+	}
+
+	return &RPCConfig{Timeout: timeout}, nil
+}
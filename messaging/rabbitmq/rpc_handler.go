@@ -0,0 +1,161 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	coreErr "github.com/enesanbar/go-service/core/errors"
+	"github.com/enesanbar/go-service/core/messaging/consumer"
+	"github.com/enesanbar/go-service/core/messaging/messages"
+	"github.com/enesanbar/go-service/core/messaging/producer"
+	"github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+// replyMessageSuffix is appended to the names of the requests for the names of their replies.
+const replyMessageSuffix = "-reply"
+
+// replyAddress is where the reply of a request is published to, from the properties of its delivery.
+type replyAddress struct {
+	replyTo       string
+	correlationID string
+}
+
+type replyAddressKey struct{}
+
+// withReplyAddress returns a context that carries the reply address of the delivery, if it is a request.
+func withReplyAddress(ctx context.Context, d amqp091.Delivery) context.Context {
+	if d.ReplyTo == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, replyAddressKey{}, replyAddress{replyTo: d.ReplyTo, correlationID: d.CorrelationId})
+}
+
+func replyAddressFromContext(ctx context.Context) (replyAddress, bool) {
+	address, ok := ctx.Value(replyAddressKey{}).(replyAddress)
+	return address, ok
+}
+
+// RPCHandler handles the requests whose payloads are decoded into Req, and replies with the Resp it returns.
+// It is adapted to a consumer.MessageHandler with NewRPCHandler.
+type RPCHandler[Req, Resp any] interface {
+	Handle(ctx context.Context, message messages.Message[Req]) (Resp, error)
+	Properties() consumer.MessageProperties
+}
+
+// NewRPCHandler adapts the handler to a consumer.MessageHandler that publishes its results to the callers with the producer.
+//
+// The errors of the handler are replied with their codes and messages, see core/errors, EINTERNAL for the errors without code,
+// and the requests are acked so that they are not handled again. The requests whose payloads are invalid are replied
+// with EINVALID by the consumer and rejected as unprocessable. The messages without a reply address are handled as any other message.
+func NewRPCHandler[Req, Resp any](handler RPCHandler[Req, Resp], p *Producer) consumer.MessageHandler {
+	return consumer.NewMessageHandler[Req](&replier[Req, Resp]{handler: handler, producer: p})
+}
+
+// internalErrorMessage is the message the internal errors are replied with.
+const internalErrorMessage = "internal error"
+
+// replier calls the handler and replies with its result.
+type replier[Req, Resp any] struct {
+	handler  RPCHandler[Req, Resp]
+	producer *Producer
+}
+
+func (r *replier[Req, Resp]) Handle(ctx context.Context, message messages.Message[Req]) error {
+	response, err := r.handler.Handle(ctx, message)
+
+	address, ok := replyAddressFromContext(ctx)
+	if !ok {
+		return err
+	}
+	if err != nil {
+		r.producer.Logger.For(ctx).
+			With(zap.String("message", message.Metadata.MessageName)).
+			With(zap.Error(err)).
+			Error("failed to handle request, replying with the error")
+		return r.producer.reply(ctx, address, message.Metadata.MessageName, nil, err)
+	}
+	return r.producer.reply(ctx, address, message.Metadata.MessageName, response, nil)
+}
+
+func (r *replier[Req, Resp]) Properties() consumer.MessageProperties {
+	return r.handler.Properties()
+}
+
+// invalidRequestMessage is the message the requests rejected before they reach their handlers are replied with.
+const invalidRequestMessage = "invalid request"
+
+// replyInvalid replies with EINVALID to the requests rejected before they reach their handlers, e.g. those whose payloads
+// cannot be decoded or do not match their schemas, so that the callers do not wait until they time out.
+// The details of the rejections are logged by the consumer instead of being replied.
+func (h *QueueConsumer) replyInvalid(ctx context.Context, messageName string, err error) error {
+	address, ok := replyAddressFromContext(ctx)
+	if !ok || h.producer == nil {
+		return err
+	}
+
+	invalid := coreErr.NewInvalidError("", invalidRequestMessage, nil)
+	if replyErr := h.producer.reply(ctx, address, messageName, nil, invalid); replyErr != nil {
+		return errors.Join(err, replyErr)
+	}
+	return err
+}
+
+// reply publishes the response of the request, or the error it failed with, to its caller,
+// and waits for the broker to confirm it. The replies are routed to the callers through the default exchange.
+func (p *Producer) reply(ctx context.Context, address replyAddress, messageName string, response any, handleErr error) error {
+	var options []producer.PublishOption
+	if handleErr != nil {
+		code := coreErr.GetCode(handleErr)
+		if code == "" {
+			code = coreErr.EINTERNAL
+		}
+		// the messages of the internal errors may hold the details of the service, which are logged instead
+		message := internalErrorMessage
+		if code != coreErr.EINTERNAL {
+			message = coreErr.ErrorMessage(handleErr)
+		}
+		options = append(options,
+			producer.WithHeader(HeaderErrorCode, code),
+			producer.WithHeader(HeaderErrorMessage, message),
+		)
+		response = nil
+	}
+
+	replyName := messageName + replyMessageSuffix
+	ctx, span := p.startPublish(ctx)
+	_, _, _, msg, err := p.publishing(ctx, producer.Publishing{
+		MessageName: replyName,
+		Message:     response,
+		Options:     options,
+	})
+	if err != nil {
+		endSpan(span, err)
+		return err
+	}
+	msg.CorrelationId = address.correlationID
+	msg.DeliveryMode = amqp091.Transient
+	annotatePublish(span, "", address.replyTo, msg)
+
+	channel, err := p.pool.get(ctx)
+	if err != nil {
+		endSpan(span, err)
+		return err
+	}
+	defer p.pool.put(channel)
+
+	confirm, err := channel.channel.PublishWithDeferredConfirmWithContext(ctx, "", address.replyTo, false, false, msg)
+	if err != nil {
+		err = fmt.Errorf("failed to publish message %s: %w", replyName, err)
+		endSpan(span, err)
+		return err
+	}
+	p.Instrumentor.Published(destinationName(""), replyName)
+	return errors.Join(p.waitForConfirmations(ctx, channel, []pendingConfirmation{{
+		messageName: replyName,
+		messageID:   msg.MessageId,
+		confirm:     confirm,
+		span:        span,
+	}})...)
+}
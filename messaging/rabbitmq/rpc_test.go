@@ -0,0 +1,259 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/enesanbar/go-service/core/config"
+	"github.com/enesanbar/go-service/core/config/configtest"
+	coreErr "github.com/enesanbar/go-service/core/errors"
+	"github.com/enesanbar/go-service/core/log"
+	"github.com/enesanbar/go-service/core/messaging/consumer"
+	"github.com/enesanbar/go-service/core/messaging/messages"
+	"github.com/enesanbar/go-service/core/messaging/producer"
+	"github.com/enesanbar/go-service/core/messaging/schema"
+	"go.opentelemetry.io/otel/propagation"
+	tracesdk "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+type priceRequest struct {
+	SKU string `json:"sku"`
+}
+
+type price struct {
+	SKU    string `json:"sku"`
+	Amount int    `json:"amount"`
+}
+
+// priceHandler replies with the prices of its catalog, and records the trace parents of the requests.
+type priceHandler struct {
+	catalog      map[string]int
+	traceparents chan string
+}
+
+func (h *priceHandler) Handle(_ context.Context, message messages.Message[priceRequest]) (price, error) {
+	h.traceparents <- message.Metadata.Traceparent
+
+	switch amount, ok := h.catalog[message.Payload.SKU]; {
+	case message.Payload.SKU == "broken":
+		return price{}, errors.New("catalog is unavailable")
+	case !ok:
+		return price{}, coreErr.NewNotFoundError("priceHandler.Handle", "sku not found", nil)
+	default:
+		return price{SKU: message.Payload.SKU, Amount: amount}, nil
+	}
+}
+
+func (h *priceHandler) Properties() consumer.MessageProperties {
+	return consumer.MessageProperties{QueueName: "pricing", MessageName: "price-requested"}
+}
+
+// newTestRPC starts a consumer of the pricing queue with the handler, and returns a client calling it.
+func newTestRPC(t *testing.T, handler *priceHandler) (*fakeServer, *RPCClient) {
+	t.Helper()
+
+	server := newFakeServer(t)
+	logger := log.NewFactory(zap.NewNop())
+	p := newTestProducer(t, server, map[string]any{"rabbitmq.producer.exchange": "shop"})
+	server.forward("shop", "price-requested", "pricing")
+
+	conf := configtest.New(t, map[string]any{
+		"rabbitmq.channels.default": map[string]any{"connection": "default"},
+		"rabbitmq.queues.pricing":   map[string]any{PropertyChannel: "default"},
+		"rabbitmq.consumers": []any{
+			map[string]any{PropertyQueue: "pricing", PropertyChannel: "default"},
+		},
+	})
+	connections := map[string]*Connection{"default": p.Connection}
+	channels, err := Channels(ChannelsParams{Conf: conf, Logger: logger, Connections: connections})
+	if err != nil {
+		t.Fatalf("unable to create channels: %v", err)
+	}
+	queues, err := Queues(conf, logger, channels)
+	if err != nil {
+		t.Fatalf("unable to create queues: %v", err)
+	}
+
+	instrumentor, err := consumer.NewInstrumentor(consumer.InstrumentorParams{})
+	if err != nil {
+		t.Fatalf("unable to create instrumentor: %v", err)
+	}
+	// the requests with an empty sku are rejected by the schema before they reach the handler
+	schemas, err := schema.NewRegistry(schema.Definition{
+		MessageName: "price-requested",
+		Version:     1,
+		Schema:      `{"properties":{"sku":{"type":"string","minLength":1}}}`,
+	})
+	if err != nil {
+		t.Fatalf("unable to create schemas: %v", err)
+	}
+	handlers, err := MapMessageHandlers(MessageHandlerParams{
+		Handlers:  []consumer.MessageHandler{NewRPCHandler[priceRequest, price](handler, p)},
+		Validator: consumer.NewPayloadValidator(consumer.PayloadValidatorParams{Schemas: schemas}),
+	})
+	if err != nil {
+		t.Fatalf("unable to map handlers: %v", err)
+	}
+	runnables, err := Consumers(ConsumersParams{
+		Conf:            conf,
+		Logger:          logger,
+		Queues:          queues,
+		Channels:        channels,
+		MessageHandlers: handlers,
+		Propagator:      propagation.TraceContext{},
+		TracerProvider:  tracesdk.NewTracerProvider(),
+		Instrumentor:    instrumentor,
+		Producer:        p,
+	})
+	if err != nil || len(runnables) != 1 {
+		t.Fatalf("Expected '%d' consumer, got '%d' (%v)", 1, len(runnables), err)
+	}
+	if err := runnables[0].Start(context.Background()); err != nil {
+		t.Fatalf("unable to start consumer: %v", err)
+	}
+	server.waitFor("basic.consume pricing", 1)
+
+	client := NewRPCClient(RPCClientParams{Logger: logger, Producer: p, Config: &RPCConfig{Timeout: time.Second}})
+	t.Cleanup(func() {
+		_ = runnables[0].Stop(context.Background())
+		_ = client.Close(context.Background())
+	})
+	return server, client
+}
+
+func TestNewRPCConfig(t *testing.T) {
+	cfg, err := NewRPCConfig(configtest.New(t, map[string]any{}))
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if cfg.Timeout != DefaultRPCTimeout {
+		t.Errorf("Expected timeout to be '%s', got '%s'", DefaultRPCTimeout, cfg.Timeout)
+	}
+
+	for _, timeout := range []string{"soon", "0s"} {
+		if _, err := NewRPCConfig(configtest.New(t, map[string]any{"rabbitmq.rpc.timeout": timeout})); err == nil {
+			t.Errorf("Expected an error for timeout '%s'", timeout)
+		}
+	}
+}
+
+func TestRPC_Call(t *testing.T) {
+	handler := &priceHandler{catalog: map[string]int{"book": 12}, traceparents: make(chan string, 1)}
+	_, client := newTestRPC(t, handler)
+
+	ctx, span := tracesdk.NewTracerProvider().Tracer("test").Start(context.Background(), "checkout")
+	defer span.End()
+
+	reply, err := Call[price](ctx, client, "price-requested", priceRequest{SKU: "book"})
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if reply.SKU != "book" || reply.Amount != 12 {
+		t.Errorf("Expected the price of the book, got '%+v'", reply)
+	}
+
+	traceparent := <-handler.traceparents
+	if !strings.Contains(traceparent, span.SpanContext().TraceID().String()) {
+		t.Errorf("Expected the request to continue trace '%s', got '%s'", span.SpanContext().TraceID(), traceparent)
+	}
+}
+
+func TestRPC_CallReturnsErrorCodes(t *testing.T) {
+	handler := &priceHandler{catalog: map[string]int{}, traceparents: make(chan string, 2)}
+	_, client := newTestRPC(t, handler)
+	ctx := context.Background()
+
+	_, err := Call[price](ctx, client, "price-requested", priceRequest{SKU: "pen"})
+	if coreErr.GetCode(err) != coreErr.ENOTFOUND || coreErr.ErrorMessage(err) != "sku not found" {
+		t.Errorf("Expected error to be '%s' with message '%s', got '%v'", coreErr.ENOTFOUND, "sku not found", err)
+	}
+
+	// the errors without codes are internal errors, replied without their details
+	_, err = Call[price](ctx, client, "price-requested", priceRequest{SKU: "broken"})
+	if coreErr.GetCode(err) != coreErr.EINTERNAL || coreErr.ErrorMessage(err) != internalErrorMessage {
+		t.Errorf("Expected error to be '%s' with message '%s', got '%v'", coreErr.EINTERNAL, internalErrorMessage, err)
+	}
+
+	// the requests that cannot be decoded or do not match their schemas never reach the handler,
+	// they are replied without the details of their rejections
+	for _, request := range []any{"book", priceRequest{SKU: ""}} {
+		_, err = Call[price](ctx, client, "price-requested", request)
+		if coreErr.GetCode(err) != coreErr.EINVALID || coreErr.ErrorMessage(err) != invalidRequestMessage {
+			t.Errorf("Expected error to be '%s' with message '%s' for '%v', got '%v'", coreErr.EINVALID, invalidRequestMessage, request, err)
+		}
+	}
+}
+
+func TestRPC_CallFailsWithoutReply(t *testing.T) {
+	handler := &priceHandler{catalog: map[string]int{}, traceparents: make(chan string, 1)}
+	server, client := newTestRPC(t, handler)
+	client.config.Timeout = 100 * time.Millisecond
+	ctx := context.Background()
+
+	_, err := Call[price](ctx, client, "stock-requested", priceRequest{SKU: "book"})
+	if !errors.Is(err, producer.ErrUnroutable) {
+		t.Errorf("Expected error to be '%v', got '%v'", producer.ErrUnroutable, err)
+	}
+
+	// the request is routed to a queue without consumers
+	server.bind("shop", "stock-requested")
+	_, err = Call[price](ctx, client, "stock-requested", priceRequest{SKU: "book"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected error to be '%v', got '%v'", context.DeadlineExceeded, err)
+	}
+
+	if err := client.Close(ctx); err != nil {
+		t.Fatalf("Expected client to close, got '%v'", err)
+	}
+	_, err = Call[price](ctx, client, "price-requested", priceRequest{SKU: "book"})
+	if !errors.Is(err, ErrRPCClientClosed) {
+		t.Errorf("Expected error to be '%v', got '%v'", ErrRPCClientClosed, err)
+	}
+}
+
+func TestRPCModule(t *testing.T) {
+	server := newFakeServer(t)
+	conf := configtest.New(t, map[string]any{
+		"rabbitmq.connections.default": map[string]any{
+			PropertyHost:     "127.0.0.1",
+			PropertyPort:     server.port(),
+			PropertyUsername: "guest",
+			PropertyPassword: "guest",
+		},
+		"rabbitmq.rpc.timeout": "1s",
+	})
+
+	var client *RPCClient
+	var p *Producer
+	var batchProducer producer.BatchProducer
+	app := fx.New(
+		fx.NopLogger,
+		fx.Provide(Connections),
+		ProducerModule,
+		RPCModule,
+		fx.Supply(
+			fx.Annotate(conf, fx.As(new(config.Config))),
+			log.NewFactory(zap.NewNop()),
+			fx.Annotate(propagation.TraceContext{}, fx.As(new(propagation.TextMapPropagator))),
+		),
+		fx.Populate(&client, &p, &batchProducer),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	t.Cleanup(func() {
+		_ = p.Connection.Close(context.Background())
+	})
+
+	if client.config.Timeout != time.Second {
+		t.Errorf("Expected timeout to be '%s', got '%s'", time.Second, client.config.Timeout)
+	}
+	if batchProducer != producer.BatchProducer(p) {
+		t.Error("Expected the producer to be provided as producer.BatchProducer")
+	}
+}
@@ -0,0 +1,257 @@
+package rabbitmq
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/enesanbar/go-service/core/config/configtest"
+	"github.com/enesanbar/go-service/core/log"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+func TestNewQueueConfig(t *testing.T) {
+	cfg, err := NewQueueConfig("orders", map[string]interface{}{
+		PropertyChannel:              "default",
+		PropertyType:                 QueueTypeQuorum,
+		PropertyDeadLetterExchange:   "dead-letters",
+		PropertyDeadLetterRoutingKey: "orders",
+		PropertyMessageTTL:           "1m",
+		PropertyMaxLength:            1000,
+		PropertyArguments:            map[string]interface{}{"x-overflow": "reject-publish", "x-delivery-limit": 5},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if !cfg.Durable {
+		t.Error("Expected quorum queues to be durable by default")
+	}
+
+	expected := amqp.Table{
+		"x-queue-type":              QueueTypeQuorum,
+		"x-dead-letter-exchange":    "dead-letters",
+		"x-dead-letter-routing-key": "orders",
+		"x-message-ttl":             time.Minute.Milliseconds(),
+		"x-max-length":              int64(1000),
+		"x-overflow":                "reject-publish",
+		"x-delivery-limit":          int64(5),
+	}
+	table := cfg.Table()
+	if len(table) != len(expected) {
+		t.Errorf("Expected arguments to be '%v', got '%v'", expected, table)
+	}
+	for name, value := range expected {
+		if table[name] != value {
+			t.Errorf("Expected argument %s to be '%v', got '%v'", name, value, table[name])
+		}
+	}
+
+	// the queues declared before the arguments have none
+	cfg, err = NewQueueConfig("orders", map[string]interface{}{PropertyChannel: "default"})
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if cfg.Durable || cfg.Table() != nil {
+		t.Errorf("Expected a non-durable queue without arguments, got '%+v'", cfg)
+	}
+}
+
+func TestNewQueueConfig_Invalid(t *testing.T) {
+	for name, properties := range map[string]map[string]interface{}{
+		"durable":        {PropertyDurable: "yes please"},
+		"type":           {PropertyType: "lazy"},
+		"quorum":         {PropertyType: QueueTypeQuorum, PropertyExclusive: true},
+		"routing key":    {PropertyDeadLetterRoutingKey: "orders"},
+		"message ttl":    {PropertyMessageTTL: 60},
+		"max length":     {PropertyMaxLength: -1},
+		"arguments":      {PropertyArguments: "x-max-length=10"},
+		"argument value": {PropertyArguments: map[string]interface{}{"x-max-length": struct{}{}}},
+	} {
+		if _, err := NewQueueConfig("orders", properties); err == nil {
+			t.Errorf("Expected an error for an invalid %s", name)
+		}
+	}
+}
+
+func TestNewExchangeConfig(t *testing.T) {
+	cfg, err := NewExchangeConfig("delayed", map[string]interface{}{
+		PropertyType:              "x-delayed-message",
+		PropertyInternal:          true,
+		PropertyAlternateExchange: "unrouted",
+		PropertyArguments:         map[string]interface{}{"x-delayed-type": "direct"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if !cfg.Internal || cfg.Durable {
+		t.Errorf("Expected an internal non-durable exchange, got '%+v'", cfg)
+	}
+	table := cfg.Table()
+	if table["alternate-exchange"] != "unrouted" || table["x-delayed-type"] != "direct" {
+		t.Errorf("Expected the alternate exchange and the delayed type arguments, got '%v'", table)
+	}
+
+	for name, properties := range map[string]map[string]interface{}{
+		"missing type": {PropertyDurable: true},
+		"type":         {PropertyType: "broadcast"},
+		"durable":      {PropertyType: "direct", PropertyDurable: 1},
+	} {
+		if _, err := NewExchangeConfig("shop", properties); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
+
+func TestNewBindingConfig(t *testing.T) {
+	cfg, err := NewBindingConfig(map[string]interface{}{
+		PropertyExchange:  "events",
+		PropertyQueue:     "audit",
+		PropertyArguments: map[string]interface{}{"x-match": "any", "tenant": "eu"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if len(cfg.RoutingKeys) != 1 || cfg.RoutingKeys[0] != "" {
+		t.Errorf("Expected the empty routing key by default, got '%v'", cfg.RoutingKeys)
+	}
+	if cfg.Arguments["x-match"] != "any" {
+		t.Errorf("Expected x-match to be '%s', got '%v'", "any", cfg.Arguments["x-match"])
+	}
+
+	for name, properties := range map[string]map[string]interface{}{
+		"missing exchange":    {PropertyQueue: "audit"},
+		"missing destination": {PropertyExchange: "events"},
+		"both destinations":   {PropertyExchange: "events", PropertyQueue: "audit", PropertyDestinationExchange: "shop"},
+		"routing keys":        {PropertyExchange: "events", PropertyQueue: "audit", PropertyRoutingKeys: "order-created"},
+	} {
+		if _, err := NewBindingConfig(properties); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
+
+func TestTopology_DeclaresExchangeToExchangeBindings(t *testing.T) {
+	server := newFakeServer(t)
+	logger := log.NewFactory(zap.NewNop())
+
+	conf := configtest.New(t, map[string]any{
+		"rabbitmq.connections.default": map[string]any{
+			PropertyHost:     "127.0.0.1",
+			PropertyPort:     server.port(),
+			PropertyUsername: "guest",
+			PropertyPassword: "guest",
+		},
+		"rabbitmq.channels.default": map[string]any{"connection": "default"},
+		// the properties left out are false, instead of panicking
+		"rabbitmq.exchanges.shop":   map[string]any{PropertyChannel: "default", PropertyType: "topic"},
+		"rabbitmq.exchanges.orders": map[string]any{PropertyChannel: "default", PropertyType: "fanout", PropertyInternal: true},
+		"rabbitmq.queues.shipping":  map[string]any{PropertyChannel: "default", PropertyType: QueueTypeQuorum},
+		"rabbitmq.bindings": []any{
+			map[string]any{PropertyExchange: "shop", PropertyDestinationExchange: "orders", PropertyRoutingKeys: []any{"order.*"}},
+			map[string]any{PropertyExchange: "orders", PropertyQueue: "shipping"},
+		},
+	})
+
+	connections, err := Connections(conf, logger)
+	if err != nil {
+		t.Fatalf("unable to connect: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = connections["default"].Close(context.Background())
+	})
+	channels, err := Channels(ChannelsParams{Conf: conf, Logger: logger, Connections: connections})
+	if err != nil {
+		t.Fatalf("unable to create channels: %v", err)
+	}
+	queues, err := Queues(conf, logger, channels)
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	exchanges, err := Exchanges(ExchangesParams{Conf: conf, Logger: logger, Channels: channels})
+	if err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+	if err := Bindings(BindingsParams{Conf: conf, Logger: logger, Queues: queues, Exchanges: exchanges}); err != nil {
+		t.Fatalf("Expected no error, got '%v'", err)
+	}
+
+	for _, method := range []string{"exchange.bind orders shop order.*", "queue.bind shipping orders "} {
+		if server.called(method) != 1 {
+			t.Errorf("Expected '%s' to be called '%d' times, got '%d'", method, 1, server.called(method))
+		}
+	}
+}
+
+func TestTopology_ReportsMalformedConfiguration(t *testing.T) {
+	server := newFakeServer(t)
+	logger := log.NewFactory(zap.NewNop())
+
+	conf := configtest.New(t, map[string]any{
+		"rabbitmq.connections.default": map[string]any{
+			PropertyHost:     "127.0.0.1",
+			PropertyPort:     server.port(),
+			PropertyUsername: "guest",
+			PropertyPassword: "guest",
+		},
+		"rabbitmq.channels.default": map[string]any{"connection": "default"},
+		"rabbitmq.queues.orders":    map[string]any{PropertyChannel: "default", PropertyDurable: "maybe"},
+		"rabbitmq.exchanges.shop":   map[string]any{PropertyDurable: true},
+	})
+
+	connections, err := Connections(conf, logger)
+	if err != nil {
+		t.Fatalf("unable to connect: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = connections["default"].Close(context.Background())
+	})
+	channels, err := Channels(ChannelsParams{Conf: conf, Logger: logger, Connections: connections})
+	if err != nil {
+		t.Fatalf("unable to create channels: %v", err)
+	}
+
+	_, err = Queues(conf, logger, channels)
+	if err == nil || !strings.Contains(err.Error(), "queue orders") {
+		t.Errorf("Expected an error naming queue orders, got '%v'", err)
+	}
+	_, err = Exchanges(ExchangesParams{Conf: conf, Logger: logger, Channels: channels})
+	if err == nil || !strings.Contains(err.Error(), "exchange shop") {
+		t.Errorf("Expected an error naming exchange shop, got '%v'", err)
+	}
+}
+
+func TestTopology_ReportsUndeclaredBindingTargets(t *testing.T) {
+	logger := log.NewFactory(zap.NewNop())
+	shop := map[string]*Exchange{"shop": {Config: &ExchangeConfig{Name: "shop"}}}
+
+	cases := []struct {
+		binding   map[string]any
+		exchanges map[string]*Exchange
+		expected  string
+	}{
+		{
+			binding:  map[string]any{PropertyExchange: "shop", PropertyQueue: "orders"},
+			expected: "binding 0: exchange shop is not declared",
+		},
+		{
+			binding:   map[string]any{PropertyExchange: "shop", PropertyQueue: "orders"},
+			exchanges: shop,
+			expected:  "binding 0: queue orders is not declared",
+		},
+		{
+			binding:   map[string]any{PropertyExchange: "shop", PropertyDestinationExchange: "orders"},
+			exchanges: shop,
+			expected:  "binding 0: destination exchange orders is not declared",
+		},
+	}
+	for _, c := range cases {
+		conf := configtest.New(t, map[string]any{"rabbitmq.bindings": []any{c.binding}})
+
+		err := Bindings(BindingsParams{Conf: conf, Logger: logger, Exchanges: c.exchanges})
+		if err == nil || !strings.Contains(err.Error(), c.expected) {
+			t.Errorf("Expected an error containing '%s', got '%v'", c.expected, err)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package rabbitmq
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// workerPool handles the deliveries of a consumer with a fixed number of workers.
+// In ordered mode, every routing key is assigned to a single worker,
+// so that the deliveries with the same routing key are handled one at a time, in order.
+type workerPool struct {
+	queues []chan amqp091.Delivery
+	wg     sync.WaitGroup
+}
+
+func newWorkerPool(concurrency int, ordered bool, handle func(d amqp091.Delivery)) *workerPool {
+	p := &workerPool{}
+
+	if ordered {
+		p.queues = make([]chan amqp091.Delivery, concurrency)
+		for i := range p.queues {
+			p.queues[i] = make(chan amqp091.Delivery)
+		}
+	} else {
+		p.queues = []chan amqp091.Delivery{make(chan amqp091.Delivery)}
+	}
+
+	p.wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		queue := p.queues[i%len(p.queues)]
+		go func() {
+			defer p.wg.Done()
+			for d := range queue {
+				handle(d)
+			}
+		}()
+	}
+
+	return p
+}
+
+// submit blocks until a worker accepts the delivery.
+func (p *workerPool) submit(d amqp091.Delivery) {
+	if len(p.queues) == 1 {
+		p.queues[0] <- d
+		return
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(originalRoutingKey(d)))
+	p.queues[hash.Sum32()%uint32(len(p.queues))] <- d
+}
+
+// close waits for the submitted deliveries to be handled, no delivery can be submitted afterward.
+func (p *workerPool) close() {
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.wg.Wait()
+}
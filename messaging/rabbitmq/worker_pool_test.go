@@ -0,0 +1,68 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+func TestWorkerPool_LimitsConcurrency(t *testing.T) {
+	var running, peak, handled atomic.Int32
+	pool := newWorkerPool(3, false, func(d amqp091.Delivery) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+		handled.Add(1)
+	})
+
+	for i := 0; i < 20; i++ {
+		pool.submit(amqp091.Delivery{RoutingKey: fmt.Sprintf("key-%d", i)})
+	}
+	pool.close()
+
+	if handled.Load() != 20 {
+		t.Errorf("Expected '%d' deliveries to be handled, got '%d'", 20, handled.Load())
+	}
+	if peak.Load() > 3 {
+		t.Errorf("Expected at most '%d' deliveries to be handled at the same time, got '%d'", 3, peak.Load())
+	}
+}
+
+func TestWorkerPool_OrdersDeliveriesByRoutingKey(t *testing.T) {
+	var mu sync.Mutex
+	handled := make(map[string][]uint64)
+	pool := newWorkerPool(4, true, func(d amqp091.Delivery) {
+		// deliveries handled later must not overtake the earlier ones
+		time.Sleep(time.Duration(10-d.DeliveryTag%10) * time.Millisecond / 10)
+		mu.Lock()
+		handled[d.RoutingKey] = append(handled[d.RoutingKey], d.DeliveryTag)
+		mu.Unlock()
+	})
+
+	for i := uint64(0); i < 60; i++ {
+		pool.submit(amqp091.Delivery{RoutingKey: fmt.Sprintf("key-%d", i%3), DeliveryTag: i})
+	}
+	pool.close()
+
+	for key, tags := range handled {
+		if len(tags) != 20 {
+			t.Errorf("Expected '%d' deliveries for '%s', got '%d'", 20, key, len(tags))
+		}
+		for i := 1; i < len(tags); i++ {
+			if tags[i] < tags[i-1] {
+				t.Errorf("Expected deliveries for '%s' to be handled in order, got '%v'", key, tags)
+				break
+			}
+		}
+	}
+}
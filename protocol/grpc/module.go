@@ -33,6 +33,7 @@ var module = fx.Module(
 		AsServerOption(NewServerOptionRequestLoggerStats),
 		// TODO: The order of interceptors matters. FX adds them randomly. Fix the order.
 		AsUnaryServerInterceptor(NewUnaryServerInterceptorProtoValidate),
+		AsUnaryServerInterceptor(NewUnaryServerInterceptorLocale),
 		AsUnaryServerInterceptor(NewUnaryServerInterceptorErrorHandler),
 		AsUnaryServerInterceptor(NewUnaryServerInterceptorPanicHandler),
 		AsStreamServerInterceptor(NewStreamServerInterceptorPanicHandler),
@@ -3,12 +3,16 @@ package grpc
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	serviceErr "github.com/enesanbar/go-service/core/errors"
 	"github.com/enesanbar/go-service/core/log"
+	"github.com/enesanbar/go-service/core/validation"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
@@ -17,6 +21,8 @@ type ServerOptionUnaryInterceptorErrorHandlerParams struct {
 	fx.In
 
 	Logger log.Factory
+	// Validator translates the validation errors of the handlers into the language of the 'accept-language' metadata.
+	Validator validation.Validator `name:"go_playground" optional:"true"`
 }
 
 // NewUnaryServerInterceptorErrorHandler creates a new gRPC server option for unary interceptor error handling.
@@ -43,6 +49,19 @@ func NewUnaryServerInterceptorErrorHandler(p ServerOptionUnaryInterceptorErrorHa
 		code := ErrorStatus(err)
 		message := serviceErr.ErrorMessage(err)
 
+		if p.Validator != nil {
+			// the interceptors of the group are chained in no particular order, so the locale
+			// interceptor may not have run yet; read the 'accept-language' metadata here as well
+			if violations := p.Validator.MessagesCtx(withAcceptLanguage(ctx), err); len(violations) > 0 {
+				err = validationStatus(message, violations)
+				p.Logger.For(ctx).With(
+					zap.String("method", info.FullMethod),
+					zap.Error(err),
+				).Error("gRPC unary interceptor validation error")
+				return m, err
+			}
+		}
+
 		//  TODO: Print only critical errors in ERROR level, others in WARN level
 		p.Logger.For(ctx).With(
 			zap.Error(errors.Unwrap(err)),
@@ -51,3 +70,20 @@ func NewUnaryServerInterceptorErrorHandler(p ServerOptionUnaryInterceptorErrorHa
 		return m, status.Error(code, message)
 	}
 }
+
+// validationStatus returns an InvalidArgument status with the translated violations as the field violations of its details.
+func validationStatus(message string, violations []validation.Error) error {
+	badRequest := &errdetails.BadRequest{}
+	for _, violation := range violations {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       violation.Field,
+			Description: fmt.Sprint(violation.Error),
+		})
+	}
+
+	st, err := status.New(codes.InvalidArgument, message).WithDetails(badRequest)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, message)
+	}
+	return st.Err()
+}
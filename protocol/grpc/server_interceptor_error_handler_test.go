@@ -0,0 +1,62 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/enesanbar/go-service/core/log"
+	"github.com/enesanbar/go-service/core/validation"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type createUserRequest struct {
+	Name string `validate:"required"`
+}
+
+func TestUnaryServerInterceptorErrorHandler_TranslatesWithoutLocaleInterceptor(t *testing.T) {
+	v, err := validation.NewGoPlayground(validation.Params{
+		Config: &validation.Config{Locales: []string{"en", "tr"}, DefaultLocale: "en"},
+	})
+	if err != nil {
+		t.Fatalf("unable to create validator: %v", err)
+	}
+	validationErr := v.Validate(createUserRequest{})
+	if validationErr == nil {
+		t.Fatal("Expected a validation error")
+	}
+
+	interceptor := NewUnaryServerInterceptorErrorHandler(ServerOptionUnaryInterceptorErrorHandlerParams{
+		Logger:    log.NewFactory(zap.NewNop()),
+		Validator: v,
+	})
+	handler := func(context.Context, any) (any, error) {
+		return nil, validationErr
+	}
+
+	for _, acceptLanguage := range []string{"en", "tr"} {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(MetadataKeyAcceptLanguage, acceptLanguage))
+		// the locale interceptor is not chained, the error handler reads the metadata itself
+		_, err := interceptor(ctx, createUserRequest{}, &grpc.UnaryServerInfo{FullMethod: "/users.Users/Create"}, handler)
+
+		st, _ := status.FromError(err)
+		if st.Code() != codes.InvalidArgument {
+			t.Fatalf("Expected code '%s' for '%s', got '%s'", codes.InvalidArgument, acceptLanguage, st.Code())
+		}
+
+		expected := v.MessagesCtx(validation.WithAcceptLanguage(context.Background(), acceptLanguage), validationErr)[0].Error
+		var violations []*errdetails.BadRequest_FieldViolation
+		for _, detail := range st.Details() {
+			if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+				violations = badRequest.GetFieldViolations()
+			}
+		}
+		if len(violations) != 1 || violations[0].GetDescription() != expected {
+			t.Errorf("Expected the violation to be translated to '%s' for '%s', got '%v'", expected, acceptLanguage, violations)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/enesanbar/go-service/core/validation"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// MetadataKeyAcceptLanguage is the metadata key the clients use to request the language of the messages.
+const MetadataKeyAcceptLanguage = "accept-language"
+
+// NewUnaryServerInterceptorLocale creates a new gRPC unary interceptor that injects
+// the 'accept-language' metadata into the context, so that validation messages are translated.
+func NewUnaryServerInterceptorLocale() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(withAcceptLanguage(ctx), req)
+	}
+}
+
+// withAcceptLanguage returns a copy of ctx carrying the 'accept-language' metadata of the incoming request, if any.
+func withAcceptLanguage(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	values := md.Get(MetadataKeyAcceptLanguage)
+	if len(values) == 0 {
+		return ctx
+	}
+
+	return validation.WithAcceptLanguage(ctx, strings.Join(values, ","))
+}
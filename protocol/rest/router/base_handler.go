@@ -5,6 +5,7 @@ import (
 
 	"github.com/enesanbar/go-service/core/errors"
 	"github.com/enesanbar/go-service/core/log"
+	"github.com/enesanbar/go-service/core/validation"
 	"github.com/labstack/echo/v4"
 	"go.uber.org/zap"
 )
@@ -39,6 +40,19 @@ func (bh BaseHandler) DecodeRequest(c echo.Context, requestObject interface{}) e
 	return nil
 }
 
+// Validate validates the request, passing the request context to the context-aware validators.
+// The violations are returned as the data of an EINVALID error, translated into the language
+// of the Accept-Language header, see middlewares.NewLocaleMiddleware.
+func (bh BaseHandler) Validate(c echo.Context, validator validation.Validator, request interface{}) error {
+	ctx := c.Request().Context()
+	if err := validator.ValidateCtx(ctx, request); err != nil {
+		return errors.NewInvalidError("Validate", "request validation failed", err).
+			SetData(validator.MessagesCtx(ctx, err))
+	}
+
+	return nil
+}
+
 func (bh *BaseHandler) NewSuccess(c echo.Context, responseObject interface{}, status int) error {
 	return c.JSON(status, NewApiResponse(status, responseObject, nil))
 }
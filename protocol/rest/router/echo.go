@@ -1,12 +1,14 @@
 package router
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/enesanbar/go-service/protocol/rest/router/middlewares"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/trace"
 
+	"github.com/enesanbar/go-service/core/cache"
 	"github.com/enesanbar/go-service/core/config"
 	"github.com/enesanbar/go-service/core/log"
 	"github.com/labstack/echo/v4"
@@ -21,6 +23,8 @@ type ApplierFunc func(router *echo.Group)
 type RouteConfig struct {
 	Path   string
 	Router ApplierFunc
+	// Cache enables the response cache of the GET routes of the group, if not nil.
+	Cache *middlewares.ResponseCacheConfig
 }
 
 type EchoServer struct {
@@ -41,9 +45,10 @@ type EchoParams struct {
 	HealthCheckerHandler *HealthCheckHandler
 	TracerProvider       *trace.TracerProvider
 	Propagator           propagation.TextMapPropagator
+	Cache                cache.Cache `optional:"true"`
 }
 
-func NewEchoRouter(p EchoParams) *EchoServer {
+func NewEchoRouter(p EchoParams) (*EchoServer, error) {
 	e := echo.New()
 
 	e.Use(middlewares.NewOtelMiddleware(middlewares.OtelMiddlewareParams{
@@ -68,7 +73,15 @@ func NewEchoRouter(p EchoParams) *EchoServer {
 
 	// apply routes
 	for _, route := range p.Routes {
-		group := contextRouter.Group(route.Path)
+		var routeMiddlewares []echo.MiddlewareFunc
+		if route.Cache != nil {
+			if p.Cache == nil && route.Cache.Cache == nil {
+				return nil, fmt.Errorf("response cache of the routes under '%s' requires a cache", route.Path)
+			}
+			routeMiddlewares = append(routeMiddlewares, middlewares.NewResponseCacheMiddleware(p.Cache, p.Logger, *route.Cache))
+		}
+
+		group := contextRouter.Group(route.Path, routeMiddlewares...)
 		route.Router(group)
 	}
 
@@ -85,7 +98,7 @@ func NewEchoRouter(p EchoParams) *EchoServer {
 		ContextRouter: contextRouter,
 		logger:        p.Logger,
 		config:        p.Config,
-	}
+	}, nil
 }
 
 func (es EchoServer) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
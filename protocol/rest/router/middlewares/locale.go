@@ -0,0 +1,27 @@
+package middlewares
+
+import (
+	"github.com/enesanbar/go-service/core/validation"
+	"github.com/labstack/echo/v4"
+)
+
+const HeaderAcceptLanguage = "Accept-Language"
+
+// NewLocaleMiddleware returns an echo middleware that
+// injects the value of the 'Accept-Language' header into the request context,
+// so that validation messages are translated into the requested language.
+func NewLocaleMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			acceptLanguage := c.Request().Header.Get(HeaderAcceptLanguage)
+			if acceptLanguage == "" {
+				return next(c)
+			}
+
+			ctx := validation.WithAcceptLanguage(c.Request().Context(), acceptLanguage)
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			return next(c)
+		}
+	}
+}
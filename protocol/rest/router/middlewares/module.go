@@ -5,6 +5,7 @@ import "go.uber.org/fx"
 var Module = fx.Provide(
 	//AsMiddleware(NewOtelMiddleware),
 	AsMiddleware(NewRequestIDMiddleware),
+	AsMiddleware(NewLocaleMiddleware),
 	AsMiddleware(NewLoggerMiddleware),
 	AsMiddleware(NewBodyDumpMiddleware),
 	// AsMiddleware(NewMetricsMiddleware),
@@ -0,0 +1,351 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/enesanbar/go-service/core/cache"
+	"github.com/enesanbar/go-service/core/log"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	HeaderCacheControl = "Cache-Control"
+	HeaderETag         = "ETag"
+	HeaderIfNoneMatch  = "If-None-Match"
+	HeaderAge          = "Age"
+	HeaderXCache       = "X-Cache"
+
+	responseCacheKeyPrefix = "http-response:"
+)
+
+// ResponseCacheConfig enables the response cache of a route group, see RouteConfig.
+type ResponseCacheConfig struct {
+	// TTL of the cached responses, zero means the default expiration of the cache.
+	// It is overridden by the max-age directive of the response, if present.
+	TTL time.Duration
+	// Vary lists the request headers that select different responses, e.g. Accept-Language.
+	Vary []string
+	// Cache holds the responses instead of the cache.Cache of the service, e.g. a named cache.
+	Cache cache.Cache
+	// SessionCookies are the cookies that authenticate the requests, e.g. session_id.
+	// Any cookie authenticates the requests if empty.
+	SessionCookies []string
+}
+
+// cachedResponse is stored in the cache as JSON, so that it can be held by any backend.
+type cachedResponse struct {
+	Status       int         `json:"status"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+	ETag         string      `json:"etag"`
+	LastModified time.Time   `json:"last_modified"`
+	StoredAt     time.Time   `json:"stored_at"`
+}
+
+// NewResponseCacheMiddleware returns an echo middleware that caches the successful responses of GET requests.
+//
+// Responses are keyed by the request path, the normalized query and the values of the Vary headers.
+// They are served with ETag and Last-Modified headers, and conditional requests are answered with 304 Not Modified.
+// Responses with 'Cache-Control: no-store' or 'private' are not cached, requests with 'no-store' bypass the cache,
+// and requests with 'no-cache' skip the cached response and refresh it.
+//
+// The responses are shared by the users, so the requests with an Authorization header or a session cookie
+// are served from the cache and cached only if the response is marked 'Cache-Control: public'.
+//
+// The response is buffered until the handler returns, so streaming handlers must not be cached.
+func NewResponseCacheMiddleware(c cache.Cache, logger log.Factory, cfg ResponseCacheConfig) echo.MiddlewareFunc {
+	if cfg.Cache != nil {
+		c = cfg.Cache
+	}
+
+	vary := make([]string, 0, len(cfg.Vary))
+	for _, header := range cfg.Vary {
+		vary = append(vary, http.CanonicalHeaderKey(header))
+	}
+	sort.Strings(vary)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			req := ctx.Request()
+			if req.Method != http.MethodGet {
+				return next(ctx)
+			}
+
+			requestDirectives := parseCacheControl(req.Header.Get(HeaderCacheControl))
+			if _, ok := requestDirectives["no-store"]; ok {
+				return next(ctx)
+			}
+
+			key := responseCacheKey(req, vary)
+			private := authenticated(req, cfg.SessionCookies)
+			if !revalidate(requestDirectives) {
+				if response, ok := loadResponse(ctx, c, logger, key); ok && (!private || public(parseCacheControl(response.Header.Get(HeaderCacheControl)))) {
+					ctx.Response().Header().Set(HeaderXCache, "HIT")
+					ctx.Response().Header().Set(HeaderAge, strconv.Itoa(int(time.Since(response.StoredAt).Seconds())))
+					return writeResponse(ctx, response)
+				}
+			}
+
+			res := ctx.Response()
+			recorder := newResponseRecorder(res.Writer)
+			res.Writer = recorder
+			err := next(ctx)
+			res.Writer = recorder.ResponseWriter
+
+			if err != nil || recorder.status != http.StatusOK {
+				// the error handler writes its own response, partial writes are passed as they are
+				recorder.flush()
+				return err
+			}
+
+			// the buffered response is written below
+			res.Committed = false
+			res.Size = 0
+
+			response := &cachedResponse{
+				Status:       recorder.status,
+				Header:       recorder.Header().Clone(),
+				Body:         recorder.body.Bytes(),
+				ETag:         recorder.Header().Get(HeaderETag),
+				LastModified: time.Now().UTC().Truncate(time.Second),
+				StoredAt:     time.Now(),
+			}
+			if response.ETag == "" {
+				sum := sha256.Sum256(response.Body)
+				response.ETag = `"` + hex.EncodeToString(sum[:16]) + `"`
+			}
+			if lastModified, err := http.ParseTime(recorder.Header().Get(echo.HeaderLastModified)); err == nil {
+				response.LastModified = lastModified
+			}
+			if len(vary) > 0 {
+				response.Header.Set(echo.HeaderVary, strings.Join(vary, ", "))
+			}
+
+			responseDirectives := parseCacheControl(recorder.Header().Get(HeaderCacheControl))
+			if storable(responseDirectives) && response.Header.Get(echo.HeaderSetCookie) == "" && (!private || public(responseDirectives)) {
+				storeResponse(ctx, c, logger, key, response, ttl(responseDirectives, cfg.TTL))
+			}
+
+			ctx.Response().Header().Set(HeaderXCache, "MISS")
+			return writeResponse(ctx, response)
+		}
+	}
+}
+
+// writeResponse writes the response, or 304 Not Modified if the client holds the same representation.
+func writeResponse(ctx echo.Context, response *cachedResponse) error {
+	header := ctx.Response().Header()
+	for name, values := range response.Header {
+		header[name] = values
+	}
+	header.Set(HeaderETag, response.ETag)
+	header.Set(echo.HeaderLastModified, response.LastModified.UTC().Format(http.TimeFormat))
+
+	// 304 is written here rather than returned as an ENOTMODIFIED error, as the error handlers write a body
+	// which a 304 must not have, and the ETag and Last-Modified headers of the representation must be kept.
+	if notModified(ctx.Request(), response) {
+		header.Del(echo.HeaderContentType)
+		header.Del(echo.HeaderContentLength)
+		return ctx.NoContent(http.StatusNotModified)
+	}
+
+	ctx.Response().WriteHeader(response.Status)
+	_, err := ctx.Response().Write(response.Body)
+	return err
+}
+
+// notModified evaluates the conditional headers of the request, If-None-Match taking precedence.
+func notModified(req *http.Request, response *cachedResponse) bool {
+	if match := req.Header.Get(HeaderIfNoneMatch); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(response.ETag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(req.Header.Get(echo.HeaderIfModifiedSince))
+	if err != nil {
+		return false
+	}
+	return !response.LastModified.Truncate(time.Second).After(since)
+}
+
+func loadResponse(ctx echo.Context, c cache.Cache, logger log.Factory, key string) (*cachedResponse, bool) {
+	value, err := c.Get(ctx.Request().Context(), key)
+	if err != nil {
+		return nil, false
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return nil, false
+	}
+
+	response := &cachedResponse{}
+	if err := json.Unmarshal(data, response); err != nil {
+		logger.For(ctx.Request().Context()).With(zap.Error(err)).Error("unable to decode cached response")
+		return nil, false
+	}
+	return response, true
+}
+
+func storeResponse(ctx echo.Context, c cache.Cache, logger log.Factory, key string, response *cachedResponse, ttl time.Duration) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		logger.For(ctx.Request().Context()).With(zap.Error(err)).Error("unable to encode response")
+		return
+	}
+
+	if err := c.Set(ctx.Request().Context(), key, data, cache.WithTTL(ttl)); err != nil {
+		logger.For(ctx.Request().Context()).With(zap.Error(err)).Error("unable to cache response")
+	}
+}
+
+// responseCacheKey hashes the path, the query with sorted keys and values, and the Vary headers.
+func responseCacheKey(req *http.Request, vary []string) string {
+	query := req.URL.Query()
+	for _, values := range query {
+		sort.Strings(values)
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(req.URL.Path))
+	hash.Write([]byte{0})
+	hash.Write([]byte(url.Values(query).Encode()))
+	for _, header := range vary {
+		hash.Write([]byte{0})
+		hash.Write([]byte(header + ":" + req.Header.Get(header)))
+	}
+
+	return responseCacheKeyPrefix + hex.EncodeToString(hash.Sum(nil))
+}
+
+// parseCacheControl returns the directives of a Cache-Control header by their lowercase names.
+func parseCacheControl(value string) map[string]string {
+	directives := make(map[string]string)
+	for _, directive := range strings.Split(value, ",") {
+		directive = strings.TrimSpace(directive)
+		if directive == "" {
+			continue
+		}
+		name, argument, _ := strings.Cut(directive, "=")
+		directives[strings.ToLower(name)] = strings.Trim(argument, `"`)
+	}
+	return directives
+}
+
+// authenticated reports whether the request carries credentials, an Authorization header or a session cookie.
+func authenticated(req *http.Request, sessionCookies []string) bool {
+	if req.Header.Get(echo.HeaderAuthorization) != "" {
+		return true
+	}
+	if len(sessionCookies) == 0 {
+		return len(req.Cookies()) > 0
+	}
+	for _, name := range sessionCookies {
+		if _, err := req.Cookie(name); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// public reports whether the response may be shared by the users, even if the request is authenticated.
+func public(directives map[string]string) bool {
+	_, ok := directives["public"]
+	return ok
+}
+
+func revalidate(directives map[string]string) bool {
+	if _, ok := directives["no-cache"]; ok {
+		return true
+	}
+	return directives["max-age"] == "0"
+}
+
+func storable(directives map[string]string) bool {
+	for _, name := range []string{"no-store", "private", "no-cache"} {
+		if _, ok := directives[name]; ok {
+			return false
+		}
+	}
+	return directives["max-age"] != "0"
+}
+
+// ttl returns the max-age of the response, falling back to the configured ttl.
+func ttl(directives map[string]string, fallback time.Duration) time.Duration {
+	for _, name := range []string{"s-maxage", "max-age"} {
+		if seconds, err := strconv.Atoi(directives[name]); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return fallback
+}
+
+// responseRecorder buffers the response of the handler.
+// It holds the headers set by the handler apart from the ones set by the preceding middlewares,
+// so that only the former are cached.
+type responseRecorder struct {
+	http.ResponseWriter
+	header      http.Header
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{
+		ResponseWriter: w,
+		header:         make(http.Header),
+		status:         http.StatusOK,
+	}
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+	r.status = status
+	r.wroteHeader = true
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.body.Write(b)
+}
+
+// flush writes the buffered response to the underlying writer.
+func (r *responseRecorder) flush() {
+	header := r.ResponseWriter.Header()
+	for name, values := range r.header {
+		header[name] = values
+	}
+
+	if !r.wroteHeader {
+		return
+	}
+	r.ResponseWriter.WriteHeader(r.status)
+	_, _ = r.ResponseWriter.Write(r.body.Bytes())
+}
@@ -0,0 +1,238 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/enesanbar/go-service/core/cache"
+	"github.com/enesanbar/go-service/core/log"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type mapCache struct {
+	mu     sync.Mutex
+	values map[string]interface{}
+}
+
+func (m *mapCache) Get(_ context.Context, key string) (interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	value, ok := m.values[key]
+	if !ok {
+		return nil, cache.ErrKeyNotFound
+	}
+	return value, nil
+}
+
+func (m *mapCache) Set(_ context.Context, key string, value interface{}, _ ...cache.SetOption) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.values[key] = value
+	return nil
+}
+
+func (m *mapCache) Invalidate(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.values, key)
+	return nil
+}
+
+func (m *mapCache) InvalidateTags(_ context.Context, _ ...string) error {
+	return nil
+}
+
+func (m *mapCache) InvalidatePrefix(_ context.Context, prefix string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key := range m.values {
+		if strings.HasPrefix(key, prefix) {
+			delete(m.values, key)
+		}
+	}
+	return nil
+}
+
+func newResponseCacheServer(cfg ResponseCacheConfig) (*echo.Echo, *int) {
+	calls := 0
+	e := echo.New()
+	e.Use(NewRequestIDMiddleware())
+
+	group := e.Group("/products", NewResponseCacheMiddleware(
+		&mapCache{values: make(map[string]interface{})},
+		log.NewFactory(zap.NewNop()),
+		cfg,
+	))
+	group.GET("", func(c echo.Context) error {
+		calls++
+		return c.JSON(http.StatusOK, map[string]string{
+			"page":     c.QueryParam("page"),
+			"language": c.Request().Header.Get(HeaderAcceptLanguage),
+		})
+	})
+	group.GET("/private", func(c echo.Context) error {
+		calls++
+		c.Response().Header().Set(HeaderCacheControl, "private")
+		return c.String(http.StatusOK, "private")
+	})
+	group.GET("/public", func(c echo.Context) error {
+		calls++
+		c.Response().Header().Set(HeaderCacheControl, "public, max-age=60")
+		return c.String(http.StatusOK, "public")
+	})
+	group.GET("/missing", func(c echo.Context) error {
+		calls++
+		return c.String(http.StatusNotFound, "missing")
+	})
+
+	return e, &calls
+}
+
+func serve(e *echo.Echo, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestResponseCache_ServesCachedResponse(t *testing.T) {
+	e, calls := newResponseCacheServer(ResponseCacheConfig{})
+
+	first := serve(e, "/products?page=1&sort=name", nil)
+	second := serve(e, "/products?sort=name&page=1", nil)
+
+	if *calls != 1 {
+		t.Errorf("Expected handler to be called '%d' times, got '%d'", 1, *calls)
+	}
+	if first.Header().Get(HeaderXCache) != "MISS" || second.Header().Get(HeaderXCache) != "HIT" {
+		t.Errorf("Expected 'MISS' then 'HIT', got '%s' and '%s'", first.Header().Get(HeaderXCache), second.Header().Get(HeaderXCache))
+	}
+	if first.Body.String() != second.Body.String() {
+		t.Errorf("Expected cached body to be '%s', got '%s'", first.Body.String(), second.Body.String())
+	}
+	if first.Header().Get(HeaderETag) == "" || first.Header().Get(HeaderETag) != second.Header().Get(HeaderETag) {
+		t.Errorf("Expected the same ETag, got '%s' and '%s'", first.Header().Get(HeaderETag), second.Header().Get(HeaderETag))
+	}
+	if second.Header().Get(echo.HeaderContentType) != echo.MIMEApplicationJSON {
+		t.Errorf("Expected content type to be '%s', got '%s'", echo.MIMEApplicationJSON, second.Header().Get(echo.HeaderContentType))
+	}
+	if first.Header().Get(echo.HeaderXRequestID) == second.Header().Get(echo.HeaderXRequestID) {
+		t.Error("Expected the request id not to be served from the cache")
+	}
+}
+
+func TestResponseCache_ConditionalRequests(t *testing.T) {
+	e, _ := newResponseCacheServer(ResponseCacheConfig{})
+
+	first := serve(e, "/products", nil)
+
+	rec := serve(e, "/products", map[string]string{HeaderIfNoneMatch: first.Header().Get(HeaderETag)})
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("Expected status to be '%d' without body, got '%d' with '%s'", http.StatusNotModified, rec.Code, rec.Body.String())
+	}
+
+	rec = serve(e, "/products", map[string]string{HeaderIfNoneMatch: `"other"`})
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status to be '%d', got '%d'", http.StatusOK, rec.Code)
+	}
+
+	rec = serve(e, "/products", map[string]string{echo.HeaderIfModifiedSince: first.Header().Get(echo.HeaderLastModified)})
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("Expected status to be '%d', got '%d'", http.StatusNotModified, rec.Code)
+	}
+}
+
+func TestResponseCache_Vary(t *testing.T) {
+	e, calls := newResponseCacheServer(ResponseCacheConfig{Vary: []string{"accept-language"}})
+
+	serve(e, "/products", map[string]string{HeaderAcceptLanguage: "en"})
+	rec := serve(e, "/products", map[string]string{HeaderAcceptLanguage: "tr"})
+	serve(e, "/products", map[string]string{HeaderAcceptLanguage: "tr"})
+
+	if *calls != 2 {
+		t.Errorf("Expected handler to be called '%d' times, got '%d'", 2, *calls)
+	}
+	if rec.Header().Get(echo.HeaderVary) != HeaderAcceptLanguage {
+		t.Errorf("Expected vary to be '%s', got '%s'", HeaderAcceptLanguage, rec.Header().Get(echo.HeaderVary))
+	}
+}
+
+func TestResponseCache_CacheControl(t *testing.T) {
+	e, calls := newResponseCacheServer(ResponseCacheConfig{})
+
+	serve(e, "/products/private", nil)
+	serve(e, "/products/private", nil)
+	if *calls != 2 {
+		t.Errorf("Expected private responses not to be cached, handler called '%d' times", *calls)
+	}
+
+	*calls = 0
+	serve(e, "/products", nil)
+	serve(e, "/products", map[string]string{HeaderCacheControl: "no-cache"})
+	serve(e, "/products", map[string]string{HeaderCacheControl: "no-store"})
+	if *calls != 3 {
+		t.Errorf("Expected requests with no-cache and no-store to reach the handler, handler called '%d' times", *calls)
+	}
+
+	*calls = 0
+	rec := serve(e, "/products/missing", nil)
+	serve(e, "/products/missing", nil)
+	if *calls != 2 || rec.Code != http.StatusNotFound || rec.Body.String() != "missing" {
+		t.Errorf("Expected unsuccessful responses to be passed through, got '%d' with '%s'", rec.Code, rec.Body.String())
+	}
+}
+
+func TestResponseCache_AuthenticatedRequests(t *testing.T) {
+	e, calls := newResponseCacheServer(ResponseCacheConfig{})
+
+	// the responses of the authenticated requests are not shared
+	first := serve(e, "/products", map[string]string{echo.HeaderAuthorization: "Bearer alice"})
+	second := serve(e, "/products", map[string]string{echo.HeaderAuthorization: "Bearer bob"})
+	serve(e, "/products", map[string]string{"Cookie": "session=alice"})
+	if *calls != 3 || first.Header().Get(HeaderXCache) != "MISS" || second.Header().Get(HeaderXCache) != "MISS" {
+		t.Errorf("Expected authenticated requests to reach the handler, handler called '%d' times", *calls)
+	}
+
+	// the anonymous responses are not served to the authenticated requests
+	*calls = 0
+	serve(e, "/products", nil)
+	rec := serve(e, "/products", map[string]string{echo.HeaderAuthorization: "Bearer alice"})
+	if *calls != 2 || rec.Header().Get(HeaderXCache) != "MISS" {
+		t.Errorf("Expected the cached response not to be served to authenticated requests, handler called '%d' times", *calls)
+	}
+
+	// unless they are public
+	*calls = 0
+	serve(e, "/products/public", map[string]string{echo.HeaderAuthorization: "Bearer alice"})
+	rec = serve(e, "/products/public", map[string]string{echo.HeaderAuthorization: "Bearer bob"})
+	if *calls != 1 || rec.Header().Get(HeaderXCache) != "HIT" {
+		t.Errorf("Expected public responses to be cached for authenticated requests, handler called '%d' times", *calls)
+	}
+}
+
+func TestResponseCache_SessionCookies(t *testing.T) {
+	e, calls := newResponseCacheServer(ResponseCacheConfig{SessionCookies: []string{"session"}})
+
+	serve(e, "/products", map[string]string{"Cookie": "theme=dark"})
+	serve(e, "/products", map[string]string{"Cookie": "theme=dark"})
+	if *calls != 1 {
+		t.Errorf("Expected requests without the session cookie to be cached, handler called '%d' times", *calls)
+	}
+
+	rec := serve(e, "/products", map[string]string{"Cookie": "theme=dark; session=alice"})
+	if *calls != 2 || rec.Header().Get(HeaderXCache) != "MISS" {
+		t.Errorf("Expected requests with the session cookie to reach the handler, handler called '%d' times", *calls)
+	}
+}